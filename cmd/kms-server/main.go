@@ -3,74 +3,232 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/siderolabs/kms-client/api/kms"
 	"github.com/soulkyu/talos-kms-vault/pkg/auth"
 	"github.com/soulkyu/talos-kms-vault/pkg/leaderelection"
 	"github.com/soulkyu/talos-kms-vault/pkg/server"
+	"github.com/soulkyu/talos-kms-vault/pkg/tracing"
 	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
 )
 
 var kmsFlags struct {
 	apiEndpoint        string
 	mountPath          string
+	verifyMount        string
 	disableValidation  bool
 	allowUUIDVersions  string
 	uuidValidationMode string
 	disableEntropy     bool
+	entropyLevel       string
+	entropyExempt      string
+	selfTestUUID       string
+	validationFailOpen bool
+	entropyMinUnique   int
+	validationFile     string
+	authConfigFile     string
+	checkConfig        bool
 	enableTLS          bool
 	tlsCertFile        string
 	tlsKeyFile         string
+	tlsClientCAFile    string
+	nodeIdentityField  string
+	tlsClientEKU       bool
+	tlsClientOrg       string
+	tlsClientOU        string
+	operationPolicy    string
+	operationField     string
+	globalRateLimit    float64
+	globalBurst        int
+	preloadKeysFile    string
+	requestLogFile     string
+	requestLogMaxSize  int64
+	auditIncludeDigest bool
+	logVaultRequestID  bool
+	enableTracing      bool
+	maxSealSize        int
+	maxUnsealSize      int
+	minDecryptVersion  int
+	minEncryptVersion  int
+	sealWrapTTL        time.Duration
+	sealDegradedAfter  int
+	sealFormatVersion  int
+	maxCiphertextAge   time.Duration
+	requestTimeout     time.Duration
+	shutdownTimeout    time.Duration
+	unsealCacheTTL     time.Duration
+	unsealCacheMax     int
+	nodeCacheMax       int
+	maintenanceMessage string
+
+	// Metadata policy flags
+	metadataPolicy      bool
+	metadataMaxEntries  int
+	metadataMaxSize     int
+	metadataAllowedKeys string
+	metadataNodeUUID    bool
 
 	// Leader election flags
-	enableLeaderElection        bool
-	leaderElectionNamespace     string
-	leaderElectionName          string
-	leaderElectionLeaseDuration time.Duration
-	leaderElectionRenewDeadline time.Duration
-	leaderElectionRetryPeriod   time.Duration
+	enableLeaderElection         bool
+	leaderElectionKubeconfig     string
+	allowNonClusterElection      bool
+	leaderElectionNamespace      string
+	leaderElectionName           string
+	leaderElectionLeaseDuration  time.Duration
+	leaderElectionRenewDeadline  time.Duration
+	leaderElectionRetryPeriod    time.Duration
+	leaderElectionReleaseTimeout time.Duration
+	leaderElectionReleasedWait   time.Duration
+	leaderElectionMaxFlaps       int
+	leaderElectionFlapWindow     time.Duration
+	leaderElectionFlapAction     string
+	leaderElectionCollision      string
+	leaderElectionHistorySize    int
+	leaderServingDelay           time.Duration
+	leaderReadinessWarmup        time.Duration
+	hideLeaderIdentity           bool
+	leaderlessFailAfter          time.Duration
+	minHealthyPeers              int
+	peerService                  string
 
 	// Health server flags
 	healthServerEnabled bool
 	healthServerAddr    string
+	metricsEndpoint     string
+	enableKeyAdmin      bool
+	keyAdminAddr        string
+	readyCheckVault     bool
+	vaultHealthInterval time.Duration
+
+	// Vault seal status flags
+	vaultSealCheckInterval time.Duration
+	vaultStandbyForwarding bool
+	sealFailOnStandby      bool
 }
 
 func main() {
 	flag.StringVar(&kmsFlags.apiEndpoint, "kms-api-endpoint", ":8080", "gRPC API endpoint for the KMS")
 	flag.StringVar(&kmsFlags.mountPath, "mount-path", "transit", "Mount path for the Transit secret engine")
+	flag.StringVar(&kmsFlags.verifyMount, "verify-mount", string(server.MountVerifyOff), "Check at startup that the mount path is a transit engine via sys/mounts: off, warn or fail")
 	flag.BoolVar(&kmsFlags.disableValidation, "disable-validation", false, "Disable UUID validation (NOT recommended for production)")
 	flag.StringVar(&kmsFlags.allowUUIDVersions, "allow-uuid-versions", "v4", "Allowed UUID versions (v4, v1-v5, or any)")
 	flag.StringVar(&kmsFlags.uuidValidationMode, "uuid-validation-mode", "strict", "UUID validation mode (strict or relaxed)")
 	flag.BoolVar(&kmsFlags.disableEntropy, "disable-entropy-check", false, "Disable entropy checking for UUIDs")
+	flag.StringVar(&kmsFlags.entropyLevel, "entropy-level", "basic", "UUID entropy check level (basic, or strict to add a byte distribution test)")
+	flag.StringVar(&kmsFlags.validationFile, "validation-config-file", "", "JSON file of UUID validation settings keyed by flag name, re-read on SIGHUP")
+	flag.StringVar(&kmsFlags.authConfigFile, "auth-config-file", "", "JSON file of VAULT_* auth settings overriding the environment, re-read on SIGHUP to switch auth without a restart")
+	flag.IntVar(&kmsFlags.entropyMinUnique, "entropy-min-unique-chars", validation.DefaultMinUniqueChars, "Minimum number of distinct hex digits in a node UUID for the entropy check (0-16)")
+	flag.StringVar(&kmsFlags.entropyExempt, "entropy-exempt-uuids", "", "Comma-separated node UUIDs exempt from the entropy check (for legacy nodes with predictable UUIDs)")
+	flag.BoolVar(&kmsFlags.validationFailOpen, "validation-fail-open", false, "Let requests through with a warning when the validator fails internally instead of rejecting them (not recommended)")
+	flag.StringVar(&kmsFlags.selfTestUUID, "self-test-uuid", validation.DefaultSelfTestUUID, "Node UUID reserved for an internal self-test, rejected from clients (empty disables)")
 	flag.BoolVar(&kmsFlags.enableTLS, "enable-tls", false, "Enable TLS/HTTPS for gRPC server")
 	flag.StringVar(&kmsFlags.tlsCertFile, "tls-cert", "server.crt", "Path to TLS certificate file")
 	flag.StringVar(&kmsFlags.tlsKeyFile, "tls-key", "server.key", "Path to TLS private key file")
+	flag.StringVar(&kmsFlags.tlsClientCAFile, "tls-client-ca", "", "Path to CA bundle for verifying client certificates (enables mTLS)")
+	flag.BoolVar(&kmsFlags.tlsClientEKU, "tls-client-require-eku", true, "Require mTLS client certificates to carry the clientAuth extended key usage")
+	flag.StringVar(&kmsFlags.tlsClientOrg, "tls-client-org", "", "Organization (O) mTLS client certificates must belong to (empty disables)")
+	flag.StringVar(&kmsFlags.tlsClientOU, "tls-client-ou", "", "Organizational unit (OU) mTLS client certificates must belong to (empty disables)")
+	flag.StringVar(&kmsFlags.operationPolicy, "operation-policy-file", "", "JSON file mapping mTLS client identities to allowed operations, reloaded on change (empty allows all)")
+	flag.StringVar(&kmsFlags.operationField, "operation-policy-field", "cn", "Client certificate field identifying clients in the operation policy (cn, dns-san, uri-san)")
+	flag.StringVar(&kmsFlags.nodeIdentityField, "node-identity-field", "", "Client certificate field that must match the node UUID under mTLS (cn, dns-san, uri-san)")
+	flag.Float64Var(&kmsFlags.globalRateLimit, "global-rate-limit", 0, "Maximum requests per second across all clients (0 disables)")
+	flag.BoolVar(&kmsFlags.enableTracing, "enable-tracing", false, "Enable OpenTelemetry tracing (configured via OTEL_EXPORTER_OTLP_* environment variables)")
+	flag.StringVar(&kmsFlags.preloadKeysFile, "preload-keys-file", "", "File listing node UUIDs (one per line) whose transit keys are created/verified at startup")
+	flag.IntVar(&kmsFlags.minDecryptVersion, "min-decryption-version", 0, "Raise min_decryption_version on node keys to at least this version (0 disables)")
+	flag.IntVar(&kmsFlags.minEncryptVersion, "min-encryption-version", 0, "Raise min_encryption_version on node keys to at least this version (0 disables)")
+	flag.IntVar(&kmsFlags.sealDegradedAfter, "seal-degraded-threshold", server.DefaultSealDegradedThreshold, "Mark Seal degraded after this many consecutive Vault permission denials while still serving Unseal (0 disables)")
+	flag.DurationVar(&kmsFlags.requestTimeout, "request-timeout", server.DefaultRequestTimeout, "Maximum time a single Seal/Unseal request may take (0 disables)")
+	flag.DurationVar(&kmsFlags.shutdownTimeout, "shutdown-timeout", server.DefaultShutdownTimeout, "Maximum time in-flight requests may take to finish on shutdown before they are cancelled and the Vault token is revoked")
+	flag.DurationVar(&kmsFlags.unsealCacheTTL, "unseal-cache-ttl", 0, "Serve repeated Unseal requests from an in-memory cache for this long (0 disables; cached entries hold plaintext)")
+	flag.IntVar(&kmsFlags.unsealCacheMax, "unseal-cache-max-entries", server.DefaultUnsealCacheMaxEntries, "Maximum number of cached Unseal responses; the least recently used is evicted when full")
+	flag.IntVar(&kmsFlags.nodeCacheMax, "node-cache-max-entries", server.DefaultNodeCacheCapacity, "Maximum number of nodes whose per-node state (such as observed key versions) is kept; the least recently used is evicted when full")
+	flag.StringVar(&kmsFlags.maintenanceMessage, "maintenance-message", server.DefaultMaintenanceMessage, "Message returned to Seal/Unseal callers while maintenance mode is enabled via POST /maintenance on -key-admin-addr")
+	flag.IntVar(&kmsFlags.sealFormatVersion, "seal-format-version", server.SealFormatRaw, "Seal output format: 0 returns the raw transit ciphertext, 1 prepends a versioned header (Unseal accepts both)")
+	flag.DurationVar(&kmsFlags.maxCiphertextAge, "max-ciphertext-age", 0, "Refuse to Unseal data sealed in the v1 format longer ago than this (0 disables; advisory only: the seal time is not authenticated and raw ciphertext is never rejected)")
+	flag.DurationVar(&kmsFlags.sealWrapTTL, "seal-response-wrap-ttl", 0, "Return Seal output as a Vault response-wrapping token with this TTL; incompatible with stock Talos clients (0 disables)")
+	flag.IntVar(&kmsFlags.maxSealSize, "max-seal-size", 0, "Maximum Seal request data size in bytes (0 uses the 4MB request limit)")
+	flag.IntVar(&kmsFlags.maxUnsealSize, "max-unseal-size", 0, "Maximum Unseal request data size in bytes (0 uses the 4MB request limit)")
+	flag.StringVar(&kmsFlags.requestLogFile, "request-log-file", "", "Write sanitized request metadata as JSON lines to this file for DR analysis (empty disables)")
+	flag.Int64Var(&kmsFlags.requestLogMaxSize, "request-log-max-size", server.DefaultRequestLogMaxSize, "Size in bytes at which the request log is rotated to <file>.1")
+	flag.BoolVar(&kmsFlags.logVaultRequestID, "log-vault-request-id", false, "Log the Vault request ID of each Transit call and add it to the request log, to join KMS logs with the Vault audit log")
+	flag.BoolVar(&kmsFlags.auditIncludeDigest, "audit-include-digest", false, "Add short SHA-256 digests of request and response data to the request log for correlation")
+	flag.IntVar(&kmsFlags.globalBurst, "global-burst", 0, "Burst size for the global rate limit (defaults to one second of requests)")
+
+	// Metadata policy flags
+	defaultMetadataPolicy := validation.DefaultMetadataPolicyConfig()
+	flag.BoolVar(&kmsFlags.metadataPolicy, "metadata-policy", false, "Reject requests with unexpected or oversized gRPC metadata")
+	flag.IntVar(&kmsFlags.metadataMaxEntries, "metadata-max-entries", defaultMetadataPolicy.MaxEntries, "Maximum number of gRPC metadata entries per request (0 disables)")
+	flag.IntVar(&kmsFlags.metadataMaxSize, "metadata-max-size", defaultMetadataPolicy.MaxSize, "Maximum combined gRPC metadata size in bytes (0 disables)")
+	flag.StringVar(&kmsFlags.metadataAllowedKeys, "metadata-allowed-keys", strings.Join(defaultMetadataPolicy.AllowedKeys, ","), "Comma-separated allowlist of gRPC metadata keys (empty allows all)")
+	flag.BoolVar(&kmsFlags.metadataNodeUUID, "metadata-node-uuid", false, "Use the x-node-uuid metadata header when the request has no node UUID, and reject requests where they disagree")
 
 	// Leader election flags
 	flag.BoolVar(&kmsFlags.enableLeaderElection, "enable-leader-election", false, "Enable leader election for multi-instance deployments")
+	flag.StringVar(&kmsFlags.leaderElectionKubeconfig, "leader-election-kubeconfig", "", "Kubeconfig used for leader election outside a Kubernetes pod (default: in-cluster config)")
+	flag.BoolVar(&kmsFlags.allowNonClusterElection, "leader-election-allow-noncluster", false, "Fall back to single-instance mode when leader election is enabled outside Kubernetes without a kubeconfig")
 	flag.StringVar(&kmsFlags.leaderElectionNamespace, "leader-election-namespace", leaderelection.GetNamespaceFromEnv(), "Kubernetes namespace for leader election")
 	flag.StringVar(&kmsFlags.leaderElectionName, "leader-election-name", leaderelection.GetLeaseNameFromEnv(), "Name of the leader election lease")
 	flag.DurationVar(&kmsFlags.leaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, "Duration of the leader election lease")
 	flag.DurationVar(&kmsFlags.leaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, "Deadline for renewing the leadership lease")
 	flag.DurationVar(&kmsFlags.leaderElectionRetryPeriod, "leader-election-retry-period", 2*time.Second, "Retry period for leadership acquisition")
+	flag.DurationVar(&kmsFlags.leaderElectionReleaseTimeout, "leader-election-release-timeout", 5*time.Second, "Timeout for each attempt to release the lease on shutdown (retried once)")
+	flag.DurationVar(&kmsFlags.leaderElectionReleasedWait, "leader-election-released-lease-wait", 2*time.Second, "How long candidates wait before acquiring a lease that was just released (0 acquires it immediately)")
+	flag.IntVar(&kmsFlags.leaderElectionMaxFlaps, "leader-election-max-flaps", 0, "Leadership changes tolerated within the flap window before alerting (0 disables flap detection)")
+	flag.DurationVar(&kmsFlags.leaderElectionFlapWindow, "leader-election-flap-window", leaderelection.DefaultFlapWindow, "Sliding window over which leadership changes are counted")
+	flag.IntVar(&kmsFlags.leaderElectionHistorySize, "leader-election-history-size", leaderelection.DefaultHistorySize, "Number of recent leadership transitions kept for the /leader endpoint")
+	flag.StringVar(&kmsFlags.leaderElectionFlapAction, "leader-election-flap-action", string(leaderelection.FlapActionAlert), "Action when leadership flaps: alert, exit (restart the pod) or observe (stop competing for one flap window)")
+	flag.StringVar(&kmsFlags.leaderElectionCollision, "leader-election-identity-collision-action", string(leaderelection.CollisionActionDecline), "Action when another process holds the lease with this identity: decline (stay a follower) or exit (restart the pod)")
+	flag.DurationVar(&kmsFlags.leaderServingDelay, "leader-serving-delay", 0, "Delay before a new leader serves requests, at least one lease duration when set (0 disables)")
+	flag.DurationVar(&kmsFlags.leaderReadinessWarmup, "leader-readiness-warmup", 0, "Keep a new leader unready for up to this long until a Vault check succeeds (0 disables)")
+	flag.BoolVar(&kmsFlags.hideLeaderIdentity, "hide-leader-identity", false, "Omit the leader identity from not-leader errors and /ready (still returned as a detail to mTLS clients)")
+	flag.DurationVar(&kmsFlags.leaderlessFailAfter, "leaderless-fail-after", 0, "Return non-retryable FAILED_PRECONDITION instead of UNAVAILABLE once no leader has been elected for this long (0 disables)")
+	flag.IntVar(&kmsFlags.minHealthyPeers, "min-healthy-peers", 0, "Keep the leader unready unless at least this many other candidates back the peer service (0 disables)")
+	flag.StringVar(&kmsFlags.peerService, "peer-service", "", "Headless service publishing not-ready addresses whose EndpointSlices list the leader election candidates, required by -min-healthy-peers")
 
 	// Health server flags
 	flag.BoolVar(&kmsFlags.healthServerEnabled, "health-server", true, "Enable health check server")
 	flag.StringVar(&kmsFlags.healthServerAddr, "health-server-addr", ":8081", "Health check server address")
+	flag.StringVar(&kmsFlags.metricsEndpoint, "metrics-endpoint", "", "Dedicated address serving only /metrics (default: /metrics is served by the health server)")
+	flag.BoolVar(&kmsFlags.enableKeyAdmin, "enable-key-admin", false, "Serve /admin/keys/ (list and delete node transit keys), /maintenance and /auth/renew on -key-admin-addr")
+	flag.StringVar(&kmsFlags.keyAdminAddr, "key-admin-addr", "127.0.0.1:8082", "Loopback address serving /admin/keys/, /maintenance and /auth/renew when -enable-key-admin is set")
+	flag.BoolVar(&kmsFlags.readyCheckVault, "ready-check-vault", false, "Report not ready when Vault is unreachable, sealed or uninitialized")
+	flag.DurationVar(&kmsFlags.vaultSealCheckInterval, "vault-seal-check-interval", 30*time.Second, "Interval between Vault seal status checks gating readiness (0 disables)")
+	flag.BoolVar(&kmsFlags.vaultStandbyForwarding, "vault-standby-forwarding", true, "Whether Vault standby nodes forward requests to the active node")
+	flag.BoolVar(&kmsFlags.sealFailOnStandby, "seal-fail-on-standby", false, "Fail Seal immediately while Vault is a standby and -vault-standby-forwarding=false")
+	flag.DurationVar(&kmsFlags.vaultHealthInterval, "vault-health-interval", 5*time.Second, "Minimum interval between Vault health checks (backs off up to 12x while failing)")
+	flag.BoolVar(&kmsFlags.checkConfig, "check-config", false, "Validate the auth, validation and leader election configuration, print a JSON report and exit without serving or contacting Vault")
 	flag.Parse()
 
+	// The report goes to stdout, so logs go to stderr in check mode
+	if kmsFlags.checkConfig {
+		if !checkConfig(slog.New(slog.NewJSONHandler(os.Stderr, nil)), os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
@@ -78,19 +236,55 @@ func main() {
 
 	if err := run(ctx, logger); err != nil {
 		logger.Error("Error during initialization", "error", err)
+		cancel()
+		os.Exit(1)
 	}
 }
 
 func run(ctx context.Context, logger *slog.Logger) error {
-	// Create authentication configuration from environment
-	authConfig := auth.NewAuthConfigFromEnvironment()
+	// Create authentication configuration from the environment and the optional auth config file
+	authConfig, err := loadAuthConfig()
+	if err != nil {
+		return err
+	}
 
 	// Validate configuration
 	if err := auth.ValidateConfig(authConfig); err != nil {
 		return err
 	}
 
-	logger.Info("Initializing authentication", "method", authConfig.Method)
+	validationConfig, err := createValidationConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := checkLeaderElectionEnvironment(logger, leaderelection.InCluster()); err != nil {
+		return err
+	}
+
+	logEffectiveConfig(logger, authConfig, validationConfig)
+
+	// Set up tracing before creating Vault clients so their transport is instrumented
+	if kmsFlags.enableTracing {
+		shutdownTracing, err := tracing.Setup(ctx, "talos-kms-vault")
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := shutdownTracing(shutdownCtx); err != nil {
+				logger.Error("Failed to flush traces", "error", err)
+			}
+		}()
+
+		authConfig.TransportWrapper = tracing.WrapTransport
+		logger.Info("OpenTelemetry tracing enabled")
+	}
+
+	logger.Info("Initializing authentication", "method", authConfig.Method, "methods", authConfig.Methods)
 
 	// Create authentication manager
 	authManager, err := auth.NewManager(authConfig, logger)
@@ -98,20 +292,37 @@ func run(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 
-	// Start authentication and token renewal
+	// A token replaced on SIGHUP or by a forced renewal stays valid while requests that took the
+	// previous client may still be running
+	revokeGrace := kmsFlags.requestTimeout
+	if revokeGrace <= 0 {
+		revokeGrace = server.DefaultRequestTimeout
+	}
+	authManager.SetRevokeGracePeriod(revokeGrace)
+
+	// Start authentication and token renewal. Every replica keeps its token renewed whatever
+	// its leadership, so a promoted follower serves without logging in first.
 	if err := authManager.Start(ctx); err != nil {
 		return err
 	}
 
-	// Ensure we clean up authentication on exit
-	defer func() {
+	// Revoke the token on exit. Once serving, this runs after the gRPC server has drained,
+	// so no in-flight request can still need the token.
+	stopAuth := sync.OnceFunc(func() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
 		if err := authManager.Stop(shutdownCtx); err != nil {
 			logger.Error("Failed to stop auth manager", "error", err)
 		}
-	}()
+	})
+	defer stopAuth()
+
+	go authManager.SampleTokenMetrics(ctx, auth.DefaultTokenSampleInterval)
+
+	if kmsFlags.authConfigFile != "" {
+		go reloadAuthOnSIGHUP(ctx, authManager, authConfig.TransportWrapper, logger)
+	}
 
 	// Get authenticated Vault client
 	client, err := authManager.GetClient()
@@ -120,28 +331,112 @@ func run(ctx context.Context, logger *slog.Logger) error {
 	}
 
 	srv := server.NewServer(client, logger, kmsFlags.mountPath)
+	srv.SetClientSource(authManager.GetClient)
+	srv.SetNodeCacheCapacity(kmsFlags.nodeCacheMax)
+	srv.SetMinKeyVersions(kmsFlags.minDecryptVersion, kmsFlags.minEncryptVersion)
+	srv.SetSealDegradedThreshold(kmsFlags.sealDegradedAfter)
+	srv.SetMaintenanceMessage(kmsFlags.maintenanceMessage)
+	if err := srv.SetSealFormatVersion(kmsFlags.sealFormatVersion); err != nil {
+		return err
+	}
+	srv.SetMaxCiphertextAge(kmsFlags.maxCiphertextAge)
+	srv.SetLogVaultRequestID(kmsFlags.logVaultRequestID)
+
+	// Catch a mistyped or non-transit mount path before the first Seal fails
+	mountVerifyMode, err := server.ParseMountVerifyMode(kmsFlags.verifyMount)
+	if err != nil {
+		return err
+	}
+	if err := srv.VerifyMount(ctx, mountVerifyMode); err != nil {
+		return err
+	}
+
+	if kmsFlags.sealWrapTTL > 0 {
+		srv.SetResponseWrapTTL(kmsFlags.sealWrapTTL)
+		logger.Warn("Seal responses are response-wrapped - clients must unwrap them via sys/wrapping/unwrap, stock Talos clients will fail to unseal",
+			"wrapTTL", kmsFlags.sealWrapTTL)
+	}
+
+	// Cached Vault health check shared by probes and the /vault/health endpoint
+	vaultHealth := server.NewVaultHealthChecker(
+		server.VaultSysHealthCheck(authManager.GetClient),
+		kmsFlags.vaultHealthInterval,
+		12*kmsFlags.vaultHealthInterval,
+	)
+	if kmsFlags.readyCheckVault {
+		srv.SetVaultHealthChecker(vaultHealth)
+	}
+
+	// Report not ready while Vault itself is sealed
+	if kmsFlags.vaultSealCheckInterval > 0 {
+		sealMonitor := server.NewSealStatusMonitor(
+			server.VaultSealStatusCheck(authManager.GetClient),
+			kmsFlags.vaultSealCheckInterval,
+			kmsFlags.vaultStandbyForwarding,
+			logger,
+		)
+		sealMonitor.Start(ctx)
+		srv.SetSealStatusMonitor(sealMonitor)
+		srv.SetFailSealOnStandby(kmsFlags.sealFailOnStandby)
+	} else if kmsFlags.sealFailOnStandby {
+		logger.Warn("Seal fail-fast on standby requires the Vault seal status check - ignoring it",
+			"vaultSealCheckInterval", kmsFlags.vaultSealCheckInterval)
+	}
 
 	// Create validation middleware based on flags
-	validationConfig := createValidationConfig()
 	validationMiddleware := validation.NewValidationMiddlewareFromConfig(validationConfig, logger)
 
 	if !validationConfig.Enabled {
 		logger.Warn("UUID validation is DISABLED - this is not recommended for production")
+	} else if !validationConfig.CheckEntropy {
+		logger.Warn("UUID entropy checking is DISABLED - predictable node UUIDs will be accepted, this is not recommended for production")
+	} else if len(validationConfig.EntropyExemptUUIDs) > 0 {
+		logger.Warn("UUID entropy checking is skipped for exempt node UUIDs",
+			"exemptUUIDs", len(validationConfig.EntropyExemptUUIDs))
+	}
+
+	if validationConfig.Enabled && validationConfig.FailOpen {
+		logger.Warn("Validation fails open - requests are let through unvalidated when the validator fails internally")
+	}
+
+	go reloadValidationOnSIGHUP(ctx, validationMiddleware, logger)
+
+	// Node UUIDs whose transit keys are warmed at startup
+	var preloadUUIDs []string
+	if kmsFlags.preloadKeysFile != "" {
+		preloadUUIDs, err = server.LoadPreloadUUIDs(kmsFlags.preloadKeysFile)
+		if err != nil {
+			logger.Warn("Skipping transit key preload", "error", err)
+		}
+		preloadUUIDs = server.FilterPreloadUUIDs(preloadUUIDs, validationConfig.NodeUUIDValidator(), logger)
 	}
 
 	// Determine which server to use (leader-aware or regular)
 	var kmsServer kms.KMSServiceServer
 	var leaderAwareServer *server.LeaderAwareServer
-	var healthHandler http.Handler
+	var healthHandler *http.ServeMux
+	var metricsHandler http.Handler
+	var electionFatal <-chan error
 
 	if kmsFlags.enableLeaderElection {
 		// Create leader election configuration
-		leaseConfig := createLeaderElectionConfig(logger)
+		leaseConfig, err := createLeaderElectionConfig(logger)
+		if err != nil {
+			return fmt.Errorf("invalid leader election configuration: %w", err)
+		}
 
-		// Create election controller with callbacks
+		// Callbacks resolve the leader-aware server lazily; they only fire after Start,
+		// by which point it has been created around the same controller
 		callbackBuilder := leaderelection.NewCallbackBuilder(logger)
-		electionController, err := leaderelection.NewElectionController(leaseConfig,
-			leaderelection.LeaderElectionCallbacks{}, logger)
+		callbackBuilder.SetIdentity(leaseConfig.Identity)
+		callbacks := callbackBuilder.BuildGracefulShutdownCallbacks(
+			func(ctx context.Context) { leaderAwareServer.OnBecomeLeader(ctx) },
+			func() { leaderAwareServer.OnLoseLeadership() },
+			5*time.Second,
+		)
+		callbacks.OnNewLeader = func(leader string) { leaderAwareServer.OnLeaderChange(leader) }
+
+		electionController, err := leaderelection.NewElectionController(leaseConfig, callbacks, logger)
 		if err != nil {
 			return fmt.Errorf("failed to create election controller: %w", err)
 		}
@@ -149,18 +444,29 @@ func run(ctx context.Context, logger *slog.Logger) error {
 		// Create leader-aware server
 		leaderAwareServer = server.NewLeaderAwareServer(srv, electionController, logger)
 
-		// Set up callbacks
-		callbacks := callbackBuilder.BuildGracefulShutdownCallbacks(
-			leaderAwareServer.OnBecomeLeader,
-			leaderAwareServer.OnLoseLeadership,
-			5*time.Second,
-		)
-		callbacks.OnNewLeader = leaderAwareServer.OnLeaderChange
+		if kmsFlags.leaderServingDelay > 0 {
+			servingDelay := kmsFlags.leaderServingDelay
+			if servingDelay < leaseConfig.LeaseDuration {
+				logger.Warn("Leader serving delay is shorter than the lease duration - using the lease duration",
+					"servingDelay", servingDelay,
+					"leaseDuration", leaseConfig.LeaseDuration)
+				servingDelay = leaseConfig.LeaseDuration
+			}
+			leaderAwareServer.SetServingDelay(servingDelay)
+		}
 
-		// Update election controller with callbacks
-		electionController, err = leaderelection.NewElectionController(leaseConfig, callbacks, logger)
-		if err != nil {
-			return fmt.Errorf("failed to create election controller with callbacks: %w", err)
+		leaderAwareServer.SetReadinessWarmup(kmsFlags.leaderReadinessWarmup)
+		leaderAwareServer.SetPreloadKeys(preloadUUIDs)
+		leaderAwareServer.SetHideLeaderIdentity(kmsFlags.hideLeaderIdentity)
+		leaderAwareServer.SetLeaderlessFailAfter(kmsFlags.leaderlessFailAfter)
+		leaderAwareServer.SetTokenStatus(authManager)
+
+		if kmsFlags.minHealthyPeers > 0 {
+			peerCounter, err := createPeerCounter(leaseConfig)
+			if err != nil {
+				return fmt.Errorf("invalid minimum healthy peers configuration: %w", err)
+			}
+			leaderAwareServer.SetMinHealthyPeers(kmsFlags.minHealthyPeers, peerCounter)
 		}
 
 		// Start leader election
@@ -170,36 +476,189 @@ func run(ctx context.Context, logger *slog.Logger) error {
 
 		defer electionController.Stop()
 
+		electionFatal = electionController.Fatal()
+
 		kmsServer = leaderAwareServer
 		healthHandler = leaderAwareServer.CreateHealthHandler()
+		metricsHandler = leaderAwareServer.MetricsHandler()
 		logger.Info("Leader election enabled", "identity", leaseConfig.Identity)
 	} else {
 		kmsServer = srv
 		healthHandler = srv.CreateHealthHandler()
+		metricsHandler = srv.MetricsHandler()
+
+		if len(preloadUUIDs) > 0 {
+			go srv.PreloadKeys(ctx, preloadUUIDs)
+		}
+
 		logger.Info("Running in single-instance mode (no leader election)")
 	}
 
+	// Metrics share the health port unless a dedicated endpoint is configured
+	if kmsFlags.metricsEndpoint == "" {
+		healthHandler.Handle("/metrics", metricsHandler)
+	}
+
+	// Admin endpoints
+	healthHandler.Handle("/auth", server.NewAuthStatusHandler(authManager))
+	healthHandler.Handle("/vault/health", server.NewVaultHealthHandler(vaultHealth))
+
+	statsSources := server.AdminStatsSources{VaultHealth: vaultHealth, Auth: authManager}
+	if validationMiddleware != nil {
+		statsSources.Validation = validationMiddleware
+	}
+	if leaderAwareServer != nil {
+		statsSources.Leadership = leaderAwareServer
+	}
+	// Validation is disabled without a middleware, so the startup config stays in effect
+	currentValidationConfig := func() *validation.ValidationConfig { return validationConfig }
+	if validationMiddleware != nil {
+		currentValidationConfig = validationMiddleware.Config
+	}
+	healthHandler.Handle("/config/validation", server.NewValidationConfigHandler(currentValidationConfig))
+
+	statsHandler := server.NewAdminStatsHandler(statsSources)
+	healthHandler.Handle("/stats", statsHandler)
+	healthHandler.Handle("/admin/stats", statsHandler)
+
 	// Create gRPC server with validation middleware
 	var grpcOptions []grpc.ServerOption
+	if kmsFlags.enableTracing {
+		grpcOptions = append(grpcOptions, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+
+	// Unary interceptors run in this order, outermost first:
+	//  1. recovery: turns a panic anywhere below into an Internal error
+	//  2. in-flight: counts every request currently being handled
+	//  3. metrics: records the final code and latency of every request, rejections included
+	//  4. Talos version meter: counts every request by client Talos version
+	//  5. request recorder (opt-in): records every request, rejections included
+	//  6-11. global rate limit, metadata policy, metadata node UUID, validation, node
+	//     identity, operation policy: the cheapest checks reject first
+	//  12. request timeout: bounds only the time spent in the handler and Vault
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		server.RecoveryInterceptor(logger),
+		server.InflightInterceptor(),
+		server.MetricsInterceptor(),
+		server.NewTalosVersionMeter(server.DefaultMaxTalosVersions).UnaryServerInterceptor(),
+	}
+	go server.SampleGoroutines(ctx, server.DefaultGoroutineSampleInterval)
+
+	// The request recorder runs before all policy checks so rejected requests are recorded too
+	if kmsFlags.requestLogFile != "" {
+		recorder, err := server.NewRequestRecorder(kmsFlags.requestLogFile, kmsFlags.requestLogMaxSize, logger)
+		if err != nil {
+			return err
+		}
+		defer recorder.Close()
+
+		recorder.SetIncludeDigest(kmsFlags.auditIncludeDigest)
+		recorder.SetIncludeVaultRequestID(kmsFlags.logVaultRequestID)
+
+		unaryInterceptors = append(unaryInterceptors, recorder.UnaryServerInterceptor())
+		logger.Info("Request recording enabled",
+			"path", kmsFlags.requestLogFile,
+			"maxSize", kmsFlags.requestLogMaxSize,
+			"includeDigest", kmsFlags.auditIncludeDigest,
+			"includeVaultRequestId", kmsFlags.logVaultRequestID)
+	} else if kmsFlags.auditIncludeDigest {
+		logger.Warn("Audit digests enabled without a request log - they will not be recorded")
+	}
+
+	// Global rate limiting runs before the policy checks so rejected requests cost as little as possible
+	if kmsFlags.globalRateLimit > 0 {
+		globalLimiter := server.NewGlobalRateLimiter(kmsFlags.globalRateLimit, kmsFlags.globalBurst, logger)
+		unaryInterceptors = append(unaryInterceptors, globalLimiter.UnaryServerInterceptor())
+		logger.Info("Global rate limit enabled",
+			"requestsPerSecond", kmsFlags.globalRateLimit,
+			"burst", kmsFlags.globalBurst)
+	}
+
+	// Metadata policy runs before validation so smuggled headers are rejected early
+	if metadataPolicy := validation.NewMetadataPolicy(createMetadataPolicyConfig(), logger); metadataPolicy != nil {
+		unaryInterceptors = append(unaryInterceptors, metadataPolicy.UnaryServerInterceptor())
+		logger.Info("Metadata policy enabled",
+			"maxEntries", kmsFlags.metadataMaxEntries,
+			"maxSize", kmsFlags.metadataMaxSize,
+			"allowedKeys", kmsFlags.metadataAllowedKeys)
+	}
+
+	// The metadata node UUID is reconciled before validation so the UUID used is validated
+	if kmsFlags.metadataNodeUUID {
+		unaryInterceptors = append(unaryInterceptors, validation.NewNodeUUIDMetadata(logger).UnaryServerInterceptor())
+		logger.Info("Node UUID metadata reconciliation enabled", "header", validation.NodeUUIDMetadataKey)
+	}
+
 	if validationMiddleware != nil {
-		grpcOptions = append(grpcOptions,
-			grpc.UnaryInterceptor(validationMiddleware.UnaryServerInterceptor()))
+		unaryInterceptors = append(unaryInterceptors, validationMiddleware.UnaryServerInterceptor())
 	}
 
 	// Add TLS credentials if enabled
 	if kmsFlags.enableTLS {
-		cert, err := tls.LoadX509KeyPair(kmsFlags.tlsCertFile, kmsFlags.tlsKeyFile)
+		tlsConfig, err := createTLSConfig(logger)
 		if err != nil {
-			logger.Error("Failed to load TLS certificate", "error", err)
+			logger.Error("Failed to configure TLS", "error", err)
 			return err
 		}
 
-		creds := credentials.NewServerTLSFromCert(&cert)
-		grpcOptions = append(grpcOptions, grpc.Creds(creds))
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
 
-		logger.Info("TLS enabled", "cert", kmsFlags.tlsCertFile, "key", kmsFlags.tlsKeyFile)
+		logger.Info("TLS enabled",
+			"cert", kmsFlags.tlsCertFile,
+			"key", kmsFlags.tlsKeyFile,
+			"mtls", kmsFlags.tlsClientCAFile != "")
 	}
 
+	// Enforce node identity matching against client certificates (mTLS only)
+	if kmsFlags.nodeIdentityField != "" {
+		if kmsFlags.tlsClientCAFile == "" {
+			logger.Warn("Node identity matching configured without mTLS - it will have no effect",
+				"field", kmsFlags.nodeIdentityField)
+		}
+
+		identityMatcher, err := validation.NewNodeIdentityMatcher(
+			validation.IdentityField(kmsFlags.nodeIdentityField), logger)
+		if err != nil {
+			return fmt.Errorf("invalid node identity configuration: %w", err)
+		}
+
+		unaryInterceptors = append(unaryInterceptors, identityMatcher.UnaryServerInterceptor())
+		logger.Info("Node identity matching enabled", "field", kmsFlags.nodeIdentityField)
+	}
+
+	// Restrict operations per client identity (mTLS only)
+	if kmsFlags.operationPolicy != "" {
+		if kmsFlags.tlsClientCAFile == "" {
+			logger.Warn("Operation policy configured without mTLS - it will have no effect",
+				"path", kmsFlags.operationPolicy)
+		}
+
+		operationPolicy, err := validation.NewOperationPolicy(kmsFlags.operationPolicy,
+			validation.IdentityField(kmsFlags.operationField), logger)
+		if err != nil {
+			return fmt.Errorf("invalid operation policy: %w", err)
+		}
+
+		go operationPolicy.Watch(ctx, 10*time.Second)
+
+		unaryInterceptors = append(unaryInterceptors, operationPolicy.UnaryServerInterceptor())
+	}
+
+	// The Unseal cache lives in the server, behind the leadership and maintenance checks
+	if kmsFlags.unsealCacheTTL > 0 {
+		srv.SetUnsealCache(server.NewUnsealCache(kmsFlags.unsealCacheTTL, kmsFlags.unsealCacheMax))
+		logger.Warn("Unseal response cache enabled - decrypted data is kept in memory",
+			"ttl", kmsFlags.unsealCacheTTL,
+			"maxEntries", kmsFlags.unsealCacheMax)
+	}
+
+	unaryInterceptors = append(unaryInterceptors, server.TimeoutInterceptor(kmsFlags.requestTimeout))
+
+	grpcOptions = append(grpcOptions, grpc.ChainUnaryInterceptor(unaryInterceptors...))
+
+	// Stopping waits for handlers to return, so the token is never revoked under a running request
+	grpcOptions = append(grpcOptions, grpc.WaitForHandlers(true))
+
 	grpcSrv := grpc.NewServer(grpcOptions...)
 
 	kms.RegisterKMSServiceServer(grpcSrv, kmsServer)
@@ -225,28 +684,94 @@ func run(ctx context.Context, logger *slog.Logger) error {
 	var healthServer *server.HealthServer
 	if kmsFlags.healthServerEnabled {
 		healthServer = server.NewHealthServer(kmsFlags.healthServerAddr, logger)
-		if err := healthServer.Start(healthHandler); err != nil {
-			return fmt.Errorf("failed to start health server: %w", err)
+		eg.Go(func() error {
+			return healthServer.Serve(healthHandler)
+		})
+	}
+
+	// Node key deletion is irreversible, maintenance mode fails every request and a forced renewal
+	// calls Vault, and none of them is authenticated, so they are opt-in and loopback-only
+	var keyAdminServer *server.HealthServer
+	if kmsFlags.enableKeyAdmin {
+		if err := server.CheckLoopbackAddr(kmsFlags.keyAdminAddr); err != nil {
+			return fmt.Errorf("invalid key admin address: %w", err)
 		}
+
+		keyAdminMux := http.NewServeMux()
+		keyAdminMux.Handle("/admin/keys/", server.NewNodeKeysHandler(srv, logger))
+		keyAdminMux.Handle("/maintenance", server.NewMaintenanceHandler(srv, logger))
+		keyAdminMux.Handle("/auth/renew", server.NewAuthRenewHandler(authManager, logger))
+
+		keyAdminServer = server.NewKeyAdminServer(kmsFlags.keyAdminAddr, logger)
+		eg.Go(func() error {
+			return keyAdminServer.Serve(keyAdminMux)
+		})
+	}
+
+	// Serve metrics on their own listener when requested
+	var metricsServer *server.HealthServer
+	if kmsFlags.metricsEndpoint != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", metricsHandler)
+
+		metricsServer = server.NewMetricsServer(kmsFlags.metricsEndpoint, logger)
+		eg.Go(func() error {
+			return metricsServer.Serve(metricsMux)
+		})
 	}
 
 	eg.Go(func() error {
 		return grpcSrv.Serve(lis)
 	})
 
+	// Exit when token renewal gives up so the orchestrator restarts the pod
+	eg.Go(func() error {
+		select {
+		case err := <-authManager.Fatal():
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	})
+
+	// Exit when leadership flapping is configured to restart the pod
+	eg.Go(func() error {
+		select {
+		case err := <-electionFatal:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	})
+
 	eg.Go(func() error {
 		<-ctx.Done()
 
-		// Shutdown health server
+		// Shutdown health and metrics servers
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
 		if healthServer != nil {
-			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
 			if err := healthServer.Stop(shutdownCtx); err != nil {
 				logger.Error("Failed to stop health server", "error", err)
 			}
 		}
 
-		grpcSrv.Stop()
+		if metricsServer != nil {
+			if err := metricsServer.Stop(shutdownCtx); err != nil {
+				logger.Error("Failed to stop metrics server", "error", err)
+			}
+		}
+
+		if keyAdminServer != nil {
+			if err := keyAdminServer.Stop(shutdownCtx); err != nil {
+				logger.Error("Failed to stop key admin server", "error", err)
+			}
+		}
+
+		// Let in-flight requests finish before revoking the token they use
+		server.DrainGRPC(grpcSrv, kmsFlags.shutdownTimeout, logger)
+		stopAuth()
 
 		return nil
 	})
@@ -258,20 +783,530 @@ func run(ctx context.Context, logger *slog.Logger) error {
 	return nil
 }
 
-// createValidationConfig creates validation config from command line flags and environment
-func createValidationConfig() *validation.ValidationConfig {
+// configCheck is the outcome of checking one part of the configuration
+type configCheck struct {
+	Name   string `json:"name"`
+	Valid  bool   `json:"valid"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// configReport is the summary printed by -check-config
+type configReport struct {
+	Valid  bool          `json:"valid"`
+	Checks []configCheck `json:"checks"`
+}
+
+// checkConfig resolves the auth, validation and leader election configuration the way startup
+// does and writes a JSON report to out, without opening listeners or contacting Vault. It
+// reports whether every part is valid.
+func checkConfig(logger *slog.Logger, out io.Writer) bool {
+	report := configReport{Valid: true}
+	record := func(name, detail string, err error) {
+		check := configCheck{Name: name, Valid: err == nil, Detail: detail}
+		if err != nil {
+			check.Error = err.Error()
+			report.Valid = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	authConfig, err := loadAuthConfig()
+	if err == nil {
+		err = auth.ValidateConfig(authConfig)
+	}
+	var authDetail string
+	if authConfig != nil {
+		authDetail = "method " + string(authConfig.Method)
+		if len(authConfig.Methods) > 1 {
+			authDetail = fmt.Sprintf("methods %v", authConfig.Methods)
+		}
+	}
+	record("auth", authDetail, err)
+
+	validationConfig, err := createValidationConfig()
+	var validationDetail string
+	if validationConfig != nil && !validationConfig.Enabled {
+		validationDetail = "disabled"
+	}
+	record("validation", validationDetail, err)
+
+	leaderDetail, err := checkLeaderElectionConfig(logger)
+	record("leaderElection", leaderDetail, err)
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Error("Failed to write the configuration report", "error", err)
+		return false
+	}
+
+	return report.Valid
+}
+
+// checkLeaderElectionConfig validates the leader election configuration when leader election
+// is enabled, returning a short description of the outcome
+func checkLeaderElectionConfig(logger *slog.Logger) (string, error) {
+	if err := checkLeaderElectionEnvironment(logger, leaderelection.InCluster()); err != nil {
+		return "", err
+	}
+
+	if !kmsFlags.enableLeaderElection {
+		return "disabled", nil
+	}
+
+	leaseConfig, err := createLeaderElectionConfig(logger)
+	if err != nil {
+		return "", err
+	}
+
+	if err := leaseConfig.Validate(); err != nil {
+		return "", err
+	}
+
+	if kmsFlags.minHealthyPeers > 0 && kmsFlags.peerService == "" {
+		return "", fmt.Errorf("-min-healthy-peers requires -peer-service")
+	}
+
+	return "lease " + leaseConfig.Namespace + "/" + leaseConfig.Name, nil
+}
+
+// logEffectiveConfig logs the resolved configuration as a single structured line, with secrets redacted
+func logEffectiveConfig(logger *slog.Logger, authConfig *auth.AuthConfig, validationConfig *validation.ValidationConfig) {
+	authAttrs := []any{
+		"method", authConfig.Method,
+		"methods", authConfig.Methods,
+		"vaultAddr", authConfig.VaultAddr,
+		"autoRenew", authConfig.AutoRenew,
+		"maxRenewalFailureDuration", authConfig.MaxRenewalFailureDuration,
+		"renewalJitter", authConfig.RenewalJitter,
+		"maxTokenAge", authConfig.MaxTokenAge,
+	}
+
+	if retry := authConfig.Retry; retry != nil {
+		authAttrs = append(authAttrs,
+			"maxRetries", retry.MaxRetries,
+			"retryWaitMin", retry.WaitMin,
+			"retryWaitMax", retry.WaitMax)
+	}
+
+	switch {
+	case authConfig.Token != nil:
+		authAttrs = append(authAttrs, "token", redact(authConfig.Token.Token))
+	case authConfig.Kubernetes != nil:
+		authAttrs = append(authAttrs,
+			"role", authConfig.Kubernetes.Role,
+			"mountPath", authConfig.Kubernetes.MountPath,
+			"namespaceRoles", authConfig.Kubernetes.NamespaceRoleMap)
+	case authConfig.AppRole != nil:
+		authAttrs = append(authAttrs,
+			"roleID", redact(authConfig.AppRole.RoleID),
+			"secretID", redact(authConfig.AppRole.SecretID),
+			"mountPath", authConfig.AppRole.MountPath)
+	}
+
+	logger.Info("Effective configuration",
+		slog.Group("auth", authAttrs...),
+		slog.Group("server",
+			"apiEndpoint", kmsFlags.apiEndpoint,
+			"mountPath", kmsFlags.mountPath,
+			"verifyMount", kmsFlags.verifyMount,
+			"preloadKeysFile", kmsFlags.preloadKeysFile,
+			"requestLogFile", kmsFlags.requestLogFile,
+			"auditIncludeDigest", kmsFlags.auditIncludeDigest,
+			"logVaultRequestId", kmsFlags.logVaultRequestID,
+			"globalRateLimit", kmsFlags.globalRateLimit,
+			"globalBurst", kmsFlags.globalBurst,
+			"minDecryptionVersion", kmsFlags.minDecryptVersion,
+			"minEncryptionVersion", kmsFlags.minEncryptVersion,
+			"sealResponseWrapTTL", kmsFlags.sealWrapTTL,
+			"sealDegradedThreshold", kmsFlags.sealDegradedAfter,
+			"sealFormatVersion", kmsFlags.sealFormatVersion,
+			"maxCiphertextAge", kmsFlags.maxCiphertextAge,
+			"requestTimeout", kmsFlags.requestTimeout,
+			"shutdownTimeout", kmsFlags.shutdownTimeout,
+			"unsealCacheTTL", kmsFlags.unsealCacheTTL,
+			"unsealCacheMaxEntries", kmsFlags.unsealCacheMax,
+			"nodeCacheMaxEntries", kmsFlags.nodeCacheMax,
+			"maintenanceMessage", kmsFlags.maintenanceMessage,
+			"tracing", kmsFlags.enableTracing),
+		slog.Group("tls",
+			"enabled", kmsFlags.enableTLS,
+			"cert", kmsFlags.tlsCertFile,
+			"key", kmsFlags.tlsKeyFile,
+			"clientCA", kmsFlags.tlsClientCAFile,
+			"clientRequireEKU", kmsFlags.tlsClientEKU,
+			"clientOrg", kmsFlags.tlsClientOrg,
+			"clientOU", kmsFlags.tlsClientOU,
+			"operationPolicy", kmsFlags.operationPolicy,
+			"operationPolicyField", kmsFlags.operationField,
+			"nodeIdentityField", kmsFlags.nodeIdentityField),
+		slog.Group("leaderElection",
+			"enabled", kmsFlags.enableLeaderElection,
+			"kubeconfig", kmsFlags.leaderElectionKubeconfig,
+			"namespace", kmsFlags.leaderElectionNamespace,
+			"name", kmsFlags.leaderElectionName,
+			"leaseDuration", kmsFlags.leaderElectionLeaseDuration,
+			"renewDeadline", kmsFlags.leaderElectionRenewDeadline,
+			"retryPeriod", kmsFlags.leaderElectionRetryPeriod,
+			"releaseTimeout", kmsFlags.leaderElectionReleaseTimeout,
+			"releasedLeaseWait", kmsFlags.leaderElectionReleasedWait,
+			"maxFlaps", kmsFlags.leaderElectionMaxFlaps,
+			"flapWindow", kmsFlags.leaderElectionFlapWindow,
+			"flapAction", kmsFlags.leaderElectionFlapAction,
+			"identityCollisionAction", kmsFlags.leaderElectionCollision,
+			"historySize", kmsFlags.leaderElectionHistorySize,
+			"servingDelay", kmsFlags.leaderServingDelay,
+			"readinessWarmup", kmsFlags.leaderReadinessWarmup,
+			"hideLeaderIdentity", kmsFlags.hideLeaderIdentity,
+			"leaderlessFailAfter", kmsFlags.leaderlessFailAfter,
+			"minHealthyPeers", kmsFlags.minHealthyPeers,
+			"peerService", kmsFlags.peerService),
+		slog.Group("validation",
+			"enabled", validationConfig.Enabled,
+			"configFile", kmsFlags.validationFile,
+			"uuidMode", validationConfig.UUIDValidationMode,
+			"requireUUIDv4", validationConfig.RequireUUIDv4,
+			"checkEntropy", validationConfig.CheckEntropy,
+			"entropyLevel", validationConfig.EntropyLevel,
+			"minUniqueChars", validationConfig.MinUniqueChars,
+			"entropyExemptUUIDs", len(validationConfig.EntropyExemptUUIDs),
+			"selfTestUUID", validationConfig.SelfTestUUID,
+			"maxRequestSize", validationConfig.MaxRequestSize,
+			"maxSealSize", validationConfig.MaxSealSize,
+			"maxUnsealSize", validationConfig.MaxUnsealSize,
+			"failOpen", validationConfig.FailOpen,
+			"metadataNodeUUID", kmsFlags.metadataNodeUUID),
+		slog.Group("healthServer",
+			"enabled", kmsFlags.healthServerEnabled,
+			"addr", kmsFlags.healthServerAddr,
+			"metricsEndpoint", kmsFlags.metricsEndpoint,
+			"enableKeyAdmin", kmsFlags.enableKeyAdmin,
+			"keyAdminAddr", kmsFlags.keyAdminAddr,
+			"readyCheckVault", kmsFlags.readyCheckVault,
+			"vaultHealthInterval", kmsFlags.vaultHealthInterval,
+			"vaultSealCheckInterval", kmsFlags.vaultSealCheckInterval,
+			"vaultStandbyForwarding", kmsFlags.vaultStandbyForwarding,
+			"sealFailOnStandby", kmsFlags.sealFailOnStandby),
+	)
+}
+
+// redact hides secret values while still showing whether they are set
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return "<redacted>"
+}
+
+// certExpiryWarning is how close to expiry the server certificate must be to log a warning
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// createTLSConfig creates the gRPC server TLS config, enabling mTLS when a client CA is configured
+func createTLSConfig(logger *slog.Logger) (*tls.Config, error) {
+	if err := checkTLSFile("-tls-cert", kmsFlags.tlsCertFile); err != nil {
+		return nil, err
+	}
+	if err := checkTLSFile("-tls-key", kmsFlags.tlsKeyFile); err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(kmsFlags.tlsCertFile, kmsFlags.tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("TLS certificate %s and key %s could not be loaded as a pair "+
+			"(check they are PEM encoded and that the key belongs to the certificate): %w",
+			kmsFlags.tlsCertFile, kmsFlags.tlsKeyFile, err)
+	}
+
+	if err := checkCertificateExpiry(logger, cert.Leaf, time.Now()); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if kmsFlags.tlsClientCAFile != "" {
+		caPEM, err := os.ReadFile(kmsFlags.tlsClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in client CA bundle %s", kmsFlags.tlsClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		tlsConfig.VerifyPeerCertificate = validation.ClientCertPolicy{
+			RequireClientAuthEKU: kmsFlags.tlsClientEKU,
+			Organization:         kmsFlags.tlsClientOrg,
+			OrganizationalUnit:   kmsFlags.tlsClientOU,
+		}.VerifyPeerCertificate
+	}
+
+	return tlsConfig, nil
+}
+
+// checkTLSFile verifies that a TLS file given by flag exists and is readable
+func checkTLSFile(flagName, path string) error {
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("TLS is enabled but the %s file %s does not exist", flagName, path)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("TLS is enabled but the %s file %s is not readable by this process", flagName, path)
+	case err != nil:
+		return fmt.Errorf("TLS is enabled but the %s file %s cannot be opened: %w", flagName, path, err)
+	}
+
+	return file.Close()
+}
+
+// checkCertificateExpiry rejects a server certificate outside its validity period and warns
+// when it expires soon
+func checkCertificateExpiry(logger *slog.Logger, cert *x509.Certificate, now time.Time) error {
+	if cert == nil {
+		return nil
+	}
+
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("TLS certificate %s expired on %s", kmsFlags.tlsCertFile, cert.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("TLS certificate %s is not valid until %s", kmsFlags.tlsCertFile, cert.NotBefore.Format(time.RFC3339))
+	}
+
+	if remaining := cert.NotAfter.Sub(now); remaining < certExpiryWarning {
+		logger.Warn("TLS certificate expires soon",
+			"cert", kmsFlags.tlsCertFile,
+			"notAfter", cert.NotAfter,
+			"remaining", remaining.Round(time.Hour))
+	}
+
+	return nil
+}
+
+// createValidationConfig creates validation config from command line flags, the validation
+// config file and environment
+func createValidationConfig() (*validation.ValidationConfig, error) {
+	source := configSource{explicit: explicitFlags(), getenv: os.Getenv}
+
+	if kmsFlags.validationFile != "" {
+		file, err := loadValidationConfigFile(kmsFlags.validationFile)
+		if err != nil {
+			return nil, err
+		}
+		source.file = file
+	}
+
+	config := resolveValidationConfig(source)
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid validation configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// reloadValidationOnSIGHUP re-resolves the validation config on each SIGHUP and applies the
+// UUID settings to the running middleware. A config that fails to load is ignored.
+func reloadValidationOnSIGHUP(ctx context.Context, middleware *validation.ValidationMiddleware, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		logger.Info("SIGHUP received, reloading validation config", "file", kmsFlags.validationFile)
+
+		if middleware == nil {
+			logger.Warn("Validation is disabled - enabling it requires a restart")
+			continue
+		}
+
+		config, err := createValidationConfig()
+		if err != nil {
+			logger.Error("Failed to reload validation config, keeping the current settings", "error", err)
+			continue
+		}
+
+		if !config.Enabled {
+			logger.Warn("Disabling validation requires a restart, keeping the current settings")
+			continue
+		}
+
+		middleware.ReloadConfig(config)
+	}
+}
+
+// loadAuthConfig builds the auth config from the environment, overridden by -auth-config-file
+func loadAuthConfig() (*auth.AuthConfig, error) {
+	if kmsFlags.authConfigFile == "" {
+		return auth.NewAuthConfigFromEnvironment(), nil
+	}
+
+	return auth.NewAuthConfigFromFile(kmsFlags.authConfigFile)
+}
+
+// reloadAuthOnSIGHUP re-reads the auth config on each SIGHUP and switches the auth manager to
+// it, which may change the auth method. A config that fails to load or log in is ignored and
+// the current authentication is kept.
+func reloadAuthOnSIGHUP(ctx context.Context, manager *auth.Manager, transportWrapper func(http.RoundTripper) http.RoundTripper, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		logger.Info("SIGHUP received, reloading auth config", "file", kmsFlags.authConfigFile)
+
+		config, err := loadAuthConfig()
+		if err != nil {
+			logger.Error("Failed to reload auth config, keeping the current authentication", "error", err)
+			continue
+		}
+		config.TransportWrapper = transportWrapper
+
+		if err := manager.SwitchConfig(ctx, config); err != nil {
+			logger.Error("Failed to switch authentication, keeping the current authentication", "error", err)
+		}
+	}
+}
+
+// validationFileKeys are the settings a validation config file may contain
+var validationFileKeys = map[string]bool{
+	"disable-validation":       true,
+	"uuid-validation-mode":     true,
+	"allow-uuid-versions":      true,
+	"disable-entropy-check":    true,
+	"entropy-level":            true,
+	"entropy-exempt-uuids":     true,
+	"entropy-min-unique-chars": true,
+}
+
+// loadValidationConfigFile reads a JSON object of validation settings keyed by flag name.
+// Values may be strings, booleans, or (for entropy-exempt-uuids) a list of strings.
+func loadValidationConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validation config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse validation config file %s: %w", path, err)
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if !validationFileKeys[key] {
+			return nil, fmt.Errorf("unknown setting %q in validation config file %s", key, path)
+		}
+
+		switch v := value.(type) {
+		case string:
+			settings[key] = v
+		case bool:
+			settings[key] = strconv.FormatBool(v)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("setting %q in validation config file %s must be a list of strings", key, path)
+				}
+				items = append(items, s)
+			}
+			settings[key] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("setting %q in validation config file %s has unsupported type %T", key, path, value)
+		}
+	}
+
+	return settings, nil
+}
+
+// configSource resolves settings with a flag > config file > environment > default precedence
+type configSource struct {
+	explicit map[string]bool
+	getenv   func(string) string
+
+	// file optionally holds settings from a config file, keyed by flag name
+	file map[string]string
+}
+
+// explicitFlags returns the names of flags set on the command line
+func explicitFlags() map[string]bool {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	return explicit
+}
+
+// value returns the flag value if it was set explicitly, else the config file value, else
+// the environment value, else the flag's default
+func (c configSource) value(flagName, envName, flagValue string) string {
+	if c.explicit[flagName] {
+		return flagValue
+	}
+
+	if fileValue, ok := c.file[flagName]; ok {
+		return fileValue
+	}
+
+	if envValue := c.getenv(envName); envValue != "" {
+		return envValue
+	}
+
+	return flagValue
+}
+
+// boolValue is like value for boolean settings, ignoring unparsable environment values
+func (c configSource) boolValue(flagName, envName string, flagValue bool) bool {
+	parsed, err := strconv.ParseBool(c.value(flagName, envName, strconv.FormatBool(flagValue)))
+	if err != nil {
+		return flagValue
+	}
+
+	return parsed
+}
+
+// intValue is like value for integer settings, ignoring unparsable values
+func (c configSource) intValue(flagName, envName string, flagValue int) int {
+	parsed, err := strconv.Atoi(c.value(flagName, envName, strconv.Itoa(flagValue)))
+	if err != nil {
+		return flagValue
+	}
+
+	return parsed
+}
+
+// resolveValidationConfig builds the validation config using flag > env > default precedence
+func resolveValidationConfig(source configSource) *validation.ValidationConfig {
 	config := validation.DefaultValidationConfig()
 
-	// Override with flags
-	if kmsFlags.disableValidation {
+	if source.boolValue("disable-validation", "KMS_DISABLE_VALIDATION", kmsFlags.disableValidation) {
 		config.Enabled = false
 		return config
 	}
 
 	// Handle UUID validation mode
-	switch kmsFlags.uuidValidationMode {
-	case "strict":
-		config.UUIDValidationMode = validation.ValidationModeStrict
+	switch source.value("uuid-validation-mode", "KMS_UUID_VALIDATION_MODE", kmsFlags.uuidValidationMode) {
 	case "relaxed":
 		config.UUIDValidationMode = validation.ValidationModeRelaxed
 	default:
@@ -279,9 +1314,7 @@ func createValidationConfig() *validation.ValidationConfig {
 	}
 
 	// Handle UUID version requirements (only applies in strict mode)
-	switch kmsFlags.allowUUIDVersions {
-	case "v4":
-		config.RequireUUIDv4 = true
+	switch source.value("allow-uuid-versions", "KMS_ALLOW_UUID_VERSIONS", kmsFlags.allowUUIDVersions) {
 	case "v1-v5", "any":
 		config.RequireUUIDv4 = false
 	default:
@@ -290,59 +1323,176 @@ func createValidationConfig() *validation.ValidationConfig {
 	}
 
 	// Entropy checking (only applies in strict mode)
-	config.CheckEntropy = !kmsFlags.disableEntropy
+	config.CheckEntropy = !source.boolValue("disable-entropy-check", "KMS_DISABLE_ENTROPY_CHECK", kmsFlags.disableEntropy)
 
-	// Environment variable overrides
-	if disableValidation := os.Getenv("KMS_DISABLE_VALIDATION"); disableValidation == "true" {
-		config.Enabled = false
+	switch source.value("entropy-level", "KMS_ENTROPY_LEVEL", kmsFlags.entropyLevel) {
+	case "strict":
+		config.EntropyLevel = validation.EntropyLevelStrict
+	default:
+		config.EntropyLevel = validation.EntropyLevelBasic
 	}
 
-	if uuidMode := os.Getenv("KMS_UUID_VALIDATION_MODE"); uuidMode != "" {
-		switch uuidMode {
-		case "strict":
-			config.UUIDValidationMode = validation.ValidationModeStrict
-		case "relaxed":
-			config.UUIDValidationMode = validation.ValidationModeRelaxed
+	config.MinUniqueChars = source.intValue("entropy-min-unique-chars", "KMS_ENTROPY_MIN_UNIQUE_CHARS", kmsFlags.entropyMinUnique)
+
+	for _, uuid := range strings.Split(source.value("entropy-exempt-uuids", "KMS_ENTROPY_EXEMPT_UUIDS", kmsFlags.entropyExempt), ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			config.EntropyExemptUUIDs = append(config.EntropyExemptUUIDs, uuid)
 		}
 	}
 
-	if disableEntropy := os.Getenv("KMS_DISABLE_ENTROPY_CHECK"); disableEntropy == "true" {
-		config.CheckEntropy = false
-	}
+	// Per-method data size limits
+	config.MaxSealSize = kmsFlags.maxSealSize
+	config.MaxUnsealSize = kmsFlags.maxUnsealSize
+
+	config.SelfTestUUID = kmsFlags.selfTestUUID
+	config.FailOpen = kmsFlags.validationFailOpen
+
+	return config
+}
 
-	if uuidVersions := os.Getenv("KMS_ALLOW_UUID_VERSIONS"); uuidVersions != "" {
-		switch uuidVersions {
-		case "v4":
-			config.RequireUUIDv4 = true
-		case "v1-v5", "any":
-			config.RequireUUIDv4 = false
+// createMetadataPolicyConfig creates the metadata policy config from command line flags
+func createMetadataPolicyConfig() *validation.MetadataPolicyConfig {
+	config := validation.DefaultMetadataPolicyConfig()
+	config.Enabled = kmsFlags.metadataPolicy
+	config.MaxEntries = kmsFlags.metadataMaxEntries
+	config.MaxSize = kmsFlags.metadataMaxSize
+	config.AllowedKeys = nil
+
+	for _, key := range strings.Split(kmsFlags.metadataAllowedKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			config.AllowedKeys = append(config.AllowedKeys, key)
 		}
 	}
 
 	return config
 }
 
+// checkLeaderElectionEnvironment makes sure leader election can reach Kubernetes before anything
+// starts. Outside a cluster it needs a kubeconfig; otherwise it fails, or falls back to
+// single-instance mode when explicitly allowed.
+func checkLeaderElectionEnvironment(logger *slog.Logger, inCluster bool) error {
+	if !kmsFlags.enableLeaderElection || inCluster || kmsFlags.leaderElectionKubeconfig != "" {
+		return nil
+	}
+
+	if !kmsFlags.allowNonClusterElection {
+		return fmt.Errorf("leader election is enabled but %w: set -leader-election-kubeconfig, "+
+			"or -leader-election-allow-noncluster to run in single-instance mode", leaderelection.ErrNotInCluster)
+	}
+
+	logger.Warn("LEADER ELECTION DISABLED: not running in Kubernetes and no kubeconfig given - " +
+		"falling back to single-instance mode. Do not run more than one instance.")
+	kmsFlags.enableLeaderElection = false
+
+	return nil
+}
+
+// createPeerCounter counts the candidates behind -peer-service through the leader election's Kubernetes config
+func createPeerCounter(leaseConfig *leaderelection.LeaseConfig) (*leaderelection.PeerCounter, error) {
+	if kmsFlags.peerService == "" {
+		return nil, fmt.Errorf("-min-healthy-peers requires -peer-service")
+	}
+
+	restConfig, err := leaderelection.KubernetesConfig(leaseConfig.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return leaderelection.NewPeerCounter(leaseConfig, restConfig, kmsFlags.peerService)
+}
+
 // createLeaderElectionConfig creates leader election config from command line flags
-func createLeaderElectionConfig(logger *slog.Logger) *leaderelection.LeaseConfig {
+func createLeaderElectionConfig(logger *slog.Logger) (*leaderelection.LeaseConfig, error) {
 	config := leaderelection.DefaultLeaseConfig()
 
-	// Use command line flags
-	config.Name = kmsFlags.leaderElectionName
+	// Use command line flags; the election pool also applies to an explicit lease name
+	config.Name = leaderelection.WithPoolSuffix(kmsFlags.leaderElectionName, leaderelection.GetPoolFromEnv())
 	config.Namespace = kmsFlags.leaderElectionNamespace
 	config.LeaseDuration = kmsFlags.leaderElectionLeaseDuration
 	config.RenewDeadline = kmsFlags.leaderElectionRenewDeadline
 	config.RetryPeriod = kmsFlags.leaderElectionRetryPeriod
+	config.ReleaseTimeout = kmsFlags.leaderElectionReleaseTimeout
+	config.ReleasedLeaseWait = kmsFlags.leaderElectionReleasedWait
+	config.Kubeconfig = kmsFlags.leaderElectionKubeconfig
+
+	if kmsFlags.leaderElectionMaxFlaps < 0 {
+		return nil, fmt.Errorf("leader-election-max-flaps must not be negative")
+	}
+	if kmsFlags.leaderElectionFlapWindow <= 0 {
+		return nil, fmt.Errorf("leader-election-flap-window must be positive")
+	}
+
+	flapAction, err := leaderelection.ParseFlapAction(kmsFlags.leaderElectionFlapAction)
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxFlaps = kmsFlags.leaderElectionMaxFlaps
+	config.FlapWindow = kmsFlags.leaderElectionFlapWindow
+	config.FlapAction = flapAction
+
+	collisionAction, err := leaderelection.ParseCollisionAction(kmsFlags.leaderElectionCollision)
+	if err != nil {
+		return nil, err
+	}
+	config.CollisionAction = collisionAction
+	config.HistorySize = kmsFlags.leaderElectionHistorySize
 
 	// Set identity from environment or defaults
 	config.Identity = leaderelection.DefaultIdentity()
 
+	// Lease object metadata from environment
+	config.Labels = leaderelection.GetLeaseLabelsFromEnv()
+	config.Annotations = leaderelection.GetLeaseAnnotationsFromEnv()
+	config.OwnerReference = resolveLeaseOwner(logger, config)
+
+	var owner string
+	if config.OwnerReference != nil {
+		owner = config.OwnerReference.Kind + "/" + config.OwnerReference.Name
+	}
+
 	logger.Info("Leader election configuration",
 		"name", config.Name,
 		"namespace", config.Namespace,
 		"identity", config.Identity,
 		"leaseDuration", config.LeaseDuration,
 		"renewDeadline", config.RenewDeadline,
-		"retryPeriod", config.RetryPeriod)
+		"retryPeriod", config.RetryPeriod,
+		"releaseTimeout", config.ReleaseTimeout,
+		"releasedLeaseWait", config.ReleasedLeaseWait,
+		"maxFlaps", config.MaxFlaps,
+		"flapWindow", config.FlapWindow,
+		"flapAction", config.FlapAction,
+		"identityCollisionAction", config.CollisionAction,
+		"labels", config.Labels,
+		"owner", owner)
+
+	return config, nil
+}
 
-	return config
+// resolveLeaseOwner returns the workload controlling this pod as the lease owner, or nil when
+// POD_NAME/POD_UID are unset or the workload can't be resolved. The lease is then never
+// garbage-collected, which is preferable to losing it with the pod that created it.
+func resolveLeaseOwner(logger *slog.Logger, config *leaderelection.LeaseConfig) *metav1.OwnerReference {
+	pod := leaderelection.PodOwnerReferenceFromEnv()
+	if pod == nil {
+		return nil
+	}
+
+	restConfig, err := leaderelection.KubernetesConfig(config.Kubeconfig)
+	if err != nil {
+		logger.Warn("unable to resolve the workload owning this pod, the lease will have no owner", "error", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	owner, err := leaderelection.ResolveWorkloadOwner(ctx, restConfig, config.Namespace, pod)
+	if err != nil {
+		logger.Warn("unable to resolve the workload owning this pod, the lease will have no owner", "error", err)
+		return nil
+	}
+
+	return owner
 }
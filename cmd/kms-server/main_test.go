@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/leaderelection"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+func TestResolveValidationConfig(t *testing.T) {
+	defaults := kmsFlags
+	t.Cleanup(func() { kmsFlags = defaults })
+
+	tests := []struct {
+		name string
+		// flags maps flag names to values applied as if set on the command line
+		flags           map[string]string
+		file            map[string]string
+		env             map[string]string
+		wantEnabled     bool
+		wantRequireV4   bool
+		wantEntropy     bool
+		wantRelaxedMode bool
+	}{
+		{
+			name:          "defaults",
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   true,
+		},
+		{
+			name:          "env overrides default versions",
+			env:           map[string]string{"KMS_ALLOW_UUID_VERSIONS": "any"},
+			wantEnabled:   true,
+			wantRequireV4: false,
+			wantEntropy:   true,
+		},
+		{
+			name:          "explicit flag beats env versions",
+			flags:         map[string]string{"allow-uuid-versions": "v4"},
+			env:           map[string]string{"KMS_ALLOW_UUID_VERSIONS": "any"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   true,
+		},
+		{
+			name:          "explicit flag disables entropy despite env",
+			flags:         map[string]string{"disable-entropy-check": "true"},
+			env:           map[string]string{"KMS_DISABLE_ENTROPY_CHECK": "false"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   false,
+		},
+		{
+			name:          "env disables entropy",
+			env:           map[string]string{"KMS_DISABLE_ENTROPY_CHECK": "true"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   false,
+		},
+		{
+			name:          "explicit flag keeps validation enabled despite env",
+			flags:         map[string]string{"disable-validation": "false"},
+			env:           map[string]string{"KMS_DISABLE_VALIDATION": "true"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   true,
+		},
+		{
+			name:        "env disables validation",
+			env:         map[string]string{"KMS_DISABLE_VALIDATION": "true"},
+			wantEnabled: false,
+		},
+		{
+			name:            "env selects relaxed mode",
+			env:             map[string]string{"KMS_UUID_VALIDATION_MODE": "relaxed"},
+			wantEnabled:     true,
+			wantRequireV4:   true,
+			wantEntropy:     true,
+			wantRelaxedMode: true,
+		},
+		{
+			name:          "config file beats env",
+			file:          map[string]string{"allow-uuid-versions": "any", "disable-entropy-check": "true"},
+			env:           map[string]string{"KMS_ALLOW_UUID_VERSIONS": "v4", "KMS_DISABLE_ENTROPY_CHECK": "false"},
+			wantEnabled:   true,
+			wantRequireV4: false,
+			wantEntropy:   false,
+		},
+		{
+			name:          "explicit flag beats config file",
+			flags:         map[string]string{"allow-uuid-versions": "v4"},
+			file:          map[string]string{"allow-uuid-versions": "any"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   true,
+		},
+		{
+			name:          "unparsable env bool falls back to default",
+			env:           map[string]string{"KMS_DISABLE_ENTROPY_CHECK": "maybe"},
+			wantEnabled:   true,
+			wantRequireV4: true,
+			wantEntropy:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmsFlags = defaults
+			kmsFlags.allowUUIDVersions = "v4"
+			kmsFlags.uuidValidationMode = "strict"
+
+			explicit := make(map[string]bool)
+			for name, value := range tt.flags {
+				explicit[name] = true
+				switch name {
+				case "disable-validation":
+					kmsFlags.disableValidation = value == "true"
+				case "allow-uuid-versions":
+					kmsFlags.allowUUIDVersions = value
+				case "disable-entropy-check":
+					kmsFlags.disableEntropy = value == "true"
+				}
+			}
+
+			source := configSource{
+				explicit: explicit,
+				getenv:   func(key string) string { return tt.env[key] },
+				file:     tt.file,
+			}
+
+			config := resolveValidationConfig(source)
+
+			if config.Enabled != tt.wantEnabled {
+				t.Errorf("Enabled = %v, want %v", config.Enabled, tt.wantEnabled)
+			}
+
+			if !tt.wantEnabled {
+				return
+			}
+
+			if config.RequireUUIDv4 != tt.wantRequireV4 {
+				t.Errorf("RequireUUIDv4 = %v, want %v", config.RequireUUIDv4, tt.wantRequireV4)
+			}
+
+			if config.CheckEntropy != tt.wantEntropy {
+				t.Errorf("CheckEntropy = %v, want %v", config.CheckEntropy, tt.wantEntropy)
+			}
+
+			if relaxed := config.UUIDValidationMode == validation.ValidationModeRelaxed; relaxed != tt.wantRelaxedMode {
+				t.Errorf("UUIDValidationMode = %v, want relaxed=%v", config.UUIDValidationMode, tt.wantRelaxedMode)
+			}
+		})
+	}
+}
+
+func TestLoadValidationConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "strings, bools and lists",
+			content: `{"allow-uuid-versions": "any", "disable-entropy-check": true, "entropy-exempt-uuids": ["a", "b"]}`,
+			want: map[string]string{
+				"allow-uuid-versions":   "any",
+				"disable-entropy-check": "true",
+				"entropy-exempt-uuids":  "a,b",
+			},
+		},
+		{
+			name:    "numeric setting as string",
+			content: `{"entropy-min-unique-chars": "6"}`,
+			want:    map[string]string{"entropy-min-unique-chars": "6"},
+		},
+		{
+			name:    "unknown setting",
+			content: `{"max-seal-size": "1024"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			content: `{"entropy-level": 2}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `allow-uuid-versions=any`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "validation.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadValidationConfigFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadValidationConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("loadValidationConfigFile() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("setting %q = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckLeaderElectionEnvironment(t *testing.T) {
+	defaults := kmsFlags
+	t.Cleanup(func() { kmsFlags = defaults })
+
+	tests := []struct {
+		name            string
+		enabled         bool
+		inCluster       bool
+		kubeconfig      string
+		allowNonCluster bool
+		wantErr         bool
+		wantElection    bool
+	}{
+		{name: "disabled", enabled: false, wantElection: false},
+		{name: "in cluster", enabled: true, inCluster: true, wantElection: true},
+		{name: "kubeconfig outside cluster", enabled: true, kubeconfig: "/tmp/kubeconfig", wantElection: true},
+		{name: "outside cluster fails", enabled: true, wantErr: true},
+		{name: "outside cluster falls back", enabled: true, allowNonCluster: true, wantElection: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmsFlags = defaults
+			kmsFlags.enableLeaderElection = tt.enabled
+			kmsFlags.leaderElectionKubeconfig = tt.kubeconfig
+			kmsFlags.allowNonClusterElection = tt.allowNonCluster
+
+			err := checkLeaderElectionEnvironment(slog.New(slog.NewTextHandler(os.Stderr, nil)), tt.inCluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkLeaderElectionEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, leaderelection.ErrNotInCluster) {
+				t.Errorf("error = %v, want ErrNotInCluster", err)
+			}
+			if !tt.wantErr && kmsFlags.enableLeaderElection != tt.wantElection {
+				t.Errorf("leader election enabled = %v, want %v", kmsFlags.enableLeaderElection, tt.wantElection)
+			}
+		})
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	defaults := kmsFlags
+	t.Cleanup(func() { kmsFlags = defaults })
+
+	withElection := func() {
+		kmsFlags.enableLeaderElection = true
+		kmsFlags.leaderElectionKubeconfig = "/tmp/kubeconfig"
+		kmsFlags.leaderElectionName = "talos-kms-leader"
+		kmsFlags.leaderElectionNamespace = "kms"
+		kmsFlags.leaderElectionLeaseDuration = 15 * time.Second
+		kmsFlags.leaderElectionRenewDeadline = 10 * time.Second
+		kmsFlags.leaderElectionRetryPeriod = 2 * time.Second
+		kmsFlags.leaderElectionFlapWindow = 5 * time.Minute
+		kmsFlags.leaderElectionFlapAction = "alert"
+		kmsFlags.leaderElectionCollision = "decline"
+	}
+
+	tests := []struct {
+		name      string
+		vaultAddr string
+		setFlags  func()
+		wantValid map[string]bool
+	}{
+		{
+			name:      "valid",
+			vaultAddr: "https://vault.example.com",
+			wantValid: map[string]bool{"auth": true, "validation": true, "leaderElection": true},
+		},
+		{
+			name:      "missing vault address",
+			wantValid: map[string]bool{"auth": false, "validation": true, "leaderElection": true},
+		},
+		{
+			name:      "malformed entropy exemption",
+			vaultAddr: "https://vault.example.com",
+			setFlags:  func() { kmsFlags.entropyExempt = "not-a-uuid" },
+			wantValid: map[string]bool{"auth": true, "validation": false, "leaderElection": true},
+		},
+		{
+			name:      "leader election",
+			vaultAddr: "https://vault.example.com",
+			setFlags:  withElection,
+			wantValid: map[string]bool{"auth": true, "validation": true, "leaderElection": true},
+		},
+		{
+			name:      "leader election timings",
+			vaultAddr: "https://vault.example.com",
+			setFlags: func() {
+				withElection()
+				kmsFlags.leaderElectionRetryPeriod = 20 * time.Second
+			},
+			wantValid: map[string]bool{"auth": true, "validation": true, "leaderElection": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmsFlags = defaults
+			if tt.setFlags != nil {
+				tt.setFlags()
+			}
+			t.Setenv("VAULT_ADDR", tt.vaultAddr)
+			t.Setenv("VAULT_TOKEN", "test-token")
+
+			var out bytes.Buffer
+			valid := checkConfig(slog.New(slog.NewTextHandler(os.Stderr, nil)), &out)
+
+			var report configReport
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("report is not JSON: %v\n%s", err, out.String())
+			}
+
+			wantAll := true
+			for _, check := range report.Checks {
+				want, ok := tt.wantValid[check.Name]
+				if !ok {
+					t.Errorf("unexpected check %q", check.Name)
+					continue
+				}
+				if check.Valid != want || (check.Error == "") != want {
+					t.Errorf("check %q valid = %v (error %q), want %v", check.Name, check.Valid, check.Error, want)
+				}
+				wantAll = wantAll && want
+			}
+
+			if len(report.Checks) != len(tt.wantValid) {
+				t.Errorf("report has %d checks, want %d", len(report.Checks), len(tt.wantValid))
+			}
+			if valid != wantAll || report.Valid != wantAll {
+				t.Errorf("checkConfig() = %v, report valid = %v, want %v", valid, report.Valid, wantAll)
+			}
+		})
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate valid until notAfter and its key as PEM files
+func writeTestCertificate(t *testing.T, dir, name string, notAfter time.Time) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kms-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestCreateTLSConfig(t *testing.T) {
+	defaults := kmsFlags
+	t.Cleanup(func() { kmsFlags = defaults })
+
+	dir := t.TempDir()
+	validCert, validKey := writeTestCertificate(t, dir, "valid", time.Now().Add(365*24*time.Hour))
+	soonCert, soonKey := writeTestCertificate(t, dir, "soon", time.Now().Add(7*24*time.Hour))
+	expiredCert, expiredKey := writeTestCertificate(t, dir, "expired", time.Now().Add(-time.Minute))
+	_, otherKey := writeTestCertificate(t, dir, "other", time.Now().Add(365*24*time.Hour))
+	missing := filepath.Join(dir, "missing.pem")
+
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		wantErr string
+	}{
+		{name: "valid pair", cert: validCert, key: validKey},
+		{name: "expiring soon still loads", cert: soonCert, key: soonKey},
+		{name: "missing certificate", cert: missing, key: validKey, wantErr: "-tls-cert file " + missing + " does not exist"},
+		{name: "missing key", cert: validCert, key: missing, wantErr: "-tls-key file " + missing + " does not exist"},
+		{name: "mismatched key", cert: validCert, key: otherKey, wantErr: "could not be loaded as a pair"},
+		{name: "expired certificate", cert: expiredCert, key: expiredKey, wantErr: "expired on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmsFlags = defaults
+			kmsFlags.tlsCertFile = tt.cert
+			kmsFlags.tlsKeyFile = tt.key
+
+			_, err := createTLSConfig(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("createTLSConfig() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("createTLSConfig() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
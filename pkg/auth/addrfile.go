@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultAddrFileCheckInterval is how often VAULT_ADDR_FILE is checked for changes
+const defaultAddrFileCheckInterval = 10 * time.Second
+
+// addressable is implemented by authenticators whose Vault address can be changed
+type addressable interface {
+	GetVaultAddr() string
+	SetVaultAddr(addr string)
+}
+
+// ReadVaultAddrFile reads a Vault address written to a file by an external controller
+func ReadVaultAddrFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault address file: %w", err)
+	}
+
+	addr := strings.TrimSpace(string(data))
+	if addr == "" {
+		return "", fmt.Errorf("vault address file %s is empty", path)
+	}
+
+	return addr, nil
+}
+
+// startAddrWatch starts watching VaultAddrFile for address changes
+func (m *Manager) startAddrWatch() {
+	target, ok := m.authenticator.(addressable)
+	if !ok {
+		m.logger.Warn("authenticator does not support changing the vault address, not watching address file",
+			"method", m.authenticator.GetMethod(),
+			"path", m.config.VaultAddrFile)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelAddrWatch = cancel
+	m.addrWatchDone = make(chan struct{})
+
+	go m.addrWatchLoop(ctx, target)
+}
+
+// addrWatchLoop polls VaultAddrFile and re-authenticates when the address changes
+func (m *Manager) addrWatchLoop(ctx context.Context, target addressable) {
+	defer close(m.addrWatchDone)
+
+	ticker := time.NewTicker(m.addrFileCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			addr, err := ReadVaultAddrFile(m.config.VaultAddrFile)
+			if err != nil {
+				m.logger.Warn("failed to read vault address file, keeping current address",
+					"path", m.config.VaultAddrFile,
+					"error", err)
+				continue
+			}
+
+			if addr != target.GetVaultAddr() {
+				m.switchVaultAddr(ctx, target, addr)
+			}
+		}
+	}
+}
+
+// switchVaultAddr re-authenticates against a new Vault address. On failure the
+// previous address is restored so the change is retried on the next check.
+func (m *Manager) switchVaultAddr(ctx context.Context, target addressable, addr string) {
+	previous := target.GetVaultAddr()
+
+	m.logger.Info("vault address changed, re-authenticating",
+		"previous", previous,
+		"address", addr)
+
+	target.SetVaultAddr(addr)
+
+	if err := m.reauthenticate(ctx); err != nil {
+		m.logger.Error("failed to re-authenticate against new vault address, keeping previous address",
+			"address", addr,
+			"error", err)
+		target.SetVaultAddr(previous)
+	}
+}
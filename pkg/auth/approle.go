@@ -2,7 +2,9 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/vault-client-go"
@@ -11,14 +13,29 @@ import (
 
 const (
 	defaultAppRoleMountPath = "approle"
+
+	// defaultSecretIDRenewBuffer is how long before SecretID expiry to rotate or warn
+	defaultSecretIDRenewBuffer = time.Hour
 )
 
 // AppRoleAuthenticator implements AppRole-based authentication
 type AppRoleAuthenticator struct {
 	BaseAuthenticator
 	roleID    string
-	secretID  string
 	mountPath string
+
+	// secretMu guards secretID, roleName and secretIDExpiry, which the renewal loop and the
+	// address file watcher may log in with while the SecretID is rotated
+	secretMu sync.RWMutex
+	secretID string
+
+	// roleName is learned from the login response metadata
+	roleName string
+
+	// SecretID lifetime tracking (zero expiry means unknown or never expires)
+	secretIDExpiry      time.Time
+	secretIDRenewBuffer time.Duration
+	autoRotateSecretID  bool
 }
 
 // NewAppRoleAuth creates a new AppRole authenticator
@@ -43,7 +60,15 @@ func NewAppRoleAuth(config *AppRoleConfig, vaultAddr string) (*AppRoleAuthentica
 	// Get SecretID
 	if config.SecretID == "" {
 		config.SecretID = os.Getenv("VAULT_SECRET_ID")
-		// SecretID might be optional for some AppRole configurations
+		// SecretID is only optional for roles with bind_secret_id=false (e.g. bound-CIDR roles)
+		if config.SecretID == "" && config.BindSecretID {
+			return nil, NewAuthError(AuthMethodAppRole, "new", ErrMissingConfiguration, "secret_id is required when the role binds a secret_id")
+		}
+	}
+
+	secretIDRenewBuffer := config.SecretIDRenewBuffer
+	if secretIDRenewBuffer <= 0 {
+		secretIDRenewBuffer = defaultSecretIDRenewBuffer
 	}
 
 	return &AppRoleAuthenticator{
@@ -52,35 +77,24 @@ func NewAppRoleAuth(config *AppRoleConfig, vaultAddr string) (*AppRoleAuthentica
 			VaultAddr:   vaultAddr,
 			RenewBuffer: 5 * time.Minute,
 		},
-		roleID:    config.RoleID,
-		secretID:  config.SecretID,
-		mountPath: config.MountPath,
+		roleID:              config.RoleID,
+		secretID:            config.SecretID,
+		mountPath:           config.MountPath,
+		secretIDRenewBuffer: secretIDRenewBuffer,
+		autoRotateSecretID:  config.AutoRotateSecretID,
 	}, nil
 }
 
 // Authenticate performs AppRole authentication
 func (a *AppRoleAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
 	// Create Vault client
-	client, err := vault.New(
-		vault.WithAddress(a.VaultAddr),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := a.newVaultClient()
 	if err != nil {
 		return nil, NewAuthError(AuthMethodAppRole, "authenticate", err, "failed to create vault client")
 	}
 
-	// Prepare login request
-	loginReq := schema.AppRoleLoginRequest{
-		RoleId: a.roleID,
-	}
-
-	// Add SecretID if provided
-	if a.secretID != "" {
-		loginReq.SecretId = a.secretID
-	}
-
 	// Perform AppRole login
-	resp, err := client.Auth.AppRoleLogin(ctx, loginReq, vault.WithMountPath(a.mountPath))
+	resp, err := client.Auth.AppRoleLogin(ctx, a.loginRequest(), vault.WithMountPath(a.mountPath))
 	if err != nil {
 		return nil, NewAuthError(AuthMethodAppRole, "authenticate", err, "approle login failed")
 	}
@@ -96,11 +110,17 @@ func (a *AppRoleAuthenticator) Authenticate(ctx context.Context) (*vault.Client,
 	}
 
 	// Store TTL and metadata
-	a.TokenTTL = time.Duration(resp.Auth.LeaseDuration) * time.Second
-	a.LastRenewal = time.Now()
+	a.SetTokenTTL(time.Duration(resp.Auth.LeaseDuration) * time.Second)
 
 	// Handle wrapped SecretID response if applicable
 	if resp.Auth.Metadata != nil {
+		a.secretMu.Lock()
+		defer a.secretMu.Unlock()
+
+		if roleName := resp.Auth.Metadata["role_name"]; roleName != "" {
+			a.roleName = roleName
+		}
+
 		if wrappedSecretID, ok := resp.Auth.Metadata["wrapped_secret_id"]; ok && wrappedSecretID != "" {
 			// Store for potential future use
 			a.secretID = wrappedSecretID
@@ -118,15 +138,7 @@ func (a *AppRoleAuthenticator) Renew(ctx context.Context, client *vault.Client)
 		// If renewal fails and we have credentials, try to re-authenticate
 		if a.roleID != "" {
 			// Re-authenticate
-			loginReq := schema.AppRoleLoginRequest{
-				RoleId: a.roleID,
-			}
-
-			if a.secretID != "" {
-				loginReq.SecretId = a.secretID
-			}
-
-			resp, err := client.Auth.AppRoleLogin(ctx, loginReq, vault.WithMountPath(a.mountPath))
+			resp, err := client.Auth.AppRoleLogin(ctx, a.loginRequest(), vault.WithMountPath(a.mountPath))
 			if err != nil {
 				return NewAuthError(AuthMethodAppRole, "renew", err, "re-authentication failed")
 			}
@@ -135,8 +147,7 @@ func (a *AppRoleAuthenticator) Renew(ctx context.Context, client *vault.Client)
 				if err := client.SetToken(resp.Auth.ClientToken); err != nil {
 					return NewAuthError(AuthMethodAppRole, "renew", err, "failed to set new token")
 				}
-				a.TokenTTL = time.Duration(resp.Auth.LeaseDuration) * time.Second
-				a.LastRenewal = time.Now()
+				a.SetTokenTTL(time.Duration(resp.Auth.LeaseDuration) * time.Second)
 				return nil
 			}
 		}
@@ -146,8 +157,7 @@ func (a *AppRoleAuthenticator) Renew(ctx context.Context, client *vault.Client)
 
 	// Update TTL from renewal response
 	if renewResp.Auth != nil {
-		a.TokenTTL = time.Duration(renewResp.Auth.LeaseDuration) * time.Second
-		a.LastRenewal = time.Now()
+		a.SetTokenTTL(time.Duration(renewResp.Auth.LeaseDuration) * time.Second)
 	}
 
 	return nil
@@ -162,27 +172,114 @@ func (a *AppRoleAuthenticator) Revoke(ctx context.Context, client *vault.Client)
 	return nil
 }
 
-// RotateSecretID generates a new SecretID for the role
+// RotateSecretID generates a new SecretID for the role.
+// The role is addressed by name (learned at login), as the secret-id endpoint requires.
 func (a *AppRoleAuthenticator) RotateSecretID(ctx context.Context, client *vault.Client) (string, error) {
-	// Generate new SecretID
-	resp, err := client.Auth.AppRoleWriteSecretId(
-		ctx,
-		a.roleID,
-		schema.AppRoleWriteSecretIdRequest{},
-		vault.WithMountPath(a.mountPath),
-	)
+	a.secretMu.RLock()
+	roleName := a.roleName
+	a.secretMu.RUnlock()
+
+	if roleName == "" {
+		return "", NewAuthError(AuthMethodAppRole, "rotate_secret_id", ErrMissingConfiguration, "role name unknown (not returned at login)")
+	}
+
+	// Generic write: Vault returns secret_id_ttl as a number, which the typed response can't decode
+	resp, err := client.Write(ctx, a.rolePath(roleName, "secret-id"), map[string]interface{}{})
 	if err != nil {
 		return "", NewAuthError(AuthMethodAppRole, "rotate_secret_id", err, "failed to generate new secret_id")
 	}
 
-	if resp.Data.SecretId == "" {
+	secretID, _ := resp.Data["secret_id"].(string)
+	if secretID == "" {
 		return "", NewAuthError(AuthMethodAppRole, "rotate_secret_id", ErrAuthenticationFailed, "no secret_id in response")
 	}
 
 	// Update internal state
-	a.secretID = resp.Data.SecretId
+	a.secretMu.Lock()
+	a.secretID = secretID
+	a.secretMu.Unlock()
+
+	return secretID, nil
+}
+
+// RefreshSecretIDExpiry looks up when the current SecretID expires
+func (a *AppRoleAuthenticator) RefreshSecretIDExpiry(ctx context.Context, client *vault.Client) error {
+	a.secretMu.RLock()
+	secretID, roleName := a.secretID, a.roleName
+	a.secretMu.RUnlock()
+
+	if secretID == "" || roleName == "" {
+		a.setSecretIDExpiry(time.Time{})
+		return nil
+	}
+
+	resp, err := client.Write(ctx, a.rolePath(roleName, "secret-id/lookup"), map[string]interface{}{
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return NewAuthError(AuthMethodAppRole, "lookup_secret_id", err, "failed to look up secret_id")
+	}
+
+	var expiry time.Time
+
+	// Vault reports the zero time for SecretIDs that never expire
+	if expiration, _ := resp.Data["expiration_time"].(string); expiration != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, expiration)
+		if err != nil {
+			a.setSecretIDExpiry(time.Time{})
+			return NewAuthError(AuthMethodAppRole, "lookup_secret_id", err, "invalid expiration_time")
+		}
+
+		if parsed.Year() > 1 {
+			expiry = parsed
+		}
+	}
+
+	a.setSecretIDExpiry(expiry)
+
+	return nil
+}
+
+// SecretIDExpiry returns when the current SecretID expires (zero if unknown or never)
+func (a *AppRoleAuthenticator) SecretIDExpiry() time.Time {
+	a.secretMu.RLock()
+	defer a.secretMu.RUnlock()
+
+	return a.secretIDExpiry
+}
+
+// setSecretIDExpiry records when the current SecretID expires
+func (a *AppRoleAuthenticator) setSecretIDExpiry(expiry time.Time) {
+	a.secretMu.Lock()
+	defer a.secretMu.Unlock()
+
+	a.secretIDExpiry = expiry
+}
+
+// loginRequest builds an AppRole login request with the current SecretID
+func (a *AppRoleAuthenticator) loginRequest() schema.AppRoleLoginRequest {
+	a.secretMu.RLock()
+	defer a.secretMu.RUnlock()
+
+	return schema.AppRoleLoginRequest{
+		RoleId:   a.roleID,
+		SecretId: a.secretID,
+	}
+}
+
+// SecretIDRenewBuffer returns how long before expiry the SecretID should be rotated
+func (a *AppRoleAuthenticator) SecretIDRenewBuffer() time.Duration {
+	return a.secretIDRenewBuffer
+}
+
+// AutoRotateSecretID reports whether expiring SecretIDs should be rotated automatically
+func (a *AppRoleAuthenticator) AutoRotateSecretID() bool {
+	return a.autoRotateSecretID
+}
 
-	return resp.Data.SecretId, nil
+// rolePath returns the API path for a sub-resource of the named AppRole role
+func (a *AppRoleAuthenticator) rolePath(roleName, suffix string) string {
+	return fmt.Sprintf("auth/%s/role/%s/%s", a.mountPath, roleName, suffix)
 }
 
 // GetRoleID returns the configured role ID
@@ -2,11 +2,22 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/vault-client-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestDetectAuthMethod(t *testing.T) {
@@ -62,7 +73,7 @@ func TestDetectAuthMethod(t *testing.T) {
 			}
 
 			// Test detection
-			result := detectAuthMethod()
+			result := detectAuthMethod(os.Getenv)
 			if result != tt.expected {
 				t.Errorf("detectAuthMethod() = %v, want %v", result, tt.expected)
 			}
@@ -120,6 +131,29 @@ func TestNewAuthConfigFromEnvironment(t *testing.T) {
 					c.Kubernetes.MountPath == "k8s-auth"
 			},
 		},
+		{
+			name: "retry settings",
+			envVars: map[string]string{
+				"VAULT_TOKEN":          "test-token",
+				"VAULT_MAX_RETRIES":    "5",
+				"VAULT_RETRY_WAIT_MIN": "250ms",
+			},
+			check: func(c *AuthConfig) bool {
+				return c.Retry != nil &&
+					c.Retry.MaxRetries == 5 &&
+					c.Retry.WaitMin == 250*time.Millisecond &&
+					c.Retry.WaitMax == DefaultRetryConfig().WaitMax
+			},
+		},
+		{
+			name: "retry defaults when unset",
+			envVars: map[string]string{
+				"VAULT_TOKEN": "test-token",
+			},
+			check: func(c *AuthConfig) bool {
+				return c.Retry == nil
+			},
+		},
 		{
 			name: "auto renew disabled",
 			envVars: map[string]string{
@@ -130,6 +164,33 @@ func TestNewAuthConfigFromEnvironment(t *testing.T) {
 				return !c.AutoRenew
 			},
 		},
+		{
+			name: "max token age",
+			envVars: map[string]string{
+				"VAULT_TOKEN":         "test-token",
+				"VAULT_MAX_TOKEN_AGE": "24h",
+			},
+			check: func(c *AuthConfig) bool {
+				return c.MaxTokenAge == 24*time.Hour
+			},
+		},
+		{
+			name: "auth method chain",
+			envVars: map[string]string{
+				"VAULT_ADDR":         "https://vault.example.com",
+				"VAULT_AUTH_METHOD":  "token",
+				"VAULT_AUTH_METHODS": "Kubernetes, approle",
+				"VAULT_K8S_ROLE":     "kms",
+				"VAULT_ROLE_ID":      "role-id",
+			},
+			check: func(c *AuthConfig) bool {
+				return c.Method == AuthMethodKubernetes &&
+					len(c.Methods) == 2 && c.Methods[1] == AuthMethodAppRole &&
+					c.Kubernetes != nil && c.Kubernetes.Role == "kms" &&
+					c.AppRole != nil && c.AppRole.RoleID == "role-id" &&
+					c.Token == nil
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -139,7 +200,7 @@ func TestNewAuthConfigFromEnvironment(t *testing.T) {
 
 			// Set test environment variables
 			for k, v := range tt.envVars {
-				os.Setenv(k, v)
+				t.Setenv(k, v)
 			}
 
 			// Create config from environment
@@ -182,6 +243,67 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "auth method chain",
+			config: &AuthConfig{
+				Method:     AuthMethodKubernetes,
+				Methods:    []AuthMethod{AuthMethodKubernetes, AuthMethodAppRole},
+				VaultAddr:  "https://vault.example.com",
+				Kubernetes: &KubernetesConfig{Role: "kms"},
+				AppRole:    &AppRoleConfig{RoleID: "role-id"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "auth method chain missing fallback settings",
+			config: &AuthConfig{
+				Method:     AuthMethodKubernetes,
+				Methods:    []AuthMethod{AuthMethodKubernetes, AuthMethodAppRole},
+				VaultAddr:  "https://vault.example.com",
+				Kubernetes: &KubernetesConfig{Role: "kms"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "renewal jitter of 1 or more",
+			config: &AuthConfig{
+				Method:        AuthMethodToken,
+				VaultAddr:     "https://vault.example.com",
+				RenewalJitter: 1,
+				Token:         &TokenConfig{Token: "test-token"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max token age",
+			config: &AuthConfig{
+				Method:      AuthMethodToken,
+				VaultAddr:   "https://vault.example.com",
+				MaxTokenAge: -time.Hour,
+				Token:       &TokenConfig{Token: "test-token"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry wait min above max",
+			config: &AuthConfig{
+				Method:    AuthMethodToken,
+				VaultAddr: "https://vault.example.com",
+				Token:     &TokenConfig{Token: "test-token"},
+				Retry:     &RetryConfig{MaxRetries: 2, WaitMin: 5 * time.Second, WaitMax: time.Second},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid max retries",
+			config: &AuthConfig{
+				Method:    AuthMethodToken,
+				VaultAddr: "https://vault.example.com",
+				Token:     &TokenConfig{Token: "test-token"},
+				Retry:     &RetryConfig{MaxRetries: -2},
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing token",
 			config: &AuthConfig{
@@ -202,6 +324,17 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "kubernetes config with namespace role map only",
+			config: &AuthConfig{
+				Method:    AuthMethodKubernetes,
+				VaultAddr: "https://vault.example.com",
+				Kubernetes: &KubernetesConfig{
+					NamespaceRoleMap: map[string]string{"team-a": "role-a"},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing kubernetes role",
 			config: &AuthConfig{
@@ -222,6 +355,18 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "approle binding secret_id without one",
+			config: &AuthConfig{
+				Method:    AuthMethodAppRole,
+				VaultAddr: "https://vault.example.com",
+				AppRole: &AppRoleConfig{
+					RoleID:       "role-id",
+					BindSecretID: true,
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "unsupported method",
 			config: &AuthConfig{
@@ -242,6 +387,200 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestParseNamespaceRoleMap(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{
+			name:  "empty",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "single mapping",
+			value: "team-a=role-a",
+			want:  map[string]string{"team-a": "role-a"},
+		},
+		{
+			name:  "multiple mappings with whitespace",
+			value: " team-a = role-a , team-b=role-b",
+			want:  map[string]string{"team-a": "role-a", "team-b": "role-b"},
+		},
+		{
+			name:  "malformed entries skipped",
+			value: "team-a,=role-b,team-c=,team-d=role-d",
+			want:  map[string]string{"team-d": "role-d"},
+		},
+		{
+			name:  "only malformed entries",
+			value: "garbage",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseNamespaceRoleMap(tt.value)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseNamespaceRoleMap() = %v, want %v", got, tt.want)
+			}
+			for namespace, role := range tt.want {
+				if got[namespace] != role {
+					t.Errorf("parseNamespaceRoleMap()[%q] = %q, want %q", namespace, got[namespace], role)
+				}
+			}
+		})
+	}
+}
+
+func TestKubernetesSelectRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		roles     map[string]string
+		namespace string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "default role without map",
+			role:      "default-role",
+			namespace: "team-a",
+			want:      "default-role",
+		},
+		{
+			name:      "mapped namespace",
+			role:      "default-role",
+			roles:     map[string]string{"team-a": "role-a"},
+			namespace: "team-a",
+			want:      "role-a",
+		},
+		{
+			name:      "unmapped namespace falls back to default",
+			role:      "default-role",
+			roles:     map[string]string{"team-a": "role-a"},
+			namespace: "team-b",
+			want:      "default-role",
+		},
+		{
+			name:      "unmapped namespace without default",
+			roles:     map[string]string{"team-a": "role-a"},
+			namespace: "team-b",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POD_NAMESPACE", tt.namespace)
+
+			k := &KubernetesAuthenticator{
+				role:               tt.role,
+				namespaceRoles:     tt.roles,
+				serviceAccountPath: t.TempDir(),
+			}
+
+			got, err := k.selectRole()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectRole() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubernetesRenewRelogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		renewStatus int
+		rotateJWT   bool
+		wantLogin   bool
+		wantErr     bool
+	}{
+		{name: "renewed", renewStatus: http.StatusOK},
+		{name: "forbidden with unchanged JWT", renewStatus: http.StatusForbidden, wantLogin: true},
+		{name: "precondition failed with unchanged JWT", renewStatus: http.StatusPreconditionFailed, wantLogin: true},
+		{name: "rotated JWT", renewStatus: http.StatusInternalServerError, rotateJWT: true, wantLogin: true},
+		{name: "other failure with unchanged JWT", renewStatus: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loginJWT string
+			vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/token/renew-self":
+					w.WriteHeader(tt.renewStatus)
+					if tt.renewStatus != http.StatusOK {
+						fmt.Fprint(w, `{"errors":["permission denied"]}`)
+						return
+					}
+					fmt.Fprint(w, `{"data":{},"auth":{"client_token":"old-token","lease_duration":3600}}`)
+				case "/v1/auth/kubernetes/login":
+					var body struct {
+						JWT string `json:"jwt"`
+					}
+					json.NewDecoder(r.Body).Decode(&body)
+					loginJWT = body.JWT
+					fmt.Fprint(w, `{"data":{},"auth":{"client_token":"new-token","lease_duration":1800}}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer vaultServer.Close()
+
+			saPath := t.TempDir()
+			jwt := "current-jwt"
+			if tt.rotateJWT {
+				jwt = "rotated-jwt"
+			}
+			if err := os.WriteFile(filepath.Join(saPath, "token"), []byte(jwt), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			k := &KubernetesAuthenticator{
+				BaseAuthenticator: BaseAuthenticator{
+					Method:    AuthMethodKubernetes,
+					VaultAddr: vaultServer.URL,
+					Retry:     &RetryConfig{MaxRetries: -1},
+				},
+				role:               "kms",
+				mountPath:          defaultKubernetesMountPath,
+				serviceAccountPath: saPath,
+				jwt:                "current-jwt",
+			}
+
+			client, err := k.newVaultClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.SetToken("old-token")
+
+			err = k.Renew(context.Background(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Renew() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if gotLogin := loginJWT != ""; gotLogin != tt.wantLogin {
+				t.Fatalf("re-login = %v, want %v", gotLogin, tt.wantLogin)
+			}
+
+			if tt.wantLogin {
+				if loginJWT != jwt {
+					t.Errorf("logged in with JWT %q, want the freshly read %q", loginJWT, jwt)
+				}
+				if k.TokenTTL != 30*time.Minute {
+					t.Errorf("TokenTTL = %v, want the TTL of the new login", k.TokenTTL)
+				}
+			}
+		})
+	}
+}
+
 func TestBaseAuthenticatorShouldRenew(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -288,6 +627,113 @@ func TestBaseAuthenticatorShouldRenew(t *testing.T) {
 	}
 }
 
+func TestTokenRenewConcurrentReads(t *testing.T) {
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+		case "/v1/auth/token/renew-self":
+			w.Write([]byte(`{"data":{},"auth":{"client_token":"t","lease_duration":3600,"renewable":true}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	authenticator, err := NewTokenAuth(&TokenConfig{Token: "t"}, vaultServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := authenticator.Authenticate(context.Background())
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	m := &Manager{authenticator: authenticator, client: client, config: &AuthConfig{}}
+
+	var wg sync.WaitGroup
+
+	// The renewal loop updates the TTL...
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			if err := authenticator.Renew(context.Background(), client); err != nil {
+				t.Errorf("Renew() error = %v", err)
+			}
+		}
+	}()
+
+	// ...while the status handler and token metrics read it
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authenticator.GetTokenTTL()
+			authenticator.GetLastRenewal()
+			authenticator.ShouldRenew()
+			m.Status()
+		}()
+	}
+	wg.Wait()
+
+	if got := authenticator.GetTokenTTL(); got != time.Hour {
+		t.Errorf("GetTokenTTL() = %v, want %v", got, time.Hour)
+	}
+	if status := m.Status(); status.RemainingSeconds < 3590 {
+		t.Errorf("RemainingSeconds = %d, want it counted from the last renewal", status.RemainingSeconds)
+	}
+}
+
+func TestManagerAdjustRenewBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		renewBuffer time.Duration
+		wantBuffer  time.Duration
+	}{
+		{name: "buffer within TTL allowance", ttl: time.Hour, renewBuffer: 5 * time.Minute, wantBuffer: 5 * time.Minute},
+		{name: "buffer exceeds short TTL", ttl: 2 * time.Minute, renewBuffer: 5 * time.Minute, wantBuffer: time.Minute},
+		{name: "buffer over half the TTL", ttl: 8 * time.Minute, renewBuffer: 5 * time.Minute, wantBuffer: 4 * time.Minute},
+		{name: "non-renewable token", ttl: 0, renewBuffer: 5 * time.Minute, wantBuffer: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := &TokenAuthenticator{BaseAuthenticator: BaseAuthenticator{
+				Method:      AuthMethodToken,
+				TokenTTL:    tt.ttl,
+				LastRenewal: time.Now(),
+				RenewBuffer: tt.renewBuffer,
+			}}
+			m := &Manager{authenticator: authenticator, logger: slog.New(slog.NewTextHandler(os.Stderr, nil))}
+			adjustmentsBefore := testutil.ToFloat64(renewBufferAdjustments.WithLabelValues(string(AuthMethodToken)))
+
+			m.adjustRenewBuffer(authenticator)
+
+			if authenticator.RenewBuffer != tt.wantBuffer {
+				t.Errorf("RenewBuffer = %v, want %v", authenticator.RenewBuffer, tt.wantBuffer)
+			}
+
+			wantAdjustments := 0.0
+			if tt.wantBuffer != tt.renewBuffer {
+				wantAdjustments = 1
+			}
+			if got := testutil.ToFloat64(renewBufferAdjustments.WithLabelValues(string(AuthMethodToken))) - adjustmentsBefore; got != wantAdjustments {
+				t.Errorf("kms_auth_renew_buffer_adjustments_total increased by %v, want %v", got, wantAdjustments)
+			}
+
+			// A freshly renewed token must not need renewing again straight away
+			if tt.ttl > 0 && authenticator.ShouldRenew() {
+				t.Error("ShouldRenew() = true right after renewal")
+			}
+		})
+	}
+}
+
 func TestAuthError(t *testing.T) {
 	err := NewAuthError(
 		AuthMethodToken,
@@ -350,31 +796,1163 @@ func TestManagerCalculateRenewalSleep(t *testing.T) {
 	}
 }
 
-// mockAuthenticator is a mock implementation for testing
-type mockAuthenticator struct {
-	ttl time.Duration
-}
+func TestJitterSleep(t *testing.T) {
+	const sleep = time.Hour
 
-func (m *mockAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
-	return nil, nil
-}
+	if got := jitterSleep(sleep, 0); got != sleep {
+		t.Errorf("jitterSleep() without jitter = %v, want %v", got, sleep)
+	}
 
-func (m *mockAuthenticator) Renew(ctx context.Context, client *vault.Client) error {
-	return nil
-}
+	spread := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		got := jitterSleep(sleep, 0.1)
+		if got > sleep || got < sleep-6*time.Minute {
+			t.Fatalf("jitterSleep() = %v, want within [%v, %v]", got, sleep-6*time.Minute, sleep)
+		}
+		spread[got] = true
+	}
 
-func (m *mockAuthenticator) ShouldRenew() bool {
-	return false
+	if len(spread) < 2 {
+		t.Error("jitterSleep() returned the same sleep every time")
+	}
 }
 
-func (m *mockAuthenticator) Revoke(ctx context.Context, client *vault.Client) error {
-	return nil
-}
+func TestManagerNextCheckInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		interval time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "renewable token uses renewal sleep",
+			ttl:      2 * time.Hour,
+			interval: 5 * time.Minute,
+			expected: time.Hour,
+		},
+		{
+			name:     "non-renewable token uses check interval",
+			ttl:      0,
+			interval: 5 * time.Minute,
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "non-renewable token without check interval",
+			ttl:      0,
+			interval: 0,
+			expected: time.Hour,
+		},
+	}
 
-func (m *mockAuthenticator) GetMethod() AuthMethod {
-	return AuthMethodToken
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				authenticator:             &mockAuthenticator{ttl: tt.ttl},
+				nonRenewableCheckInterval: tt.interval,
+			}
+
+			if result := m.nextCheckInterval(); result != tt.expected {
+				t.Errorf("nextCheckInterval() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
 }
 
-func (m *mockAuthenticator) GetTokenTTL() time.Duration {
-	return m.ttl
+func TestManagerRenewalFailureBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxFailure   time.Duration
+		failingSince time.Duration // how long ago failures started (0 = first failure)
+		wantFatal    bool
+	}{
+		{
+			name:       "retry forever when unset",
+			maxFailure: 0,
+			wantFatal:  false,
+		},
+		{
+			name:       "first failure within budget",
+			maxFailure: time.Minute,
+			wantFatal:  false,
+		},
+		{
+			name:         "failures within budget",
+			maxFailure:   time.Minute,
+			failingSince: 30 * time.Second,
+			wantFatal:    false,
+		},
+		{
+			name:         "failures exceed budget",
+			maxFailure:   time.Minute,
+			failingSince: 2 * time.Minute,
+			wantFatal:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				config: &AuthConfig{MaxRenewalFailureDuration: tt.maxFailure},
+			}
+			if tt.failingSince > 0 {
+				m.failingSince = time.Now().Add(-tt.failingSince)
+			}
+
+			err := m.renewalFailed(ErrTokenRenewalFailed)
+			if (err != nil) != tt.wantFatal {
+				t.Errorf("renewalFailed() error = %v, wantFatal %v", err, tt.wantFatal)
+			}
+
+			if tt.wantFatal && !errors.Is(err, ErrTokenRenewalFailed) {
+				t.Errorf("Expected fatal error to wrap the renewal error, got %v", err)
+			}
+
+			m.renewalSucceeded()
+			if !m.failingSince.IsZero() {
+				t.Error("Expected renewalSucceeded() to reset failure tracking")
+			}
+		})
+	}
+}
+
+func TestManagerTokenAge(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		lastAuth      time.Time
+		wantAge       time.Duration
+	}{
+		{
+			name:          "base authenticator last renewal",
+			authenticator: &TokenAuthenticator{BaseAuthenticator: BaseAuthenticator{LastRenewal: now.Add(-90 * time.Second)}},
+			wantAge:       90 * time.Second,
+		},
+		{
+			name:          "falls back to manager auth time",
+			authenticator: &mockAuthenticator{ttl: time.Hour},
+			lastAuth:      now.Add(-30 * time.Second),
+			wantAge:       30 * time.Second,
+		},
+		{
+			name:          "unknown",
+			authenticator: &mockAuthenticator{ttl: time.Hour},
+			wantAge:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{authenticator: tt.authenticator, lastAuth: tt.lastAuth}
+
+			m.sampleTokenAge(now)
+			if got := testutil.ToFloat64(tokenAgeSeconds); got != tt.wantAge.Seconds() {
+				t.Errorf("kms_auth_token_age_seconds = %v, want %v", got, tt.wantAge.Seconds())
+			}
+		})
+	}
+}
+
+// reloginAuthenticator is a mockAuthenticator for a method that can log in again
+type reloginAuthenticator struct {
+	mockAuthenticator
+}
+
+func (r *reloginAuthenticator) GetMethod() AuthMethod {
+	return AuthMethodAppRole
+}
+
+// renewingAuthenticator is a renewable AppRole-like authenticator that records renewals and
+// logins that overlap each other
+type renewingAuthenticator struct {
+	mockAuthenticator
+	renewErr error
+
+	inFlight atomic.Int32
+	overlaps atomic.Int32
+	revoked  atomic.Int32
+}
+
+func (r *renewingAuthenticator) enter() {
+	if r.inFlight.Add(1) > 1 {
+		r.overlaps.Add(1)
+	}
+	time.Sleep(5 * time.Millisecond)
+	r.inFlight.Add(-1)
+}
+
+func (r *renewingAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
+	r.enter()
+	return vault.New(vault.WithAddress("http://127.0.0.1:8200"))
+}
+
+func (r *renewingAuthenticator) Renew(ctx context.Context, client *vault.Client) error {
+	r.enter()
+	return r.renewErr
+}
+
+func (r *renewingAuthenticator) ShouldRenew() bool {
+	return true
+}
+
+func (r *renewingAuthenticator) Revoke(ctx context.Context, client *vault.Client) error {
+	r.revoked.Add(1)
+	return nil
+}
+
+func (r *renewingAuthenticator) GetMethod() AuthMethod {
+	return AuthMethodAppRole
+}
+
+func TestManagerForceRenewal(t *testing.T) {
+	authenticator := &renewingAuthenticator{mockAuthenticator: mockAuthenticator{ttl: time.Hour}}
+	oldClient, _ := vault.New(vault.WithAddress("http://127.0.0.1:8200"))
+
+	m := &Manager{
+		authenticator: authenticator,
+		client:        oldClient,
+		config:        &AuthConfig{},
+		logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	// A forced renewal never overlaps a cycle of the renewal loop
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.renewalCycle(context.Background(), time.Second)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := m.ForceRenewal(context.Background()); err != nil {
+				t.Errorf("ForceRenewal() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := authenticator.overlaps.Load(); got != 0 {
+		t.Errorf("%d renewals overlapped", got)
+	}
+
+	// Re-authenticating clears the failure tracking and revokes the replaced token
+	authenticator.renewErr = ErrTokenRenewalFailed
+	m.renewalFailed(ErrTokenRenewalFailed)
+
+	if err := m.ForceRenewal(context.Background()); err != nil {
+		t.Fatalf("ForceRenewal() error = %v", err)
+	}
+
+	if m.client == oldClient {
+		t.Error("expected ForceRenewal to swap in the re-authenticated client")
+	}
+	if !m.failingSince.IsZero() || m.lastErr != nil {
+		t.Error("expected a forced re-authentication to clear failure tracking")
+	}
+	if got := authenticator.revoked.Load(); got != 1 {
+		t.Errorf("revoked %d tokens, want the replaced one", got)
+	}
+}
+
+func TestManagerTokenDeadline(t *testing.T) {
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		lookup       string
+		maxTokenAge  time.Duration
+		wantDeadline time.Time
+	}{
+		{
+			name:         "explicit max ttl",
+			lookup:       fmt.Sprintf(`{"data":{"creation_time":%d,"explicit_max_ttl":7200}}`, created.Unix()),
+			wantDeadline: created.Add(2 * time.Hour),
+		},
+		{
+			name:         "max token age before explicit max ttl",
+			lookup:       fmt.Sprintf(`{"data":{"creation_time":%d,"explicit_max_ttl":7200}}`, created.Unix()),
+			maxTokenAge:  90 * time.Minute,
+			wantDeadline: created.Add(90 * time.Minute),
+		},
+		{
+			name:         "explicit max ttl before max token age",
+			lookup:       fmt.Sprintf(`{"data":{"creation_time":%d,"explicit_max_ttl":3900}}`, created.Unix()),
+			maxTokenAge:  24 * time.Hour,
+			wantDeadline: created.Add(65 * time.Minute),
+		},
+		{
+			name:   "no limit",
+			lookup: fmt.Sprintf(`{"data":{"creation_time":%d,"explicit_max_ttl":0}}`, created.Unix()),
+		},
+		{
+			name:         "lookup failure counts from the login",
+			maxTokenAge:  2 * time.Hour,
+			wantDeadline: created.Add(3 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.lookup == "" || r.URL.Path != "/v1/auth/token/lookup-self" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.lookup))
+			}))
+			defer vaultServer.Close()
+
+			client, _ := vault.New(vault.WithAddress(vaultServer.URL), vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}))
+			m := &Manager{
+				authenticator: &reloginAuthenticator{},
+				client:        client,
+				config:        &AuthConfig{MaxTokenAge: tt.maxTokenAge},
+				logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
+				lastAuth:      created.Add(time.Hour),
+			}
+
+			m.refreshTokenDeadline(context.Background())
+			if got := m.getTokenDeadline(); !got.Equal(tt.wantDeadline) {
+				t.Errorf("token deadline = %v, want %v", got, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestManagerCheckTokenDeadline(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		deadline      time.Time
+		wantRelogin   bool
+	}{
+		{name: "no deadline", authenticator: &reloginAuthenticator{}},
+		{name: "deadline far away", authenticator: &reloginAuthenticator{}, deadline: time.Now().Add(time.Hour)},
+		{name: "deadline within the buffer", authenticator: &reloginAuthenticator{}, deadline: time.Now().Add(time.Minute), wantRelogin: true},
+		{name: "static token can't log in again", authenticator: &mockAuthenticator{}, deadline: time.Now().Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{
+				authenticator: tt.authenticator,
+				config:        &AuthConfig{},
+				logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
+				tokenDeadline: tt.deadline,
+			}
+			reloginsBefore := testutil.ToFloat64(tokenMaxAgeReauthentications)
+
+			if got := m.checkTokenDeadline(context.Background()); got != tt.wantRelogin {
+				t.Errorf("checkTokenDeadline() = %v, want %v", got, tt.wantRelogin)
+			}
+
+			wantCount := 0.0
+			if tt.wantRelogin {
+				wantCount = 1
+			}
+			if got := testutil.ToFloat64(tokenMaxAgeReauthentications) - reloginsBefore; got != wantCount {
+				t.Errorf("kms_auth_token_max_age_reauthentications_total increased by %v, want %v", got, wantCount)
+			}
+		})
+	}
+}
+
+func TestManagerStatus(t *testing.T) {
+	m := &Manager{
+		authenticator: &mockAuthenticator{ttl: time.Hour},
+		config:        &AuthConfig{},
+	}
+
+	status := m.Status()
+	if status.Authenticated || status.Healthy {
+		t.Errorf("Expected unauthenticated status before login, got %+v", status)
+	}
+
+	m.client = &vault.Client{}
+	m.lastAuth = time.Now().Add(-20 * time.Minute)
+
+	status = m.Status()
+	if status.Method != AuthMethodToken || !status.Healthy || !status.Renewable {
+		t.Errorf("Expected healthy renewable token status, got %+v", status)
+	}
+	if status.TTLSeconds != 3600 {
+		t.Errorf("Expected ttlSeconds=3600, got %d", status.TTLSeconds)
+	}
+	if status.RemainingSeconds < 2390 || status.RemainingSeconds > 2400 {
+		t.Errorf("Expected about 40m remaining, got %ds", status.RemainingSeconds)
+	}
+	if status.LastRenewal != nil {
+		t.Errorf("Expected no renewal yet, got %v", status.LastRenewal)
+	}
+
+	m.recordRenewal()
+	if status = m.Status(); status.LastRenewal == nil || status.RemainingSeconds < 3590 {
+		t.Errorf("Expected renewal to reset the remaining TTL, got %+v", status)
+	}
+
+	m.renewalFailed(ErrTokenRenewalFailed)
+	status = m.Status()
+	if status.Healthy || status.LastError == "" || status.FailingSince == nil {
+		t.Errorf("Expected unhealthy status after failed renewal, got %+v", status)
+	}
+
+	m.renewalSucceeded()
+	if status = m.Status(); !status.Healthy || status.LastError != "" {
+		t.Errorf("Expected healthy status after recovery, got %+v", status)
+	}
+}
+
+func TestAuthenticatorRetryConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		retry     *RetryConfig
+		wantCalls int32
+	}{
+		{
+			name:      "retries disabled",
+			retry:     &RetryConfig{MaxRetries: -1},
+			wantCalls: 1,
+		},
+		{
+			name:      "three retries",
+			retry:     &RetryConfig{MaxRetries: 3, WaitMin: time.Millisecond, WaitMax: time.Millisecond},
+			wantCalls: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer vaultServer.Close()
+
+			authenticator, err := NewAuthenticator(&AuthConfig{
+				Method:    AuthMethodToken,
+				VaultAddr: vaultServer.URL,
+				Token:     &TokenConfig{Token: "test-token"},
+				Retry:     tt.retry,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := authenticator.Authenticate(context.Background()); err == nil {
+				t.Fatal("Expected authentication against an unavailable Vault to fail")
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("Vault received %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRecordAuthOperation(t *testing.T) {
+	success := authOperations.WithLabelValues(string(AuthMethodAppRole), opRenew, "success")
+	failure := authOperations.WithLabelValues(string(AuthMethodAppRole), opRenew, "failure")
+	successBefore, failureBefore := testutil.ToFloat64(success), testutil.ToFloat64(failure)
+
+	recordAuthOperation(AuthMethodAppRole, opRenew, nil)
+	recordAuthOperation(AuthMethodAppRole, opRenew, ErrTokenRenewalFailed)
+	recordAuthOperation(AuthMethodAppRole, opRenew, ErrTokenRenewalFailed)
+
+	if got := testutil.ToFloat64(success) - successBefore; got != 1 {
+		t.Errorf("Expected 1 successful renewal recorded, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(failure) - failureBefore; got != 2 {
+		t.Errorf("Expected 2 failed renewals recorded, got %v", got)
+	}
+}
+
+// mockAuthenticator is a mock implementation for testing
+type mockAuthenticator struct {
+	ttl time.Duration
+}
+
+func (m *mockAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
+	return nil, nil
+}
+
+func (m *mockAuthenticator) Renew(ctx context.Context, client *vault.Client) error {
+	return nil
+}
+
+func (m *mockAuthenticator) ShouldRenew() bool {
+	return false
+}
+
+func (m *mockAuthenticator) Revoke(ctx context.Context, client *vault.Client) error {
+	return nil
+}
+
+func (m *mockAuthenticator) GetMethod() AuthMethod {
+	return AuthMethodToken
+}
+
+func (m *mockAuthenticator) GetTokenTTL() time.Duration {
+	return m.ttl
+}
+
+// chainMember is an authenticator of a given method that fails to log in while err is set
+type chainMember struct {
+	mockAuthenticator
+	method AuthMethod
+	err    error
+	logins int
+}
+
+func (c *chainMember) Authenticate(ctx context.Context) (*vault.Client, error) {
+	c.logins++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return vault.New(vault.WithAddress("http://127.0.0.1:8200"))
+}
+
+func (c *chainMember) GetMethod() AuthMethod {
+	return c.method
+}
+
+func TestChainAuthenticator(t *testing.T) {
+	kubernetes := &chainMember{method: AuthMethodKubernetes, mockAuthenticator: mockAuthenticator{ttl: time.Hour}}
+	approle := &chainMember{method: AuthMethodAppRole, mockAuthenticator: mockAuthenticator{ttl: 20 * time.Minute}}
+
+	chain, err := NewChainAuthenticator(kubernetes, approle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := chain.Authenticate(context.Background()); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if chain.GetMethod() != AuthMethodKubernetes || approle.logins != 0 {
+		t.Errorf("active method = %s after %d AppRole logins, want kubernetes without trying AppRole", chain.GetMethod(), approle.logins)
+	}
+
+	// The kubernetes backend breaks: AppRole takes over
+	kubernetes.err = errors.New("permission denied")
+	fallbacksBefore := testutil.ToFloat64(authChainFallbacks.WithLabelValues(string(AuthMethodAppRole)))
+
+	if _, err := chain.Authenticate(context.Background()); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if chain.GetMethod() != AuthMethodAppRole || chain.GetTokenTTL() != 20*time.Minute {
+		t.Errorf("active = %s with TTL %s, want approle with 20m", chain.GetMethod(), chain.GetTokenTTL())
+	}
+	if got := testutil.ToFloat64(authChainFallbacks.WithLabelValues(string(AuthMethodAppRole))) - fallbacksBefore; got != 1 {
+		t.Errorf("kms_auth_chain_fallbacks_total increased by %v, want 1", got)
+	}
+
+	// Every method failing reports all errors
+	approle.err = errors.New("invalid secret id")
+	_, err = chain.Authenticate(context.Background())
+	if !errors.Is(err, ErrAuthenticationFailed) || !strings.Contains(err.Error(), "permission denied") || !strings.Contains(err.Error(), "invalid secret id") {
+		t.Errorf("Authenticate() error = %v, want both failures", err)
+	}
+
+	// The preferred method is back once its backend recovers
+	kubernetes.err = nil
+	if _, err := chain.Authenticate(context.Background()); err != nil || chain.GetMethod() != AuthMethodKubernetes {
+		t.Errorf("Authenticate() = %v with method %s, want kubernetes", err, chain.GetMethod())
+	}
+
+	if _, err := NewChainAuthenticator(); err == nil {
+		t.Error("NewChainAuthenticator() with no authenticators succeeded")
+	}
+}
+
+func TestNewAuthenticatorChain(t *testing.T) {
+	authenticator, err := NewAuthenticator(&AuthConfig{
+		Method:    AuthMethodAppRole,
+		Methods:   []AuthMethod{AuthMethodAppRole, AuthMethodToken},
+		VaultAddr: "https://vault.example.com",
+		AppRole:   &AppRoleConfig{RoleID: "role-id"},
+		Token:     &TokenConfig{Token: "backup-token"},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	chain, ok := authenticator.(*ChainAuthenticator)
+	if !ok {
+		t.Fatalf("NewAuthenticator() = %T, want *ChainAuthenticator", authenticator)
+	}
+	if methods := chain.Methods(); len(methods) != 2 || methods[0] != AuthMethodAppRole || methods[1] != AuthMethodToken {
+		t.Errorf("chain methods = %v, want [approle token]", methods)
+	}
+
+	chain.SetVaultAddr("https://vault-b.example.com")
+	for _, member := range chain.authenticators {
+		if addr := member.(addressable).GetVaultAddr(); addr != "https://vault-b.example.com" {
+			t.Errorf("%s address = %q, want the new address", member.GetMethod(), addr)
+		}
+	}
+}
+
+func TestReadVaultAddrFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "addr")
+	if err := os.WriteFile(path, []byte("  https://vault-a:8200\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := ReadVaultAddrFile(path)
+	if err != nil || addr != "https://vault-a:8200" {
+		t.Errorf("ReadVaultAddrFile() = %q, %v", addr, err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadVaultAddrFile(empty); err == nil {
+		t.Error("Expected error for empty address file")
+	}
+
+	t.Setenv("VAULT_ADDR", "https://from-env:8200")
+	t.Setenv("VAULT_ADDR_FILE", path)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	config := NewAuthConfigFromEnvironment()
+	if config.VaultAddr != "https://vault-a:8200" || config.VaultAddrFile != path {
+		t.Errorf("Expected address from file, got VaultAddr=%q VaultAddrFile=%q", config.VaultAddr, config.VaultAddrFile)
+	}
+}
+
+func TestManagerWatchesVaultAddrFile(t *testing.T) {
+	newVault := func(healthy bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !healthy {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data":{"ttl":0}}`))
+		}))
+	}
+
+	vaultA, vaultB, broken := newVault(true), newVault(true), newVault(false)
+	defer vaultA.Close()
+	defer vaultB.Close()
+	defer broken.Close()
+
+	path := filepath.Join(t.TempDir(), "addr")
+	writeAddr := func(addr string) {
+		if err := os.WriteFile(path, []byte(addr), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeAddr(vaultA.URL)
+
+	m, err := NewManager(&AuthConfig{
+		Method:        AuthMethodToken,
+		VaultAddr:     vaultA.URL,
+		VaultAddrFile: path,
+		Token:         &TokenConfig{Token: "test-token"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.addrFileCheckInterval = 10 * time.Millisecond
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer m.Stop(context.Background())
+
+	currentAddr := func() string {
+		client, err := m.GetClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.Configuration().Address
+	}
+
+	waitForAddr := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for currentAddr() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("client address = %q, want %q", currentAddr(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	writeAddr(vaultB.URL + "\n")
+	waitForAddr(vaultB.URL)
+
+	// A failing address keeps the previous client
+	writeAddr(broken.URL)
+	time.Sleep(100 * time.Millisecond)
+	if got := currentAddr(); got != vaultB.URL {
+		t.Errorf("client address = %q after failed switch, want %q", got, vaultB.URL)
+	}
+}
+
+func TestNewAuthConfigFromFile(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "https://vault.example.com")
+	t.Setenv("VAULT_TOKEN", "env-token")
+	t.Setenv("VAULT_AUTH_METHOD", "")
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	config, err := NewAuthConfigFromFile(writeFile("approle.json", `{"VAULT_AUTH_METHOD": "approle", "VAULT_ROLE_ID": "role-id"}`))
+	if err != nil {
+		t.Fatalf("NewAuthConfigFromFile() error = %v", err)
+	}
+	if config.Method != AuthMethodAppRole || config.AppRole == nil || config.AppRole.RoleID != "role-id" {
+		t.Errorf("Expected approle config from the file, got %+v", config)
+	}
+	if config.VaultAddr != "https://vault.example.com" {
+		t.Errorf("Expected VaultAddr to fall back to the environment, got %q", config.VaultAddr)
+	}
+
+	for name, content := range map[string]string{
+		"unknown key":  `{"VAULT_TOKEN": "file-token", "LOG_LEVEL": "debug"}`,
+		"invalid json": `{"VAULT_TOKEN": 42}`,
+	} {
+		if _, err := NewAuthConfigFromFile(writeFile("bad.json", content)); err == nil {
+			t.Errorf("%s: expected NewAuthConfigFromFile to fail", name)
+		}
+	}
+
+	if _, err := NewAuthConfigFromFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected NewAuthConfigFromFile to fail for a missing file")
+	}
+}
+
+func TestManagerSwitchConfig(t *testing.T) {
+	var mu sync.Mutex
+	var revoked []string
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			if token == "bad-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data":{"ttl":3600}}`))
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"data":{},"auth":{"client_token":"approle-token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/auth/token/revoke-self":
+			mu.Lock()
+			revoked = append(revoked, token)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	revokedTokens := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), revoked...)
+	}
+
+	tokenConfig := func(token string) *AuthConfig {
+		return &AuthConfig{
+			Method:    AuthMethodToken,
+			VaultAddr: vaultServer.URL,
+			AutoRenew: true,
+			Token:     &TokenConfig{Token: token},
+		}
+	}
+
+	m, err := NewManager(tokenConfig("token-a"), slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Readers keep using the manager while it switches
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				m.GetClient()
+				m.Status()
+				m.LastRenewal()
+			}
+		}
+	}()
+
+	original, _ := m.GetClient()
+
+	// A config that fails to log in keeps the current authentication
+	if err := m.SwitchConfig(context.Background(), tokenConfig("bad-token")); err == nil {
+		t.Fatal("SwitchConfig() succeeded with a rejected token")
+	}
+	if client, _ := m.GetClient(); client != original || m.GetMethod() != AuthMethodToken {
+		t.Error("a failed switch replaced the client")
+	}
+
+	// The same static token is not revoked, the new client still uses it
+	if err := m.SwitchConfig(context.Background(), tokenConfig("token-a")); err != nil {
+		t.Fatalf("SwitchConfig() to the same token error = %v", err)
+	}
+	if got := revokedTokens(); len(got) != 0 {
+		t.Errorf("revoked %v when switching to the same token", got)
+	}
+
+	if err := m.SwitchConfig(context.Background(), &AuthConfig{
+		Method:    AuthMethodAppRole,
+		VaultAddr: vaultServer.URL,
+		AutoRenew: true,
+		AppRole:   &AppRoleConfig{RoleID: "role-id"},
+	}); err != nil {
+		t.Fatalf("SwitchConfig() to approle error = %v", err)
+	}
+
+	close(done)
+	readers.Wait()
+
+	if m.GetMethod() != AuthMethodAppRole {
+		t.Errorf("method = %s after switching, want %s", m.GetMethod(), AuthMethodAppRole)
+	}
+	if client, _ := m.GetClient(); client == original {
+		t.Error("client not swapped after switching")
+	}
+	if m.cancelRenewal == nil {
+		t.Error("renewal not restarted after switching")
+	}
+	if got := revokedTokens(); len(got) != 1 || got[0] != "token-a" {
+		t.Errorf("revoked tokens = %v after switching, want [token-a]", got)
+	}
+
+	// Stop revokes the token of the new method
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := revokedTokens(); len(got) != 2 || got[1] != "approle-token" {
+		t.Errorf("revoked tokens = %v after Stop, want [token-a approle-token]", got)
+	}
+}
+
+func TestManagerSwitchConfigRevokeGrace(t *testing.T) {
+	var mu sync.Mutex
+	var revoked []string
+	isRevoked := func(token string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range revoked {
+			if r == token {
+				return true
+			}
+		}
+		return false
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			w.Write([]byte(`{"data":{"ttl":3600}}`))
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"data":{},"auth":{"client_token":"approle-token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/auth/token/revoke-self":
+			mu.Lock()
+			revoked = append(revoked, token)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/transit/decrypt/node":
+			// A Seal or Unseal that took the client before the swap
+			started <- struct{}{}
+			<-release
+			if isRevoked(token) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"data":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	tokenConfig := func(token string) *AuthConfig {
+		return &AuthConfig{Method: AuthMethodToken, VaultAddr: vaultServer.URL, Token: &TokenConfig{Token: token}}
+	}
+
+	m, err := NewManager(tokenConfig("token-a"), slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetRevokeGracePeriod(100 * time.Millisecond)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	original, _ := m.GetClient()
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := original.Write(context.Background(), "/transit/decrypt/node", nil)
+		inFlight <- err
+	}()
+	<-started
+
+	if err := m.SwitchConfig(context.Background(), tokenConfig("token-b")); err != nil {
+		t.Fatalf("SwitchConfig() error = %v", err)
+	}
+	close(release)
+
+	if err := <-inFlight; err != nil {
+		t.Errorf("request in flight across the switch failed: %v", err)
+	}
+
+	// The previous token is revoked once the grace period has passed
+	deadline := time.Now().Add(5 * time.Second)
+	for !isRevoked("token-a") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !isRevoked("token-a") {
+		t.Error("previous token not revoked after the grace period")
+	}
+
+	// Stop revokes tokens still waiting out the grace period
+	m.SetRevokeGracePeriod(time.Hour)
+	if err := m.SwitchConfig(context.Background(), &AuthConfig{
+		Method:    AuthMethodAppRole,
+		VaultAddr: vaultServer.URL,
+		AppRole:   &AppRoleConfig{RoleID: "role-id"},
+	}); err != nil {
+		t.Fatalf("SwitchConfig() to approle error = %v", err)
+	}
+	if isRevoked("token-b") {
+		t.Error("previous token revoked before the grace period passed")
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if !isRevoked("token-b") || !isRevoked("approle-token") {
+		t.Errorf("revoked tokens = %v after Stop, want token-b and approle-token", revoked)
+	}
+}
+
+func TestAppRoleSecretIDRotation(t *testing.T) {
+	var mu sync.Mutex
+	expiries := map[string]string{"secret-1": time.Now().Add(30 * time.Minute).UTC().Format(time.RFC3339)}
+	var loginSecretIDs []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			secretID, _ := body["secret_id"].(string)
+			loginSecretIDs = append(loginSecretIDs, secretID)
+			w.Write([]byte(`{"data":null,"auth":{"client_token":"t","lease_duration":3600,"renewable":true,"metadata":{"role_name":"kms"}}}`))
+		case "/v1/auth/approle/role/kms/secret-id/lookup":
+			secretID, _ := body["secret_id"].(string)
+			fmt.Fprintf(w, `{"data":{"expiration_time":%q,"secret_id_ttl":1800}}`, expiries[secretID])
+		case "/v1/auth/approle/role/kms/secret-id":
+			expiries["secret-2"] = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+			w.Write([]byte(`{"data":{"secret_id":"secret-2","secret_id_accessor":"a","secret_id_ttl":86400}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		autoRotate   bool
+		wantSecretID string
+	}{
+		{name: "warns without auto-rotation", autoRotate: false, wantSecretID: "secret-1"},
+		{name: "rotates within renew buffer", autoRotate: true, wantSecretID: "secret-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewManager(&AuthConfig{
+				Method:    AuthMethodAppRole,
+				VaultAddr: srv.URL,
+				AppRole: &AppRoleConfig{
+					RoleID:             "role",
+					SecretID:           "secret-1",
+					MountPath:          defaultAppRoleMountPath,
+					AutoRotateSecretID: tt.autoRotate,
+				},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.Start(context.Background()); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+
+			approle := m.authenticator.(*AppRoleAuthenticator)
+			if approle.SecretIDExpiry().IsZero() {
+				t.Fatal("SecretIDExpiry() is zero after start")
+			}
+
+			// The SecretID expires within the default 1h buffer, so the loop must wake up early
+			if sleep := m.nextCheckInterval(); sleep != minSecretIDCheckInterval {
+				t.Errorf("nextCheckInterval() = %v, want %v", sleep, minSecretIDCheckInterval)
+			}
+
+			m.checkSecretID(context.Background())
+
+			if err := m.reauthenticate(context.Background()); err != nil {
+				t.Fatalf("reauthenticate() error = %v", err)
+			}
+
+			mu.Lock()
+			last := loginSecretIDs[len(loginSecretIDs)-1]
+			mu.Unlock()
+			if last != tt.wantSecretID {
+				t.Errorf("re-authenticated with %q, want %q", last, tt.wantSecretID)
+			}
+
+			if tt.autoRotate && time.Until(approle.SecretIDExpiry()) < 23*time.Hour {
+				t.Errorf("SecretIDExpiry() = %v, want the rotated SecretID's expiry", approle.SecretIDExpiry())
+			}
+		})
+	}
+}
+
+// TestAppRoleConcurrentSecretIDAccess logs in while the SecretID is rotated and looked up, as
+// the renewal loop and the address file watcher do; run with -race to catch unguarded state
+func TestAppRoleConcurrentSecretIDAccess(t *testing.T) {
+	var rotations atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"data":null,"auth":{"client_token":"t","lease_duration":3600,"renewable":true,"metadata":{"role_name":"kms"}}}`))
+		case "/v1/auth/approle/role/kms/secret-id/lookup":
+			fmt.Fprintf(w, `{"data":{"expiration_time":%q}}`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		case "/v1/auth/approle/role/kms/secret-id":
+			fmt.Fprintf(w, `{"data":{"secret_id":"secret-%d"}}`, rotations.Add(1))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	approle, err := NewAppRoleAuth(&AppRoleConfig{RoleID: "role", SecretID: "secret-0"}, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := approle.Authenticate(context.Background())
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	// The address file watcher logs in...
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			if _, err := approle.Authenticate(context.Background()); err != nil {
+				t.Errorf("Authenticate() error = %v", err)
+			}
+		}
+	}()
+
+	// ...while the renewal loop rotates and looks up the SecretID
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := approle.RotateSecretID(context.Background(), client); err != nil {
+				t.Errorf("RotateSecretID() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := approle.RefreshSecretIDExpiry(context.Background(), client); err != nil {
+				t.Errorf("RefreshSecretIDExpiry() error = %v", err)
+			}
+			approle.SecretIDExpiry()
+		}()
+	}
+	wg.Wait()
+
+	if got := approle.loginRequest().SecretId; got == "secret-0" {
+		t.Errorf("SecretID = %q, want a rotated one", got)
+	}
+}
+
+func TestNewAppRoleAuthBindSecretID(t *testing.T) {
+	t.Setenv("VAULT_SECRET_ID", "")
+
+	tests := []struct {
+		name    string
+		config  *AppRoleConfig
+		wantErr bool
+	}{
+		{
+			name:   "empty secret_id allowed without binding",
+			config: &AppRoleConfig{RoleID: "role-id"},
+		},
+		{
+			name:    "empty secret_id rejected when bound",
+			config:  &AppRoleConfig{RoleID: "role-id", BindSecretID: true},
+			wantErr: true,
+		},
+		{
+			name:   "secret_id provided when bound",
+			config: &AppRoleConfig{RoleID: "role-id", SecretID: "secret-id", BindSecretID: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAppRoleAuth(tt.config, "https://vault.example.com")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAppRoleAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrMissingConfiguration) {
+				t.Errorf("NewAppRoleAuth() error = %v, want ErrMissingConfiguration", err)
+			}
+		})
+	}
 }
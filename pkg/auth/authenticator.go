@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/vault-client-go"
@@ -45,6 +47,66 @@ type BaseAuthenticator struct {
 	TokenTTL    time.Duration
 	LastRenewal time.Time
 	RenewBuffer time.Duration // Renew when this much time is left
+
+	// TransportWrapper optionally wraps the Vault HTTP transport (e.g. for tracing)
+	TransportWrapper func(http.RoundTripper) http.RoundTripper
+
+	// Retry optionally overrides the Vault client's retry behaviour
+	Retry *RetryConfig
+
+	// addrMu guards VaultAddr, which may be changed by the address file watcher
+	addrMu sync.RWMutex
+
+	// tokenMu guards TokenTTL and LastRenewal, which are read by the status and metrics
+	// goroutines while the renewal loop updates them
+	tokenMu sync.RWMutex
+}
+
+// GetVaultAddr returns the Vault address used for new clients
+func (b *BaseAuthenticator) GetVaultAddr() string {
+	b.addrMu.RLock()
+	defer b.addrMu.RUnlock()
+
+	return b.VaultAddr
+}
+
+// SetVaultAddr changes the Vault address used by the next Authenticate call
+func (b *BaseAuthenticator) SetVaultAddr(addr string) {
+	b.addrMu.Lock()
+	defer b.addrMu.Unlock()
+
+	b.VaultAddr = addr
+}
+
+// SetTransportWrapper sets the hook used to wrap the Vault HTTP transport
+func (b *BaseAuthenticator) SetTransportWrapper(wrapper func(http.RoundTripper) http.RoundTripper) {
+	b.TransportWrapper = wrapper
+}
+
+// SetRetryConfig sets the retry behaviour of new Vault clients
+func (b *BaseAuthenticator) SetRetryConfig(retry *RetryConfig) {
+	b.Retry = retry
+}
+
+// newVaultClient creates a Vault client for the authenticator, applying the transport wrapper
+// and retry settings if set
+func (b *BaseAuthenticator) newVaultClient() (*vault.Client, error) {
+	options := []vault.ClientOption{
+		vault.WithAddress(b.GetVaultAddr()),
+		vault.WithRequestTimeout(30 * time.Second),
+	}
+
+	if b.Retry != nil {
+		options = append(options, vault.WithRetryConfiguration(b.Retry.vaultRetryConfiguration()))
+	}
+
+	if b.TransportWrapper != nil {
+		httpClient := vault.DefaultConfiguration().HTTPClient
+		httpClient.Transport = b.TransportWrapper(httpClient.Transport)
+		options = append(options, vault.WithHTTPClient(httpClient))
+	}
+
+	return vault.New(options...)
 }
 
 // GetMethod returns the authentication method
@@ -54,15 +116,32 @@ func (b *BaseAuthenticator) GetMethod() AuthMethod {
 
 // GetTokenTTL returns the current token TTL
 func (b *BaseAuthenticator) GetTokenTTL() time.Duration {
+	b.tokenMu.RLock()
+	defer b.tokenMu.RUnlock()
+
 	return b.TokenTTL
 }
 
+// SetTokenTTL records the TTL of a newly issued or renewed token and resets LastRenewal
+func (b *BaseAuthenticator) SetTokenTTL(ttl time.Duration) {
+	b.tokenMu.Lock()
+	defer b.tokenMu.Unlock()
+
+	b.TokenTTL = ttl
+	b.LastRenewal = time.Now()
+}
+
 // ShouldRenew checks if token renewal is needed
 func (b *BaseAuthenticator) ShouldRenew() bool {
+	b.tokenMu.RLock()
+	defer b.tokenMu.RUnlock()
+
 	if b.TokenTTL == 0 {
 		return false // Non-renewable token
 	}
 
+	// LastRenewal is always a local time.Now(), so this uses the monotonic clock and a wall
+	// clock jump can't trigger or delay renewal
 	elapsed := time.Since(b.LastRenewal)
 	remaining := b.TokenTTL - elapsed
 
@@ -72,17 +151,90 @@ func (b *BaseAuthenticator) ShouldRenew() bool {
 
 // AuthConfig holds configuration for authentication
 type AuthConfig struct {
-	Method     AuthMethod
+	Method AuthMethod
+
+	// Methods lists auth methods tried in order by a ChainAuthenticator when it holds more
+	// than one; Method is then the first of them
+	Methods []AuthMethod
+
 	VaultAddr  string
 	AutoRenew  bool
 	RenewGrace time.Duration
 
+	// MaxRenewalFailureDuration stops renewal as fatal once renewal and
+	// re-authentication have failed continuously for this long (0 retries forever)
+	MaxRenewalFailureDuration time.Duration
+
+	// RenewalJitter shortens the first renewal sleep by a random fraction up to this value (0 to <1),
+	// so replicas started together don't renew in lockstep
+	RenewalJitter float64
+
+	// MaxTokenAge re-authenticates before the token is this old, even if it could still be
+	// renewed (0 only honours the token's explicit_max_ttl)
+	MaxTokenAge time.Duration
+
+	// VaultAddrFile optionally names a file holding the Vault address. It is
+	// watched for changes, re-authenticating against the new address.
+	VaultAddrFile string
+
+	// TransportWrapper optionally wraps the Vault HTTP transport (e.g. for tracing)
+	TransportWrapper func(http.RoundTripper) http.RoundTripper
+
+	// Retry controls how the Vault client retries 5xx and 412 responses (nil keeps the client defaults)
+	Retry *RetryConfig
+
 	// Method-specific configurations
 	Token      *TokenConfig
 	Kubernetes *KubernetesConfig
 	AppRole    *AppRoleConfig
 }
 
+// methods returns the configured methods in order: Methods for a chain, else Method alone
+func (c *AuthConfig) methods() []AuthMethod {
+	if len(c.Methods) > 1 {
+		return c.Methods
+	}
+
+	return []AuthMethod{c.Method}
+}
+
+// RetryConfig controls the Vault client's own retries of 5xx and 412 responses, independent
+// of token renewal and re-authentication
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt (-1 disables retrying)
+	MaxRetries int
+
+	// WaitMin and WaitMax bound the backoff between retries (0 keeps the client default)
+	WaitMin time.Duration
+	WaitMax time.Duration
+}
+
+// DefaultRetryConfig returns the vault-client-go retry defaults
+func DefaultRetryConfig() *RetryConfig {
+	defaults := vault.DefaultConfiguration().RetryConfiguration
+
+	return &RetryConfig{
+		MaxRetries: defaults.RetryMax,
+		WaitMin:    defaults.RetryWaitMin,
+		WaitMax:    defaults.RetryWaitMax,
+	}
+}
+
+// vaultRetryConfiguration applies the settings on top of the client's default retry policy
+func (r *RetryConfig) vaultRetryConfiguration() vault.RetryConfiguration {
+	retry := vault.DefaultConfiguration().RetryConfiguration
+	retry.RetryMax = r.MaxRetries
+
+	if r.WaitMin > 0 {
+		retry.RetryWaitMin = r.WaitMin
+	}
+	if r.WaitMax > 0 {
+		retry.RetryWaitMax = r.WaitMax
+	}
+
+	return retry
+}
+
 // TokenConfig holds token-specific configuration
 type TokenConfig struct {
 	Token string
@@ -93,6 +245,9 @@ type KubernetesConfig struct {
 	Role               string
 	MountPath          string
 	ServiceAccountPath string
+
+	// NamespaceRoleMap selects a role by pod namespace, falling back to Role
+	NamespaceRoleMap map[string]string
 }
 
 // AppRoleConfig holds AppRole-specific configuration
@@ -100,4 +255,14 @@ type AppRoleConfig struct {
 	RoleID    string
 	SecretID  string
 	MountPath string
+
+	// BindSecretID declares that the role requires a SecretID, so an empty one fails fast.
+	// An empty SecretID is only valid for roles with bind_secret_id=false (bound-CIDR roles).
+	BindSecretID bool
+
+	// AutoRotateSecretID generates a new SecretID before the current one expires
+	AutoRotateSecretID bool
+
+	// SecretIDRenewBuffer is how long before SecretID expiry to rotate or warn (default 1h)
+	SecretIDRenewBuffer time.Duration
 }
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// ChainAuthenticator tries an ordered list of authenticators, so a backup method (e.g. an
+// AppRole) takes over while the preferred one's auth backend is broken. The method that
+// logged in last is the active one: it renews and revokes the token it issued, and reports
+// the method and TTL. A failed renewal makes the manager re-authenticate, which walks the
+// chain from the first method again, so the preferred method is back once it recovers.
+type ChainAuthenticator struct {
+	authenticators []Authenticator
+
+	mu     sync.RWMutex
+	active int
+}
+
+// NewChainAuthenticator creates an authenticator trying authenticators in order
+func NewChainAuthenticator(authenticators ...Authenticator) (*ChainAuthenticator, error) {
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("auth chain requires at least one authenticator")
+	}
+
+	return &ChainAuthenticator{authenticators: authenticators}, nil
+}
+
+// Authenticate logs in with the first method that succeeds and makes it the active one
+func (c *ChainAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
+	var errs []error
+
+	for i, authenticator := range c.authenticators {
+		client, err := authenticator.Authenticate(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		c.mu.Lock()
+		c.active = i
+		c.mu.Unlock()
+
+		if i > 0 {
+			authChainFallbacks.WithLabelValues(string(authenticator.GetMethod())).Inc()
+		}
+
+		return client, nil
+	}
+
+	return nil, fmt.Errorf("%w: every method in the auth chain failed: %w", ErrAuthenticationFailed, errors.Join(errs...))
+}
+
+// Active returns the authenticator that issued the current token
+func (c *ChainAuthenticator) Active() Authenticator {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.authenticators[c.active]
+}
+
+// Methods returns the methods of the chain in order
+func (c *ChainAuthenticator) Methods() []AuthMethod {
+	methods := make([]AuthMethod, len(c.authenticators))
+	for i, authenticator := range c.authenticators {
+		methods[i] = authenticator.GetMethod()
+	}
+
+	return methods
+}
+
+// Renew renews the token through the active method
+func (c *ChainAuthenticator) Renew(ctx context.Context, client *vault.Client) error {
+	return c.Active().Renew(ctx, client)
+}
+
+// ShouldRenew checks if the active method's token should be renewed
+func (c *ChainAuthenticator) ShouldRenew() bool {
+	return c.Active().ShouldRenew()
+}
+
+// Revoke revokes the token through the active method
+func (c *ChainAuthenticator) Revoke(ctx context.Context, client *vault.Client) error {
+	return c.Active().Revoke(ctx, client)
+}
+
+// GetMethod returns the active method
+func (c *ChainAuthenticator) GetMethod() AuthMethod {
+	return c.Active().GetMethod()
+}
+
+// GetTokenTTL returns the active method's token TTL
+func (c *ChainAuthenticator) GetTokenTTL() time.Duration {
+	return c.Active().GetTokenTTL()
+}
+
+// GetLastRenewal returns when the active method's token was last issued or renewed
+func (c *ChainAuthenticator) GetLastRenewal() time.Time {
+	if tracker, ok := c.Active().(renewalTracker); ok {
+		return tracker.GetLastRenewal()
+	}
+
+	return time.Time{}
+}
+
+// GetRenewBuffer returns the active method's renew buffer
+func (c *ChainAuthenticator) GetRenewBuffer() time.Duration {
+	if adjuster, ok := c.Active().(renewBufferAdjuster); ok {
+		return adjuster.GetRenewBuffer()
+	}
+
+	return 0
+}
+
+// SetRenewBuffer sets the active method's renew buffer
+func (c *ChainAuthenticator) SetRenewBuffer(buffer time.Duration) {
+	if adjuster, ok := c.Active().(renewBufferAdjuster); ok {
+		adjuster.SetRenewBuffer(buffer)
+	}
+}
+
+// GetVaultAddr returns the Vault address used by the active method
+func (c *ChainAuthenticator) GetVaultAddr() string {
+	if target, ok := c.Active().(addressable); ok {
+		return target.GetVaultAddr()
+	}
+
+	return ""
+}
+
+// SetVaultAddr changes the Vault address of every method in the chain
+func (c *ChainAuthenticator) SetVaultAddr(addr string) {
+	for _, authenticator := range c.authenticators {
+		if target, ok := authenticator.(addressable); ok {
+			target.SetVaultAddr(addr)
+		}
+	}
+}
+
+// SetTransportWrapper sets the Vault transport hook of every method in the chain
+func (c *ChainAuthenticator) SetTransportWrapper(wrapper func(http.RoundTripper) http.RoundTripper) {
+	for _, authenticator := range c.authenticators {
+		if setter, ok := authenticator.(interface {
+			SetTransportWrapper(func(http.RoundTripper) http.RoundTripper)
+		}); ok {
+			setter.SetTransportWrapper(wrapper)
+		}
+	}
+}
+
+// SetRetryConfig sets the Vault client retry behaviour of every method in the chain
+func (c *ChainAuthenticator) SetRetryConfig(retry *RetryConfig) {
+	for _, authenticator := range c.authenticators {
+		if setter, ok := authenticator.(interface{ SetRetryConfig(*RetryConfig) }); ok {
+			setter.SetRetryConfig(retry)
+		}
+	}
+}
+
+// parseAuthMethods parses a comma-separated list of auth methods, such as VAULT_AUTH_METHODS
+func parseAuthMethods(value string) []AuthMethod {
+	var methods []AuthMethod
+	for _, method := range strings.Split(value, ",") {
+		if method = strings.ToLower(strings.TrimSpace(method)); method != "" {
+			methods = append(methods, AuthMethod(method))
+		}
+	}
+
+	return methods
+}
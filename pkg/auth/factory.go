@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // NewAuthenticator creates an authenticator based on the provided configuration
@@ -14,7 +18,7 @@ func NewAuthenticator(config *AuthConfig) (Authenticator, error) {
 
 	// If no method specified, try to auto-detect
 	if config.Method == "" {
-		config.Method = detectAuthMethod()
+		config.Method = detectAuthMethod(os.Getenv)
 		if config.Method == "" {
 			return nil, ErrNoAuthMethod
 		}
@@ -25,12 +29,55 @@ func NewAuthenticator(config *AuthConfig) (Authenticator, error) {
 	if vaultAddr == "" {
 		vaultAddr = os.Getenv("VAULT_ADDR")
 		if vaultAddr == "" {
-			return nil, fmt.Errorf("vault address is required (set VAULT_ADDR)")
+			return nil, fmt.Errorf("vault address is required (set VAULT_ADDR or VAULT_ADDR_FILE)")
 		}
 	}
 
-	// Create authenticator based on method
-	switch config.Method {
+	// Create authenticator based on method, chaining them when several are configured
+	var (
+		authenticator Authenticator
+		err           error
+	)
+
+	if len(config.Methods) > 1 {
+		authenticators := make([]Authenticator, 0, len(config.Methods))
+		for _, method := range config.Methods {
+			methodAuthenticator, err := newMethodAuthenticator(config, method, vaultAddr)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, methodAuthenticator)
+		}
+
+		authenticator, err = NewChainAuthenticator(authenticators...)
+	} else {
+		authenticator, err = newMethodAuthenticator(config, config.Method, vaultAddr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TransportWrapper != nil {
+		if setter, ok := authenticator.(interface {
+			SetTransportWrapper(func(http.RoundTripper) http.RoundTripper)
+		}); ok {
+			setter.SetTransportWrapper(config.TransportWrapper)
+		}
+	}
+
+	if config.Retry != nil {
+		if setter, ok := authenticator.(interface{ SetRetryConfig(*RetryConfig) }); ok {
+			setter.SetRetryConfig(config.Retry)
+		}
+	}
+
+	return authenticator, nil
+}
+
+// newMethodAuthenticator creates the authenticator for one method of config
+func newMethodAuthenticator(config *AuthConfig, method AuthMethod, vaultAddr string) (Authenticator, error) {
+	switch method {
 	case AuthMethodToken:
 		return NewTokenAuth(config.Token, vaultAddr)
 
@@ -41,31 +88,31 @@ func NewAuthenticator(config *AuthConfig) (Authenticator, error) {
 		return NewAppRoleAuth(config.AppRole, vaultAddr)
 
 	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAuthMethod, config.Method)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAuthMethod, method)
 	}
 }
 
 // detectAuthMethod attempts to detect the authentication method from environment
-func detectAuthMethod() AuthMethod {
+func detectAuthMethod(getenv func(string) string) AuthMethod {
 	// Check explicit method first
-	if method := os.Getenv("VAULT_AUTH_METHOD"); method != "" {
+	if method := getenv("VAULT_AUTH_METHOD"); method != "" {
 		return AuthMethod(strings.ToLower(method))
 	}
 
 	// Check for Kubernetes environment
-	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
 		if _, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount/token"); err == nil {
 			return AuthMethodKubernetes
 		}
 	}
 
 	// Check for AppRole credentials
-	if os.Getenv("VAULT_ROLE_ID") != "" {
+	if getenv("VAULT_ROLE_ID") != "" {
 		return AuthMethodAppRole
 	}
 
 	// Check for token
-	if os.Getenv("VAULT_TOKEN") != "" {
+	if getenv("VAULT_TOKEN") != "" {
 		return AuthMethodToken
 	}
 
@@ -74,40 +121,128 @@ func detectAuthMethod() AuthMethod {
 
 // NewAuthConfigFromEnvironment creates an AuthConfig from environment variables
 func NewAuthConfigFromEnvironment() *AuthConfig {
+	return newAuthConfig(os.Getenv)
+}
+
+// NewAuthConfigFromFile creates an AuthConfig from a JSON object of environment variable
+// names to values, e.g. {"VAULT_AUTH_METHOD": "kubernetes", "VAULT_K8S_ROLE": "kms"}.
+// Variables missing from the file fall back to the environment. Unlike the environment, the
+// file can change while the server runs, which lets the auth method be switched on SIGHUP.
+func NewAuthConfigFromFile(path string) (*AuthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth config file: %w", err)
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse auth config file %s: %w", path, err)
+	}
+
+	for key := range values {
+		if !strings.HasPrefix(key, "VAULT_") {
+			return nil, fmt.Errorf("auth config file %s: unsupported key %q (expected VAULT_* variables)", path, key)
+		}
+	}
+
+	return newAuthConfig(func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}), nil
+}
+
+// newAuthConfig creates an AuthConfig reading settings through getenv
+func newAuthConfig(getenv func(string) string) *AuthConfig {
 	config := &AuthConfig{
-		Method:    detectAuthMethod(),
-		VaultAddr: os.Getenv("VAULT_ADDR"),
-		AutoRenew: true, // Default to auto-renew
+		Method:        detectAuthMethod(getenv),
+		VaultAddr:     getenv("VAULT_ADDR"),
+		AutoRenew:     true, // Default to auto-renew
+		RenewalJitter: DefaultRenewalJitter,
+	}
+
+	// An address file written by an external controller takes precedence over VAULT_ADDR
+	if addrFile := getenv("VAULT_ADDR_FILE"); addrFile != "" {
+		config.VaultAddrFile = addrFile
+		if addr, err := ReadVaultAddrFile(addrFile); err == nil {
+			config.VaultAddr = addr
+		}
 	}
 
 	// Parse auto-renew setting
-	if autoRenew := os.Getenv("VAULT_AUTO_RENEW"); autoRenew != "" {
+	if autoRenew := getenv("VAULT_AUTO_RENEW"); autoRenew != "" {
 		config.AutoRenew = strings.ToLower(autoRenew) != "false"
 	}
 
-	// Configure based on detected method
-	switch config.Method {
+	// Parse the renewal failure budget
+	if maxFailure := getenv("VAULT_MAX_RENEWAL_FAILURE_DURATION"); maxFailure != "" {
+		if d, err := time.ParseDuration(maxFailure); err == nil {
+			config.MaxRenewalFailureDuration = d
+		}
+	}
+
+	// Parse the initial renewal jitter
+	if jitter := getenv("VAULT_RENEWAL_JITTER"); jitter != "" {
+		if f, err := strconv.ParseFloat(jitter, 64); err == nil {
+			config.RenewalJitter = f
+		}
+	}
+
+	// Parse the maximum token age
+	if maxAge := getenv("VAULT_MAX_TOKEN_AGE"); maxAge != "" {
+		if d, err := time.ParseDuration(maxAge); err == nil {
+			config.MaxTokenAge = d
+		}
+	}
+
+	config.Retry = retryConfigFromEnvironment(getenv)
+
+	// VAULT_AUTH_METHODS lists methods to try in order, the first being the preferred one
+	if methods := parseAuthMethods(getenv("VAULT_AUTH_METHODS")); len(methods) > 0 {
+		config.Methods = methods
+		config.Method = methods[0]
+	}
+
+	// Configure based on detected method, or every method of the chain
+	for _, method := range config.methods() {
+		configureMethod(config, method, getenv)
+	}
+
+	return config
+}
+
+// configureMethod reads the settings of one auth method through getenv
+func configureMethod(config *AuthConfig, method AuthMethod, getenv func(string) string) {
+	switch method {
 	case AuthMethodToken:
 		config.Token = &TokenConfig{
-			Token: os.Getenv("VAULT_TOKEN"),
+			Token: getenv("VAULT_TOKEN"),
 		}
 
 	case AuthMethodKubernetes:
 		config.Kubernetes = &KubernetesConfig{
-			Role:               os.Getenv("VAULT_K8S_ROLE"),
-			MountPath:          os.Getenv("VAULT_K8S_MOUNT_PATH"),
-			ServiceAccountPath: os.Getenv("VAULT_K8S_SERVICE_ACCOUNT_PATH"),
+			Role:               getenv("VAULT_K8S_ROLE"),
+			MountPath:          getenv("VAULT_K8S_MOUNT_PATH"),
+			ServiceAccountPath: getenv("VAULT_K8S_SERVICE_ACCOUNT_PATH"),
+			NamespaceRoleMap:   parseNamespaceRoleMap(getenv("VAULT_K8S_NAMESPACE_ROLES")),
 		}
 
 	case AuthMethodAppRole:
 		config.AppRole = &AppRoleConfig{
-			RoleID:    os.Getenv("VAULT_ROLE_ID"),
-			SecretID:  os.Getenv("VAULT_SECRET_ID"),
-			MountPath: os.Getenv("VAULT_APPROLE_MOUNT_PATH"),
+			RoleID:             getenv("VAULT_ROLE_ID"),
+			SecretID:           getenv("VAULT_SECRET_ID"),
+			MountPath:          getenv("VAULT_APPROLE_MOUNT_PATH"),
+			BindSecretID:       strings.ToLower(getenv("VAULT_APPROLE_BIND_SECRET_ID")) == "true",
+			AutoRotateSecretID: strings.ToLower(getenv("VAULT_SECRET_ID_AUTO_ROTATE")) == "true",
 		}
-	}
 
-	return config
+		if buffer := getenv("VAULT_SECRET_ID_RENEW_BUFFER"); buffer != "" {
+			if d, err := time.ParseDuration(buffer); err == nil {
+				config.AppRole.SecretIDRenewBuffer = d
+			}
+		}
+	}
 }
 
 // ValidateConfig validates the authentication configuration
@@ -120,15 +255,48 @@ func ValidateConfig(config *AuthConfig) error {
 		return fmt.Errorf("vault address is required")
 	}
 
-	switch config.Method {
+	if config.RenewalJitter < 0 || config.RenewalJitter >= 1 {
+		return fmt.Errorf("renewal jitter must be at least 0 and below 1, got %g", config.RenewalJitter)
+	}
+
+	if config.MaxTokenAge < 0 {
+		return fmt.Errorf("max token age must not be negative, got %s", config.MaxTokenAge)
+	}
+
+	if retry := config.Retry; retry != nil {
+		if retry.MaxRetries < -1 {
+			return fmt.Errorf("max retries must be -1 (disabled) or more, got %d", retry.MaxRetries)
+		}
+
+		if retry.WaitMin > 0 && retry.WaitMax > 0 && retry.WaitMin > retry.WaitMax {
+			return fmt.Errorf("retry wait min (%s) must not exceed retry wait max (%s)", retry.WaitMin, retry.WaitMax)
+		}
+	}
+
+	if config.Method == "" {
+		return fmt.Errorf("authentication method is required")
+	}
+
+	for _, method := range config.methods() {
+		if err := validateMethodConfig(config, method); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateMethodConfig validates the settings of one auth method
+func validateMethodConfig(config *AuthConfig, method AuthMethod) error {
+	switch method {
 	case AuthMethodToken:
 		if config.Token == nil || config.Token.Token == "" {
 			return fmt.Errorf("token is required for token auth")
 		}
 
 	case AuthMethodKubernetes:
-		if config.Kubernetes == nil || config.Kubernetes.Role == "" {
-			return fmt.Errorf("role is required for kubernetes auth")
+		if config.Kubernetes == nil || (config.Kubernetes.Role == "" && len(config.Kubernetes.NamespaceRoleMap) == 0) {
+			return fmt.Errorf("role or namespace role map is required for kubernetes auth")
 		}
 
 	case AuthMethodAppRole:
@@ -136,12 +304,68 @@ func ValidateConfig(config *AuthConfig) error {
 			return fmt.Errorf("role_id is required for approle auth")
 		}
 
-	case "":
-		return fmt.Errorf("authentication method is required")
+		if config.AppRole.BindSecretID && config.AppRole.SecretID == "" {
+			return fmt.Errorf("secret_id is required for approle auth when VAULT_APPROLE_BIND_SECRET_ID is set")
+		}
 
 	default:
-		return fmt.Errorf("unsupported authentication method: %s", config.Method)
+		return fmt.Errorf("unsupported authentication method: %s", method)
 	}
 
 	return nil
 }
+
+// retryConfigFromEnvironment reads VAULT_MAX_RETRIES, VAULT_RETRY_WAIT_MIN and
+// VAULT_RETRY_WAIT_MAX, returning nil when none are set so the client defaults apply
+func retryConfigFromEnvironment(getenv func(string) string) *RetryConfig {
+	maxRetries := getenv("VAULT_MAX_RETRIES")
+	waitMin := getenv("VAULT_RETRY_WAIT_MIN")
+	waitMax := getenv("VAULT_RETRY_WAIT_MAX")
+
+	if maxRetries == "" && waitMin == "" && waitMax == "" {
+		return nil
+	}
+
+	retry := DefaultRetryConfig()
+
+	if n, err := strconv.Atoi(maxRetries); err == nil {
+		retry.MaxRetries = n
+	}
+	if d, err := time.ParseDuration(waitMin); err == nil {
+		retry.WaitMin = d
+	}
+	if d, err := time.ParseDuration(waitMax); err == nil {
+		retry.WaitMax = d
+	}
+
+	return retry
+}
+
+// parseNamespaceRoleMap parses a "namespace=role,namespace=role" mapping
+func parseNamespaceRoleMap(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		namespace, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+
+		namespace = strings.TrimSpace(namespace)
+		role = strings.TrimSpace(role)
+		if namespace == "" || role == "" {
+			continue
+		}
+
+		roles[namespace] = role
+	}
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	return roles
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +22,7 @@ const (
 type KubernetesAuthenticator struct {
 	BaseAuthenticator
 	role               string
+	namespaceRoles     map[string]string
 	mountPath          string
 	serviceAccountPath string
 	jwt                string
@@ -40,11 +42,11 @@ func NewKubernetesAuth(config *KubernetesConfig, vaultAddr string) (*KubernetesA
 		config.MountPath = defaultKubernetesMountPath
 	}
 
-	// Role is required
+	// Role is required unless a namespace role map is provided
 	if config.Role == "" {
 		// Try to get from environment
 		config.Role = os.Getenv("VAULT_K8S_ROLE")
-		if config.Role == "" {
+		if config.Role == "" && len(config.NamespaceRoleMap) == 0 {
 			return nil, NewAuthError(AuthMethodKubernetes, "new", ErrMissingConfiguration, "role is required")
 		}
 	}
@@ -61,6 +63,7 @@ func NewKubernetesAuth(config *KubernetesConfig, vaultAddr string) (*KubernetesA
 			RenewBuffer: 5 * time.Minute,
 		},
 		role:               config.Role,
+		namespaceRoles:     config.NamespaceRoleMap,
 		mountPath:          config.MountPath,
 		serviceAccountPath: config.ServiceAccountPath,
 	}, nil
@@ -76,18 +79,21 @@ func (k *KubernetesAuthenticator) Authenticate(ctx context.Context) (*vault.Clie
 	k.jwt = jwt
 
 	// Create Vault client
-	client, err := vault.New(
-		vault.WithAddress(k.VaultAddr),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := k.newVaultClient()
 	if err != nil {
 		return nil, NewAuthError(AuthMethodKubernetes, "authenticate", err, "failed to create vault client")
 	}
 
+	// Select the role for the namespace we're running in
+	role, err := k.selectRole()
+	if err != nil {
+		return nil, NewAuthError(AuthMethodKubernetes, "authenticate", err, "failed to select role")
+	}
+
 	// Perform Kubernetes auth
 	authReq := schema.KubernetesLoginRequest{
 		Jwt:  jwt,
-		Role: k.role,
+		Role: role,
 	}
 
 	resp, err := client.Auth.KubernetesLogin(ctx, authReq, vault.WithMountPath(k.mountPath))
@@ -105,8 +111,7 @@ func (k *KubernetesAuthenticator) Authenticate(ctx context.Context) (*vault.Clie
 	}
 
 	// Store TTL
-	k.TokenTTL = time.Duration(resp.Auth.LeaseDuration) * time.Second
-	k.LastRenewal = time.Now()
+	k.SetTokenTTL(time.Duration(resp.Auth.LeaseDuration) * time.Second)
 
 	return client, nil
 }
@@ -114,50 +119,68 @@ func (k *KubernetesAuthenticator) Authenticate(ctx context.Context) (*vault.Clie
 // Renew renews the Kubernetes auth token
 func (k *KubernetesAuthenticator) Renew(ctx context.Context, client *vault.Client) error {
 	// Try to renew the existing token first
-	renewResp, err := client.Auth.TokenRenewSelf(ctx, schema.TokenRenewSelfRequest{})
-	if err != nil {
+	renewResp, renewErr := client.Auth.TokenRenewSelf(ctx, schema.TokenRenewSelfRequest{})
+	if renewErr != nil {
 		// If renewal fails, re-authenticate
 		newJWT, err := k.readServiceAccountJWT()
 		if err != nil {
 			return NewAuthError(AuthMethodKubernetes, "renew", err, "failed to read new JWT")
 		}
 
-		// Check if JWT has changed (in case of rotation)
-		if newJWT != k.jwt {
-			// Re-authenticate with new JWT
-			authReq := schema.KubernetesLoginRequest{
-				Jwt:  newJWT,
-				Role: k.role,
-			}
-
-			resp, err := client.Auth.KubernetesLogin(ctx, authReq, vault.WithMountPath(k.mountPath))
-			if err != nil {
-				return NewAuthError(AuthMethodKubernetes, "renew", err, "re-authentication failed")
-			}
-
-			if resp.Auth != nil && resp.Auth.ClientToken != "" {
-				if err := client.SetToken(resp.Auth.ClientToken); err != nil {
-					return NewAuthError(AuthMethodKubernetes, "renew", err, "failed to set new token")
-				}
-				k.jwt = newJWT
-				k.TokenTTL = time.Duration(resp.Auth.LeaseDuration) * time.Second
-				k.LastRenewal = time.Now()
-				return nil
-			}
+		// Log in again when the JWT was rotated, or when Vault rejects the token because its
+		// kubernetes auth configuration changed (e.g. a new audience) while the JWT did not
+		if newJWT != k.jwt || requiresRelogin(renewErr) {
+			return k.relogin(ctx, client, newJWT)
 		}
 
-		return NewAuthError(AuthMethodKubernetes, "renew", err, "token renewal failed")
+		return NewAuthError(AuthMethodKubernetes, "renew", renewErr, "token renewal failed")
 	}
 
 	// Update TTL from renewal response
 	if renewResp.Auth != nil {
-		k.TokenTTL = time.Duration(renewResp.Auth.LeaseDuration) * time.Second
-		k.LastRenewal = time.Now()
+		k.SetTokenTTL(time.Duration(renewResp.Auth.LeaseDuration) * time.Second)
+	}
+
+	return nil
+}
+
+// relogin logs in with the freshly read JWT and sets the new token on the existing client
+func (k *KubernetesAuthenticator) relogin(ctx context.Context, client *vault.Client, jwt string) error {
+	role, err := k.selectRole()
+	if err != nil {
+		return NewAuthError(AuthMethodKubernetes, "renew", err, "failed to select role")
+	}
+
+	authReq := schema.KubernetesLoginRequest{
+		Jwt:  jwt,
+		Role: role,
+	}
+
+	resp, err := client.Auth.KubernetesLogin(ctx, authReq, vault.WithMountPath(k.mountPath))
+	if err != nil {
+		return NewAuthError(AuthMethodKubernetes, "renew", err, "re-authentication failed")
+	}
+
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return NewAuthError(AuthMethodKubernetes, "renew", ErrAuthenticationFailed, "no token received from Vault")
+	}
+
+	if err := client.SetToken(resp.Auth.ClientToken); err != nil {
+		return NewAuthError(AuthMethodKubernetes, "renew", err, "failed to set new token")
 	}
 
+	k.jwt = jwt
+	k.SetTokenTTL(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+
 	return nil
 }
 
+// requiresRelogin reports whether a renewal error means Vault no longer accepts the token
+// as issued, so a fresh login is needed even with an unchanged JWT
+func requiresRelogin(err error) bool {
+	return vault.IsErrorStatus(err, http.StatusForbidden) || vault.IsErrorStatus(err, http.StatusPreconditionFailed)
+}
+
 // Revoke revokes the Kubernetes auth token
 func (k *KubernetesAuthenticator) Revoke(ctx context.Context, client *vault.Client) error {
 	_, err := client.Auth.TokenRevokeSelf(ctx)
@@ -178,6 +201,37 @@ func (k *KubernetesAuthenticator) readServiceAccountJWT() (string, error) {
 	return strings.TrimSpace(string(tokenBytes)), nil
 }
 
+// selectRole returns the role mapped to the pod namespace, or the default role
+func (k *KubernetesAuthenticator) selectRole() (string, error) {
+	if len(k.namespaceRoles) > 0 {
+		if namespace := k.podNamespace(); namespace != "" {
+			if role, ok := k.namespaceRoles[namespace]; ok && role != "" {
+				return role, nil
+			}
+		}
+	}
+
+	if k.role == "" {
+		return "", fmt.Errorf("%w: no role mapped for namespace %q and no default role", ErrMissingConfiguration, k.podNamespace())
+	}
+
+	return k.role, nil
+}
+
+// podNamespace returns the pod namespace from POD_NAMESPACE or the service account mount
+func (k *KubernetesAuthenticator) podNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+
+	namespaceBytes, err := os.ReadFile(filepath.Join(k.serviceAccountPath, "namespace"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(namespaceBytes))
+}
+
 // isRunningInKubernetes checks if we're running in a Kubernetes pod
 func isRunningInKubernetes(serviceAccountPath string) bool {
 	// Check for service account token
@@ -194,7 +248,11 @@ func isRunningInKubernetes(serviceAccountPath string) bool {
 	return false
 }
 
-// GetRole returns the configured Kubernetes role
+// GetRole returns the Kubernetes role selected for the current namespace
 func (k *KubernetesAuthenticator) GetRole() string {
-	return k.role
+	role, err := k.selectRole()
+	if err != nil {
+		return k.role
+	}
+	return role
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -17,9 +18,41 @@ type Manager struct {
 	config        *AuthConfig
 	logger        *slog.Logger
 
+	// switchMu serializes SwitchConfig with Stop and ForceRenewal
+	switchMu sync.Mutex
+
+	// renewMu serializes renewal cycles of the renewal loop with ForceRenewal
+	renewMu sync.Mutex
+
 	mu            sync.RWMutex
 	cancelRenewal context.CancelFunc
 	renewalDone   chan struct{}
+
+	// How often to verify tokens that can't be renewed
+	nonRenewableCheckInterval time.Duration
+
+	// Vault address file watching
+	addrFileCheckInterval time.Duration
+	cancelAddrWatch       context.CancelFunc
+	addrWatchDone         chan struct{}
+
+	// failingSince is when renewal started failing continuously (zero when healthy)
+	failingSince time.Time
+	fatal        chan error
+
+	// Status reporting, guarded by mu
+	lastAuth    time.Time
+	lastRenewal time.Time
+	lastErr     error
+
+	// tokenDeadline is when the current token must be replaced (zero when unlimited), guarded by mu
+	tokenDeadline time.Time
+
+	// revokeGracePeriod delays revoking replaced tokens, guarded by mu. revokeNow is closed by
+	// Stop to revoke the pending ones at once, and revocations waits for them.
+	revokeGracePeriod time.Duration
+	revokeNow         chan struct{}
+	revocations       sync.WaitGroup
 }
 
 // NewManager creates a new authentication manager
@@ -39,9 +72,12 @@ func NewManager(config *AuthConfig, logger *slog.Logger) (*Manager, error) {
 	}
 
 	return &Manager{
-		authenticator: authenticator,
-		config:        config,
-		logger:        logger.With("component", "auth-manager"),
+		authenticator:             authenticator,
+		config:                    config,
+		logger:                    logger.With("component", "auth-manager"),
+		nonRenewableCheckInterval: 5 * time.Minute,
+		addrFileCheckInterval:     defaultAddrFileCheckInterval,
+		fatal:                     make(chan error, 1),
 	}, nil
 }
 
@@ -49,28 +85,80 @@ func NewManager(config *AuthConfig, logger *slog.Logger) (*Manager, error) {
 func (m *Manager) Start(ctx context.Context) error {
 	// Perform initial authentication
 	client, err := m.authenticator.Authenticate(ctx)
+	recordAuthOperation(m.authenticator.GetMethod(), opAuthenticate, err)
 	if err != nil {
 		return fmt.Errorf("initial authentication failed: %w", err)
 	}
 
 	m.mu.Lock()
 	m.client = client
+	m.lastAuth = time.Now()
 	m.mu.Unlock()
 
 	m.logger.Info("authentication successful",
 		"method", m.authenticator.GetMethod(),
 		"ttl", m.authenticator.GetTokenTTL())
 
-	// Start renewal if auto-renew is enabled
-	if m.config.AutoRenew {
-		m.startRenewal()
+	if m.authenticator.GetTokenTTL() == 0 {
+		if m.authenticator.GetMethod() == AuthMethodToken {
+			m.logger.Warn("token is non-renewable - if it expires or is revoked the KMS cannot recover without a new VAULT_TOKEN and a restart",
+				"method", m.authenticator.GetMethod())
+		} else {
+			m.logger.Warn("token is non-renewable - it will be health-checked periodically and re-authenticated on expiry",
+				"method", m.authenticator.GetMethod(),
+				"checkInterval", m.nonRenewableCheckInterval)
+		}
 	}
 
+	m.adjustRenewBuffer(m.authenticator)
+	m.initSecretIDTracking(ctx)
+	m.refreshTokenDeadline(ctx)
+	m.startBackground()
+
 	return nil
 }
 
 // Stop stops the renewal process and revokes the token
 func (m *Manager) Stop(ctx context.Context) error {
+	m.switchMu.Lock()
+	defer m.switchMu.Unlock()
+
+	m.stopBackground()
+	m.flushRevocations()
+
+	// Revoke token
+	m.mu.RLock()
+	client := m.client
+	m.mu.RUnlock()
+
+	if client != nil {
+		err := m.authenticator.Revoke(ctx, client)
+		recordAuthOperation(m.authenticator.GetMethod(), opRevoke, err)
+		if err != nil {
+			m.logger.Error("failed to revoke token", "error", err)
+			return err
+		}
+		m.logger.Info("token revoked successfully")
+	}
+
+	return nil
+}
+
+// startBackground starts token renewal and address file watching as configured
+func (m *Manager) startBackground() {
+	// Start renewal if auto-renew is enabled
+	if m.config.AutoRenew {
+		m.startRenewal()
+	}
+
+	// Follow Vault address changes written by an external controller
+	if m.config.VaultAddrFile != "" {
+		m.startAddrWatch()
+	}
+}
+
+// stopBackground stops token renewal and address file watching and waits for them to exit
+func (m *Manager) stopBackground() {
 	// Stop renewal
 	if m.cancelRenewal != nil {
 		m.cancelRenewal()
@@ -84,20 +172,14 @@ func (m *Manager) Stop(ctx context.Context) error {
 		}
 	}
 
-	// Revoke token
-	m.mu.RLock()
-	client := m.client
-	m.mu.RUnlock()
-
-	if client != nil {
-		if err := m.authenticator.Revoke(ctx, client); err != nil {
-			m.logger.Error("failed to revoke token", "error", err)
-			return err
-		}
-		m.logger.Info("token revoked successfully")
+	// Stop watching the address file
+	if m.cancelAddrWatch != nil {
+		m.cancelAddrWatch()
+		<-m.addrWatchDone
 	}
 
-	return nil
+	m.cancelRenewal = nil
+	m.cancelAddrWatch = nil
 }
 
 // GetClient returns the authenticated Vault client
@@ -112,6 +194,25 @@ func (m *Manager) GetClient() (*vault.Client, error) {
 	return m.client, nil
 }
 
+// GetTokenTTL returns the TTL of the current token
+func (m *Manager) GetTokenTTL() time.Duration {
+	return m.currentAuthenticator().GetTokenTTL()
+}
+
+// currentAuthenticator returns the active authenticator for callers outside the background
+// loops, which SwitchConfig stops before swapping it
+func (m *Manager) currentAuthenticator() Authenticator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.authenticator
+}
+
+// Fatal returns a channel that receives an error when renewal gives up permanently
+func (m *Manager) Fatal() <-chan error {
+	return m.fatal
+}
+
 // startRenewal starts the token renewal goroutine
 func (m *Manager) startRenewal() {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,8 +226,8 @@ func (m *Manager) startRenewal() {
 func (m *Manager) renewalLoop(ctx context.Context) {
 	defer close(m.renewalDone)
 
-	// Calculate initial sleep duration
-	sleepDuration := m.calculateRenewalSleep()
+	// Calculate initial sleep duration, jittered so replicas don't renew in lockstep
+	sleepDuration := jitterSleep(m.nextCheckInterval(), m.config.RenewalJitter)
 
 	for {
 		select {
@@ -135,50 +236,206 @@ func (m *Manager) renewalLoop(ctx context.Context) {
 			return
 
 		case <-time.After(sleepDuration):
-			// Check if renewal is needed
-			if !m.authenticator.ShouldRenew() {
-				sleepDuration = m.calculateRenewalSleep()
-				continue
+			next, ok := m.renewalCycle(ctx, sleepDuration)
+			if !ok {
+				return
 			}
+			sleepDuration = next
+		}
+	}
+}
 
-			// Perform renewal
-			m.mu.RLock()
-			client := m.client
-			m.mu.RUnlock()
+// renewalCycle runs one check of the renewal loop and returns how long to sleep before the
+// next one. It reports false once renewal has given up permanently.
+func (m *Manager) renewalCycle(ctx context.Context, sleepDuration time.Duration) (time.Duration, bool) {
+	m.renewMu.Lock()
+	defer m.renewMu.Unlock()
 
-			if client == nil {
-				m.logger.Error("client is nil, cannot renew", "component", "auth-manager")
-				sleepDuration = 10 * time.Second
-				continue
-			}
+	m.checkSecretID(ctx)
 
-			err := m.authenticator.Renew(ctx, client)
-			if err != nil {
-				m.logger.Error("token renewal failed", "error", err)
-
-				// Try to re-authenticate
-				m.logger.Info("attempting re-authentication")
-				newClient, authErr := m.authenticator.Authenticate(ctx)
-				if authErr != nil {
-					m.logger.Error("re-authentication failed", "error", authErr)
-					// Exponential backoff on failure
-					sleepDuration = min(sleepDuration*2, 5*time.Minute)
-				} else {
-					m.mu.Lock()
-					m.client = newClient
-					m.mu.Unlock()
-
-					m.logger.Info("re-authentication successful",
-						"ttl", m.authenticator.GetTokenTTL())
-					sleepDuration = m.calculateRenewalSleep()
-				}
-			} else {
-				m.logger.Info("token renewed successfully",
-					"ttl", m.authenticator.GetTokenTTL())
-				sleepDuration = m.calculateRenewalSleep()
+	// Replace the token before it reaches its maximum lifetime
+	if m.checkTokenDeadline(ctx) {
+		m.renewalSucceeded()
+		return m.nextCheckInterval(), true
+	}
+
+	// Non-renewable tokens can't be renewed, only verified
+	if m.authenticator.GetTokenTTL() == 0 {
+		if err := m.checkNonRenewableToken(ctx); err != nil {
+			if fatalErr := m.renewalFailed(err); fatalErr != nil {
+				m.signalFatal(fatalErr)
+				return 0, false
 			}
+		} else {
+			m.renewalSucceeded()
 		}
+		return m.nextCheckInterval(), true
+	}
+
+	// Check if renewal is needed
+	if !m.authenticator.ShouldRenew() {
+		return m.nextCheckInterval(), true
 	}
+
+	// Perform renewal
+	m.mu.RLock()
+	client := m.client
+	m.mu.RUnlock()
+
+	if client == nil {
+		m.logger.Error("client is nil, cannot renew", "component", "auth-manager")
+		return 10 * time.Second, true
+	}
+
+	err := m.authenticator.Renew(ctx, client)
+	recordAuthOperation(m.authenticator.GetMethod(), opRenew, err)
+	recordTokenRenewal(err)
+	if err == nil {
+		m.recordRenewal()
+		m.renewalSucceeded()
+		m.logger.Info("token renewed successfully",
+			"ttl", m.authenticator.GetTokenTTL())
+		return m.nextCheckInterval(), true
+	}
+
+	m.logger.Error("token renewal failed", "error", err)
+
+	// Try to re-authenticate
+	if authErr := m.reauthenticate(ctx); authErr != nil {
+		if fatalErr := m.renewalFailed(authErr); fatalErr != nil {
+			m.signalFatal(fatalErr)
+			return 0, false
+		}
+
+		// Exponential backoff on failure
+		return min(sleepDuration*2, 5*time.Minute), true
+	}
+
+	m.renewalSucceeded()
+
+	return m.nextCheckInterval(), true
+}
+
+// renewalFailed records a failed renewal cycle and returns a fatal error once
+// failures have lasted longer than MaxRenewalFailureDuration
+func (m *Manager) renewalFailed(err error) error {
+	m.mu.Lock()
+	if m.failingSince.IsZero() {
+		m.failingSince = time.Now()
+	}
+	m.lastErr = err
+	failingFor := time.Since(m.failingSince)
+	m.mu.Unlock()
+
+	maxFailure := m.config.MaxRenewalFailureDuration
+
+	if maxFailure <= 0 || failingFor < maxFailure {
+		return nil
+	}
+
+	return fmt.Errorf("token renewal has failed continuously for %s (limit %s): %w",
+		failingFor.Round(time.Second), maxFailure, err)
+}
+
+// renewalSucceeded resets the continuous failure tracking
+func (m *Manager) renewalSucceeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failingSince = time.Time{}
+	m.lastErr = nil
+}
+
+// recordRenewal records a successful renewal of the current token
+func (m *Manager) recordRenewal() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastRenewal = time.Now()
+}
+
+// signalFatal reports a permanent renewal failure to the owner of the manager
+func (m *Manager) signalFatal(err error) {
+	m.logger.Error("giving up on token renewal", "error", err)
+
+	select {
+	case m.fatal <- err:
+	default:
+	}
+}
+
+// reauthenticate performs a full authentication and swaps in the new client
+func (m *Manager) reauthenticate(ctx context.Context) error {
+	m.logger.Info("attempting re-authentication")
+
+	newClient, err := m.authenticator.Authenticate(ctx)
+	recordAuthOperation(m.authenticator.GetMethod(), opReauthenticate, err)
+	if err != nil {
+		m.logger.Error("re-authentication failed", "error", err)
+		return err
+	}
+
+	m.mu.Lock()
+	m.client = newClient
+	m.lastAuth = time.Now()
+	m.mu.Unlock()
+
+	m.logger.Info("re-authentication successful",
+		"ttl", m.authenticator.GetTokenTTL())
+	m.refreshTokenDeadline(ctx)
+
+	return nil
+}
+
+// checkNonRenewableToken verifies a non-renewable token is still valid and
+// re-authenticates when it is not. It returns an error if the token is still unusable.
+func (m *Manager) checkNonRenewableToken(ctx context.Context) error {
+	m.mu.RLock()
+	client := m.client
+	m.mu.RUnlock()
+
+	if client == nil {
+		m.logger.Error("client is nil, cannot check token")
+		return fmt.Errorf("not authenticated")
+	}
+
+	_, err := client.Auth.TokenLookUpSelf(ctx)
+	if err == nil {
+		m.logger.Debug("non-renewable token is still valid")
+		return nil
+	}
+
+	m.logger.Warn("non-renewable token failed health check", "error", err)
+
+	if m.authenticator.GetMethod() == AuthMethodToken {
+		m.logger.Error("static token appears expired or revoked - re-authentication cannot mint a new token, provide a fresh VAULT_TOKEN and restart",
+			"method", m.authenticator.GetMethod())
+		return err
+	}
+
+	return m.reauthenticate(ctx)
+}
+
+// nextCheckInterval returns how long to wait before the next token check
+func (m *Manager) nextCheckInterval() time.Duration {
+	if m.authenticator.GetTokenTTL() == 0 && m.nonRenewableCheckInterval > 0 {
+		return m.capForTokenDeadline(m.capForSecretID(m.nonRenewableCheckInterval))
+	}
+
+	return m.capForTokenDeadline(m.capForSecretID(m.calculateRenewalSleep()))
+}
+
+// DefaultRenewalJitter is the default maximum fraction the first renewal sleep is shortened by
+const DefaultRenewalJitter = 0.1
+
+// jitterSleep shortens a sleep by a random fraction of up to jitter. It only ever shortens,
+// so a jittered renewal never runs later than planned.
+func jitterSleep(sleep time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return sleep
+	}
+
+	return sleep - time.Duration(rand.Float64()*jitter*float64(sleep))
 }
 
 // calculateRenewalSleep calculates how long to sleep before next renewal check
@@ -200,8 +457,15 @@ func (m *Manager) calculateRenewalSleep() time.Duration {
 	return sleep
 }
 
-// ForceRenewal forces an immediate token renewal
+// ForceRenewal forces an immediate token renewal. It waits for a renewal cycle of the
+// renewal loop in progress, so the two never renew or re-authenticate concurrently.
 func (m *Manager) ForceRenewal(ctx context.Context) error {
+	m.switchMu.Lock()
+	defer m.switchMu.Unlock()
+
+	m.renewMu.Lock()
+	defer m.renewMu.Unlock()
+
 	m.mu.RLock()
 	client := m.client
 	m.mu.RUnlock()
@@ -211,22 +475,36 @@ func (m *Manager) ForceRenewal(ctx context.Context) error {
 	}
 
 	err := m.authenticator.Renew(ctx, client)
-	if err != nil {
-		// Try to re-authenticate
-		newClient, authErr := m.authenticator.Authenticate(ctx)
-		if authErr != nil {
-			return fmt.Errorf("renewal and re-authentication failed: %w", authErr)
-		}
-
-		m.mu.Lock()
-		m.client = newClient
-		m.mu.Unlock()
-
-		m.logger.Info("force renewal: re-authenticated",
-			"ttl", m.authenticator.GetTokenTTL())
-	} else {
+	recordTokenRenewal(err)
+	if err == nil {
+		recordAuthOperation(m.authenticator.GetMethod(), opForceRenew, nil)
+		m.recordRenewal()
+		m.renewalSucceeded()
 		m.logger.Info("force renewal: token renewed",
 			"ttl", m.authenticator.GetTokenTTL())
+		return nil
+	}
+
+	// Try to re-authenticate
+	newClient, authErr := m.authenticator.Authenticate(ctx)
+	recordAuthOperation(m.authenticator.GetMethod(), opForceRenew, authErr)
+	if authErr != nil {
+		return fmt.Errorf("renewal and re-authentication failed: %w", authErr)
+	}
+
+	m.mu.Lock()
+	m.client = newClient
+	m.lastAuth = time.Now()
+	m.mu.Unlock()
+
+	m.renewalSucceeded()
+	m.logger.Info("force renewal: re-authenticated",
+		"ttl", m.authenticator.GetTokenTTL())
+	m.refreshTokenDeadline(ctx)
+
+	// A static token logs in again with the same token, which the new client still uses
+	if m.authenticator.GetMethod() != AuthMethodToken {
+		m.revokeAfterGrace(m.authenticator, client)
 	}
 
 	return nil
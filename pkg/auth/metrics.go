@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Authentication operations tracked by kms_auth_operations_total
+const (
+	opAuthenticate   = "authenticate"
+	opRenew          = "renew"
+	opReauthenticate = "reauthenticate"
+	opForceRenew     = "force_renew"
+	opRevoke         = "revoke"
+	opSwitch         = "switch"
+)
+
+var authOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_auth_operations_total",
+	Help: "Total number of Vault authentication operations by method, operation and result",
+}, []string{"method", "operation", "result"})
+
+// recordAuthOperation increments the auth operation counter for the given outcome
+func recordAuthOperation(method AuthMethod, operation string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	authOperations.WithLabelValues(string(method), operation, result).Inc()
+}
+
+var secretIDExpirySeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_approle_secret_id_expiry_seconds",
+	Help: "Seconds until the AppRole SecretID expires (0 when unknown or non-expiring)",
+})
+
+var tokenAgeSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_auth_token_age_seconds",
+	Help: "Seconds since the Vault token was last issued or renewed, sampled periodically",
+})
+
+var tokenMaxAgeReauthentications = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_auth_token_max_age_reauthentications_total",
+	Help: "Total number of re-authentications because the token was about to reach its maximum lifetime",
+})
+
+var renewBufferAdjustments = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_auth_renew_buffer_adjustments_total",
+	Help: "Total number of times the renew buffer was shrunk because it exceeded half the token TTL",
+}, []string{"method"})
+
+var tokenRenewals = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_auth_renewals_total",
+	Help: "Total number of Vault token renewal attempts by result",
+}, []string{"result"})
+
+// recordTokenRenewal increments the renewal counter for the given outcome
+func recordTokenRenewal(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	tokenRenewals.WithLabelValues(result).Inc()
+}
+
+var authChainFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_auth_chain_fallbacks_total",
+	Help: "Total number of logins through a fallback method of the auth chain after the preferred methods failed",
+}, []string{"method"})
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"time"
+)
+
+// maxRenewBufferFraction is the largest share of the token TTL the renew buffer may take.
+// With a larger buffer ShouldRenew is true right after every renewal and the token is
+// renewed continuously.
+const maxRenewBufferFraction = 0.5
+
+// renewBufferAdjuster is implemented by authenticators with an adjustable renew buffer
+type renewBufferAdjuster interface {
+	GetRenewBuffer() time.Duration
+	SetRenewBuffer(buffer time.Duration)
+}
+
+// GetRenewBuffer returns how long before expiry the token is renewed
+func (b *BaseAuthenticator) GetRenewBuffer() time.Duration {
+	return b.RenewBuffer
+}
+
+// SetRenewBuffer sets how long before expiry the token is renewed
+func (b *BaseAuthenticator) SetRenewBuffer(buffer time.Duration) {
+	b.RenewBuffer = buffer
+}
+
+// adjustRenewBuffer shrinks the renew buffer to maxRenewBufferFraction of the observed token
+// TTL when it is larger. It must run before the renewal loop uses the authenticator.
+func (m *Manager) adjustRenewBuffer(authenticator Authenticator) {
+	adjuster, ok := authenticator.(renewBufferAdjuster)
+	if !ok {
+		return
+	}
+
+	ttl := authenticator.GetTokenTTL()
+	if ttl <= 0 {
+		return // Non-renewable token
+	}
+
+	buffer := adjuster.GetRenewBuffer()
+	limit := time.Duration(float64(ttl) * maxRenewBufferFraction)
+	if buffer <= limit {
+		return
+	}
+
+	adjuster.SetRenewBuffer(limit)
+	renewBufferAdjustments.WithLabelValues(string(authenticator.GetMethod())).Inc()
+
+	m.logger.Warn("renew buffer exceeds the token TTL allowance, shrinking it to avoid continuous renewal",
+		"method", authenticator.GetMethod(),
+		"ttl", ttl,
+		"configuredBuffer", buffer,
+		"renewBuffer", limit)
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// minSecretIDCheckInterval bounds how often the renewal loop wakes up for SecretID checks
+const minSecretIDCheckInterval = 10 * time.Second
+
+// secretIDTracker is implemented by authenticators whose login credential can expire
+// independently of the token (AppRole SecretIDs)
+type secretIDTracker interface {
+	RefreshSecretIDExpiry(ctx context.Context, client *vault.Client) error
+	RotateSecretID(ctx context.Context, client *vault.Client) (string, error)
+	SecretIDExpiry() time.Time
+	SecretIDRenewBuffer() time.Duration
+	AutoRotateSecretID() bool
+}
+
+// initSecretIDTracking looks up the SecretID expiry after the initial login
+func (m *Manager) initSecretIDTracking(ctx context.Context) {
+	tracker, ok := m.authenticator.(secretIDTracker)
+	if !ok {
+		return
+	}
+
+	m.mu.RLock()
+	client := m.client
+	m.mu.RUnlock()
+
+	if err := tracker.RefreshSecretIDExpiry(ctx, client); err != nil {
+		m.logger.Warn("unable to look up secret_id expiry - grant the role read on its secret-id/lookup endpoint to get pre-expiry warnings",
+			"error", err)
+		return
+	}
+
+	expiry := tracker.SecretIDExpiry()
+	secretIDExpirySeconds.Set(secondsUntil(expiry))
+
+	if expiry.IsZero() {
+		m.logger.Debug("secret_id does not expire")
+		return
+	}
+
+	m.logger.Info("secret_id expiry tracked",
+		"expiresAt", expiry,
+		"renewBuffer", tracker.SecretIDRenewBuffer(),
+		"autoRotate", tracker.AutoRotateSecretID())
+}
+
+// checkSecretID rotates the SecretID (or warns) once it is within the renew buffer of expiry,
+// so re-authentication stays possible after the current token lapses
+func (m *Manager) checkSecretID(ctx context.Context) {
+	tracker, ok := m.authenticator.(secretIDTracker)
+	if !ok {
+		return
+	}
+
+	expiry := tracker.SecretIDExpiry()
+	secretIDExpirySeconds.Set(secondsUntil(expiry))
+
+	if expiry.IsZero() || time.Until(expiry) > tracker.SecretIDRenewBuffer() {
+		return
+	}
+
+	if !tracker.AutoRotateSecretID() {
+		m.logger.Warn("secret_id is about to expire - re-authentication will fail afterwards unless a new VAULT_SECRET_ID is provided",
+			"expiresAt", expiry,
+			"remaining", time.Until(expiry).Round(time.Second))
+		return
+	}
+
+	m.mu.RLock()
+	client := m.client
+	m.mu.RUnlock()
+
+	if client == nil {
+		return
+	}
+
+	if _, err := tracker.RotateSecretID(ctx, client); err != nil {
+		m.logger.Error("secret_id rotation failed",
+			"expiresAt", expiry,
+			"error", err)
+		return
+	}
+
+	if err := tracker.RefreshSecretIDExpiry(ctx, client); err != nil {
+		m.logger.Warn("secret_id rotated but its expiry could not be looked up", "error", err)
+	}
+
+	secretIDExpirySeconds.Set(secondsUntil(tracker.SecretIDExpiry()))
+	m.logger.Info("secret_id rotated before expiry",
+		"expiresAt", tracker.SecretIDExpiry())
+}
+
+// capForSecretID shortens sleep so the renewal loop wakes up when the SecretID enters its renew buffer
+func (m *Manager) capForSecretID(sleep time.Duration) time.Duration {
+	tracker, ok := m.authenticator.(secretIDTracker)
+	if !ok || tracker.SecretIDExpiry().IsZero() {
+		return sleep
+	}
+
+	untilBuffer := time.Until(tracker.SecretIDExpiry().Add(-tracker.SecretIDRenewBuffer()))
+	if untilBuffer < minSecretIDCheckInterval {
+		untilBuffer = minSecretIDCheckInterval
+	}
+
+	return min(sleep, untilBuffer)
+}
+
+// secondsUntil returns the seconds remaining until t, or 0 if t is zero or past
+func secondsUntil(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return max(time.Until(t).Seconds(), 0)
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"time"
+)
+
+// Status describes the active authentication method and the health of its token
+type Status struct {
+	Method        AuthMethod `json:"method"`
+	Authenticated bool       `json:"authenticated"`
+	Healthy       bool       `json:"healthy"`
+	Renewable     bool       `json:"renewable"`
+
+	// TTL is the token lifetime granted at the last login or renewal
+	TTL        string `json:"ttl"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+
+	// RemainingSeconds is the estimated time left before the token expires
+	RemainingSeconds int64 `json:"remainingSeconds,omitempty"`
+
+	LastAuthentication *time.Time `json:"lastAuthentication,omitempty"`
+	LastRenewal        *time.Time `json:"lastRenewal,omitempty"`
+	LastError          string     `json:"lastError,omitempty"`
+	FailingSince       *time.Time `json:"failingSince,omitempty"`
+}
+
+// GetMethod returns the authentication method in use
+func (m *Manager) GetMethod() AuthMethod {
+	return m.currentAuthenticator().GetMethod()
+}
+
+// Status returns a snapshot of the authentication state
+func (m *Manager) Status() Status {
+	// Both are read through the authenticator's locked accessors, the renewal loop may be
+	// updating them concurrently
+	ttl := m.currentAuthenticator().GetTokenTTL()
+	issued := m.LastRenewal()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := Status{
+		Method:        m.authenticator.GetMethod(),
+		Authenticated: m.client != nil,
+		Healthy:       m.client != nil && m.lastErr == nil,
+		Renewable:     ttl > 0,
+		TTL:           ttl.String(),
+		TTLSeconds:    int64(ttl.Seconds()),
+	}
+
+	if m.lastErr != nil {
+		status.LastError = m.lastErr.Error()
+	}
+
+	status.LastAuthentication = timeOrNil(m.lastAuth)
+	status.LastRenewal = timeOrNil(m.lastRenewal)
+	status.FailingSince = timeOrNil(m.failingSince)
+
+	// The token TTL restarts at the latest login or renewal
+	if ttl > 0 && !issued.IsZero() {
+		status.RemainingSeconds = int64(max(ttl-time.Since(issued), 0).Seconds())
+	}
+
+	return status
+}
+
+// timeOrNil returns a pointer to t, or nil when t is zero
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// SwitchConfig authenticates with a new configuration, which may use a different method,
+// and swaps it in without a restart. The new login happens first, so on failure the current
+// authentication stays in place. Renewal and address watching are stopped during the swap
+// and restarted for the new configuration, then the previous token is revoked once the revoke
+// grace period has passed, so requests still using the previous client can finish.
+func (m *Manager) SwitchConfig(ctx context.Context, config *AuthConfig) error {
+	if err := ValidateConfig(config); err != nil {
+		return fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	authenticator, err := NewAuthenticator(config)
+	if err != nil {
+		return fmt.Errorf("failed to create authenticator: %w", err)
+	}
+
+	m.switchMu.Lock()
+	defer m.switchMu.Unlock()
+
+	client, err := authenticator.Authenticate(ctx)
+	recordAuthOperation(authenticator.GetMethod(), opSwitch, err)
+	if err != nil {
+		return fmt.Errorf("authentication with the new config failed: %w", err)
+	}
+
+	// The background loops use the authenticator without locking, so they must be
+	// stopped before it changes
+	m.stopBackground()
+
+	m.mu.Lock()
+	previous, previousClient := m.authenticator, m.client
+	m.authenticator = authenticator
+	m.config = config
+	m.client = client
+	m.lastAuth = time.Now()
+	m.lastRenewal = time.Time{}
+	m.failingSince = time.Time{}
+	m.lastErr = nil
+	m.mu.Unlock()
+
+	m.logger.Info("switched authentication",
+		"previousMethod", previous.GetMethod(),
+		"method", authenticator.GetMethod(),
+		"ttl", authenticator.GetTokenTTL())
+
+	m.adjustRenewBuffer(authenticator)
+	m.initSecretIDTracking(ctx)
+	m.refreshTokenDeadline(ctx)
+	m.startBackground()
+
+	if previousClient != nil && !sameStaticToken(previous, authenticator) {
+		m.revokeAfterGrace(previous, previousClient)
+	}
+
+	return nil
+}
+
+// revokeTimeout bounds the revocation of a replaced token
+const revokeTimeout = 10 * time.Second
+
+// SetRevokeGracePeriod delays revoking a token replaced by SwitchConfig or ForceRenewal, so
+// requests that took the previous client just before the swap don't fail with a permission
+// error. Set it to the longest a request may take; 0 revokes at once.
+func (m *Manager) SetRevokeGracePeriod(grace time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeGracePeriod = grace
+}
+
+// revokeAfterGrace revokes a replaced token once the revoke grace period has passed, in the
+// background, or as soon as the manager stops. Without a grace period it is revoked at once.
+func (m *Manager) revokeAfterGrace(previous Authenticator, previousClient *vault.Client) {
+	m.mu.Lock()
+	grace := m.revokeGracePeriod
+	if grace <= 0 {
+		m.mu.Unlock()
+		m.revokeReplacedWithTimeout(previous, previousClient)
+		return
+	}
+	if m.revokeNow == nil {
+		m.revokeNow = make(chan struct{})
+	}
+	revokeNow := m.revokeNow
+	m.revocations.Add(1)
+	m.mu.Unlock()
+
+	go func() {
+		defer m.revocations.Done()
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-revokeNow:
+		}
+
+		m.revokeReplacedWithTimeout(previous, previousClient)
+	}()
+}
+
+// revokeReplacedWithTimeout revokes a replaced token, bounded by revokeTimeout rather than the
+// context of the swap, which may be over by then
+func (m *Manager) revokeReplacedWithTimeout(previous Authenticator, previousClient *vault.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), revokeTimeout)
+	defer cancel()
+
+	m.revokeReplaced(ctx, previous, previousClient)
+}
+
+// flushRevocations revokes the replaced tokens still waiting out the grace period and waits
+// for every pending revocation to finish
+func (m *Manager) flushRevocations() {
+	m.mu.Lock()
+	if m.revokeNow != nil {
+		close(m.revokeNow)
+		m.revokeNow = nil
+	}
+	m.mu.Unlock()
+
+	m.revocations.Wait()
+}
+
+// revokeReplaced revokes a token that a new login has replaced. The new login already
+// succeeded, so a token that can't be revoked only lingers until it expires.
+func (m *Manager) revokeReplaced(ctx context.Context, previous Authenticator, previousClient *vault.Client) {
+	err := previous.Revoke(ctx, previousClient)
+	recordAuthOperation(previous.GetMethod(), opRevoke, err)
+	if err != nil {
+		m.logger.Warn("failed to revoke the previous token, it remains valid until it expires",
+			"method", previous.GetMethod(),
+			"error", err)
+		return
+	}
+
+	m.logger.Info("previous token revoked", "method", previous.GetMethod())
+}
+
+// sameStaticToken reports whether both authenticators use the same static token, which
+// must not be revoked as the new client still uses it
+func sameStaticToken(a, b Authenticator) bool {
+	type staticToken interface{ GetToken() string }
+
+	ta, ok := a.(staticToken)
+	if !ok {
+		return false
+	}
+
+	tb, ok := b.(staticToken)
+
+	return ok && ta.GetToken() == tb.GetToken()
+}
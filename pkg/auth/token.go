@@ -44,10 +44,7 @@ func NewTokenAuth(config *TokenConfig, vaultAddr string) (*TokenAuthenticator, e
 // Authenticate performs token authentication
 func (t *TokenAuthenticator) Authenticate(ctx context.Context) (*vault.Client, error) {
 	// Create client with token
-	client, err := vault.New(
-		vault.WithAddress(t.VaultAddr),
-		vault.WithRequestTimeout(30*time.Second),
-	)
+	client, err := t.newVaultClient()
 	if err != nil {
 		return nil, NewAuthError(AuthMethodToken, "authenticate", err, "failed to create vault client")
 	}
@@ -65,8 +62,7 @@ func (t *TokenAuthenticator) Authenticate(ctx context.Context) (*vault.Client, e
 
 	// Extract TTL from response
 	if ttl, ok := resp.Data["ttl"].(float64); ok {
-		t.TokenTTL = time.Duration(ttl) * time.Second
-		t.LastRenewal = time.Now()
+		t.SetTokenTTL(time.Duration(ttl) * time.Second)
 	}
 
 	return client, nil
@@ -93,8 +89,7 @@ func (t *TokenAuthenticator) Renew(ctx context.Context, client *vault.Client) er
 
 	// Update TTL
 	if auth := renewResp.Auth; auth != nil {
-		t.TokenTTL = time.Duration(auth.LeaseDuration) * time.Second
-		t.LastRenewal = time.Now()
+		t.SetTokenTTL(time.Duration(auth.LeaseDuration) * time.Second)
 	}
 
 	return nil
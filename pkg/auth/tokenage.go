@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTokenSampleInterval is how often the token age is sampled
+const DefaultTokenSampleInterval = 15 * time.Second
+
+// renewalTracker is implemented by authenticators that record when their token was last renewed
+type renewalTracker interface {
+	GetLastRenewal() time.Time
+}
+
+// GetLastRenewal returns when the token was last issued or renewed
+func (b *BaseAuthenticator) GetLastRenewal() time.Time {
+	b.tokenMu.RLock()
+	defer b.tokenMu.RUnlock()
+
+	return b.LastRenewal
+}
+
+// LastRenewal returns when the current token was last issued or renewed (zero when unknown)
+func (m *Manager) LastRenewal() time.Time {
+	if tracker, ok := m.currentAuthenticator().(renewalTracker); ok {
+		return tracker.GetLastRenewal()
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.lastRenewal.After(m.lastAuth) {
+		return m.lastRenewal
+	}
+
+	return m.lastAuth
+}
+
+// SampleTokenMetrics records the token age immediately and then every interval until ctx is done
+func (m *Manager) SampleTokenMetrics(ctx context.Context, interval time.Duration) {
+	m.sampleTokenAge(time.Now())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.sampleTokenAge(now)
+		}
+	}
+}
+
+// sampleTokenAge sets kms_auth_token_age_seconds from the last renewal time
+func (m *Manager) sampleTokenAge(now time.Time) {
+	last := m.LastRenewal()
+	if last.IsZero() {
+		tokenAgeSeconds.Set(0)
+		return
+	}
+
+	tokenAgeSeconds.Set(now.Sub(last).Seconds())
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// minTokenDeadlineCheckInterval bounds how often the renewal loop wakes up for the token deadline
+const minTokenDeadlineCheckInterval = 10 * time.Second
+
+// defaultTokenDeadlineBuffer is how long before its deadline the token is replaced when the
+// authenticator has no renew buffer
+const defaultTokenDeadlineBuffer = 5 * time.Minute
+
+// refreshTokenDeadline works out when the current token must be replaced after a login: the
+// earlier of its explicit_max_ttl, which renewal can't extend, and MaxTokenAge. Both count from
+// the token's creation_time as reported by lookup-self, or from the login when the lookup fails.
+func (m *Manager) refreshTokenDeadline(ctx context.Context) {
+	m.mu.RLock()
+	client, created, maxAge := m.client, m.lastAuth, m.config.MaxTokenAge
+	m.mu.RUnlock()
+
+	var deadline time.Time
+
+	if client != nil {
+		resp, err := client.Auth.TokenLookUpSelf(ctx)
+		if err != nil {
+			m.logger.Warn("unable to look up the token's maximum lifetime, counting its age from the login",
+				"error", err)
+		} else {
+			if creation := int64Value(resp.Data["creation_time"]); creation > 0 {
+				created = time.Unix(creation, 0)
+			}
+			if explicitMaxTTL := int64Value(resp.Data["explicit_max_ttl"]); explicitMaxTTL > 0 {
+				deadline = created.Add(time.Duration(explicitMaxTTL) * time.Second)
+			}
+		}
+	}
+
+	if maxAge > 0 {
+		if byAge := created.Add(maxAge); deadline.IsZero() || byAge.Before(deadline) {
+			deadline = byAge
+		}
+	}
+
+	m.mu.Lock()
+	m.tokenDeadline = deadline
+	m.mu.Unlock()
+
+	if deadline.IsZero() {
+		return
+	}
+
+	m.logger.Info("token maximum lifetime tracked",
+		"createdAt", created,
+		"expiresAt", deadline,
+		"maxTokenAge", maxAge)
+}
+
+// getTokenDeadline returns when the current token must be replaced (zero when it has no limit)
+func (m *Manager) getTokenDeadline() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.tokenDeadline
+}
+
+// tokenDeadlineBuffer returns how long before its deadline the token is replaced
+func (m *Manager) tokenDeadlineBuffer() time.Duration {
+	if adjuster, ok := m.authenticator.(renewBufferAdjuster); ok && adjuster.GetRenewBuffer() > 0 {
+		return adjuster.GetRenewBuffer()
+	}
+
+	return defaultTokenDeadlineBuffer
+}
+
+// checkTokenDeadline re-authenticates once the token is within the renew buffer of its
+// deadline, instead of waiting for renewal to fail. It reports whether a new token was obtained.
+func (m *Manager) checkTokenDeadline(ctx context.Context) bool {
+	deadline := m.getTokenDeadline()
+	if deadline.IsZero() || time.Until(deadline) > m.tokenDeadlineBuffer() {
+		return false
+	}
+
+	if m.authenticator.GetMethod() == AuthMethodToken {
+		m.logger.Warn("static token is about to reach its maximum lifetime - provide a fresh VAULT_TOKEN and restart",
+			"expiresAt", deadline,
+			"remaining", time.Until(deadline).Round(time.Second))
+		return false
+	}
+
+	m.logger.Info("token is about to reach its maximum lifetime, re-authenticating",
+		"expiresAt", deadline)
+
+	if err := m.reauthenticate(ctx); err != nil {
+		return false
+	}
+
+	tokenMaxAgeReauthentications.Inc()
+
+	return true
+}
+
+// capForTokenDeadline shortens sleep so the renewal loop wakes up when the token enters the
+// renew buffer of its deadline
+func (m *Manager) capForTokenDeadline(sleep time.Duration) time.Duration {
+	deadline := m.getTokenDeadline()
+	if deadline.IsZero() || m.authenticator.GetMethod() == AuthMethodToken {
+		return sleep
+	}
+
+	untilBuffer := time.Until(deadline.Add(-m.tokenDeadlineBuffer()))
+	if untilBuffer < minTokenDeadlineCheckInterval {
+		untilBuffer = minTokenDeadlineCheckInterval
+	}
+
+	return min(sleep, untilBuffer)
+}
+
+// int64Value converts a numeric Vault response field to an int64
+func int64Value(v interface{}) int64 {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return i
+	case float64:
+		return int64(n)
+	default:
+		return 0
+	}
+}
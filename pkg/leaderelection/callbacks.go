@@ -4,17 +4,31 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // CallbackBuilder helps build leader election callbacks with common patterns
 type CallbackBuilder struct {
 	logger *slog.Logger
+
+	// identity is this instance's election identity, used to recognise itself as leader
+	identity string
 }
 
-// NewCallbackBuilder creates a new callback builder
+// NewCallbackBuilder creates a new callback builder. The instance is identified by
+// DefaultIdentity() unless SetIdentity is called.
 func NewCallbackBuilder(logger *slog.Logger) *CallbackBuilder {
-	return &CallbackBuilder{logger: logger}
+	return &CallbackBuilder{logger: logger, identity: DefaultIdentity()}
+}
+
+// SetIdentity sets the election identity of this instance, normally LeaseConfig.Identity.
+// It must match the identity the election runs with for leaders to be recognised as self.
+func (cb *CallbackBuilder) SetIdentity(identity string) {
+	cb.identity = identity
 }
 
 // BuildServerCallbacks creates callbacks that integrate with a server lifecycle
@@ -41,12 +55,10 @@ func (cb *CallbackBuilder) BuildServerCallbacks(
 		},
 
 		OnNewLeader: func(identity string) {
-			hostname, _ := os.Hostname()
-			isCurrentInstance := identity == hostname
-
+			// Compare with the election identity, which is often the pod name rather than the hostname
 			cb.logger.Info("New leader elected",
 				"leader", identity,
-				"isSelf", isCurrentInstance)
+				"isSelf", identity != "" && identity == cb.identity)
 
 			if onLeaderChange != nil {
 				onLeaderChange(identity)
@@ -172,9 +184,76 @@ func GetNamespaceFromEnv() string {
 
 // GetLeaseNameFromEnv returns the lease name from environment or default
 func GetLeaseNameFromEnv() string {
-	if name := os.Getenv("LEADER_ELECTION_NAME"); name != "" {
+	name := os.Getenv("LEADER_ELECTION_NAME")
+	if name == "" {
+		name = "talos-kms-leader"
+	}
+
+	return WithPoolSuffix(name, GetPoolFromEnv())
+}
+
+// GetPoolFromEnv returns the election pool (e.g. "canary") from LEADER_ELECTION_POOL.
+// Pods in different pools elect independent leaders.
+func GetPoolFromEnv() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv("LEADER_ELECTION_POOL")))
+}
+
+// WithPoolSuffix appends the pool to a lease name. An empty pool leaves the name
+// unchanged, and a name that already carries the suffix is not suffixed twice.
+func WithPoolSuffix(name, pool string) string {
+	if pool == "" || strings.HasSuffix(name, "-"+pool) {
 		return name
 	}
 
-	return "talos-kms-leader"
+	return name + "-" + pool
+}
+
+// GetLeaseLabelsFromEnv returns lease labels from LEADER_ELECTION_LABELS ("key=value,key=value")
+func GetLeaseLabelsFromEnv() map[string]string {
+	return parseKeyValueList(os.Getenv("LEADER_ELECTION_LABELS"))
+}
+
+// GetLeaseAnnotationsFromEnv returns lease annotations from LEADER_ELECTION_ANNOTATIONS ("key=value,key=value")
+func GetLeaseAnnotationsFromEnv() map[string]string {
+	return parseKeyValueList(os.Getenv("LEADER_ELECTION_ANNOTATIONS"))
+}
+
+// PodOwnerReferenceFromEnv returns a reference to the current pod when POD_NAME and POD_UID
+// are available. Pass it to ResolveWorkloadOwner to find the lease owner.
+func PodOwnerReferenceFromEnv() *metav1.OwnerReference {
+	podName := os.Getenv("POD_NAME")
+	podUID := os.Getenv("POD_UID")
+	if podName == "" || podUID == "" {
+		return nil
+	}
+
+	return &metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       podName,
+		UID:        types.UID(podUID),
+	}
+}
+
+// parseKeyValueList parses a comma-separated list of key=value pairs
+func parseKeyValueList(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		result[key] = strings.TrimSpace(val)
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
 }
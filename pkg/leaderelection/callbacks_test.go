@@ -1,9 +1,12 @@
 package leaderelection
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -161,6 +164,67 @@ func TestGetLeaseNameFromEnv(t *testing.T) {
 	}
 }
 
+func TestGetLeaseNameFromEnvWithPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{
+			name:     "no pool",
+			env:      map[string]string{},
+			expected: "talos-kms-leader",
+		},
+		{
+			name:     "canary pool",
+			env:      map[string]string{"LEADER_ELECTION_POOL": "canary"},
+			expected: "talos-kms-leader-canary",
+		},
+		{
+			name:     "pool is normalized",
+			env:      map[string]string{"LEADER_ELECTION_POOL": " Canary "},
+			expected: "talos-kms-leader-canary",
+		},
+		{
+			name:     "pool with custom name",
+			env:      map[string]string{"LEADER_ELECTION_NAME": "kms", "LEADER_ELECTION_POOL": "stable"},
+			expected: "kms-stable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LEADER_ELECTION_NAME", "")
+			t.Setenv("LEADER_ELECTION_POOL", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if name := GetLeaseNameFromEnv(); name != tt.expected {
+				t.Errorf("Expected lease name %s, got %s", tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestWithPoolSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     string
+		expected string
+	}{
+		{name: "talos-kms-leader", pool: "", expected: "talos-kms-leader"},
+		{name: "talos-kms-leader", pool: "canary", expected: "talos-kms-leader-canary"},
+		{name: "talos-kms-leader-canary", pool: "canary", expected: "talos-kms-leader-canary"},
+	}
+
+	for _, tt := range tests {
+		if got := WithPoolSuffix(tt.name, tt.pool); got != tt.expected {
+			t.Errorf("WithPoolSuffix(%q, %q) = %q, want %q", tt.name, tt.pool, got, tt.expected)
+		}
+	}
+}
+
 func TestCallbackBuilder(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	builder := NewCallbackBuilder(logger)
@@ -174,6 +238,41 @@ func TestCallbackBuilder(t *testing.T) {
 	}
 }
 
+func TestBuildServerCallbacksIsSelf(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name       string
+		identity   string
+		leader     string
+		wantIsSelf bool
+	}{
+		{name: "own pod name", identity: "kms-0", leader: "kms-0", wantIsSelf: true},
+		{name: "other pod", identity: "kms-0", leader: "kms-1", wantIsSelf: false},
+		{name: "hostname is not the identity", identity: "kms-0", leader: hostname, wantIsSelf: false},
+		{name: "no leader", identity: "kms-0", leader: "", wantIsSelf: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			builder := NewCallbackBuilder(slog.New(slog.NewTextHandler(&logs, nil)))
+			builder.SetIdentity(tt.identity)
+
+			var changedTo string
+			callbacks := builder.BuildServerCallbacks(nil, nil, func(leader string) { changedTo = leader })
+			callbacks.OnNewLeader(tt.leader)
+
+			if want := fmt.Sprintf("isSelf=%v", tt.wantIsSelf); !strings.Contains(logs.String(), want) {
+				t.Errorf("log %q does not contain %s", logs.String(), want)
+			}
+			if changedTo != tt.leader {
+				t.Errorf("onLeaderChange got %q, want %q", changedTo, tt.leader)
+			}
+		})
+	}
+}
+
 func TestBuildLoggingCallbacks(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	builder := NewCallbackBuilder(logger)
@@ -252,3 +351,66 @@ func TestBuildGracefulShutdownCallbacks(t *testing.T) {
 		t.Error("Expected onLoseLeadership to be called")
 	}
 }
+
+func TestParseKeyValueList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]string
+	}{
+		{
+			name:  "empty",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "multiple pairs",
+			value: "app.kubernetes.io/owner=platform, team = kms",
+			want:  map[string]string{"app.kubernetes.io/owner": "platform", "team": "kms"},
+		},
+		{
+			name:  "empty value allowed",
+			value: "flag=",
+			want:  map[string]string{"flag": ""},
+		},
+		{
+			name:  "malformed entries skipped",
+			value: "novalue,=nokey,ok=yes",
+			want:  map[string]string{"ok": "yes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseKeyValueList(tt.value)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseKeyValueList() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("parseKeyValueList()[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestPodOwnerReferenceFromEnv(t *testing.T) {
+	t.Setenv("POD_NAME", "kms-0")
+	t.Setenv("POD_UID", "")
+
+	if ref := PodOwnerReferenceFromEnv(); ref != nil {
+		t.Errorf("Expected no owner reference without POD_UID, got %v", ref)
+	}
+
+	t.Setenv("POD_UID", "1234-5678")
+
+	ref := PodOwnerReferenceFromEnv()
+	if ref == nil {
+		t.Fatal("Expected owner reference")
+	}
+
+	if ref.Kind != "Pod" || ref.Name != "kms-0" || string(ref.UID) != "1234-5678" {
+		t.Errorf("Unexpected owner reference: %+v", ref)
+	}
+}
@@ -0,0 +1,99 @@
+package leaderelection
+
+import (
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+)
+
+// clockSkewTolerance is how far the local wall clock may disagree with the lease, on top of
+// one retry period, before clock skew is suspected
+const clockSkewTolerance = 2 * time.Second
+
+// renewObservation is a lease record as first seen by this instance
+type renewObservation struct {
+	holder    string
+	renewTime time.Time
+
+	// seenAt is when the record was first seen, with a monotonic clock reading
+	seenAt time.Time
+
+	// changed is set when the record replaced one seen on an earlier poll, so it was
+	// written at most about one retry period before seenAt
+	changed bool
+
+	// skewReported is set once clock skew was counted for this record
+	skewReported bool
+}
+
+// observeRenewal returns the observation of the lease's current record, recording it when
+// the holder or renew time differ from the last one seen
+func (lm *LeaseManager) observeRenewal(lease *coordinationv1.Lease) renewObservation {
+	var holder string
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+
+	var renewTime time.Time
+	if lease.Spec.RenewTime != nil {
+		renewTime = lease.Spec.RenewTime.Time
+	}
+
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	if prev := lm.renewal; prev != nil && prev.holder == holder && prev.renewTime.Equal(renewTime) {
+		return *prev
+	}
+
+	lm.renewal = &renewObservation{
+		holder:    holder,
+		renewTime: renewTime,
+		seenAt:    time.Now(),
+		changed:   lm.renewal != nil,
+	}
+
+	return *lm.renewal
+}
+
+// sinceRenewal returns how long ago the lease was renewed. The renew time was written with
+// another instance's wall clock, so the result is checked against the monotonic time since
+// the record was first seen. When the two disagree by more than a retry period plus
+// clockSkewTolerance, clock skew is suspected and the monotonic time is used, so a clock
+// jumping forward doesn't take the lease from a leader that is still renewing it.
+func (lm *LeaseManager) sinceRenewal(lease *coordinationv1.Lease, now time.Time) time.Duration {
+	observation := lm.observeRenewal(lease)
+	wall := now.Sub(lease.Spec.RenewTime.Time)
+
+	switch {
+	case wall < -clockSkewTolerance:
+		// Renewed in the future: the other instance's clock is ahead of ours
+		lm.suspectClockSkew()
+
+	case observation.changed && wall-time.Since(observation.seenAt) > lm.config.RetryPeriod+clockSkewTolerance:
+		// Seen renewed moments ago, yet the wall clock says it was long ago
+		lm.suspectClockSkew()
+
+	default:
+		return wall
+	}
+
+	if !observation.changed {
+		return wall
+	}
+
+	return time.Since(observation.seenAt)
+}
+
+// suspectClockSkew counts suspected clock skew once per observed lease record
+func (lm *LeaseManager) suspectClockSkew() {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	if lm.renewal == nil || lm.renewal.skewReported {
+		return
+	}
+
+	lm.renewal.skewReported = true
+	suspectedClockSkew.Inc()
+}
@@ -0,0 +1,106 @@
+package leaderelection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+)
+
+// CollisionAction is what the controller does when another process seems to share its identity
+type CollisionAction string
+
+const (
+	// CollisionActionDecline logs a critical error and stays a follower until the other
+	// holder stops renewing the lease
+	CollisionActionDecline CollisionAction = "decline"
+	// CollisionActionExit reports a fatal error so the process exits and is rescheduled
+	CollisionActionExit CollisionAction = "exit"
+)
+
+// ErrIdentityCollision is returned by AcquireLease when the lease is held under this
+// instance's identity by another process
+var ErrIdentityCollision = errors.New("another process holds the lease with this identity")
+
+// processStart is when this process started; leases acquired earlier under our identity
+// were acquired by someone else
+var processStart = time.Now()
+
+// ParseCollisionAction parses an identity collision action name
+func ParseCollisionAction(value string) (CollisionAction, error) {
+	switch action := CollisionAction(value); action {
+	case CollisionActionDecline, CollisionActionExit:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown identity collision action %q (expected decline or exit)", value)
+	}
+}
+
+// acquiredBeforeStart reports whether the lease was acquired before this process started,
+// so a holder with our identity is another process or an earlier run of this pod
+func (lm *LeaseManager) acquiredBeforeStart(lease *coordinationv1.Lease) bool {
+	return !lm.startedAt.IsZero() && lease.Spec.AcquireTime != nil &&
+		lease.Spec.AcquireTime.Time.Before(lm.startedAt)
+}
+
+// identityCollision reports whether another process holds the lease under this instance's
+// identity: it was acquired before this process started, yet it was renewed since and has not
+// expired. An earlier run of this pod stopped renewing before we started, so its lease is left
+// to expire and taken over without suspecting a collision.
+func (lm *LeaseManager) identityCollision(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != lm.config.Identity {
+		return false
+	}
+
+	if !lm.acquiredBeforeStart(lease) || lease.Spec.RenewTime == nil || !lease.Spec.RenewTime.Time.After(lm.startedAt) {
+		return false
+	}
+
+	leaseDuration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+
+	return lm.sinceRenewal(lease, now) <= leaseDuration
+}
+
+// recordIdentityCollision logs a suspected identity collision once when it starts and applies
+// the configured action. A nil error clears the collision state.
+func (ec *ElectionController) recordIdentityCollision(err error) {
+	ec.mu.Lock()
+	started := err != nil && !ec.identityCollision
+	ended := err == nil && ec.identityCollision
+	ec.identityCollision = err != nil
+	ec.mu.Unlock()
+
+	switch {
+	case started:
+		identityCollisions.Inc()
+
+		action := ec.config.CollisionAction
+		if action == "" {
+			action = CollisionActionDecline
+		}
+
+		outcome := "declining leadership"
+		if action == CollisionActionExit {
+			outcome = "exiting"
+		}
+
+		ec.logger.Error("CRITICAL: another instance appears to share this leader identity, "+outcome+" - give every instance a unique identity (POD_NAME)",
+			"identity", ec.config.Identity,
+			"lease", ec.config.Name,
+			"namespace", ec.config.Namespace,
+			"action", action,
+			"error", err)
+
+		if action == CollisionActionExit {
+			select {
+			case ec.fatal <- err:
+			default:
+			}
+		}
+
+	case ended:
+		ec.logger.Info("Identity collision cleared, competing for leadership again",
+			"identity", ec.config.Identity)
+	}
+}
@@ -2,12 +2,24 @@ package leaderelection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 )
 
+const (
+	// defaultReleaseTimeout bounds each lease release attempt when ReleaseTimeout is unset
+	defaultReleaseTimeout = 5 * time.Second
+
+	// releaseAttempts is how many times the lease release is tried on exit
+	releaseAttempts = 2
+
+	// releaseRetryBackoff is the pause before retrying a failed lease release
+	releaseRetryBackoff = 500 * time.Millisecond
+)
+
 // LeaderElectionCallbacks define the callbacks for leader election events
 type LeaderElectionCallbacks struct {
 	// OnStartedLeading is called when this instance becomes the leader
@@ -18,10 +30,20 @@ type LeaderElectionCallbacks struct {
 	OnNewLeader func(identity string)
 }
 
+// LockBackend acquires and releases the leadership lock. LeaseManager implements it with a
+// Kubernetes Lease; tests can substitute an in-memory implementation.
+type LockBackend interface {
+	// AcquireLease attempts to acquire or renew the lock, returning the observed lock state
+	AcquireLease(ctx context.Context) (bool, *LeaseInfo, error)
+
+	// ReleaseLease releases the lock if this instance holds it
+	ReleaseLease(ctx context.Context) error
+}
+
 // ElectionController manages the leader election process
 type ElectionController struct {
 	config       *LeaseConfig
-	leaseManager *LeaseManager
+	leaseManager LockBackend
 	callbacks    LeaderElectionCallbacks
 	logger       *slog.Logger
 
@@ -31,6 +53,27 @@ type ElectionController struct {
 	isRunning        bool
 	currentLeader    string
 	lastLeaderChange time.Time
+	lastLeaseInfo    *LeaseInfo
+	rbacErr          error
+
+	// identityCollision is set while another process holds the lease with our identity
+	identityCollision bool
+
+	// transitioning is set while leadership callbacks for the latest change are still running
+	transitioning bool
+	transitionGen uint64
+
+	// history holds the most recent leadership transitions
+	history leadershipHistory
+
+	// Flap detection: recent leadership changes and the resulting state
+	flapTimes    []time.Time
+	flapping     bool
+	observeUntil time.Time
+	fatal        chan error
+
+	// releaseBackoff is the pause before retrying a failed lease release
+	releaseBackoff time.Duration
 
 	// Control channels
 	stopCh    chan struct{}
@@ -49,14 +92,22 @@ func NewElectionController(config *LeaseConfig, callbacks LeaderElectionCallback
 		return nil, fmt.Errorf("failed to create lease manager: %w", err)
 	}
 
+	return NewElectionControllerWithLock(config, leaseManager, callbacks, logger), nil
+}
+
+// NewElectionControllerWithLock creates a leader election controller using the given lock backend
+func NewElectionControllerWithLock(config *LeaseConfig, lock LockBackend, callbacks LeaderElectionCallbacks, logger *slog.Logger) *ElectionController {
 	return &ElectionController{
-		config:       config,
-		leaseManager: leaseManager,
-		callbacks:    callbacks,
-		logger:       logger,
-		stopCh:       make(chan struct{}),
-		stoppedCh:    make(chan struct{}),
-	}, nil
+		config:         config,
+		leaseManager:   lock,
+		callbacks:      callbacks,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+		stoppedCh:      make(chan struct{}),
+		fatal:          make(chan error, 1),
+		history:        newLeadershipHistory(config.HistorySize),
+		releaseBackoff: releaseRetryBackoff,
+	}
 }
 
 // Start begins the leader election process
@@ -101,6 +152,14 @@ func (ec *ElectionController) IsLeader() bool {
 	return ec.isLeader
 }
 
+// InTransition reports whether leadership changed and the callbacks have not finished yet.
+// Requests should not be served in this window since the server state may lag the lease.
+func (ec *ElectionController) InTransition() bool {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return ec.transitioning
+}
+
 // GetCurrentLeader returns the identity of the current leader
 func (ec *ElectionController) GetCurrentLeader() string {
 	ec.mu.RLock()
@@ -108,6 +167,31 @@ func (ec *ElectionController) GetCurrentLeader() string {
 	return ec.currentLeader
 }
 
+// GetLastLeaseInfo returns a copy of the lease as last observed by the election loop, or nil
+func (ec *ElectionController) GetLastLeaseInfo() *LeaseInfo {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+
+	if ec.lastLeaseInfo == nil {
+		return nil
+	}
+
+	info := *ec.lastLeaseInfo
+	return &info
+}
+
+// RetryPeriod returns how often the controller attempts to acquire the lease
+func (ec *ElectionController) RetryPeriod() time.Duration {
+	return ec.config.RetryPeriod
+}
+
+// RBACError returns the last Lease API permission error, or nil once lease calls succeed again
+func (ec *ElectionController) RBACError() error {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return ec.rbacErr
+}
+
 // GetMetrics returns leadership metrics
 func (ec *ElectionController) GetMetrics() ElectionMetrics {
 	ec.mu.RLock()
@@ -120,6 +204,7 @@ func (ec *ElectionController) GetMetrics() ElectionMetrics {
 		AcquisitionErrors: ec.acquisitionErrors,
 		RenewalErrors:     ec.renewalErrors,
 		LastLeaderChange:  ec.lastLeaderChange,
+		History:           ec.history.list(),
 	}
 }
 
@@ -150,38 +235,86 @@ func (ec *ElectionController) run(ctx context.Context) {
 
 // tryAcquireLease attempts to acquire or renew the lease
 func (ec *ElectionController) tryAcquireLease(ctx context.Context) {
-	acquired, err := ec.leaseManager.AcquireLease(ctx)
+	now := time.Now()
+
+	ec.mu.Lock()
+	ec.updateFlapState(now)
+	ec.mu.Unlock()
+
+	// A flapping instance in observer mode stops competing for the lease
+	if ec.observing(now) {
+		if ec.IsLeader() {
+			ec.stepDown("leadership is flapping, observing")
+			ec.releaseLease()
+		}
+		return
+	}
+
+	// Classify errors by whether we held the lease when the attempt started, leadership can
+	// change while the call is in flight
+	heldLease := ec.IsLeader()
+
+	acquired, leaseInfo, err := ec.leaseManager.AcquireLease(ctx)
+
+	if errors.Is(err, ErrIdentityCollision) {
+		ec.recordLeaseError(nil)
+		ec.recordIdentityCollision(err)
+		return
+	}
+	ec.recordIdentityCollision(nil)
 
 	if err != nil {
 		ec.mu.Lock()
-		if ec.isLeader {
-			ec.acquisitionErrors++
-		} else {
+		if heldLease {
 			ec.renewalErrors++
+		} else {
+			ec.acquisitionErrors++
 		}
 		ec.mu.Unlock()
 
-		ec.logger.Error("Failed to acquire/renew lease",
-			"identity", ec.config.Identity,
-			"error", err)
+		ec.recordLeaseError(err)
 
 		// If we were the leader but failed to renew, step down
-		if ec.isLeader {
-			ec.stepDown()
+		if ec.IsLeader() {
+			ec.stepDown("lease renewal failure")
 		}
 		return
 	}
 
-	// Get current lease info to check who the leader is
-	leaseInfo, err := ec.leaseManager.GetLeaseInfo(ctx)
-	if err != nil {
-		ec.logger.Error("Failed to get lease info",
+	ec.recordLeaseError(nil)
+
+	// Reuse the lease observed during acquisition to check who the leader is
+	ec.updateLeadershipState(acquired, leaseInfo)
+}
+
+// recordLeaseError logs a lease failure, calling out permission problems distinctly from
+// contention so a misconfigured Role is obvious. A nil error clears the RBAC state.
+func (ec *ElectionController) recordLeaseError(err error) {
+	ec.mu.Lock()
+	hadRBACErr := ec.rbacErr != nil
+	if errors.Is(err, ErrLeaseRBAC) {
+		ec.rbacErr = err
+	} else {
+		ec.rbacErr = nil
+	}
+	ec.mu.Unlock()
+
+	switch {
+	case errors.Is(err, ErrLeaseRBAC):
+		ec.logger.Error("Lease API permission denied - this instance can never become leader until RBAC is fixed (grant get, create, update on leases.coordination.k8s.io)",
 			"identity", ec.config.Identity,
+			"lease", ec.config.Name,
+			"namespace", ec.config.Namespace,
 			"error", err)
-		return
-	}
 
-	ec.updateLeadershipState(acquired, leaseInfo)
+	case err != nil:
+		ec.logger.Error("Failed to acquire/renew lease",
+			"identity", ec.config.Identity,
+			"error", err)
+
+	case hadRBACErr:
+		ec.logger.Info("Lease API access restored", "identity", ec.config.Identity)
+	}
 }
 
 // updateLeadershipState updates the internal state based on lease acquisition results
@@ -194,6 +327,7 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 
 	ec.isLeader = acquired
 	ec.currentLeader = leaseInfo.HolderIdentity
+	ec.lastLeaseInfo = leaseInfo
 
 	// Check if leadership changed
 	leadershipChanged := wasLeader != ec.isLeader
@@ -202,6 +336,8 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 	if leadershipChanged || leaderChanged {
 		ec.lastLeaderChange = time.Now()
 		ec.leadershipChanges++
+		ec.recordFlap(ec.lastLeaderChange)
+		ec.history.add(LeadershipTransition{Time: ec.lastLeaderChange, From: oldLeader, To: ec.currentLeader})
 
 		ec.logger.Info("Leadership state changed",
 			"identity", ec.config.Identity,
@@ -213,6 +349,8 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 
 	// Handle leadership transitions
 	if leadershipChanged {
+		gen := ec.beginTransition()
+
 		if ec.isLeader {
 			ec.logger.Info("Became leader",
 				"identity", ec.config.Identity,
@@ -220,6 +358,7 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 
 			// Call the callback outside of the lock
 			go func() {
+				defer ec.endTransition(gen)
 				if ec.callbacks.OnStartedLeading != nil {
 					ec.callbacks.OnStartedLeading(context.Background())
 				}
@@ -231,6 +370,7 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 
 			// Call the callback outside of the lock
 			go func() {
+				defer ec.endTransition(gen)
 				if ec.callbacks.OnStoppedLeading != nil {
 					ec.callbacks.OnStoppedLeading()
 				}
@@ -240,26 +380,53 @@ func (ec *ElectionController) updateLeadershipState(acquired bool, leaseInfo *Le
 
 	// Handle leader change notifications
 	if leaderChanged && ec.callbacks.OnNewLeader != nil {
+		newLeader := ec.currentLeader
 		go func() {
-			ec.callbacks.OnNewLeader(ec.currentLeader)
+			ec.callbacks.OnNewLeader(newLeader)
 		}()
 	}
 }
 
-// stepDown forces this instance to step down from leadership
-func (ec *ElectionController) stepDown() {
+// stepDown forces this instance to step down from leadership, logging why
+func (ec *ElectionController) stepDown(reason string) {
 	ec.mu.Lock()
 	wasLeader := ec.isLeader
 	ec.isLeader = false
+
+	var gen uint64
+	if wasLeader {
+		gen = ec.beginTransition()
+	}
 	ec.mu.Unlock()
 
 	if wasLeader {
-		ec.logger.Warn("Stepping down from leadership due to lease renewal failure",
-			"identity", ec.config.Identity)
+		ec.logger.Warn("Stepping down from leadership",
+			"identity", ec.config.Identity,
+			"reason", reason)
 
-		if ec.callbacks.OnStoppedLeading != nil {
-			go ec.callbacks.OnStoppedLeading()
-		}
+		go func() {
+			defer ec.endTransition(gen)
+			if ec.callbacks.OnStoppedLeading != nil {
+				ec.callbacks.OnStoppedLeading()
+			}
+		}()
+	}
+}
+
+// beginTransition marks a leadership change as in progress; the caller must hold ec.mu
+func (ec *ElectionController) beginTransition() uint64 {
+	ec.transitioning = true
+	ec.transitionGen++
+	return ec.transitionGen
+}
+
+// endTransition clears the transition state unless a newer change has started since gen
+func (ec *ElectionController) endTransition(gen uint64) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if ec.transitionGen == gen {
+		ec.transitioning = false
 	}
 }
 
@@ -273,15 +440,7 @@ func (ec *ElectionController) releaseLeadershipOnExit(ctx context.Context) {
 	if wasLeader {
 		ec.logger.Info("Releasing leadership on exit", "identity", ec.config.Identity)
 
-		// Create a timeout context for lease release
-		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		if err := ec.leaseManager.ReleaseLease(releaseCtx); err != nil {
-			ec.logger.Error("Failed to release lease on exit",
-				"identity", ec.config.Identity,
-				"error", err)
-		}
+		ec.releaseLease()
 
 		if ec.callbacks.OnStoppedLeading != nil {
 			ec.callbacks.OnStoppedLeading()
@@ -289,6 +448,45 @@ func (ec *ElectionController) releaseLeadershipOnExit(ctx context.Context) {
 	}
 }
 
+// releaseLease releases the lease, retrying once, so followers can take over without waiting
+// for the lease to expire. It reports whether the release succeeded.
+func (ec *ElectionController) releaseLease() bool {
+	timeout := ec.config.ReleaseTimeout
+	if timeout <= 0 {
+		timeout = defaultReleaseTimeout
+	}
+
+	var err error
+	for attempt := 1; attempt <= releaseAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(ec.releaseBackoff)
+		}
+
+		releaseCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = ec.leaseManager.ReleaseLease(releaseCtx)
+		cancel()
+
+		if err == nil {
+			ec.logger.Info("Lease released",
+				"identity", ec.config.Identity,
+				"attempts", attempt)
+			return true
+		}
+
+		ec.logger.Warn("Lease release attempt failed",
+			"identity", ec.config.Identity,
+			"attempt", attempt,
+			"timeout", timeout,
+			"error", err)
+	}
+
+	ec.logger.Error("Failed to release lease - followers must wait for it to expire",
+		"identity", ec.config.Identity,
+		"leaseDuration", ec.config.LeaseDuration,
+		"error", err)
+	return false
+}
+
 // ElectionMetrics contains metrics about the election process
 type ElectionMetrics struct {
 	IsLeader          bool
@@ -297,4 +495,5 @@ type ElectionMetrics struct {
 	AcquisitionErrors int64
 	RenewalErrors     int64
 	LastLeaderChange  time.Time
+	History           []LeadershipTransition
 }
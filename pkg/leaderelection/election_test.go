@@ -0,0 +1,563 @@
+package leaderelection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestElectionControllerLastLeaseInfo(t *testing.T) {
+	ec := &ElectionController{
+		config: DefaultLeaseConfig(),
+		logger: slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	if ec.GetLastLeaseInfo() != nil {
+		t.Fatal("Expected no lease info before the first observation")
+	}
+
+	renewTime := time.Now().Add(-3 * time.Second)
+	ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: "other-pod", RenewTime: renewTime})
+
+	info := ec.GetLastLeaseInfo()
+	if info == nil {
+		t.Fatal("Expected lease info after an observation")
+	}
+
+	if info.HolderIdentity != "other-pod" || !info.RenewTime.Equal(renewTime) {
+		t.Errorf("Unexpected lease info %+v", info)
+	}
+
+	// The returned value is a copy
+	info.HolderIdentity = "mutated"
+	if ec.GetLastLeaseInfo().HolderIdentity != "other-pod" {
+		t.Error("Expected GetLastLeaseInfo() to return a copy")
+	}
+}
+
+func TestElectionControllerRBACError(t *testing.T) {
+	ec := &ElectionController{
+		config: DefaultLeaseConfig(),
+		logger: slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	ec.recordLeaseError(fmt.Errorf("%w: failed to get lease", ErrLeaseRBAC))
+	if !errors.Is(ec.RBACError(), ErrLeaseRBAC) {
+		t.Fatalf("RBACError() = %v, want ErrLeaseRBAC", ec.RBACError())
+	}
+
+	// Other failures are not reported as permission problems
+	ec.recordLeaseError(errors.New("connection refused"))
+	if ec.RBACError() != nil {
+		t.Errorf("RBACError() = %v after non-RBAC failure, want nil", ec.RBACError())
+	}
+
+	ec.recordLeaseError(fmt.Errorf("%w: failed to get lease", ErrLeaseRBAC))
+	ec.recordLeaseError(nil)
+	if ec.RBACError() != nil {
+		t.Errorf("RBACError() = %v after success, want nil", ec.RBACError())
+	}
+}
+
+// callbackRecorder records election callbacks, which the controller invokes asynchronously
+type callbackRecorder struct {
+	events chan string
+}
+
+func newCallbackRecorder() *callbackRecorder {
+	return &callbackRecorder{events: make(chan string, 16)}
+}
+
+func (r *callbackRecorder) callbacks() LeaderElectionCallbacks {
+	return LeaderElectionCallbacks{
+		OnStartedLeading: func(ctx context.Context) { r.events <- "started" },
+		OnStoppedLeading: func() { r.events <- "stopped" },
+		OnNewLeader:      func(identity string) { r.events <- "leader:" + identity },
+	}
+}
+
+// expect waits for the given events in any order and fails on anything else
+func (r *callbackRecorder) expect(t *testing.T, want ...string) {
+	t.Helper()
+
+	remaining := make(map[string]int)
+	for _, event := range want {
+		remaining[event]++
+	}
+
+	for range want {
+		select {
+		case event := <-r.events:
+			if remaining[event] == 0 {
+				t.Fatalf("unexpected callback %q, want %v", event, want)
+			}
+			remaining[event]--
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for callbacks %v", want)
+		}
+	}
+
+	r.expectNone(t)
+}
+
+// expectNone fails if any callback fires shortly after
+func (r *callbackRecorder) expectNone(t *testing.T) {
+	t.Helper()
+
+	select {
+	case event := <-r.events:
+		t.Fatalf("unexpected callback %q", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func newTestController(identity string, lock LockBackend, recorder *callbackRecorder) *ElectionController {
+	config := DefaultLeaseConfig()
+	config.Identity = identity
+
+	return NewElectionControllerWithLock(config, lock, recorder.callbacks(), slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+func TestElectionControllerUpdateLeadershipState(t *testing.T) {
+	tests := []struct {
+		name          string
+		wasLeader     bool
+		oldLeader     string
+		acquired      bool
+		holder        string
+		wantEvents    []string
+		wantChanges   int64
+		wantIsLeader  bool
+		wantNewLeader string
+	}{
+		{
+			name:          "become leader",
+			acquired:      true,
+			holder:        "pod-a",
+			wantEvents:    []string{"started", "leader:pod-a"},
+			wantChanges:   1,
+			wantIsLeader:  true,
+			wantNewLeader: "pod-a",
+		},
+		{
+			name:          "lose leadership",
+			wasLeader:     true,
+			oldLeader:     "pod-a",
+			acquired:      false,
+			holder:        "pod-b",
+			wantEvents:    []string{"stopped", "leader:pod-b"},
+			wantChanges:   1,
+			wantNewLeader: "pod-b",
+		},
+		{
+			name:          "observe another leader",
+			oldLeader:     "pod-b",
+			acquired:      false,
+			holder:        "pod-c",
+			wantEvents:    []string{"leader:pod-c"},
+			wantChanges:   1,
+			wantNewLeader: "pod-c",
+		},
+		{
+			name:          "renew as leader",
+			wasLeader:     true,
+			oldLeader:     "pod-a",
+			acquired:      true,
+			holder:        "pod-a",
+			wantIsLeader:  true,
+			wantNewLeader: "pod-a",
+		},
+		{
+			name:          "unchanged follower",
+			oldLeader:     "pod-b",
+			acquired:      false,
+			holder:        "pod-b",
+			wantNewLeader: "pod-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := newCallbackRecorder()
+			ec := newTestController("pod-a", nil, recorder)
+			ec.isLeader = tt.wasLeader
+			ec.currentLeader = tt.oldLeader
+
+			ec.updateLeadershipState(tt.acquired, &LeaseInfo{HolderIdentity: tt.holder})
+
+			recorder.expect(t, tt.wantEvents...)
+
+			metrics := ec.GetMetrics()
+			if metrics.IsLeader != tt.wantIsLeader || metrics.CurrentLeader != tt.wantNewLeader {
+				t.Errorf("state = leader %v (%q), want %v (%q)",
+					metrics.IsLeader, metrics.CurrentLeader, tt.wantIsLeader, tt.wantNewLeader)
+			}
+
+			if metrics.LeadershipChanges != tt.wantChanges {
+				t.Errorf("LeadershipChanges = %d, want %d", metrics.LeadershipChanges, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestElectionControllerStepDown(t *testing.T) {
+	recorder := newCallbackRecorder()
+	ec := newTestController("pod-a", nil, recorder)
+
+	var logs bytes.Buffer
+	ec.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	// Stepping down as a follower is a no-op
+	ec.stepDown("lease renewal failure")
+	recorder.expectNone(t)
+
+	ec.isLeader = true
+	ec.stepDown("leadership is flapping, observing")
+	recorder.expect(t, "stopped")
+
+	if ec.IsLeader() {
+		t.Error("Expected IsLeader() to be false after stepping down")
+	}
+
+	if got := logs.String(); !strings.Contains(got, `reason="leadership is flapping, observing"`) || strings.Contains(got, "renewal") {
+		t.Errorf("step-down log = %q, want only the flapping reason", got)
+	}
+}
+
+func TestElectionControllerWithFakeLock(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeLockStore(15 * time.Second)
+
+	recorderA, recorderB := newCallbackRecorder(), newCallbackRecorder()
+	podA := newTestController("pod-a", store.lockFor("pod-a"), recorderA)
+	podB := newTestController("pod-b", store.lockFor("pod-b"), recorderB)
+
+	// The first candidate acquires the free lease
+	podA.tryAcquireLease(ctx)
+	recorderA.expect(t, "started", "leader:pod-a")
+
+	// Contention: the second candidate sees the holder and stays a follower
+	podB.tryAcquireLease(ctx)
+	recorderB.expect(t, "leader:pod-a")
+	if podB.IsLeader() {
+		t.Fatal("pod-b must not lead while pod-a holds a valid lease")
+	}
+
+	// Renewal keeps pod-a leading without new callbacks
+	store.advance(10 * time.Second)
+	podA.tryAcquireLease(ctx)
+	recorderA.expectNone(t)
+
+	// pod-a stops renewing; once the lease expires pod-b takes over
+	store.advance(10 * time.Second)
+	podB.tryAcquireLease(ctx)
+	recorderB.expectNone(t)
+
+	store.advance(10 * time.Second)
+	podB.tryAcquireLease(ctx)
+	recorderB.expect(t, "started", "leader:pod-b")
+
+	if info := podB.GetLastLeaseInfo(); info.LeaseTransitions != 1 {
+		t.Errorf("LeaseTransitions = %d, want 1", info.LeaseTransitions)
+	}
+
+	// pod-a notices it lost the lease
+	podA.tryAcquireLease(ctx)
+	recorderA.expect(t, "stopped", "leader:pod-b")
+
+	// A lock failure makes the leader step down
+	store.setError(errors.New("connection refused"))
+	podB.tryAcquireLease(ctx)
+	recorderB.expect(t, "stopped")
+	if podB.IsLeader() {
+		t.Error("Expected pod-b to step down after a lock failure")
+	}
+}
+
+// transitionLock runs duringAcquire while the acquisition is in flight, then fails it
+type transitionLock struct {
+	*FakeLock
+	duringAcquire func()
+}
+
+func (l *transitionLock) AcquireLease(ctx context.Context) (bool, *LeaseInfo, error) {
+	l.duringAcquire()
+	return false, nil, errors.New("connection refused")
+}
+
+func TestElectionControllerLeaseErrorClassification(t *testing.T) {
+	tests := []struct {
+		name                  string
+		wasLeader             bool
+		leaderDuringAttempt   bool
+		wantAcquisitionErrors int64
+		wantRenewalErrors     int64
+	}{
+		{name: "follower", wantAcquisitionErrors: 1},
+		{name: "leader", wasLeader: true, leaderDuringAttempt: true, wantRenewalErrors: 1},
+		{name: "leader stepped down during renewal", wasLeader: true, wantRenewalErrors: 1},
+		{name: "follower became leader during acquisition", leaderDuringAttempt: true, wantAcquisitionErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock := &transitionLock{FakeLock: newFakeLockStore(15 * time.Second).lockFor("pod-a")}
+			ec := newTestController("pod-a", lock, newCallbackRecorder())
+			ec.isLeader = tt.wasLeader
+			lock.duringAcquire = func() {
+				ec.mu.Lock()
+				ec.isLeader = tt.leaderDuringAttempt
+				ec.mu.Unlock()
+			}
+
+			ec.tryAcquireLease(context.Background())
+
+			metrics := ec.GetMetrics()
+			if metrics.AcquisitionErrors != tt.wantAcquisitionErrors || metrics.RenewalErrors != tt.wantRenewalErrors {
+				t.Errorf("AcquisitionErrors = %d, RenewalErrors = %d, want %d, %d",
+					metrics.AcquisitionErrors, metrics.RenewalErrors, tt.wantAcquisitionErrors, tt.wantRenewalErrors)
+			}
+		})
+	}
+}
+
+// flakyReleaseLock fails the first releaseFailures release attempts
+type flakyReleaseLock struct {
+	*FakeLock
+	releaseFailures int
+	releaseCalls    int
+}
+
+func (l *flakyReleaseLock) ReleaseLease(ctx context.Context) error {
+	l.releaseCalls++
+	if l.releaseCalls <= l.releaseFailures {
+		return errors.New("context deadline exceeded")
+	}
+	return l.FakeLock.ReleaseLease(ctx)
+}
+
+func TestElectionControllerReleaseRetry(t *testing.T) {
+	tests := []struct {
+		name            string
+		releaseFailures int
+		wantReleased    bool
+		wantCalls       int
+	}{
+		{name: "first attempt succeeds", releaseFailures: 0, wantReleased: true, wantCalls: 1},
+		{name: "retry succeeds", releaseFailures: 1, wantReleased: true, wantCalls: 2},
+		{name: "both attempts fail", releaseFailures: 2, wantReleased: false, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeLockStore(15 * time.Second)
+			lock := &flakyReleaseLock{FakeLock: store.lockFor("pod-a"), releaseFailures: tt.releaseFailures}
+
+			recorder := newCallbackRecorder()
+			ec := newTestController("pod-a", lock, recorder)
+			ec.config.ReleaseTimeout = time.Second
+			ec.releaseBackoff = 0
+
+			ec.tryAcquireLease(context.Background())
+			recorder.expect(t, "started", "leader:pod-a")
+
+			if released := ec.releaseLease(); released != tt.wantReleased {
+				t.Errorf("releaseLease() = %v, want %v", released, tt.wantReleased)
+			}
+			if lock.releaseCalls != tt.wantCalls {
+				t.Errorf("release attempts = %d, want %d", lock.releaseCalls, tt.wantCalls)
+			}
+
+			wantHolder := "pod-a"
+			if tt.wantReleased {
+				wantHolder = ""
+			}
+			if store.holder != wantHolder {
+				t.Errorf("holder = %q, want %q", store.holder, wantHolder)
+			}
+		})
+	}
+}
+
+func TestElectionControllerFlapDetection(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        FlapAction
+		wantFatal     bool
+		wantObserving bool
+	}{
+		{name: "alert", action: FlapActionAlert},
+		{name: "exit", action: FlapActionExit, wantFatal: true},
+		{name: "observe", action: FlapActionObserve, wantObserving: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeLockStore(15 * time.Second)
+			ec := newTestController("pod-a", store.lockFor("pod-a"), newCallbackRecorder())
+			ec.config.MaxFlaps = 2
+			ec.config.FlapWindow = time.Minute
+			ec.config.FlapAction = tt.action
+
+			// Two changes stay within the limit
+			ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: "pod-b"})
+			ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: "pod-c"})
+			if ec.flapping {
+				t.Fatal("flapping detected at the limit")
+			}
+
+			ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: "pod-b"})
+			if !ec.flapping {
+				t.Fatal("flapping not detected above the limit")
+			}
+			if got := testutil.ToFloat64(leadershipFlapping); got != 1 {
+				t.Errorf("kms_leadership_flapping = %v, want 1", got)
+			}
+
+			select {
+			case err := <-ec.Fatal():
+				if !tt.wantFatal {
+					t.Errorf("unexpected fatal error: %v", err)
+				} else if !errors.Is(err, ErrLeadershipFlapping) {
+					t.Errorf("fatal error = %v, want ErrLeadershipFlapping", err)
+				}
+			default:
+				if tt.wantFatal {
+					t.Error("expected a fatal error")
+				}
+			}
+
+			if observing := ec.observing(time.Now()); observing != tt.wantObserving {
+				t.Errorf("observing = %v, want %v", observing, tt.wantObserving)
+			}
+
+			// An observing instance does not take the free lease
+			ec.tryAcquireLease(context.Background())
+			if ec.IsLeader() == tt.wantObserving {
+				t.Errorf("IsLeader() = %v while observing = %v", ec.IsLeader(), tt.wantObserving)
+			}
+
+			// Changes age out of the window
+			ec.mu.Lock()
+			ec.updateFlapState(time.Now().Add(2 * time.Minute))
+			flapping := ec.flapping
+			ec.mu.Unlock()
+			if flapping {
+				t.Error("flapping not cleared once changes left the window")
+			}
+		})
+	}
+}
+
+func TestElectionControllerLeadershipHistory(t *testing.T) {
+	config := DefaultLeaseConfig()
+	config.Identity = "pod-a"
+	config.HistorySize = 3
+	ec := NewElectionControllerWithLock(config, nil, LeaderElectionCallbacks{}, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	if history := ec.GetMetrics().History; len(history) != 0 {
+		t.Fatalf("history = %v, want empty", history)
+	}
+
+	for _, holder := range []string{"pod-b", "pod-c", "pod-b", "pod-d"} {
+		ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: holder})
+	}
+	// Renewals by the same holder are not transitions
+	ec.updateLeadershipState(false, &LeaseInfo{HolderIdentity: "pod-d"})
+
+	history := ec.GetMetrics().History
+	want := [][2]string{{"pod-b", "pod-c"}, {"pod-c", "pod-b"}, {"pod-b", "pod-d"}}
+	if len(history) != len(want) {
+		t.Fatalf("history has %d entries, want %d: %v", len(history), len(want), history)
+	}
+
+	for i, w := range want {
+		if history[i].From != w[0] || history[i].To != w[1] || history[i].Time.IsZero() {
+			t.Errorf("history[%d] = %+v, want %s -> %s", i, history[i], w[0], w[1])
+		}
+	}
+	if history[0].Time.After(history[2].Time) {
+		t.Error("history is not ordered oldest first")
+	}
+}
+
+func TestElectionControllerIdentityCollision(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    CollisionAction
+		wantFatal bool
+		wantLog   string
+	}{
+		{name: "decline", action: CollisionActionDecline, wantLog: "declining leadership"},
+		{name: "exit", action: CollisionActionExit, wantFatal: true, wantLog: "exiting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeLockStore(15 * time.Second)
+			ec := newTestController("pod-a", store.lockFor("pod-a"), newCallbackRecorder())
+			ec.config.CollisionAction = tt.action
+			before := testutil.ToFloat64(identityCollisions)
+
+			var logs bytes.Buffer
+			ec.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+			store.setError(fmt.Errorf("%w: test", ErrIdentityCollision))
+			ec.tryAcquireLease(context.Background())
+			ec.tryAcquireLease(context.Background())
+
+			if ec.IsLeader() {
+				t.Error("became leader despite the identity collision")
+			}
+			if got := testutil.ToFloat64(identityCollisions) - before; got != 1 {
+				t.Errorf("kms_identity_collision_suspected_total increased by %v, want 1", got)
+			}
+			if got := ec.GetMetrics().AcquisitionErrors; got != 0 {
+				t.Errorf("acquisitionErrors = %v, want 0", got)
+			}
+			if got := logs.String(); !strings.Contains(got, "share this leader identity, "+tt.wantLog) {
+				t.Errorf("collision log = %q, want it to say %q", got, tt.wantLog)
+			}
+
+			select {
+			case err := <-ec.Fatal():
+				if !tt.wantFatal {
+					t.Errorf("unexpected fatal error: %v", err)
+				} else if !errors.Is(err, ErrIdentityCollision) {
+					t.Errorf("fatal error = %v, want ErrIdentityCollision", err)
+				}
+			default:
+				if tt.wantFatal {
+					t.Error("expected a fatal error")
+				}
+			}
+
+			// Once the other holder is gone, the lease is acquired again
+			store.setError(nil)
+			ec.tryAcquireLease(context.Background())
+			if !ec.IsLeader() {
+				t.Error("not leader after the collision cleared")
+			}
+		})
+	}
+}
+
+func TestParseCollisionAction(t *testing.T) {
+	for _, value := range []string{"decline", "exit"} {
+		if action, err := ParseCollisionAction(value); err != nil || string(action) != value {
+			t.Errorf("ParseCollisionAction(%q) = %q, %v", value, action, err)
+		}
+	}
+
+	if _, err := ParseCollisionAction("ignore"); err == nil {
+		t.Error("ParseCollisionAction(\"ignore\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,112 @@
+package leaderelection
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fakeLockStore is an in-memory lease shared by the FakeLocks of competing candidates.
+// Time only moves when advance is called, so expiry is deterministic.
+type fakeLockStore struct {
+	mu            sync.Mutex
+	now           time.Time
+	leaseDuration time.Duration
+	holder        string
+	acquireTime   time.Time
+	renewTime     time.Time
+	transitions   int32
+	err           error
+}
+
+// newFakeLockStore creates an empty in-memory lease with the given duration
+func newFakeLockStore(leaseDuration time.Duration) *fakeLockStore {
+	return &fakeLockStore{
+		now:           time.Unix(1700000000, 0),
+		leaseDuration: leaseDuration,
+	}
+}
+
+// advance moves the fake clock forward
+func (s *fakeLockStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+// setError makes every lock call fail with err until it is cleared with nil
+func (s *fakeLockStore) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// lockFor returns the FakeLock used by the candidate with the given identity
+func (s *fakeLockStore) lockFor(identity string) *FakeLock {
+	return &FakeLock{store: s, identity: identity}
+}
+
+// FakeLock is an in-memory LockBackend simulating acquisition, expiry and contention
+type FakeLock struct {
+	store    *fakeLockStore
+	identity string
+}
+
+// AcquireLease takes the lease if it is free, expired or already held by this candidate
+func (f *FakeLock) AcquireLease(ctx context.Context) (bool, *LeaseInfo, error) {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return false, nil, s.err
+	}
+
+	expired := s.now.Sub(s.renewTime) > s.leaseDuration
+	if s.holder != "" && s.holder != f.identity && !expired {
+		return false, f.info(), nil
+	}
+
+	if s.holder != f.identity {
+		if s.holder != "" {
+			s.transitions++
+		}
+		s.holder = f.identity
+		s.acquireTime = s.now
+	}
+	s.renewTime = s.now
+
+	return true, f.info(), nil
+}
+
+// ReleaseLease clears the holder if this candidate holds the lease
+func (f *FakeLock) ReleaseLease(ctx context.Context) error {
+	s := f.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.holder == f.identity {
+		s.holder = ""
+	}
+
+	return nil
+}
+
+// info returns the lease state as seen by this candidate; the store lock must be held
+func (f *FakeLock) info() *LeaseInfo {
+	s := f.store
+	return &LeaseInfo{
+		Name:             "fake-lease",
+		Namespace:        "default",
+		HolderIdentity:   s.holder,
+		IsLeader:         s.holder == f.identity,
+		AcquireTime:      s.acquireTime,
+		RenewTime:        s.renewTime,
+		LeaseTransitions: s.transitions,
+		LeaseDuration:    s.leaseDuration,
+	}
+}
@@ -0,0 +1,135 @@
+package leaderelection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FlapAction is what the controller does once leadership changes too often
+type FlapAction string
+
+const (
+	// FlapActionAlert only logs a critical alert
+	FlapActionAlert FlapAction = "alert"
+	// FlapActionExit reports a fatal error so the process exits and is rescheduled
+	FlapActionExit FlapAction = "exit"
+	// FlapActionObserve stops competing for leadership for one flap window
+	FlapActionObserve FlapAction = "observe"
+)
+
+// DefaultFlapWindow is the sliding window over which leadership changes are counted
+const DefaultFlapWindow = 5 * time.Minute
+
+// ErrLeadershipFlapping is reported through Fatal when flapping triggers FlapActionExit
+var ErrLeadershipFlapping = errors.New("leadership is flapping")
+
+// ParseFlapAction parses a flap action name
+func ParseFlapAction(value string) (FlapAction, error) {
+	switch action := FlapAction(value); action {
+	case FlapActionAlert, FlapActionExit, FlapActionObserve:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown flap action %q (expected alert, exit or observe)", value)
+	}
+}
+
+// Fatal returns a channel that receives an error when flapping triggers FlapActionExit or an
+// identity collision triggers CollisionActionExit
+func (ec *ElectionController) Fatal() <-chan error {
+	return ec.fatal
+}
+
+// flapWindow returns the configured flap window or its default
+func (ec *ElectionController) flapWindow() time.Duration {
+	if ec.config.FlapWindow > 0 {
+		return ec.config.FlapWindow
+	}
+
+	return DefaultFlapWindow
+}
+
+// recordFlap records a leadership change; the caller must hold ec.mu
+func (ec *ElectionController) recordFlap(now time.Time) {
+	if ec.config.MaxFlaps <= 0 {
+		return
+	}
+
+	ec.flapTimes = append(ec.flapTimes, now)
+	ec.updateFlapState(now)
+}
+
+// updateFlapState drops changes outside the window, updates the flap metrics and acts when
+// the threshold is first exceeded; the caller must hold ec.mu
+func (ec *ElectionController) updateFlapState(now time.Time) {
+	if ec.config.MaxFlaps <= 0 {
+		return
+	}
+
+	window := ec.flapWindow()
+	cutoff := now.Add(-window)
+
+	kept := ec.flapTimes[:0]
+	for _, t := range ec.flapTimes {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	ec.flapTimes = kept
+
+	leadershipFlapRate.Set(float64(len(ec.flapTimes)) / window.Minutes())
+
+	flapping := len(ec.flapTimes) > ec.config.MaxFlaps
+	if flapping {
+		leadershipFlapping.Set(1)
+	} else {
+		leadershipFlapping.Set(0)
+	}
+
+	switch {
+	case flapping && !ec.flapping:
+		ec.flapping = true
+		ec.onFlapping(now, len(ec.flapTimes), window)
+
+	case !flapping && ec.flapping:
+		ec.flapping = false
+		ec.logger.Info("Leadership flapping subsided",
+			"identity", ec.config.Identity,
+			"changes", len(ec.flapTimes),
+			"window", window)
+	}
+}
+
+// onFlapping raises the flapping alert and applies the configured action; the caller must hold ec.mu
+func (ec *ElectionController) onFlapping(now time.Time, changes int, window time.Duration) {
+	action := ec.config.FlapAction
+	if action == "" {
+		action = FlapActionAlert
+	}
+
+	ec.logger.Error("CRITICAL: leadership is flapping - check for clock skew, API server throttling or network partitions",
+		"identity", ec.config.Identity,
+		"changes", changes,
+		"window", window,
+		"maxFlaps", ec.config.MaxFlaps,
+		"action", action)
+
+	switch action {
+	case FlapActionExit:
+		select {
+		case ec.fatal <- fmt.Errorf("%w: %d leadership changes within %s (limit %d)",
+			ErrLeadershipFlapping, changes, window, ec.config.MaxFlaps):
+		default:
+		}
+
+	case FlapActionObserve:
+		ec.observeUntil = now.Add(window)
+	}
+}
+
+// observing reports whether the controller has stopped competing for leadership
+func (ec *ElectionController) observing(now time.Time) bool {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return now.Before(ec.observeUntil)
+}
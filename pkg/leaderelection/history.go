@@ -0,0 +1,53 @@
+package leaderelection
+
+import "time"
+
+// DefaultHistorySize is how many leadership transitions are kept when HistorySize is unset
+const DefaultHistorySize = 20
+
+// LeadershipTransition is one observed change of leader
+type LeadershipTransition struct {
+	Time time.Time `json:"time"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+// leadershipHistory is a fixed-size ring of the most recent leadership transitions. The zero
+// value holds DefaultHistorySize entries.
+type leadershipHistory struct {
+	entries []LeadershipTransition
+	next    int
+	full    bool
+}
+
+func newLeadershipHistory(size int) leadershipHistory {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+
+	return leadershipHistory{entries: make([]LeadershipTransition, size)}
+}
+
+// add records a transition, overwriting the oldest once the ring is full
+func (h *leadershipHistory) add(transition LeadershipTransition) {
+	if h.entries == nil {
+		h.entries = make([]LeadershipTransition, DefaultHistorySize)
+	}
+
+	h.entries[h.next] = transition
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns a copy of the recorded transitions, oldest first
+func (h *leadershipHistory) list() []LeadershipTransition {
+	if !h.full {
+		return append([]LeadershipTransition{}, h.entries[:h.next]...)
+	}
+
+	transitions := make([]LeadershipTransition, 0, len(h.entries))
+	transitions = append(transitions, h.entries[h.next:]...)
+	return append(transitions, h.entries[:h.next]...)
+}
@@ -0,0 +1,92 @@
+package leaderelection
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TransitionRetryDelay is the retry hint returned while leadership is changing
+const TransitionRetryDelay = 500 * time.Millisecond
+
+// RequireLeaderInterceptor returns a unary interceptor that rejects requests with
+// Unavailable while the controller does not hold leadership or leadership is changing.
+// With hideLeaderIdentity, the current leader is left out of the error.
+func RequireLeaderInterceptor(controller *ElectionController, hideLeaderIdentity bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if controller.InTransition() {
+			return nil, TransitionError()
+		}
+
+		if !controller.IsLeader() {
+			if hideLeaderIdentity {
+				return nil, NotLeaderErrorWithoutIdentity(controller.GetCurrentLeader(), controller.RetryPeriod(), false)
+			}
+			return nil, NotLeaderError(controller.GetCurrentLeader(), controller.RetryPeriod())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// TransitionError builds the Unavailable error returned while leadership is changing
+func TransitionError() error {
+	return withRetryInfo(status.New(codes.Unavailable, "Leadership transition in progress - service unavailable"), TransitionRetryDelay)
+}
+
+// NotLeaderError builds the Unavailable error returned by non-leaders. It carries a
+// RetryInfo detail so clients know when trying again may succeed.
+func NotLeaderError(currentLeader string, retryDelay time.Duration) error {
+	var st *status.Status
+	if currentLeader == "" {
+		st = status.New(codes.Unavailable, "No leader elected - service unavailable")
+	} else {
+		st = status.Newf(codes.Unavailable, "Not the leader - current leader is %s", currentLeader)
+	}
+
+	return withRetryInfo(st, retryDelay)
+}
+
+// LeaderlessError builds the FailedPrecondition error returned once no leader has been
+// elected for too long, so clients fail fast instead of retrying indefinitely
+func LeaderlessError(leaderlessFor time.Duration) error {
+	return status.Errorf(codes.FailedPrecondition, "No leader elected for %s - failing fast", leaderlessFor.Truncate(time.Second))
+}
+
+// NotLeaderErrorWithoutIdentity is like NotLeaderError but keeps the leader identity out of
+// the message. With includeDetails, the identity is attached as an ErrorInfo detail instead.
+func NotLeaderErrorWithoutIdentity(currentLeader string, retryDelay time.Duration, includeDetails bool) error {
+	st := status.New(codes.Unavailable, "Not the leader - service unavailable")
+
+	if includeDetails && currentLeader != "" {
+		withInfo, err := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   "NOT_LEADER",
+			Domain:   "talos-kms-vault",
+			Metadata: map[string]string{"leader": currentLeader},
+		})
+		if err == nil {
+			st = withInfo
+		}
+	}
+
+	return withRetryInfo(st, retryDelay)
+}
+
+// withRetryInfo attaches a RetryInfo detail to st when retryDelay is positive
+func withRetryInfo(st *status.Status, retryDelay time.Duration) error {
+	if retryDelay <= 0 {
+		return st.Err()
+	}
+
+	withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withRetry.Err()
+}
@@ -0,0 +1,110 @@
+package leaderelection
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequireLeaderInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		isLeader      bool
+		transitioning bool
+		leader        string
+		hideLeader    bool
+		wantCode      codes.Code
+		wantMessage   string
+		wantDelay     time.Duration
+	}{
+		{
+			name:     "leader passes through",
+			isLeader: true,
+			leader:   "pod-a",
+			wantCode: codes.OK,
+		},
+		{
+			name:        "follower rejected with current leader",
+			leader:      "pod-b",
+			wantCode:    codes.Unavailable,
+			wantMessage: "Not the leader - current leader is pod-b",
+			wantDelay:   2 * time.Second,
+		},
+		{
+			name:        "follower hides current leader",
+			leader:      "pod-b",
+			hideLeader:  true,
+			wantCode:    codes.Unavailable,
+			wantMessage: "Not the leader - service unavailable",
+			wantDelay:   2 * time.Second,
+		},
+		{
+			name:        "no leader elected",
+			wantCode:    codes.Unavailable,
+			wantMessage: "No leader elected - service unavailable",
+			wantDelay:   2 * time.Second,
+		},
+		{
+			name:          "leadership transition in progress",
+			isLeader:      true,
+			transitioning: true,
+			leader:        "pod-a",
+			wantCode:      codes.Unavailable,
+			wantMessage:   "Leadership transition in progress - service unavailable",
+			wantDelay:     TransitionRetryDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultLeaseConfig()
+			ec := NewElectionControllerWithLock(config, nil, LeaderElectionCallbacks{}, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			ec.isLeader = tt.isLeader
+			ec.currentLeader = tt.leader
+			ec.transitioning = tt.transitioning
+
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return "ok", nil
+			}
+
+			_, err := RequireLeaderInterceptor(ec, tt.hideLeader)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}, handler)
+
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Fatalf("code = %v, want %v", st.Code(), tt.wantCode)
+			}
+			if wantCalled := tt.wantCode == codes.OK; called != wantCalled {
+				t.Errorf("handler called = %v, want %v", called, wantCalled)
+			}
+			if tt.wantCode == codes.OK {
+				return
+			}
+
+			if st.Message() != tt.wantMessage {
+				t.Errorf("message = %q, want %q", st.Message(), tt.wantMessage)
+			}
+
+			var retryInfo *errdetails.RetryInfo
+			for _, detail := range st.Details() {
+				if ri, ok := detail.(*errdetails.RetryInfo); ok {
+					retryInfo = ri
+				}
+			}
+			if retryInfo == nil {
+				t.Fatal("missing RetryInfo detail")
+			}
+			if got := retryInfo.RetryDelay.AsDuration(); got != tt.wantDelay {
+				t.Errorf("retry delay = %v, want %v", got, tt.wantDelay)
+			}
+		})
+	}
+}
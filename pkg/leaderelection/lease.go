@@ -2,15 +2,28 @@ package leaderelection
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+// ErrLeaseRBAC is returned when the Lease API rejects a call as Forbidden or Unauthorized
+var ErrLeaseRBAC = errors.New("lease API access denied, check RBAC for leases.coordination.k8s.io")
+
+// ErrNotInCluster is returned when no kubeconfig is given and the process is not running in Kubernetes
+var ErrNotInCluster = errors.New("not running in a Kubernetes cluster and no kubeconfig given")
+
 // LeaseConfig holds configuration for leader election leases
 type LeaseConfig struct {
 	// Name of the lease resource
@@ -19,60 +32,153 @@ type LeaseConfig struct {
 	Namespace string
 	// Identity of this instance (usually pod name or hostname)
 	Identity string
+	// Kubeconfig is an optional kubeconfig path used instead of the in-cluster config
+	Kubeconfig string
 	// Duration that non-leader candidates will wait to force acquire leadership
 	LeaseDuration time.Duration
 	// Duration that the leader will renew the lease
 	RenewDeadline time.Duration
 	// Duration that the leader will retry renewing the lease
 	RetryPeriod time.Duration
+	// Timeout for each attempt to release the lease on shutdown
+	ReleaseTimeout time.Duration
+	// ReleasedLeaseWait is how long candidates leave a just-released lease alone before
+	// acquiring it, so they don't scramble for it (0 acquires it immediately)
+	ReleasedLeaseWait time.Duration
+	// MaxFlaps is the number of leadership changes tolerated within FlapWindow (0 disables flap detection)
+	MaxFlaps int
+	// FlapWindow is the sliding window leadership changes are counted over (default 5m)
+	FlapWindow time.Duration
+	// FlapAction is taken once MaxFlaps is exceeded (default alert)
+	FlapAction FlapAction
+	// CollisionAction is taken when another process holds the lease with this identity (default decline)
+	CollisionAction CollisionAction
+	// HistorySize is how many recent leadership transitions are kept (default 20)
+	HistorySize int
+	// Labels applied to the lease object
+	Labels map[string]string
+	// Annotations applied to the lease object
+	Annotations map[string]string
+	// OwnerReference set on the lease when it is created (usually the pod's Deployment, see
+	// ResolveWorkloadOwner)
+	OwnerReference *metav1.OwnerReference
 }
 
 // DefaultLeaseConfig returns a default lease configuration
 func DefaultLeaseConfig() *LeaseConfig {
 	return &LeaseConfig{
-		Name:          "talos-kms-leader",
-		Namespace:     "default",
-		Identity:      "", // Must be set by caller
-		LeaseDuration: 15 * time.Second,
-		RenewDeadline: 10 * time.Second,
-		RetryPeriod:   2 * time.Second,
+		Name:           "talos-kms-leader",
+		Namespace:      "default",
+		Identity:       "", // Must be set by caller
+		LeaseDuration:  15 * time.Second,
+		RenewDeadline:  10 * time.Second,
+		RetryPeriod:    2 * time.Second,
+		ReleaseTimeout: defaultReleaseTimeout,
+		FlapWindow:     DefaultFlapWindow,
+		FlapAction:     FlapActionAlert,
+
+		CollisionAction:   CollisionActionDecline,
+		ReleasedLeaseWait: 2 * time.Second,
+	}
+}
+
+// Validate checks the lease identity, that the name and namespace follow the Kubernetes
+// naming rules (RFC 1123 subdomain and label), and the timing invariants client-go also
+// enforces: all durations positive and RetryPeriod < RenewDeadline < LeaseDuration
+func (c *LeaseConfig) Validate() error {
+	if c.Identity == "" {
+		return fmt.Errorf("lease identity cannot be empty")
+	}
+
+	if errs := validation.IsDNS1123Subdomain(c.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid lease name %q: %s", c.Name, strings.Join(errs, "; "))
+	}
+
+	if errs := validation.IsDNS1123Label(c.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid lease namespace %q: %s", c.Namespace, strings.Join(errs, "; "))
+	}
+
+	switch {
+	case c.LeaseDuration <= 0:
+		return fmt.Errorf("lease duration must be positive, got %s", c.LeaseDuration)
+	case c.RenewDeadline <= 0:
+		return fmt.Errorf("renew deadline must be positive, got %s", c.RenewDeadline)
+	case c.RetryPeriod <= 0:
+		return fmt.Errorf("retry period must be positive, got %s", c.RetryPeriod)
+	case c.RenewDeadline >= c.LeaseDuration:
+		return fmt.Errorf("renew deadline (%s) must be less than lease duration (%s)", c.RenewDeadline, c.LeaseDuration)
+	case c.RetryPeriod >= c.RenewDeadline:
+		return fmt.Errorf("retry period (%s) must be less than renew deadline (%s)", c.RetryPeriod, c.RenewDeadline)
+	case c.ReleasedLeaseWait < 0:
+		return fmt.Errorf("released lease wait must not be negative, got %s", c.ReleasedLeaseWait)
+	case c.ReleasedLeaseWait >= c.LeaseDuration:
+		return fmt.Errorf("released lease wait (%s) must be less than lease duration (%s)", c.ReleasedLeaseWait, c.LeaseDuration)
 	}
+
+	return nil
 }
 
 // LeaseManager handles Kubernetes lease operations for leader election
 type LeaseManager struct {
 	config    *LeaseConfig
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
+
+	// observed caches the last lease state seen, so a lease deleted out from under us
+	// can be recreated without resetting its transition count
+	mu       sync.Mutex
+	observed *LeaseInfo
+
+	// renewal is the last lease record seen by canAcquireLease, used to detect clock skew
+	renewal *renewObservation
+
+	// startedAt is when this process started, used to detect identity collisions (zero disables)
+	startedAt time.Time
 }
 
 // NewLeaseManager creates a new lease manager
 func NewLeaseManager(config *LeaseConfig) (*LeaseManager, error) {
-	if config.Identity == "" {
-		return nil, fmt.Errorf("lease identity cannot be empty")
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
-	// Create in-cluster config
-	restConfig, err := rest.InClusterConfig()
+	restConfig, err := KubernetesConfig(config.Kubeconfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create in-cluster config: %w", err)
+		return nil, err
 	}
 
-	// Create clientset
-	clientset, err := kubernetes.NewForConfig(restConfig)
+	return NewLeaseManagerWithConfig(config, restConfig)
+}
+
+// KubernetesConfig loads the given kubeconfig, or the in-cluster config when the path is empty
+func KubernetesConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig %q: %w", kubeconfig, err)
+		}
+		return restConfig, nil
+	}
+
+	restConfig, err := rest.InClusterConfig()
+	if errors.Is(err, rest.ErrNotInCluster) {
+		return nil, ErrNotInCluster
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+		return nil, fmt.Errorf("failed to create in-cluster config: %w", err)
 	}
 
-	return &LeaseManager{
-		config:    config,
-		clientset: clientset,
-	}, nil
+	return restConfig, nil
+}
+
+// InCluster reports whether the process runs in a Kubernetes pod with the in-cluster environment
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
 }
 
 // NewLeaseManagerWithConfig creates a lease manager with custom Kubernetes config
 func NewLeaseManagerWithConfig(config *LeaseConfig, restConfig *rest.Config) (*LeaseManager, error) {
-	if config.Identity == "" {
-		return nil, fmt.Errorf("lease identity cannot be empty")
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(restConfig)
@@ -83,11 +189,13 @@ func NewLeaseManagerWithConfig(config *LeaseConfig, restConfig *rest.Config) (*L
 	return &LeaseManager{
 		config:    config,
 		clientset: clientset,
+		startedAt: processStart,
 	}, nil
 }
 
-// AcquireLease attempts to acquire or renew the leadership lease
-func (lm *LeaseManager) AcquireLease(ctx context.Context) (bool, error) {
+// AcquireLease attempts to acquire or renew the leadership lease.
+// It returns the observed lease state so callers don't need to fetch it again.
+func (lm *LeaseManager) AcquireLease(ctx context.Context) (bool, *LeaseInfo, error) {
 	now := metav1.NewMicroTime(time.Now())
 
 	// Try to get existing lease
@@ -95,20 +203,36 @@ func (lm *LeaseManager) AcquireLease(ctx context.Context) (bool, error) {
 		ctx, lm.config.Name, metav1.GetOptions{})
 
 	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, nil, lm.leaseError("get", err)
+		}
+
 		// Lease doesn't exist, try to create it
 		return lm.createLease(ctx, now)
 	}
 
+	lm.recordObserved(lease)
+
+	if lm.identityCollision(lease, now.Time) {
+		return false, lm.leaseInfoFromLease(lease), fmt.Errorf("%w: lease %s/%s acquired at %s, before this process started",
+			ErrIdentityCollision, lm.config.Namespace, lm.config.Name, lease.Spec.AcquireTime.Time.Format(time.RFC3339))
+	}
+
 	// Check if we can acquire the lease
 	if lm.canAcquireLease(lease, now) {
 		return lm.updateLease(ctx, lease, now)
 	}
 
-	return false, nil
+	return false, lm.leaseInfoFromLease(lease), nil
 }
 
 // createLease creates a new lease with this instance as the leader
-func (lm *LeaseManager) createLease(ctx context.Context, now metav1.MicroTime) (bool, error) {
+func (lm *LeaseManager) createLease(ctx context.Context, now metav1.MicroTime) (bool, *LeaseInfo, error) {
+	transitions, acquireTime, recreated := lm.recreateState(now)
+	if recreated {
+		leaseRecreations.Inc()
+	}
+
 	lease := &coordinationv1.Lease{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      lm.config.Name,
@@ -117,25 +241,66 @@ func (lm *LeaseManager) createLease(ctx context.Context, now metav1.MicroTime) (
 		Spec: coordinationv1.LeaseSpec{
 			HolderIdentity:       &lm.config.Identity,
 			LeaseDurationSeconds: int32Ptr(int32(lm.config.LeaseDuration.Seconds())),
-			AcquireTime:          &now,
+			AcquireTime:          &acquireTime,
 			RenewTime:            &now,
-			LeaseTransitions:     int32Ptr(0),
+			LeaseTransitions:     int32Ptr(transitions),
 		},
 	}
 
-	_, err := lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Create(
+	lm.applyMetadata(&lease.ObjectMeta)
+	if lm.config.OwnerReference != nil {
+		lease.OwnerReferences = []metav1.OwnerReference{*lm.config.OwnerReference}
+	}
+
+	created, err := lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Create(
 		ctx, lease, metav1.CreateOptions{})
 
 	if err != nil {
-		return false, fmt.Errorf("failed to create lease: %w", err)
+		return false, nil, lm.leaseError("create", err)
 	}
 
-	return true, nil
+	lm.recordObserved(created)
+
+	return true, lm.leaseInfoFromLease(created), nil
+}
+
+// recreateState returns the transition count and acquire time for a lease about to be
+// created. When a previously observed lease was deleted, its state is carried over: the
+// count is kept if this instance held it and bumped if leadership moves to this instance.
+func (lm *LeaseManager) recreateState(now metav1.MicroTime) (int32, metav1.MicroTime, bool) {
+	lm.mu.Lock()
+	prev := lm.observed
+	lm.mu.Unlock()
+
+	if prev == nil {
+		return 0, now, false
+	}
+
+	if prev.HolderIdentity == lm.config.Identity && !prev.AcquireTime.IsZero() &&
+		(lm.startedAt.IsZero() || !prev.AcquireTime.Before(lm.startedAt)) {
+		return prev.LeaseTransitions, metav1.NewMicroTime(prev.AcquireTime), true
+	}
+
+	return prev.LeaseTransitions + 1, now, true
+}
+
+// recordObserved caches the state of a lease read from or written to the API
+func (lm *LeaseManager) recordObserved(lease *coordinationv1.Lease) {
+	info := lm.leaseInfoFromLease(lease)
+
+	lm.mu.Lock()
+	lm.observed = info
+	lm.mu.Unlock()
 }
 
 // updateLease updates an existing lease with this instance as the leader
-func (lm *LeaseManager) updateLease(ctx context.Context, lease *coordinationv1.Lease, now metav1.MicroTime) (bool, error) {
-	wasLeader := lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == lm.config.Identity
+func (lm *LeaseManager) updateLease(ctx context.Context, lease *coordinationv1.Lease, now metav1.MicroTime) (bool, *LeaseInfo, error) {
+	// A lease left under our identity by an earlier run of this pod is taken over, not renewed
+	wasLeader := lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == lm.config.Identity &&
+		!lm.acquiredBeforeStart(lease)
+
+	// Keep configured labels and annotations in place
+	lm.applyMetadata(&lease.ObjectMeta)
 
 	// Update lease with our identity
 	lease.Spec.HolderIdentity = &lm.config.Identity
@@ -150,26 +315,70 @@ func (lm *LeaseManager) updateLease(ctx context.Context, lease *coordinationv1.L
 		}
 	}
 
-	_, err := lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Update(
+	updated, err := lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Update(
 		ctx, lease, metav1.UpdateOptions{})
 
+	if apierrors.IsNotFound(err) {
+		// Deleted between our read and write; recreate it from the cached state
+		return lm.createLease(ctx, now)
+	}
+
 	if err != nil {
-		return false, fmt.Errorf("failed to update lease: %w", err)
+		return false, nil, lm.leaseError("update", err)
 	}
 
-	return true, nil
+	lm.recordObserved(updated)
+
+	return true, lm.leaseInfoFromLease(updated), nil
+}
+
+// leaseError wraps a Lease API error, marking permission failures with ErrLeaseRBAC
+func (lm *LeaseManager) leaseError(operation string, err error) error {
+	if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+		leaseRBACErrors.WithLabelValues(operation).Inc()
+		return fmt.Errorf("%w: failed to %s lease %s/%s: %v",
+			ErrLeaseRBAC, operation, lm.config.Namespace, lm.config.Name, err)
+	}
+
+	return fmt.Errorf("failed to %s lease: %w", operation, err)
+}
+
+// applyMetadata merges the configured labels and annotations into the lease metadata
+func (lm *LeaseManager) applyMetadata(meta *metav1.ObjectMeta) {
+	if len(lm.config.Labels) > 0 {
+		if meta.Labels == nil {
+			meta.Labels = make(map[string]string, len(lm.config.Labels))
+		}
+		for k, v := range lm.config.Labels {
+			meta.Labels[k] = v
+		}
+	}
+
+	if len(lm.config.Annotations) > 0 {
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string, len(lm.config.Annotations))
+		}
+		for k, v := range lm.config.Annotations {
+			meta.Annotations[k] = v
+		}
+	}
 }
 
 // canAcquireLease determines if this instance can acquire the lease
 func (lm *LeaseManager) canAcquireLease(lease *coordinationv1.Lease, now metav1.MicroTime) bool {
 	// If we're already the leader, we can always renew
 	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == lm.config.Identity {
+		lm.observeRenewal(lease)
 		return true
 	}
 
-	// If there's no current holder, we can acquire it
+	// If there's no current holder, we can acquire it once the release is not too recent.
+	// Otherwise every candidate polling at the same time jumps on a lease released moments ago.
 	if lease.Spec.HolderIdentity == nil {
-		return true
+		if lease.Spec.RenewTime == nil {
+			return true
+		}
+		return lm.sinceRenewal(lease, now.Time) >= lm.config.ReleasedLeaseWait
 	}
 
 	// Check if the lease has expired
@@ -178,9 +387,8 @@ func (lm *LeaseManager) canAcquireLease(lease *coordinationv1.Lease, now metav1.
 	}
 
 	leaseDuration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
-	expiry := lease.Spec.RenewTime.Add(leaseDuration)
 
-	return now.Time.After(expiry)
+	return lm.sinceRenewal(lease, now.Time) > leaseDuration
 }
 
 // ReleaseLease releases the lease if this instance is the current leader
@@ -197,18 +405,22 @@ func (lm *LeaseManager) ReleaseLease(ctx context.Context) error {
 		return nil // Not our lease to release
 	}
 
-	// Clear the holder identity
+	// Clear the holder identity, recording the release time so candidates can see how
+	// recently the lease was given up
+	releasedAt := metav1.NewMicroTime(time.Now())
 	lease.Spec.HolderIdentity = nil
-	lease.Spec.RenewTime = nil
+	lease.Spec.RenewTime = &releasedAt
 	lease.Spec.AcquireTime = nil
 
-	_, err = lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Update(
+	released, err := lm.clientset.CoordinationV1().Leases(lm.config.Namespace).Update(
 		ctx, lease, metav1.UpdateOptions{})
 
 	if err != nil {
 		return fmt.Errorf("failed to release lease: %w", err)
 	}
 
+	lm.recordObserved(released)
+
 	return nil
 }
 
@@ -221,6 +433,11 @@ func (lm *LeaseManager) GetLeaseInfo(ctx context.Context) (*LeaseInfo, error) {
 		return nil, fmt.Errorf("failed to get lease info: %w", err)
 	}
 
+	return lm.leaseInfoFromLease(lease), nil
+}
+
+// leaseInfoFromLease converts a lease object into LeaseInfo
+func (lm *LeaseManager) leaseInfoFromLease(lease *coordinationv1.Lease) *LeaseInfo {
 	info := &LeaseInfo{
 		Name:      lease.Name,
 		Namespace: lease.Namespace,
@@ -247,7 +464,7 @@ func (lm *LeaseManager) GetLeaseInfo(ctx context.Context) (*LeaseInfo, error) {
 		info.LeaseDuration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
 	}
 
-	return info, nil
+	return info
 }
 
 // LeaseInfo contains information about the current lease state
@@ -1,8 +1,21 @@
 package leaderelection
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestDefaultLeaseConfig(t *testing.T) {
@@ -28,6 +41,10 @@ func TestDefaultLeaseConfig(t *testing.T) {
 		t.Errorf("Expected retry period 2s, got %s", config.RetryPeriod)
 	}
 
+	if config.ReleasedLeaseWait != 2*time.Second {
+		t.Errorf("Expected released lease wait 2s, got %s", config.ReleasedLeaseWait)
+	}
+
 	if config.Identity != "" {
 		t.Errorf("Expected empty identity, got %s", config.Identity)
 	}
@@ -63,19 +80,179 @@ func TestLeaseConfigValidation(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "uppercase lease name",
+			config: &LeaseConfig{
+				Name:          "Test-Lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "lease name with underscore",
+			config: &LeaseConfig{
+				Name:          "test_lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "empty lease name",
+			config: &LeaseConfig{
+				Name:          "",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "empty namespace",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "namespace with dots",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "test.ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "namespace longer than 63 characters",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     strings.Repeat("n", 64),
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "dotted lease name",
+			config: &LeaseConfig{
+				Name:          "talos-kms.leader",
+				Namespace:     "kube-system",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: false,
+		},
+		{
+			name: "zero lease duration",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative retry period",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "renew deadline equal to lease duration",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 10 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "retry period longer than renew deadline",
+			config: &LeaseConfig{
+				Name:          "test-lease",
+				Namespace:     "test-ns",
+				Identity:      "test-identity",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   12 * time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "negative released lease wait",
+			config: &LeaseConfig{
+				Name:              "test-lease",
+				Namespace:         "test-ns",
+				Identity:          "test-identity",
+				LeaseDuration:     15 * time.Second,
+				RenewDeadline:     10 * time.Second,
+				RetryPeriod:       2 * time.Second,
+				ReleasedLeaseWait: -time.Second,
+			},
+			expectError: true,
+		},
+		{
+			name: "released lease wait as long as the lease",
+			config: &LeaseConfig{
+				Name:              "test-lease",
+				Namespace:         "test-ns",
+				Identity:          "test-identity",
+				LeaseDuration:     15 * time.Second,
+				RenewDeadline:     10 * time.Second,
+				RetryPeriod:       2 * time.Second,
+				ReleasedLeaseWait: 15 * time.Second,
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// We can't easily test NewLeaseManager without a real Kubernetes cluster,
-			// so we'll just test the validation logic here
-			if tt.config.Identity == "" {
-				if !tt.expectError {
-					t.Error("Expected validation to pass, but identity is empty")
-				}
-			} else {
-				if tt.expectError {
-					t.Error("Expected validation to fail, but config looks valid")
+			err := tt.config.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation to fail")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected validation to pass, got %v", err)
+			}
+
+			// The constructors reject the same configs before touching Kubernetes
+			if tt.expectError {
+				if _, err := NewLeaseManagerWithConfig(tt.config, &rest.Config{}); err == nil {
+					t.Error("Expected NewLeaseManagerWithConfig to fail")
 				}
 			}
 		})
@@ -94,3 +271,524 @@ func TestInt32Ptr(t *testing.T) {
 		t.Errorf("Expected %d, got %d", val, *ptr)
 	}
 }
+
+func TestLeaseInfoFromLease(t *testing.T) {
+	lm := &LeaseManager{config: &LeaseConfig{Identity: "pod-a"}}
+
+	holder := "pod-a"
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-lease", Namespace: "test-ns"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: int32Ptr(15),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+			LeaseTransitions:     int32Ptr(3),
+		},
+	}
+
+	info := lm.leaseInfoFromLease(lease)
+
+	if info.Name != "test-lease" || info.Namespace != "test-ns" {
+		t.Errorf("Unexpected lease name/namespace: %s/%s", info.Namespace, info.Name)
+	}
+
+	if info.HolderIdentity != "pod-a" || !info.IsLeader {
+		t.Errorf("Expected pod-a to be leader, got holder %q isLeader %v", info.HolderIdentity, info.IsLeader)
+	}
+
+	if info.LeaseTransitions != 3 {
+		t.Errorf("Expected 3 transitions, got %d", info.LeaseTransitions)
+	}
+
+	if info.LeaseDuration != 15*time.Second {
+		t.Errorf("Expected lease duration 15s, got %s", info.LeaseDuration)
+	}
+
+	// A lease released by its holder has no identity
+	lease.Spec.HolderIdentity = nil
+	info = lm.leaseInfoFromLease(lease)
+	if info.HolderIdentity != "" || info.IsLeader {
+		t.Errorf("Expected no holder, got %q isLeader %v", info.HolderIdentity, info.IsLeader)
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	lm := &LeaseManager{config: &LeaseConfig{
+		Identity:    "pod-a",
+		Labels:      map[string]string{"owner": "platform"},
+		Annotations: map[string]string{"note": "kms"},
+	}}
+
+	meta := &metav1.ObjectMeta{
+		Labels: map[string]string{"existing": "label", "owner": "someone-else"},
+	}
+
+	lm.applyMetadata(meta)
+
+	if meta.Labels["owner"] != "platform" {
+		t.Errorf("Expected configured label to win, got %q", meta.Labels["owner"])
+	}
+
+	if meta.Labels["existing"] != "label" {
+		t.Error("Expected existing labels to be preserved")
+	}
+
+	if meta.Annotations["note"] != "kms" {
+		t.Errorf("Expected annotation to be set, got %v", meta.Annotations)
+	}
+}
+
+func TestLeaseErrorClassification(t *testing.T) {
+	lm := &LeaseManager{config: DefaultLeaseConfig()}
+	resource := schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantRBAC bool
+	}{
+		{
+			name:     "forbidden",
+			err:      apierrors.NewForbidden(resource, "talos-kms-leader", errors.New("no access")),
+			wantRBAC: true,
+		},
+		{
+			name:     "unauthorized",
+			err:      apierrors.NewUnauthorized("bad token"),
+			wantRBAC: true,
+		},
+		{
+			name:     "conflict",
+			err:      apierrors.NewConflict(resource, "talos-kms-leader", errors.New("modified")),
+			wantRBAC: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(leaseRBACErrors.WithLabelValues("update"))
+
+			err := lm.leaseError("update", tt.err)
+			if errors.Is(err, ErrLeaseRBAC) != tt.wantRBAC {
+				t.Errorf("leaseError() = %v, wantRBAC %v", err, tt.wantRBAC)
+			}
+
+			want := before
+			if tt.wantRBAC {
+				want++
+			}
+			if got := testutil.ToFloat64(leaseRBACErrors.WithLabelValues("update")); got != want {
+				t.Errorf("kms_lease_rbac_errors_total = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLeaseManagerRecreatesDeletedLease(t *testing.T) {
+	ctx := context.Background()
+	leases := schema.GroupVersionResource{Group: "coordination.k8s.io", Version: "v1", Resource: "leases"}
+
+	newManager := func(identity string, objects ...runtime.Object) (*LeaseManager, *fake.Clientset) {
+		config := DefaultLeaseConfig()
+		config.Identity = identity
+		clientset := fake.NewSimpleClientset(objects...)
+		return &LeaseManager{config: config, clientset: clientset}, clientset
+	}
+
+	deleteLease := func(t *testing.T, clientset *fake.Clientset) {
+		t.Helper()
+		if err := clientset.CoordinationV1().Leases("default").Delete(ctx, "talos-kms-leader", metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	heldBy := func(holder string, transitions int32, renewed time.Time) *coordinationv1.Lease {
+		renewTime := metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-kms-leader", Namespace: "default"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: int32Ptr(15),
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+				LeaseTransitions:     int32Ptr(transitions),
+			},
+		}
+	}
+
+	t.Run("leader keeps transitions and acquire time", func(t *testing.T) {
+		lm, clientset := newManager("pod-a", heldBy("pod-a", 3, time.Now()))
+
+		_, first, err := lm.AcquireLease(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		before := testutil.ToFloat64(leaseRecreations)
+		deleteLease(t, clientset)
+
+		acquired, info, err := lm.AcquireLease(ctx)
+		if err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+		if info.LeaseTransitions != 3 {
+			t.Errorf("LeaseTransitions = %d, want 3", info.LeaseTransitions)
+		}
+		if !info.AcquireTime.Equal(first.AcquireTime) {
+			t.Errorf("AcquireTime = %v, want %v", info.AcquireTime, first.AcquireTime)
+		}
+		if got := testutil.ToFloat64(leaseRecreations); got != before+1 {
+			t.Errorf("kms_lease_recreations_total = %v, want %v", got, before+1)
+		}
+	})
+
+	t.Run("follower taking over counts a transition", func(t *testing.T) {
+		lm, clientset := newManager("pod-b", heldBy("pod-a", 3, time.Now()))
+
+		if acquired, _, err := lm.AcquireLease(ctx); err != nil || acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want not acquired", acquired, err)
+		}
+
+		deleteLease(t, clientset)
+
+		acquired, info, err := lm.AcquireLease(ctx)
+		if err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+		if info.LeaseTransitions != 4 {
+			t.Errorf("LeaseTransitions = %d, want 4", info.LeaseTransitions)
+		}
+	})
+
+	t.Run("lease deleted between get and update", func(t *testing.T) {
+		lm, clientset := newManager("pod-a", heldBy("pod-a", 5, time.Now()))
+
+		clientset.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			clientset.Tracker().Delete(leases, "default", "talos-kms-leader")
+			return true, nil, apierrors.NewNotFound(leases.GroupResource(), "talos-kms-leader")
+		})
+
+		acquired, info, err := lm.AcquireLease(ctx)
+		if err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+		if info.LeaseTransitions != 5 {
+			t.Errorf("LeaseTransitions = %d, want 5", info.LeaseTransitions)
+		}
+	})
+
+	t.Run("first creation starts at zero", func(t *testing.T) {
+		lm, _ := newManager("pod-a")
+
+		before := testutil.ToFloat64(leaseRecreations)
+		acquired, info, err := lm.AcquireLease(ctx)
+		if err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+		if info.LeaseTransitions != 0 {
+			t.Errorf("LeaseTransitions = %d, want 0", info.LeaseTransitions)
+		}
+		if got := testutil.ToFloat64(leaseRecreations); got != before {
+			t.Errorf("kms_lease_recreations_total = %v, want %v", got, before)
+		}
+	})
+}
+
+func TestLeaseManagerReleasedLeaseWait(t *testing.T) {
+	ctx := context.Background()
+
+	newManager := func(clientset *fake.Clientset, identity string, wait time.Duration) *LeaseManager {
+		config := DefaultLeaseConfig()
+		config.Identity = identity
+		config.ReleasedLeaseWait = wait
+		return &LeaseManager{config: config, clientset: clientset}
+	}
+
+	// A lease held by pod-a with three transitions, then released by it
+	releasedLease := func(t *testing.T) *fake.Clientset {
+		t.Helper()
+
+		holder, renewed := "pod-a", metav1.NewMicroTime(time.Now())
+		clientset := fake.NewSimpleClientset(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-kms-leader", Namespace: "default"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: int32Ptr(15),
+				AcquireTime:          &renewed,
+				RenewTime:            &renewed,
+				LeaseTransitions:     int32Ptr(3),
+			},
+		})
+
+		if err := newManager(clientset, "pod-a", 0).ReleaseLease(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		return clientset
+	}
+
+	// setReleasedAt rewrites when the lease was released (nil as written by older releases)
+	setReleasedAt := func(t *testing.T, clientset *fake.Clientset, releasedAt *metav1.MicroTime) {
+		t.Helper()
+
+		lease, err := clientset.CoordinationV1().Leases("default").Get(ctx, "talos-kms-leader", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lease.Spec.RenewTime = releasedAt
+		if _, err := clientset.CoordinationV1().Leases("default").Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("release records its time", func(t *testing.T) {
+		clientset := releasedLease(t)
+
+		info, err := newManager(clientset, "pod-b", 0).GetLeaseInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.HolderIdentity != "" || time.Since(info.RenewTime) > time.Minute {
+			t.Errorf("released lease holder = %q, renew time = %v, want no holder and a recent renew time", info.HolderIdentity, info.RenewTime)
+		}
+	})
+
+	t.Run("contenders wait after a recent release", func(t *testing.T) {
+		clientset := releasedLease(t)
+		b, c := newManager(clientset, "pod-b", 2*time.Second), newManager(clientset, "pod-c", 2*time.Second)
+
+		// Both candidates poll right after the release and leave the lease alone
+		for _, lm := range []*LeaseManager{b, c} {
+			acquired, info, err := lm.AcquireLease(ctx)
+			if err != nil || acquired {
+				t.Fatalf("%s AcquireLease() = %v, %v, want not acquired", lm.config.Identity, acquired, err)
+			}
+			if info.LeaseTransitions != 3 {
+				t.Errorf("LeaseTransitions = %d, want 3", info.LeaseTransitions)
+			}
+		}
+
+		// One retry period later the first to poll takes it, the other sees it held
+		releasedAt := metav1.NewMicroTime(time.Now().Add(-3 * time.Second))
+		setReleasedAt(t, clientset, &releasedAt)
+
+		if acquired, info, err := b.AcquireLease(ctx); err != nil || !acquired || info.LeaseTransitions != 4 {
+			t.Fatalf("pod-b AcquireLease() = %v, %+v, %v, want acquired with 4 transitions", acquired, info, err)
+		}
+		if acquired, info, err := c.AcquireLease(ctx); err != nil || acquired || info.LeaseTransitions != 4 {
+			t.Fatalf("pod-c AcquireLease() = %v, %+v, %v, want not acquired with 4 transitions", acquired, info, err)
+		}
+	})
+
+	t.Run("no wait acquires immediately", func(t *testing.T) {
+		clientset := releasedLease(t)
+
+		if acquired, _, err := newManager(clientset, "pod-b", 0).AcquireLease(ctx); err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+	})
+
+	t.Run("release without a renew time", func(t *testing.T) {
+		clientset := releasedLease(t)
+		setReleasedAt(t, clientset, nil)
+
+		if acquired, _, err := newManager(clientset, "pod-b", 2*time.Second).AcquireLease(ctx); err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+	})
+}
+
+func TestLeaseManagerClockSkew(t *testing.T) {
+	ctx := context.Background()
+
+	newManager := func(clientset *fake.Clientset) *LeaseManager {
+		config := DefaultLeaseConfig()
+		config.Identity = "pod-b"
+		return &LeaseManager{config: config, clientset: clientset}
+	}
+
+	// heldLease is a lease held by pod-a, last renewed at renewed by pod-a's clock
+	heldLease := func(renewed time.Time) *coordinationv1.Lease {
+		holder, renewTime := "pod-a", metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-kms-leader", Namespace: "default"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: int32Ptr(15),
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+				LeaseTransitions:     int32Ptr(1),
+			},
+		}
+	}
+
+	// renew rewrites the renew time as pod-a renewing the lease would
+	renew := func(t *testing.T, clientset *fake.Clientset, renewed time.Time) {
+		t.Helper()
+
+		lease, err := clientset.CoordinationV1().Leases("default").Get(ctx, "talos-kms-leader", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		renewTime := metav1.NewMicroTime(renewed)
+		lease.Spec.RenewTime = &renewTime
+		if _, err := clientset.CoordinationV1().Leases("default").Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wantNotAcquired := func(t *testing.T, lm *LeaseManager) {
+		t.Helper()
+
+		if acquired, _, err := lm.AcquireLease(ctx); err != nil || acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want not acquired", acquired, err)
+		}
+	}
+
+	t.Run("clock jumping forward keeps the lease with its leader", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(heldLease(time.Now()))
+		lm := newManager(clientset)
+		skewBefore := testutil.ToFloat64(suspectedClockSkew)
+
+		wantNotAcquired(t, lm)
+
+		// pod-a renews moments later, but our wall clock has jumped a minute ahead
+		renew(t, clientset, time.Now().Add(-time.Minute))
+		wantNotAcquired(t, lm)
+		wantNotAcquired(t, lm)
+
+		if got := testutil.ToFloat64(suspectedClockSkew) - skewBefore; got != 1 {
+			t.Errorf("kms_suspected_clock_skew_total increased by %v, want 1", got)
+		}
+	})
+
+	t.Run("renew time in the future", func(t *testing.T) {
+		lm := newManager(fake.NewSimpleClientset(heldLease(time.Now().Add(time.Minute))))
+		skewBefore := testutil.ToFloat64(suspectedClockSkew)
+
+		wantNotAcquired(t, lm)
+
+		if got := testutil.ToFloat64(suspectedClockSkew) - skewBefore; got != 1 {
+			t.Errorf("kms_suspected_clock_skew_total increased by %v, want 1", got)
+		}
+	})
+
+	t.Run("expired lease seen for the first time", func(t *testing.T) {
+		lm := newManager(fake.NewSimpleClientset(heldLease(time.Now().Add(-time.Minute))))
+		skewBefore := testutil.ToFloat64(suspectedClockSkew)
+
+		if acquired, _, err := lm.AcquireLease(ctx); err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+		if got := testutil.ToFloat64(suspectedClockSkew) - skewBefore; got != 0 {
+			t.Errorf("kms_suspected_clock_skew_total increased by %v, want 0", got)
+		}
+	})
+
+	t.Run("lease expires by the monotonic clock", func(t *testing.T) {
+		lease := heldLease(time.Now())
+		lease.Spec.LeaseDurationSeconds = int32Ptr(1)
+		clientset := fake.NewSimpleClientset(lease)
+		lm := newManager(clientset)
+
+		wantNotAcquired(t, lm)
+		renew(t, clientset, time.Now().Add(-time.Minute))
+		wantNotAcquired(t, lm)
+
+		// pod-a stops renewing, a lease duration later the lease is expired by our own clock
+		time.Sleep(1100 * time.Millisecond)
+
+		if acquired, _, err := lm.AcquireLease(ctx); err != nil || !acquired {
+			t.Fatalf("AcquireLease() = %v, %v, want acquired", acquired, err)
+		}
+	})
+}
+
+func TestLeaseManagerIdentityCollision(t *testing.T) {
+	ctx := context.Background()
+	started := time.Now().Add(-time.Minute)
+
+	// ownLease is a lease held under our identity, acquired and last renewed at the given times
+	ownLease := func(acquired, renewed time.Time) *coordinationv1.Lease {
+		holder := "pod-a"
+		acquireTime, renewTime := metav1.NewMicroTime(acquired), metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "talos-kms-leader", Namespace: "default"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: int32Ptr(15),
+				AcquireTime:          &acquireTime,
+				RenewTime:            &renewTime,
+				LeaseTransitions:     int32Ptr(3),
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		lease         *coordinationv1.Lease
+		wantCollision bool
+		wantAcquired  bool
+	}{
+		{
+			name:          "renewed since start by another process",
+			lease:         ownLease(started.Add(-time.Hour), time.Now().Add(-time.Second)),
+			wantCollision: true,
+		},
+		{
+			name:         "acquired by this process",
+			lease:        ownLease(started.Add(time.Second), time.Now().Add(-time.Second)),
+			wantAcquired: true,
+		},
+		{
+			name:         "left by an earlier run before start",
+			lease:        ownLease(started.Add(-time.Hour), started.Add(-10*time.Second)),
+			wantAcquired: true,
+		},
+		{
+			name:         "other process stopped renewing",
+			lease:        ownLease(started.Add(-time.Hour), time.Now().Add(-30*time.Second)),
+			wantAcquired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.lease)
+			config := DefaultLeaseConfig()
+			config.Identity = "pod-a"
+			lm := &LeaseManager{config: config, clientset: clientset, startedAt: started}
+
+			acquired, _, err := lm.AcquireLease(ctx)
+			if collision := errors.Is(err, ErrIdentityCollision); collision != tt.wantCollision {
+				t.Fatalf("AcquireLease() error = %v, want collision %v", err, tt.wantCollision)
+			}
+			if acquired != tt.wantAcquired {
+				t.Fatalf("AcquireLease() acquired = %v, want %v", acquired, tt.wantAcquired)
+			}
+
+			if !acquired || !tt.lease.Spec.AcquireTime.Time.Before(started) {
+				return
+			}
+
+			// Taking over a lease left under our identity starts a new tenure
+			lease, err := clientset.CoordinationV1().Leases("default").Get(ctx, "talos-kms-leader", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lease.Spec.AcquireTime.Time.Before(started) {
+				t.Errorf("acquire time = %v, want it reset after the takeover", lease.Spec.AcquireTime.Time)
+			}
+			if got := *lease.Spec.LeaseTransitions; got != 4 {
+				t.Errorf("transitions = %d, want 4", got)
+			}
+
+			// The renewed lease is ours from now on
+			if acquired, _, err := lm.AcquireLease(ctx); err != nil || !acquired {
+				t.Errorf("renewal = %v, %v, want acquired", acquired, err)
+			}
+		})
+	}
+}
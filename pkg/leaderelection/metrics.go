@@ -0,0 +1,38 @@
+package leaderelection
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var leaseRBACErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_lease_rbac_errors_total",
+	Help: "Total number of Lease API calls rejected as Forbidden or Unauthorized",
+}, []string{"operation"})
+
+var leaseRecreations = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_lease_recreations_total",
+	Help: "Total number of times a deleted Lease was recreated from the cached lease state",
+})
+
+var suspectedClockSkew = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_suspected_clock_skew_total",
+	Help: "Total number of lease renewals whose renew time disagreed with the local clock beyond tolerance",
+})
+
+var identityCollisions = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_identity_collision_suspected_total",
+	Help: "Total number of times another process was seen holding the lease with this instance's identity",
+})
+
+var (
+	leadershipFlapRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_leadership_flap_rate",
+		Help: "Leadership changes per minute over the flap detection window",
+	})
+
+	leadershipFlapping = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_leadership_flapping",
+		Help: "Whether leadership changes exceed the flap threshold (1) or not (0)",
+	})
+)
@@ -0,0 +1,60 @@
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ResolveWorkloadOwner returns an owner reference to the workload controlling pod, for use as
+// the lease owner. Owning the lease by the pod would garbage-collect it whenever that pod is
+// replaced, so a ReplicaSet is followed up to its Deployment and other controllers (such as a
+// StatefulSet) are used as is. It returns nil when the pod has no controller. The pod is looked
+// up in namespace, the lease namespace, as an owner must live in the same namespace.
+func ResolveWorkloadOwner(ctx context.Context, restConfig *rest.Config, namespace string, pod *metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+	}
+
+	return workloadOwner(ctx, clientset, namespace, pod)
+}
+
+// workloadOwner resolves the workload controlling pod through clientset
+func workloadOwner(ctx context.Context, clientset kubernetes.Interface, namespace string, pod *metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	object, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod %s/%s: %w", namespace, pod.Name, err)
+	}
+
+	if object.UID != pod.UID {
+		return nil, fmt.Errorf("pod %s/%s has UID %s, not %s", namespace, pod.Name, object.UID, pod.UID)
+	}
+
+	controller := metav1.GetControllerOf(object)
+	if controller == nil {
+		return nil, nil
+	}
+
+	if controller.Kind == "ReplicaSet" && controller.APIVersion == "apps/v1" {
+		replicaSet, err := clientset.AppsV1().ReplicaSets(namespace).Get(ctx, controller.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replica set %s/%s: %w", namespace, controller.Name, err)
+		}
+
+		if deployment := metav1.GetControllerOf(replicaSet); deployment != nil {
+			controller = deployment
+		}
+	}
+
+	// The lease only needs to be garbage-collected with its owner, not block its deletion
+	return &metav1.OwnerReference{
+		APIVersion: controller.APIVersion,
+		Kind:       controller.Kind,
+		Name:       controller.Name,
+		UID:        controller.UID,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package leaderelection
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadOwner(t *testing.T) {
+	controlledBy := func(apiVersion, kind, name string, uid types.UID) []metav1.OwnerReference {
+		controller := true
+		return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid, Controller: &controller}}
+	}
+
+	pod := func(name string, uid types.UID, owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid, OwnerReferences: owners}}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		pod("kms-abc", "pod-1", controlledBy("apps/v1", "ReplicaSet", "kms-7d9f", "rs-1")),
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "kms-7d9f",
+			Namespace:       "default",
+			OwnerReferences: controlledBy("apps/v1", "Deployment", "kms", "deploy-1"),
+		}},
+		pod("kms-0", "pod-2", controlledBy("apps/v1", "StatefulSet", "kms", "sts-1")),
+		pod("kms-bare", "pod-3", nil),
+		pod("kms-orphan", "pod-4", controlledBy("apps/v1", "ReplicaSet", "kms-gone", "rs-2")),
+	)
+
+	tests := []struct {
+		name      string
+		pod       metav1.OwnerReference
+		wantOwner *metav1.OwnerReference
+		wantErr   bool
+	}{
+		{
+			name:      "deployment pod",
+			pod:       metav1.OwnerReference{Name: "kms-abc", UID: "pod-1"},
+			wantOwner: &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "kms", UID: "deploy-1"},
+		},
+		{
+			name:      "statefulset pod",
+			pod:       metav1.OwnerReference{Name: "kms-0", UID: "pod-2"},
+			wantOwner: &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "kms", UID: "sts-1"},
+		},
+		{
+			name: "pod without controller",
+			pod:  metav1.OwnerReference{Name: "kms-bare", UID: "pod-3"},
+		},
+		{
+			name:    "replica set not found",
+			pod:     metav1.OwnerReference{Name: "kms-orphan", UID: "pod-4"},
+			wantErr: true,
+		},
+		{
+			name:    "pod not found",
+			pod:     metav1.OwnerReference{Name: "kms-missing", UID: "pod-5"},
+			wantErr: true,
+		},
+		{
+			name:    "pod replaced under the same name",
+			pod:     metav1.OwnerReference{Name: "kms-0", UID: "pod-old"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, err := workloadOwner(context.Background(), clientset, "default", &tt.pod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("workloadOwner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			switch {
+			case tt.wantOwner == nil && owner != nil:
+				t.Errorf("workloadOwner() = %+v, want no owner", owner)
+			case tt.wantOwner != nil && (owner == nil || *owner != *tt.wantOwner):
+				t.Errorf("workloadOwner() = %+v, want %+v", owner, tt.wantOwner)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// PeerCounter counts the other lease candidates behind a Service from its EndpointSlices.
+// The count is cached for cacheTTL, so frequent readiness probes don't each list the slices.
+type PeerCounter struct {
+	clientset kubernetes.Interface
+	namespace string
+	service   string
+	identity  string
+
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	count     int
+	err       error
+	fetchedAt time.Time
+}
+
+// NewPeerCounter creates a counter for the candidates behind service in the lease namespace,
+// leaving out this instance's own pod. Counts are cached for one retry period.
+func NewPeerCounter(config *LeaseConfig, restConfig *rest.Config, service string) (*PeerCounter, error) {
+	if service == "" {
+		return nil, fmt.Errorf("peer service name cannot be empty")
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+	}
+
+	return &PeerCounter{
+		clientset: clientset,
+		namespace: config.Namespace,
+		service:   service,
+		identity:  config.Identity,
+		cacheTTL:  config.RetryPeriod,
+		now:       time.Now,
+	}, nil
+}
+
+// CountPeers returns how many other ready candidate pods back the service, from a count at
+// most cacheTTL old. Followers are never ready themselves, so the service is expected to
+// publish not-ready addresses, which Kubernetes then reports as ready while the pod runs.
+// A pod listed in several slices (one per address family) counts once.
+func (pc *PeerCounter) CountPeers(ctx context.Context) (int, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if now := pc.now(); pc.fetchedAt.IsZero() || now.Sub(pc.fetchedAt) >= pc.cacheTTL {
+		pc.count, pc.err = pc.listPeers(ctx)
+		pc.fetchedAt = now
+	}
+
+	return pc.count, pc.err
+}
+
+// listPeers counts the ready candidates in the service's EndpointSlices
+func (pc *PeerCounter) listPeers(ctx context.Context) (int, error) {
+	slices, err := pc.clientset.DiscoveryV1().EndpointSlices(pc.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + pc.service,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list endpoint slices for service %s/%s: %w", pc.namespace, pc.service, err)
+	}
+
+	peers := make(map[string]struct{})
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition means ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			if endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating {
+				continue
+			}
+
+			name := endpointName(endpoint)
+			if name == "" || name == pc.identity {
+				continue
+			}
+			peers[name] = struct{}{}
+		}
+	}
+
+	return len(peers), nil
+}
+
+// endpointName identifies the pod behind an endpoint, by its target reference or else its first address
+func endpointName(endpoint discoveryv1.Endpoint) string {
+	if endpoint.TargetRef != nil && endpoint.TargetRef.Name != "" {
+		return endpoint.TargetRef.Name
+	}
+
+	if len(endpoint.Addresses) > 0 {
+		return endpoint.Addresses[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,77 @@
+package leaderelection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPeerCounter(t *testing.T) {
+	terminating, notReady := true, false
+
+	endpoint := func(pod, address string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses: []string{address},
+			TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod},
+		}
+	}
+
+	slice := func(name, service string, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+			},
+			Endpoints: endpoints,
+		}
+	}
+
+	leaving := endpoint("kms-3", "10.0.0.3")
+	leaving.Conditions.Terminating = &terminating
+	unready := endpoint("kms-5", "10.0.0.5")
+	unready.Conditions.Ready = &notReady
+
+	clientset := fake.NewSimpleClientset(
+		slice("kms-ipv4", "kms", endpoint("kms-0", "10.0.0.1"), endpoint("kms-1", "10.0.0.2"), leaving, unready,
+			discoveryv1.Endpoint{Addresses: []string{"10.0.0.4"}}),
+		slice("kms-ipv6", "kms", endpoint("kms-0", "fd00::1"), endpoint("kms-1", "fd00::2")),
+		slice("other-ipv4", "other", endpoint("other-0", "10.0.1.1")),
+	)
+
+	now := time.Now()
+	pc := &PeerCounter{clientset: clientset, namespace: "default", service: "kms", identity: "kms-0",
+		cacheTTL: 2 * time.Second, now: func() time.Time { return now }}
+
+	// kms-1 in both address families and the endpoint without a pod reference; not this
+	// instance, the terminating or unready pods, or another service's pods
+	peers, err := pc.CountPeers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peers != 2 {
+		t.Errorf("CountPeers() = %d, want 2", peers)
+	}
+
+	// The count is cached for the TTL
+	clientset.DiscoveryV1().EndpointSlices("default").Delete(context.Background(), "kms-ipv4", metav1.DeleteOptions{})
+	if peers, _ := pc.CountPeers(context.Background()); peers != 2 {
+		t.Errorf("CountPeers() within the cache TTL = %d, want the cached 2", peers)
+	}
+
+	now = now.Add(2 * time.Second)
+	if peers, _ := pc.CountPeers(context.Background()); peers != 1 {
+		t.Errorf("CountPeers() after the cache TTL = %d, want 1", peers)
+	}
+
+	now = now.Add(2 * time.Second)
+	pc.service = "missing"
+	if peers, err := pc.CountPeers(context.Background()); err != nil || peers != 0 {
+		t.Errorf("CountPeers() for a service without endpoints = %d, %v, want 0", peers, err)
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/auth"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+// TokenRenewer is implemented by the auth manager to force a token renewal
+type TokenRenewer interface {
+	ForceRenewal(ctx context.Context) error
+	GetTokenTTL() time.Duration
+}
+
+// RenewResponse is returned by the auth renew endpoint
+type RenewResponse struct {
+	Renewed    bool   `json:"renewed"`
+	TTL        string `json:"ttl,omitempty"`
+	TTLSeconds int64  `json:"ttlSeconds,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewAuthRenewHandler creates a handler that forces an immediate token renewal on POST
+func NewAuthRenewHandler(renewer TokenRenewer, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		logger.Info("Forced token renewal requested", "remote", r.RemoteAddr)
+
+		if err := renewer.ForceRenewal(ctx); err != nil {
+			logger.Error("Forced token renewal failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, RenewResponse{Error: err.Error()})
+			return
+		}
+
+		ttl := renewer.GetTokenTTL()
+		writeJSON(w, http.StatusOK, RenewResponse{
+			Renewed:    true,
+			TTL:        ttl.String(),
+			TTLSeconds: int64(ttl.Seconds()),
+		})
+	})
+}
+
+// AuthStatusReporter is implemented by the auth manager to report the active method and token state
+type AuthStatusReporter interface {
+	Status() auth.Status
+}
+
+// NewAuthStatusHandler creates a handler reporting the resolved auth method and token health,
+// returning 503 while renewal is failing
+func NewAuthStatusHandler(reporter AuthStatusReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := reporter.Status()
+
+		code := http.StatusOK
+		if !status.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, code, status)
+	})
+}
+
+// ValidationStatsReporter is implemented by the validation middleware to report request counts
+type ValidationStatsReporter interface {
+	GetValidationStats() (success, failures int64)
+}
+
+// LeadershipReporter is implemented by the leader-aware server to report leadership state
+type LeadershipReporter interface {
+	GetLeadershipInfo() LeadershipInfo
+}
+
+// AdminStatsSources are the subsystems aggregated by the stats endpoints. Nil
+// sources are left out of the response.
+type AdminStatsSources struct {
+	Validation  ValidationStatsReporter
+	Leadership  LeadershipReporter
+	VaultHealth *VaultHealthChecker
+	Auth        AuthStatusReporter
+}
+
+// ValidationStats are the request validation counters
+type ValidationStats struct {
+	Successes int64 `json:"successes"`
+	Failures  int64 `json:"failures"`
+}
+
+// AdminStats is returned by the stats endpoints
+type AdminStats struct {
+	Timestamp  time.Time          `json:"timestamp"`
+	Validation *ValidationStats   `json:"validation,omitempty"`
+	Leadership *LeadershipInfo    `json:"leadership,omitempty"`
+	Vault      *VaultHealthStatus `json:"vault,omitempty"`
+	Auth       *auth.Status       `json:"auth,omitempty"`
+	Requests   *RequestStats      `json:"requests,omitempty"`
+}
+
+// NewAdminStatsHandler creates a handler returning validation stats, leadership state,
+// Vault health, token state and request counters in one JSON document
+func NewAdminStatsHandler(sources AdminStatsSources) http.Handler {
+	return NewStatsAggregator(sources)
+}
+
+// ValidationConfigResponse is returned by the validation config endpoint. The seal and
+// unseal size limits are the ones enforced, after falling back to the request size limit.
+type ValidationConfigResponse struct {
+	Enabled            bool                      `json:"enabled"`
+	UUIDMode           validation.ValidationMode `json:"uuidMode"`
+	RequireUUIDv4      bool                      `json:"requireUUIDv4"`
+	CheckEntropy       bool                      `json:"checkEntropy"`
+	EntropyCheckActive bool                      `json:"entropyCheckActive"`
+	EntropyLevel       validation.EntropyLevel   `json:"entropyLevel"`
+	MinUniqueChars     int                       `json:"minUniqueChars"`
+	EntropyExemptUUIDs []string                  `json:"entropyExemptUUIDs"`
+	MaxUUIDLength      int                       `json:"maxUUIDLength"`
+	MaxRequestSize     int                       `json:"maxRequestSize"`
+	MaxSealSize        int                       `json:"maxSealSize"`
+	MaxUnsealSize      int                       `json:"maxUnsealSize"`
+	MethodAllowlist    []string                  `json:"methodAllowlist"`
+	SelfTestUUID       string                    `json:"selfTestUUID"`
+	FailOpen           bool                      `json:"failOpen"`
+}
+
+// NewValidationConfigResponse describes config as enforced
+func NewValidationConfigResponse(config *validation.ValidationConfig) ValidationConfigResponse {
+	maxRequestSize := config.MaxRequestSize
+	if maxRequestSize <= 0 {
+		maxRequestSize = validation.DefaultMaxRequestSize
+	}
+
+	response := ValidationConfigResponse{
+		Enabled:            config.Enabled,
+		UUIDMode:           config.UUIDValidationMode,
+		RequireUUIDv4:      config.RequireUUIDv4,
+		CheckEntropy:       config.CheckEntropy,
+		EntropyCheckActive: config.EntropyCheckActive(),
+		EntropyLevel:       config.EntropyLevel,
+		MinUniqueChars:     config.MinUniqueChars,
+		EntropyExemptUUIDs: config.EntropyExemptUUIDs,
+		MaxUUIDLength:      config.MaxUUIDLength,
+		MaxRequestSize:     maxRequestSize,
+		MaxSealSize:        config.MaxSealSize,
+		MaxUnsealSize:      config.MaxUnsealSize,
+		MethodAllowlist:    config.MethodAllowlist,
+		SelfTestUUID:       config.SelfTestUUID,
+		FailOpen:           config.FailOpen,
+	}
+
+	if response.MaxSealSize <= 0 {
+		response.MaxSealSize = maxRequestSize
+	}
+	if response.MaxUnsealSize <= 0 {
+		response.MaxUnsealSize = maxRequestSize
+	}
+	if response.EntropyExemptUUIDs == nil {
+		response.EntropyExemptUUIDs = []string{}
+	}
+	if response.MethodAllowlist == nil {
+		response.MethodAllowlist = []string{}
+	}
+
+	return response
+}
+
+// NewValidationConfigHandler creates a handler returning the validation config in effect, as
+// read from current on each request so settings reloaded on SIGHUP are shown. None of it is
+// secret, so nothing is redacted.
+func NewValidationConfigHandler(current func() *validation.ValidationConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, NewValidationConfigResponse(current()))
+	})
+}
+
+// NodeKeyManager lists and deletes per-node transit keys
+type NodeKeyManager interface {
+	ListNodeKeys(ctx context.Context) ([]string, error)
+	DeleteNodeKey(ctx context.Context, nodeUUID string) error
+}
+
+// NodeKeysResponse is returned by the node keys endpoint
+type NodeKeysResponse struct {
+	Keys    []string `json:"keys,omitempty"`
+	Deleted string   `json:"deleted,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewNodeKeysHandler creates a handler mounted at /admin/keys/ that lists node keys on
+// GET /admin/keys/ and deletes a retired node's key on DELETE /admin/keys/<uuid>
+func NewNodeKeysHandler(keys NodeKeyManager, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		nodeUUID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/keys"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && nodeUUID == "":
+			names, err := keys.ListNodeKeys(ctx)
+			if err != nil {
+				logger.Error("Failed to list node keys", "error", err)
+				writeJSON(w, http.StatusInternalServerError, NodeKeysResponse{Error: err.Error()})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, NodeKeysResponse{Keys: names})
+
+		case r.Method == http.MethodDelete && nodeUUID != "":
+			logger.Warn("Node key deletion requested",
+				"node", validation.SanitizeForLogging(nodeUUID),
+				"remote", r.RemoteAddr)
+
+			if err := keys.DeleteNodeKey(ctx, nodeUUID); err != nil {
+				logger.Error("Failed to delete node key", "error", err)
+				writeJSON(w, nodeKeyErrorStatus(err), NodeKeysResponse{Error: err.Error()})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, NodeKeysResponse{Deleted: nodeUUID})
+
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// nodeKeyErrorStatus maps node key errors to HTTP status codes
+func nodeKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidNodeKey):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNodeKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrKeyDeletionNotAllowed):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/auth"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+// fakeRenewer is a TokenRenewer for testing
+type fakeRenewer struct {
+	err   error
+	ttl   time.Duration
+	calls int
+}
+
+func (f *fakeRenewer) ForceRenewal(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeRenewer) GetTokenTTL() time.Duration {
+	return f.ttl
+}
+
+func TestAuthRenewHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tests := []struct {
+		name        string
+		method      string
+		renewer     *fakeRenewer
+		wantCode    int
+		wantRenewed bool
+		wantCalls   int
+	}{
+		{
+			name:        "successful renewal",
+			method:      http.MethodPost,
+			renewer:     &fakeRenewer{ttl: time.Hour},
+			wantCode:    http.StatusOK,
+			wantRenewed: true,
+			wantCalls:   1,
+		},
+		{
+			name:      "failed renewal",
+			method:    http.MethodPost,
+			renewer:   &fakeRenewer{err: errors.New("vault unavailable")},
+			wantCode:  http.StatusInternalServerError,
+			wantCalls: 1,
+		},
+		{
+			name:      "GET not allowed",
+			method:    http.MethodGet,
+			renewer:   &fakeRenewer{ttl: time.Hour},
+			wantCode:  http.StatusMethodNotAllowed,
+			wantCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthRenewHandler(tt.renewer, logger)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/auth/renew", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+
+			if tt.renewer.calls != tt.wantCalls {
+				t.Errorf("Expected %d renewal calls, got %d", tt.wantCalls, tt.renewer.calls)
+			}
+
+			if tt.method != http.MethodPost {
+				return
+			}
+
+			var resp RenewResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if resp.Renewed != tt.wantRenewed {
+				t.Errorf("Expected renewed=%v, got %v", tt.wantRenewed, resp.Renewed)
+			}
+
+			if tt.wantRenewed && resp.TTLSeconds != 3600 {
+				t.Errorf("Expected ttlSeconds=3600, got %d", resp.TTLSeconds)
+			}
+
+			if !tt.wantRenewed && resp.Error == "" {
+				t.Error("Expected error message in response")
+			}
+		})
+	}
+}
+
+type fakeAuthStatus struct {
+	status auth.Status
+}
+
+func (f *fakeAuthStatus) Status() auth.Status {
+	return f.status
+}
+
+func TestAuthStatusHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   auth.Status
+		wantCode int
+	}{
+		{
+			name:     "healthy",
+			status:   auth.Status{Method: auth.AuthMethodKubernetes, Authenticated: true, Healthy: true, TTLSeconds: 3600},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "renewal failing",
+			status:   auth.Status{Method: auth.AuthMethodKubernetes, Authenticated: true, LastError: "permission denied"},
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewAuthStatusHandler(&fakeAuthStatus{status: tt.status}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+
+			var resp auth.Status
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if resp.Method != tt.status.Method || resp.LastError != tt.status.LastError {
+				t.Errorf("Expected %+v, got %+v", tt.status, resp)
+			}
+		})
+	}
+}
+
+type fakeValidationStats struct {
+	success, failures int64
+}
+
+func (f *fakeValidationStats) GetValidationStats() (int64, int64) {
+	return f.success, f.failures
+}
+
+type fakeLeadership struct {
+	info LeadershipInfo
+}
+
+func (f *fakeLeadership) GetLeadershipInfo() LeadershipInfo {
+	return f.info
+}
+
+func TestAdminStatsHandler(t *testing.T) {
+	vaultHealth := NewVaultHealthChecker(func(ctx context.Context) error { return nil }, time.Minute, time.Minute)
+
+	tests := []struct {
+		name    string
+		sources AdminStatsSources
+		check   func(t *testing.T, stats AdminStats)
+	}{
+		{
+			name: "all sources",
+			sources: AdminStatsSources{
+				Validation:  &fakeValidationStats{success: 7, failures: 2},
+				Leadership:  &fakeLeadership{info: LeadershipInfo{IsLeader: true, CurrentLeader: "kms-0"}},
+				VaultHealth: vaultHealth,
+				Auth:        &fakeAuthStatus{status: auth.Status{Method: auth.AuthMethodToken, Healthy: true}},
+			},
+			check: func(t *testing.T, stats AdminStats) {
+				if stats.Validation == nil || stats.Validation.Successes != 7 || stats.Validation.Failures != 2 {
+					t.Errorf("validation = %+v, want 7 successes and 2 failures", stats.Validation)
+				}
+				if stats.Leadership == nil || !stats.Leadership.IsLeader || stats.Leadership.CurrentLeader != "kms-0" {
+					t.Errorf("leadership = %+v", stats.Leadership)
+				}
+				if stats.Vault == nil || !stats.Vault.Healthy {
+					t.Errorf("vault = %+v, want healthy", stats.Vault)
+				}
+				if stats.Auth == nil || stats.Auth.Method != auth.AuthMethodToken {
+					t.Errorf("auth = %+v", stats.Auth)
+				}
+			},
+		},
+		{
+			name:    "missing sources are omitted",
+			sources: AdminStatsSources{Auth: &fakeAuthStatus{status: auth.Status{Method: auth.AuthMethodToken}}},
+			check: func(t *testing.T, stats AdminStats) {
+				if stats.Validation != nil || stats.Leadership != nil || stats.Vault != nil {
+					t.Errorf("Expected only auth, got %+v", stats)
+				}
+				if stats.Auth == nil {
+					t.Error("Expected auth status")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewAdminStatsHandler(tt.sources).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var stats AdminStats
+			if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			tt.check(t, stats)
+		})
+	}
+}
+
+func TestValidationConfigHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	config := validation.DefaultValidationConfig()
+	config.MaxUnsealSize = 8192
+	middleware := validation.NewValidationMiddlewareFromConfig(config, logger)
+
+	handler := NewValidationConfigHandler(middleware.Config)
+
+	get := func(t *testing.T) ValidationConfigResponse {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/validation", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var response ValidationConfigResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	response := get(t)
+	if !response.Enabled || !response.RequireUUIDv4 || !response.EntropyCheckActive {
+		t.Errorf("response = %+v, want validation, UUID v4 and entropy checks enabled", response)
+	}
+	if response.EntropyLevel != validation.EntropyLevelBasic {
+		t.Errorf("entropyLevel = %q, want %q", response.EntropyLevel, validation.EntropyLevelBasic)
+	}
+	if response.MaxSealSize != validation.DefaultMaxRequestSize || response.MaxUnsealSize != 8192 {
+		t.Errorf("seal/unseal size = %d/%d, want %d/8192",
+			response.MaxSealSize, response.MaxUnsealSize, validation.DefaultMaxRequestSize)
+	}
+
+	// Reloaded UUID settings are reported, the size limits that need a restart are kept
+	reloaded := validation.DefaultValidationConfig()
+	reloaded.EntropyLevel = validation.EntropyLevelStrict
+	reloaded.EntropyExemptUUIDs = []string{"00000000-0000-4000-8000-000000000001"}
+	reloaded.MaxUnsealSize = 1
+	middleware.ReloadConfig(reloaded)
+
+	response = get(t)
+	if response.EntropyLevel != validation.EntropyLevelStrict || len(response.EntropyExemptUUIDs) != 1 {
+		t.Errorf("response = %+v, want the reloaded entropy settings", response)
+	}
+	if response.MaxUnsealSize != 8192 {
+		t.Errorf("maxUnsealSize = %d after reload, want 8192 until a restart", response.MaxUnsealSize)
+	}
+
+	disabled := &validation.ValidationConfig{}
+	rec := httptest.NewRecorder()
+	NewValidationConfigHandler(func() *validation.ValidationConfig { return disabled }).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config/validation", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d for a disabled config, got %d", http.StatusOK, rec.Code)
+	}
+	var raw map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&raw); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if raw["enabled"] != false {
+		t.Errorf("enabled = %v, want false", raw["enabled"])
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config/validation", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+type fakeKeyManager struct {
+	keys      []string
+	deleteErr error
+	deleted   string
+}
+
+func (f *fakeKeyManager) ListNodeKeys(ctx context.Context) ([]string, error) {
+	return f.keys, nil
+}
+
+func (f *fakeKeyManager) DeleteNodeKey(ctx context.Context, nodeUUID string) error {
+	f.deleted = nodeUUID
+	return f.deleteErr
+}
+
+func TestNodeKeysHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		deleteErr  error
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/admin/keys/", nil, http.StatusOK},
+		{"delete", http.MethodDelete, "/admin/keys/" + retiredNode, nil, http.StatusOK},
+		{"invalid uuid", http.MethodDelete, "/admin/keys/shared-key", ErrInvalidNodeKey, http.StatusBadRequest},
+		{"not found", http.MethodDelete, "/admin/keys/" + retiredNode, ErrNodeKeyNotFound, http.StatusNotFound},
+		{"deletion not allowed", http.MethodDelete, "/admin/keys/" + retiredNode, ErrKeyDeletionNotAllowed, http.StatusConflict},
+		{"delete without uuid", http.MethodDelete, "/admin/keys/", nil, http.StatusMethodNotAllowed},
+		{"wrong method", http.MethodPost, "/admin/keys/" + retiredNode, nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeKeyManager{keys: []string{retiredNode}, deleteErr: tt.deleteErr}
+			handler := NewNodeKeysHandler(keys, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ConvergentMetadataKey is the gRPC metadata key selecting convergent encryption for a Seal
+// request, so the same plaintext always seals to the same ciphertext
+const ConvergentMetadataKey = "x-kms-convergent"
+
+// errConvergentKey is returned when a Seal without x-kms-convergent targets a convergent key,
+// which can't produce unique ciphertext
+var errConvergentKey = status.Errorf(codes.FailedPrecondition,
+	"transit key uses convergent encryption, set %s to seal with it", ConvergentMetadataKey)
+
+// convergentRequested reports whether the incoming metadata asks for convergent encryption
+func convergentRequested(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	values := md.Get(ConvergentMetadataKey)
+	if len(values) == 0 {
+		return false, nil
+	}
+
+	if len(values) > 1 {
+		return false, status.Errorf(codes.InvalidArgument, "%s must be set at most once", ConvergentMetadataKey)
+	}
+
+	convergent, err := strconv.ParseBool(strings.TrimSpace(values[0]))
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "%s must be true or false", ConvergentMetadataKey)
+	}
+
+	return convergent, nil
+}
+
+// derivationContext is the key derivation context for a node key. It is derived from the key
+// name alone, so Unseal can recompute it without the caller resending it.
+func derivationContext(keyName string) string {
+	return base64.StdEncoding.EncodeToString([]byte(keyName))
+}
+
+// checkConvergentKey makes sure the transit key exists and was created with derived and
+// convergent_encryption, the only keys Vault encrypts convergently with
+func (s Server) checkConvergentKey(ctx context.Context, client *vault.Client, keyName string) error {
+	res, err := client.Secrets.TransitReadKey(ctx, keyName, s.vaultRequestOption)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return status.Error(codes.FailedPrecondition,
+				"convergent encryption requires an existing transit key created with derived and convergent_encryption")
+		}
+		return wrapError(err)
+	}
+
+	derived, _ := res.Data["derived"].(bool)
+	convergent, _ := res.Data["convergent_encryption"].(bool)
+	if !derived || !convergent {
+		return status.Error(codes.FailedPrecondition,
+			"transit key does not support convergent encryption (requires derived and convergent_encryption)")
+	}
+
+	return nil
+}
+
+// isMissingContextError reports whether Vault rejected an operation because the key is
+// derived and no derivation context was given
+func isMissingContextError(err error) bool {
+	return err != nil && vault.IsErrorStatus(err, http.StatusBadRequest) && strings.Contains(err.Error(), "missing 'context'")
+}
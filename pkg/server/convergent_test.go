@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_ConvergentSeal(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	transit.createConvergentKey(retiredNode)
+	srv := newTestServer(t, transit)
+
+	convergentCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ConvergentMetadataKey, "true"))
+	plaintext := []byte("deduplicated data")
+
+	first, err := srv.Seal(convergentCtx, &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	second, err := srv.Seal(convergentCtx, &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if !bytes.Equal(first.Data, second.Data) {
+		t.Errorf("convergent Seal() returned %q then %q, want identical ciphertext", first.Data, second.Data)
+	}
+
+	// Unseal needs no metadata, the derivation context is recomputed
+	unsealed, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: first.Data})
+	if err != nil {
+		t.Fatalf("Unseal() error = %v", err)
+	}
+	if !bytes.Equal(unsealed.Data, plaintext) {
+		t.Errorf("Unseal() data = %q, want %q", unsealed.Data, plaintext)
+	}
+
+	// A convergent key can't seal uniquely
+	_, err = srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Seal() without %s on a convergent key error = %v, want FailedPrecondition", ConvergentMetadataKey, err)
+	}
+}
+
+func TestServer_ConvergentSealRejected(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+
+	// Creates a regular, non-derived key for retiredNode
+	if _, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")}); err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		node     string
+		value    []string
+		wantCode codes.Code
+	}{
+		{name: "non-convergent key", node: retiredNode, value: []string{"true"}, wantCode: codes.FailedPrecondition},
+		{name: "missing key", node: otherNode, value: []string{"true"}, wantCode: codes.FailedPrecondition},
+		{name: "invalid value", node: retiredNode, value: []string{"yes please"}, wantCode: codes.InvalidArgument},
+		{name: "repeated", node: retiredNode, value: []string{"true", "true"}, wantCode: codes.InvalidArgument},
+		{name: "explicitly off", node: retiredNode, value: []string{"false"}, wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			md.Append(ConvergentMetadataKey, tt.value...)
+
+			_, err := srv.Seal(metadata.NewIncomingContext(context.Background(), md), &kms.Request{NodeUuid: tt.node, Data: []byte("data")})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Seal() code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
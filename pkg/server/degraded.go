@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultSealDegradedThreshold is how many consecutive Seal permission denials mark Seal as degraded
+const DefaultSealDegradedThreshold = 3
+
+// errSealDegraded is returned by Seal while Vault keeps denying transit encrypt
+var errSealDegraded = status.Error(codes.PermissionDenied,
+	"seal degraded: Vault denies transit encrypt, unseal remains available")
+
+// sealDegradation tracks consecutive Seal permission denials. Once the threshold is
+// reached Seal is reported as degraded until a Seal succeeds again. Unseal is unaffected.
+type sealDegradation struct {
+	threshold int
+
+	mu       sync.Mutex
+	denials  int
+	degraded bool
+}
+
+// SetSealDegradedThreshold marks Seal as degraded after this many consecutive permission
+// denials from Vault, while Unseal keeps being served (0 disables)
+func (s *Server) SetSealDegradedThreshold(threshold int) {
+	if threshold <= 0 {
+		s.sealDegradation = nil
+		return
+	}
+
+	s.sealDegradation = &sealDegradation{threshold: threshold}
+}
+
+// SealDegraded reports whether Seal is currently degraded
+func (s *Server) SealDegraded() bool {
+	if s.sealDegradation == nil {
+		return false
+	}
+
+	s.sealDegradation.mu.Lock()
+	defer s.sealDegradation.mu.Unlock()
+
+	return s.sealDegradation.degraded
+}
+
+// recordSealResult updates the degradation state from a Seal outcome and returns the
+// error to hand back to the caller
+func (s Server) recordSealResult(ctx context.Context, nodeUUID string, err error) error {
+	d := s.sealDegradation
+	if d == nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		if d.degraded {
+			s.logger.InfoContext(ctx, "Seal recovered, Vault accepts transit encrypt again",
+				"node", validation.SanitizeForLogging(nodeUUID))
+		}
+
+		d.denials = 0
+		d.degraded = false
+		sealDegraded.Set(0)
+		return nil
+	}
+
+	// Only a permission problem degrades Seal; other failures are reported as they are
+	if status.Code(err) != codes.PermissionDenied {
+		return err
+	}
+
+	d.denials++
+	if !d.degraded && d.denials >= d.threshold {
+		d.degraded = true
+		sealDegraded.Set(1)
+		s.logger.ErrorContext(ctx, "Seal degraded after repeated permission denials, serving Unseal only",
+			"denials", d.denials)
+	}
+
+	if d.degraded {
+		return errSealDegraded
+	}
+
+	return err
+}
+
+// readyMessage is the /ready body of a ready server, noting a degraded Seal
+func (s *Server) readyMessage() string {
+	if s.SealDegraded() {
+		return "ready (seal degraded, unseal only)"
+	}
+
+	return "ready"
+}
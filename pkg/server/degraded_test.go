@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_SealDegradation(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetSealDegradedThreshold(2)
+
+	ctx := context.Background()
+	request := &kms.Request{NodeUuid: retiredNode, Data: []byte("disk encryption key")}
+
+	sealed, err := srv.Seal(ctx, request)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	readyBody := func() string {
+		rec := httptest.NewRecorder()
+		srv.CreateHealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("/ready status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		body, _ := io.ReadAll(rec.Body)
+		return string(body)
+	}
+
+	// Denials below the threshold are reported as they are
+	transit.setFailure(http.StatusForbidden)
+	if _, err := srv.Seal(ctx, request); status.Code(err) != codes.PermissionDenied || srv.SealDegraded() {
+		t.Fatalf("Seal() error = %v, degraded = %v after one denial", err, srv.SealDegraded())
+	}
+
+	_, err = srv.Seal(ctx, request)
+	if !srv.SealDegraded() {
+		t.Fatal("Seal not degraded at the threshold")
+	}
+	if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "unseal remains available") {
+		t.Errorf("degraded Seal() error = %v, want the degraded error", err)
+	}
+	if got := testutil.ToFloat64(sealDegraded); got != 1 {
+		t.Errorf("kms_seal_degraded = %v, want 1", got)
+	}
+	if body := readyBody(); body != "ready (seal degraded, unseal only)" {
+		t.Errorf("/ready body = %q while degraded", body)
+	}
+
+	// Unseal keeps working while Seal is degraded
+	transit.setFailure(0)
+	if _, err := srv.Unseal(ctx, &kms.Request{NodeUuid: retiredNode, Data: sealed.Data}); err != nil {
+		t.Fatalf("Unseal() error = %v while Seal is degraded", err)
+	}
+	if !srv.SealDegraded() {
+		t.Error("Unseal cleared the Seal degradation")
+	}
+
+	// A successful Seal recovers
+	if _, err := srv.Seal(ctx, request); err != nil {
+		t.Fatalf("Seal() error = %v after recovery", err)
+	}
+	if srv.SealDegraded() || testutil.ToFloat64(sealDegraded) != 0 {
+		t.Error("Seal still degraded after a successful Seal")
+	}
+	if body := readyBody(); body != "ready" {
+		t.Errorf("/ready body = %q after recovery", body)
+	}
+}
+
+func TestServer_SealDegradationIgnoresOtherErrors(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetSealDegradedThreshold(1)
+
+	transit.setFailure(http.StatusInternalServerError)
+	if _, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("key")}); err == nil {
+		t.Fatal("Seal() succeeded against a failing Vault")
+	}
+
+	if srv.SealDegraded() {
+		t.Error("a non-permission failure degraded Seal")
+	}
+}
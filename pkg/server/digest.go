@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// payloadDigestBytes is how much of the SHA-256 sum is kept in audit records
+const payloadDigestBytes = 8
+
+// payloadDigest returns a short, non-reversible digest of a payload for audit correlation
+func payloadDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:payloadDigestBytes])
+}
+
+// auditDigests collects the payload digests of one request for the request recorder
+type auditDigests struct {
+	request  string
+	response string
+}
+
+type auditDigestsKey struct{}
+
+// withAuditDigests returns a context in which the server records payload digests
+func withAuditDigests(ctx context.Context) (context.Context, *auditDigests) {
+	digests := &auditDigests{}
+	return context.WithValue(ctx, auditDigestsKey{}, digests), digests
+}
+
+// recordRequestDigest records the digest of validated request data, if digests are being collected
+func recordRequestDigest(ctx context.Context, data []byte) {
+	if digests, ok := ctx.Value(auditDigestsKey{}).(*auditDigests); ok {
+		digests.request = payloadDigest(data)
+	}
+}
+
+// recordResponseDigest records the digest of response data, if digests are being collected
+func recordResponseDigest(ctx context.Context, data []byte) {
+	if digests, ok := ctx.Value(auditDigestsKey{}).(*auditDigests); ok {
+		digests.response = payloadDigest(data)
+	}
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// fakeTransit is an in-memory Vault Transit engine served over HTTP. Keys are created on
+// first encrypt, and ciphertext is bound to the key that produced it like the real engine.
+// Convergent keys are derived: they need a context, which the ciphertext is bound to as well.
+type fakeTransit struct {
+	server *httptest.Server
+	mount  string
+
+	mu         sync.Mutex
+	keys       map[string]int  // key name -> latest version
+	convergent map[string]bool // key name -> derived with convergent_encryption
+	failStatus int             // when set, every request fails with this status
+	block      chan struct{}   // when set, requests wait for it to close or the client to go away
+	requests   int
+}
+
+// newFakeTransit starts a fake Transit engine at the given mount, stopped when the test ends
+func newFakeTransit(t *testing.T, mount string) *fakeTransit {
+	t.Helper()
+
+	f := &fakeTransit{mount: mount, keys: make(map[string]int), convergent: make(map[string]bool)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+// client returns a Vault client for the fake with client-side retries disabled
+func (f *fakeTransit) client(t *testing.T) *vault.Client {
+	t.Helper()
+
+	client, err := vault.New(
+		vault.WithAddress(f.server.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// setFailure makes every request fail with the given HTTP status (0 clears it)
+func (f *fakeTransit) setFailure(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failStatus = status
+}
+
+// setBlock makes requests hang until ch is closed or the client cancels (nil clears it)
+func (f *fakeTransit) setBlock(ch chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.block = ch
+}
+
+// createConvergentKey creates a key with derived and convergent_encryption set
+func (f *fakeTransit) createConvergentKey(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[key] = 1
+	f.convergent[key] = true
+}
+
+// requestCount returns how many requests the fake has received
+func (f *fakeTransit) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *fakeTransit) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	failStatus, block := f.failStatus, f.block
+	f.mu.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if failStatus != 0 {
+		writeVaultError(w, failStatus, http.StatusText(failStatus))
+		return
+	}
+
+	operation, key, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"+f.mount+"/"), "/")
+	if ok && operation == "keys" && r.Method == http.MethodGet {
+		f.readKey(w, key)
+		return
+	}
+	if !ok || r.Method != http.MethodPost {
+		writeVaultError(w, http.StatusNotFound, "unsupported path")
+		return
+	}
+
+	var body struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+		Context    string `json:"context"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch operation {
+	case "encrypt":
+		f.encrypt(w, key, body.Plaintext, body.Context)
+	case "decrypt":
+		f.decrypt(w, key, body.Ciphertext, body.Context)
+	default:
+		writeVaultError(w, http.StatusNotFound, "unsupported operation")
+	}
+}
+
+func (f *fakeTransit) readKey(w http.ResponseWriter, key string) {
+	f.mu.Lock()
+	latest, convergent := f.keys[key], f.convergent[key]
+	f.mu.Unlock()
+
+	if latest == 0 {
+		writeVaultError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	writeVaultData(w, map[string]interface{}{
+		"name":                  key,
+		"latest_version":        latest,
+		"derived":               convergent,
+		"convergent_encryption": convergent,
+	})
+}
+
+// binding is what ciphertext is bound to: the key, plus the context for derived keys
+func (f *fakeTransit) binding(key, context string) (string, error) {
+	f.mu.Lock()
+	derived := f.convergent[key]
+	f.mu.Unlock()
+
+	if !derived {
+		return key, nil
+	}
+
+	if context == "" {
+		return "", errors.New("missing 'context' for key derivation; the key was created using a derived key, " +
+			"which means additional, per-request information must be included in order to perform operations with the key")
+	}
+
+	return key + "/" + context, nil
+}
+
+func (f *fakeTransit) encrypt(w http.ResponseWriter, key, plaintext, context string) {
+	if _, err := base64.StdEncoding.DecodeString(plaintext); err != nil {
+		writeVaultError(w, http.StatusBadRequest, "plaintext is not base64")
+		return
+	}
+
+	binding, err := f.binding(key, context)
+	if err != nil {
+		writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	if f.keys[key] == 0 {
+		f.keys[key] = 1
+	}
+	version := f.keys[key]
+	f.mu.Unlock()
+
+	sealed := base64.StdEncoding.EncodeToString([]byte(binding + "|" + plaintext))
+	writeVaultData(w, map[string]interface{}{
+		"ciphertext":  fmt.Sprintf("vault:v%d:%s", version, sealed),
+		"key_version": version,
+	})
+}
+
+func (f *fakeTransit) decrypt(w http.ResponseWriter, key, ciphertext, context string) {
+	plaintext, err := f.open(key, ciphertext, context)
+	if err != nil {
+		writeVaultError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeVaultData(w, map[string]interface{}{"plaintext": plaintext})
+}
+
+// open returns the base64 plaintext of ciphertext produced by key
+func (f *fakeTransit) open(key, ciphertext, context string) (string, error) {
+	var version int
+	var sealed string
+	if _, err := fmt.Sscanf(ciphertext, "vault:v%d:%s", &version, &sealed); err != nil {
+		return "", errors.New("invalid ciphertext: no prefix")
+	}
+
+	f.mu.Lock()
+	latest := f.keys[key]
+	f.mu.Unlock()
+
+	if latest == 0 {
+		return "", errors.New("encryption key not found")
+	}
+
+	binding, err := f.binding(key, context)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	boundTo, plaintext, ok := strings.Cut(string(raw), "|")
+	if err != nil || !ok || boundTo != binding || version > latest {
+		return "", errors.New("cipher: message authentication failed")
+	}
+
+	return plaintext, nil
+}
+
+// fakeRequestIDs numbers the request IDs of successful fake Vault responses
+var fakeRequestIDs atomic.Int64
+
+// writeVaultData writes a successful Vault response
+func writeVaultData(w http.ResponseWriter, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"request_id": fmt.Sprintf("fake-request-%d", fakeRequestIDs.Add(1)),
+		"data":       data,
+	})
+}
+
+// writeVaultError writes a Vault error response
+func writeVaultError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{message}})
+}
@@ -5,18 +5,61 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 )
 
 // HealthServer provides health check endpoints for Kubernetes probes
 type HealthServer struct {
 	server *http.Server
 	logger *slog.Logger
+
+	// name identifies the server in logs
+	name string
 }
 
 // NewHealthServer creates a new health server instance
 func NewHealthServer(addr string, logger *slog.Logger) *HealthServer {
+	return newHTTPServer(addr, "health", logger)
+}
+
+// NewMetricsServer creates an HTTP server dedicated to metrics scraping
+func NewMetricsServer(addr string, logger *slog.Logger) *HealthServer {
+	return newHTTPServer(addr, "metrics", logger)
+}
+
+// NewKeyAdminServer creates an HTTP server dedicated to node key administration, maintenance mode
+// and forced token renewal. Its endpoints are unauthenticated, so addr must be a loopback address
+// (see CheckLoopbackAddr).
+func NewKeyAdminServer(addr string, logger *slog.Logger) *HealthServer {
+	return newHTTPServer(addr, "key-admin", logger)
+}
+
+// CheckLoopbackAddr checks that addr only listens on a loopback interface, so a listener
+// without authentication is reachable from inside the pod alone
+func CheckLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("address %q is not a loopback address", addr)
+	}
+
+	return nil
+}
+
+func newHTTPServer(addr, name string, logger *slog.Logger) *HealthServer {
 	return &HealthServer{
 		server: &http.Server{
 			Addr:         addr,
@@ -25,31 +68,41 @@ func NewHealthServer(addr string, logger *slog.Logger) *HealthServer {
 			IdleTimeout:  60 * time.Second,
 		},
 		logger: logger,
+		name:   name,
 	}
 }
 
-// Start starts the health server
+// Start starts the health server in the background, logging any serve error
 func (hs *HealthServer) Start(handler http.Handler) error {
-	hs.server.Handler = handler
-	hs.logger.Info("Starting health server", "address", hs.server.Addr)
-
 	go func() {
-		if err := hs.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			hs.logger.Error("Health server error", "error", err)
+		if err := hs.Serve(handler); err != nil {
+			hs.logger.Error("HTTP server error", "server", hs.name, "error", err)
 		}
 	}()
 
 	return nil
 }
 
+// Serve runs the server until it is stopped, returning nil after a graceful shutdown
+func (hs *HealthServer) Serve(handler http.Handler) error {
+	hs.server.Handler = handler
+	hs.logger.Info("Starting HTTP server", "server", hs.name, "address", hs.server.Addr)
+
+	if err := hs.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("%s server: %w", hs.name, err)
+	}
+
+	return nil
+}
+
 // Stop gracefully shuts down the health server
 func (hs *HealthServer) Stop(ctx context.Context) error {
-	hs.logger.Info("Stopping health server")
+	hs.logger.Info("Stopping HTTP server", "server", hs.name)
 	return hs.server.Shutdown(ctx)
 }
 
-// CreateHealthHandler creates HTTP handlers for health checks
-func (las *LeaderAwareServer) CreateHealthHandler() http.Handler {
+// CreateHealthHandler creates HTTP handlers for health checks; metrics are served by MetricsHandler
+func (las *LeaderAwareServer) CreateHealthHandler() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Liveness probe - always returns 200 if the process is alive
@@ -64,15 +117,44 @@ func (las *LeaderAwareServer) CreateHealthHandler() http.Handler {
 		w.Header().Set("Content-Type", "text/plain")
 
 		if las.IsReady() {
+			if ok, reason := las.server.vaultReady(r.Context()); !ok {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, reason)
+				return
+			}
+
+			if ok, reason := las.peersReady(r.Context()); !ok {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, reason)
+				return
+			}
+
 			w.WriteHeader(http.StatusOK)
-			fmt.Fprint(w, "ready")
+			fmt.Fprint(w, las.server.readyMessage())
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
+			if las.WarmingUp() {
+				fmt.Fprint(w, "leader warming up (waiting for a successful Vault check)")
+				return
+			}
+
+			if rbacErr := las.electionController.RBACError(); rbacErr != nil {
+				fmt.Fprintf(w, "not leader (lease RBAC error: %v)", rbacErr)
+				return
+			}
+
+			las.mu.RLock()
+			hideLeader := las.hideLeaderIdentity
+			las.mu.RUnlock()
+
 			currentLeader := las.electionController.GetCurrentLeader()
-			if currentLeader != "" {
-				fmt.Fprintf(w, "not leader (current leader: %s)", currentLeader)
-			} else {
+			switch {
+			case currentLeader == "":
 				fmt.Fprint(w, "not leader (no leader elected)")
+			case hideLeader:
+				fmt.Fprint(w, "not leader")
+			default:
+				fmt.Fprintf(w, "not leader (current leader: %s)", currentLeader)
 			}
 		}
 	})
@@ -85,8 +167,12 @@ func (las *LeaderAwareServer) CreateHealthHandler() http.Handler {
 		json.NewEncoder(w).Encode(info)
 	})
 
-	// Metrics endpoint (placeholder for future Prometheus metrics)
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+	return mux
+}
+
+// MetricsHandler serves Prometheus metrics, including leadership state
+func (las *LeaderAwareServer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		info := las.GetLeadershipInfo()
 
@@ -102,13 +188,21 @@ func (las *LeaderAwareServer) CreateHealthHandler() http.Handler {
 		fmt.Fprintf(w, "# HELP kms_leadership_changes_total Total number of leadership changes\n")
 		fmt.Fprintf(w, "# TYPE kms_leadership_changes_total counter\n")
 		fmt.Fprintf(w, "kms_leadership_changes_total %d\n", info.LeadershipChanges)
-	})
 
-	return mux
+		las.updateLeaseMetrics()
+		las.updateTokenMetrics()
+		families, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			las.logger.Warn("Failed to gather metrics", "error", err)
+		}
+		for _, family := range families {
+			expfmt.MetricFamilyToText(w, family)
+		}
+	})
 }
 
-// CreateHealthHandler for regular (non-leader-aware) server
-func (s *Server) CreateHealthHandler() http.Handler {
+// CreateHealthHandler for regular (non-leader-aware) server; metrics are served by MetricsHandler
+func (s *Server) CreateHealthHandler() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Liveness probe - always returns 200 if the process is alive
@@ -118,22 +212,41 @@ func (s *Server) CreateHealthHandler() http.Handler {
 		fmt.Fprint(w, "ok")
 	})
 
-	// Readiness probe - always ready for non-leader-aware mode
+	// Readiness probe - ready unless the optional Vault health check fails
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
+
+		if ok, reason := s.vaultReady(r.Context()); !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, reason)
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprint(w, "ready")
+		fmt.Fprint(w, s.readyMessage())
 	})
 
 	// Basic info endpoint
 	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		info := map[string]interface{}{
+			"mode":         "single-instance",
+			"ready":        true,
+			"sealDegraded": s.SealDegraded(),
+		}
+
+		if keyVersions := s.KeyVersionInfo(); keyVersions != nil {
+			info["keyVersions"] = keyVersions
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"mode": "single-instance",
-			"ready": true,
-		})
+		json.NewEncoder(w).Encode(info)
 	})
 
 	return mux
-}
\ No newline at end of file
+}
+
+// MetricsHandler serves Prometheus metrics
+func (s *Server) MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
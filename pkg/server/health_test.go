@@ -0,0 +1,52 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServer_MetricsHandlerSeparateFromHealth(t *testing.T) {
+	srv := NewServer(nil, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+
+	rec := httptest.NewRecorder()
+	srv.CreateHealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("health handler /metrics status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("metrics handler status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE") {
+		t.Errorf("metrics handler body has no metrics: %q", rec.Body.String())
+	}
+}
+
+func TestCheckLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "127.0.0.1:8082"},
+		{addr: "[::1]:8082"},
+		{addr: "localhost:8082"},
+		{addr: ":8082", wantErr: true},
+		{addr: "0.0.0.0:8082", wantErr: true},
+		{addr: "10.0.0.5:8082", wantErr: true},
+		{addr: "127.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := CheckLoopbackAddr(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("CheckLoopbackAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRequestTimeout bounds how long a single KMS request may take
+const DefaultRequestTimeout = 30 * time.Second
+
+// RecoveryInterceptor returns a unary interceptor that turns a panic in a later interceptor
+// or the handler into an Internal error, so one bad request cannot crash the server
+func RecoveryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	logger = logger.With("component", "recovery")
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				grpcPanics.Inc()
+				logger.ErrorContext(ctx, "Recovered from panic while handling request",
+					"method", info.FullMethod,
+					"panic", r,
+					"stack", string(debug.Stack()))
+
+				resp, err = nil, status.Error(codes.Internal, "Internal Error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// TimeoutInterceptor returns a unary interceptor that cancels the request context after
+// timeout, so a stalled Vault call cannot hold a request forever. 0 disables it.
+func TimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return nil, status.Error(codes.DeadlineExceeded, "request timed out")
+		}
+
+		return resp, err
+	}
+}
+
+// MetricsInterceptor returns a unary interceptor that counts requests by method and result
+// code and records their duration
+func MetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		grpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		grpcRequestDuration.Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptor(t *testing.T) {
+	interceptor := RecoveryInterceptor(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+
+	before := testutil.ToFloat64(grpcPanics)
+
+	resp, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Errorf("interceptor() = %v, %v, want nil, Internal", resp, err)
+	}
+	if got := testutil.ToFloat64(grpcPanics) - before; got != 1 {
+		t.Errorf("kms_grpc_panics_total increased by %v, want 1", got)
+	}
+
+	// Requests that do not panic pass through untouched
+	resp, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if resp != "ok" || err != nil {
+		t.Errorf("interceptor() = %v, %v, want ok, nil", resp, err)
+	}
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+
+	// Blocks until the request context is cancelled, like a stalled Vault call
+	stalled := func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	_, err := TimeoutInterceptor(10*time.Millisecond)(context.Background(), nil, info, stalled)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("stalled request error = %v, want DeadlineExceeded", err)
+	}
+
+	// A zero timeout adds no deadline
+	_, err = TimeoutInterceptor(0)(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, ok := ctx.Deadline(); ok {
+			return nil, errors.New("unexpected deadline")
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Errorf("disabled timeout error = %v", err)
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	interceptor := MetricsInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Unseal"}
+
+	ok := grpcRequests.WithLabelValues(info.FullMethod, codes.OK.String())
+	denied := grpcRequests.WithLabelValues(info.FullMethod, codes.PermissionDenied.String())
+	okBefore, deniedBefore, countBefore := testutil.ToFloat64(ok), testutil.ToFloat64(denied), histogramCount(t, grpcRequestDuration)
+
+	interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.PermissionDenied, "Forbidden")
+	})
+
+	if got := testutil.ToFloat64(ok) - okBefore; got != 1 {
+		t.Errorf("OK requests increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(denied) - deniedBefore; got != 1 {
+		t.Errorf("PermissionDenied requests increased by %v, want 1", got)
+	}
+	if got := histogramCount(t, grpcRequestDuration) - countBefore; got != 2 {
+		t.Errorf("duration observations increased by %v, want 2", got)
+	}
+}
+
+// histogramCount returns the number of observations recorded by h
+func histogramCount(t *testing.T, h prometheus.Histogram) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
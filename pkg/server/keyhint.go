@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// KeyVersionMetadataKey is the gRPC metadata key carrying the transit key version a client
+// expects its ciphertext to have been sealed with. Transit always decrypts with the version
+// embedded in the ciphertext, so the hint can only be checked, never used to pick a version.
+const KeyVersionMetadataKey = "x-kms-key-version"
+
+// keyVersionHint returns the key version requested in the incoming metadata, or 0 when absent
+func keyVersionHint(ctx context.Context) (int, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(KeyVersionMetadataKey)
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	if len(values) > 1 {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be set at most once", KeyVersionMetadataKey)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(values[0]))
+	if err != nil || version <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must be a positive integer", KeyVersionMetadataKey)
+	}
+
+	return version, nil
+}
+
+// checkKeyVersion checks that a transit ciphertext ("vault:vN:...") was sealed with the
+// given key version
+func checkKeyVersion(ciphertext string, version int) error {
+	rest, ok := strings.CutPrefix(ciphertext, "vault:v")
+	if !ok {
+		return status.Error(codes.InvalidArgument, "ciphertext is not in transit format")
+	}
+
+	current, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return status.Error(codes.InvalidArgument, "ciphertext is not in transit format")
+	}
+
+	sealedWith, err := strconv.Atoi(current)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "ciphertext is not in transit format")
+	}
+
+	if sealedWith != version {
+		return status.Errorf(codes.FailedPrecondition, "ciphertext was sealed with key version %d, not %d", sealedWith, version)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_UnsealKeyVersionHint(t *testing.T) {
+	var gotCiphertext string
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotCiphertext, _ = body["ciphertext"].(string)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"plaintext":"c2VjcmV0"}}`))
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+
+	tests := []struct {
+		name           string
+		hint           []string
+		ciphertext     string
+		wantCode       codes.Code
+		wantCiphertext string
+	}{
+		{
+			name:           "no hint keeps embedded version",
+			ciphertext:     "vault:v3:abcd",
+			wantCode:       codes.OK,
+			wantCiphertext: "vault:v3:abcd",
+		},
+		{
+			name:           "matching hint",
+			hint:           []string{"3"},
+			ciphertext:     "vault:v3:abcd",
+			wantCode:       codes.OK,
+			wantCiphertext: "vault:v3:abcd",
+		},
+		{
+			name:       "mismatching hint rejected",
+			hint:       []string{"1"},
+			ciphertext: "vault:v3:abcd",
+			wantCode:   codes.FailedPrecondition,
+		},
+		{
+			name:       "zero rejected",
+			hint:       []string{"0"},
+			ciphertext: "vault:v3:abcd",
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:       "non-numeric rejected",
+			hint:       []string{"latest"},
+			ciphertext: "vault:v3:abcd",
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:       "repeated hint rejected",
+			hint:       []string{"1", "2"},
+			ciphertext: "vault:v3:abcd",
+			wantCode:   codes.InvalidArgument,
+		},
+		{
+			name:       "non-transit ciphertext rejected",
+			hint:       []string{"1"},
+			ciphertext: "abcd",
+			wantCode:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCiphertext = ""
+
+			md := metadata.MD{}
+			for _, v := range tt.hint {
+				md.Append(KeyVersionMetadataKey, v)
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			resp, err := srv.Unseal(ctx, &kms.Request{NodeUuid: retiredNode, Data: []byte(tt.ciphertext)})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Unseal() code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+
+			if tt.wantCode != codes.OK {
+				if gotCiphertext != "" {
+					t.Errorf("rejected request reached Vault with %q", gotCiphertext)
+				}
+				return
+			}
+
+			if gotCiphertext != tt.wantCiphertext {
+				t.Errorf("decrypt ciphertext = %q, want %q", gotCiphertext, tt.wantCiphertext)
+			}
+			if string(resp.Data) != "secret" {
+				t.Errorf("plaintext = %q, want %q", resp.Data, "secret")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+var (
+	// ErrInvalidNodeKey is returned when a key name is not a node UUID
+	ErrInvalidNodeKey = errors.New("key name is not a valid node UUID")
+
+	// ErrNodeKeyNotFound is returned when the node's transit key does not exist
+	ErrNodeKeyNotFound = errors.New("node transit key not found")
+
+	// ErrKeyDeletionNotAllowed is returned when the key is not configured with deletion_allowed
+	ErrKeyDeletionNotAllowed = errors.New("transit key deletion is not allowed (set deletion_allowed on the key first)")
+)
+
+// nodeKeyValidator checks key names against the UUID format only, so any per-node key qualifies
+var nodeKeyValidator = &validation.UUIDValidator{
+	ValidationMode: validation.ValidationModeRelaxed,
+	AllowHyphens:   true,
+	MaxLength:      36,
+}
+
+// NormalizeNodeUUID trims a node UUID and checks it is UUID-shaped. Only UUID-named keys
+// are per-node keys, so shared keys can never be targeted. Case is kept: Seal creates keys
+// under the UUID exactly as the node sends it, so they are looked up by that exact name.
+func NormalizeNodeUUID(nodeUUID string) (string, error) {
+	normalized := strings.TrimSpace(nodeUUID)
+
+	if err := nodeKeyValidator.ValidateNodeUUID(normalized); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidNodeKey, err)
+	}
+
+	return normalized, nil
+}
+
+// ListNodeKeys returns the names of the per-node (UUID-named) transit keys
+func (s *Server) ListNodeKeys(ctx context.Context) ([]string, error) {
+	client, err := s.vaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Secrets.TransitListKeys(ctx, s.vaultRequestOption)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(res.Data.Keys))
+	for _, key := range res.Data.Keys {
+		if _, err := NormalizeNodeUUID(key); err == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// DeleteNodeKey deletes a retired node's transit key, making its ciphertext unrecoverable.
+// The key must already be configured with deletion_allowed; this method never changes that.
+func (s *Server) DeleteNodeKey(ctx context.Context, nodeUUID string) error {
+	name, err := NormalizeNodeUUID(nodeUUID)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.vaultClient()
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Secrets.TransitReadKey(ctx, name, s.vaultRequestOption)
+	if err != nil {
+		if vault.IsErrorStatus(err, http.StatusNotFound) {
+			return ErrNodeKeyNotFound
+		}
+		return err
+	}
+
+	if allowed, _ := res.Data["deletion_allowed"].(bool); !allowed {
+		return ErrKeyDeletionNotAllowed
+	}
+
+	if _, err := client.Secrets.TransitDeleteKey(ctx, name, s.vaultRequestOption); err != nil {
+		return err
+	}
+
+	s.forgetKeyVersions(name)
+	s.unsealCache.forgetNode(name)
+
+	s.logger.WarnContext(ctx, "Deleted node transit key",
+		"node", validation.SanitizeForLogging(name))
+
+	return nil
+}
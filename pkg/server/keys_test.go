@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+const (
+	retiredNode   = "550e8400-e29b-41d4-a716-446655440000"
+	protectedNode = "6ba7b810-9dad-41d1-80b4-00c04fd430c8"
+
+	// mixedCaseNode sends its UUID upper-cased, so Seal created its key under that name
+	mixedCaseNode = "3F2504E0-4F89-41D3-9A0C-0305E82C3301"
+)
+
+func newKeysTestServer(t *testing.T) (*Server, *[]string) {
+	t.Helper()
+
+	var deleted []string
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/transit/keys"), "/")
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case name == "" && (r.Method == "LIST" || r.URL.Query().Get("list") == "true"):
+			w.Write([]byte(`{"data":{"keys":["` + retiredNode + `","` + protectedNode + `","` + mixedCaseNode + `","shared-key"]}}`))
+		case r.Method == http.MethodGet && (name == retiredNode || name == mixedCaseNode):
+			w.Write([]byte(`{"data":{"name":"` + retiredNode + `","deletion_allowed":true}}`))
+		case r.Method == http.MethodGet && name == protectedNode:
+			w.Write([]byte(`{"data":{"name":"` + protectedNode + `","deletion_allowed":false}}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(vaultServer.Close)
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit"), &deleted
+}
+
+func TestServer_ListNodeKeys(t *testing.T) {
+	srv, _ := newKeysTestServer(t)
+
+	keys, err := srv.ListNodeKeys(context.Background())
+	if err != nil {
+		t.Fatalf("ListNodeKeys() error = %v", err)
+	}
+
+	want := []string{retiredNode, protectedNode, mixedCaseNode}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListNodeKeys() = %v, want %v", keys, want)
+	}
+}
+
+func TestServer_DeleteNodeKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		uuid        string
+		wantErr     error
+		wantDeleted []string
+	}{
+		{
+			name:        "deletion allowed",
+			uuid:        "  " + retiredNode + " ",
+			wantDeleted: []string{retiredNode},
+		},
+		{
+			name:        "mixed-case key by its exact name",
+			uuid:        mixedCaseNode,
+			wantDeleted: []string{mixedCaseNode},
+		},
+		{
+			name:    "case differs from the key name",
+			uuid:    strings.ToUpper(retiredNode),
+			wantErr: ErrNodeKeyNotFound,
+		},
+		{
+			name:    "deletion not allowed",
+			uuid:    protectedNode,
+			wantErr: ErrKeyDeletionNotAllowed,
+		},
+		{
+			name:    "missing key",
+			uuid:    "123e4567-e89b-12d3-a456-426614174000",
+			wantErr: ErrNodeKeyNotFound,
+		},
+		{
+			name:    "non-UUID key",
+			uuid:    "shared-key",
+			wantErr: ErrInvalidNodeKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, deleted := newKeysTestServer(t)
+
+			err := srv.DeleteNodeKey(context.Background(), tt.uuid)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteNodeKey() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(*deleted, tt.wantDeleted) {
+				t.Errorf("deleted keys = %v, want %v", *deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+// KeyVersions describes the version state of a transit key
+type KeyVersions struct {
+	Latest        int `json:"latestVersion"`
+	MinDecryption int `json:"minDecryptionVersion"`
+	MinEncryption int `json:"minEncryptionVersion"`
+}
+
+// KeyVersionPolicy is the minimum key versions enforced on node keys
+type KeyVersionPolicy struct {
+	MinDecryption int `json:"minDecryptionVersion"`
+	MinEncryption int `json:"minEncryptionVersion"`
+}
+
+// keyVersionsTTL is how long observed key versions are trusted. After that the key is read
+// and enforced again, so a key rotated in Vault picks up its new latest version.
+const keyVersionsTTL = 10 * time.Minute
+
+// keyVersionEnforcer raises min_decryption_version/min_encryption_version on node keys
+// and remembers the versions it last observed for each key. An evicted or expired key is
+// simply enforced again the next time it is used.
+type keyVersionEnforcer struct {
+	policy   KeyVersionPolicy
+	observed *nodeCache[observedKeyVersions]
+}
+
+// observedKeyVersions are the versions of a key and when they were read
+type observedKeyVersions struct {
+	versions   KeyVersions
+	observedAt time.Time
+}
+
+// SetMinKeyVersions makes the server enforce minimum decryption/encryption versions on
+// node keys when they are preloaded or first used. Zero leaves a version untouched.
+func (s *Server) SetMinKeyVersions(minDecryption, minEncryption int) {
+	if minDecryption <= 0 && minEncryption <= 0 {
+		s.keyVersions = nil
+		return
+	}
+
+	s.keyVersions = &keyVersionEnforcer{
+		policy:   KeyVersionPolicy{MinDecryption: max(minDecryption, 0), MinEncryption: max(minEncryption, 0)},
+		observed: newNodeCache[observedKeyVersions]("key_versions", s.nodeCacheCapacity),
+	}
+}
+
+// KeyVersionInfo returns the enforced policy and the key versions last observed, or nil if disabled
+func (s *Server) KeyVersionInfo() map[string]interface{} {
+	if s.keyVersions == nil {
+		return nil
+	}
+
+	keys := make(map[string]KeyVersions)
+	for nodeUUID, observed := range s.keyVersions.observed.snapshot() {
+		keys[nodeUUID] = observed.versions
+	}
+
+	return map[string]interface{}{
+		"policy": s.keyVersions.policy,
+		"keys":   keys,
+	}
+}
+
+// forgetKeyVersions drops the versions observed for a key, e.g. once it has been deleted
+func (s *Server) forgetKeyVersions(nodeUUID string) {
+	if s.keyVersions != nil {
+		s.keyVersions.observed.delete(nodeUUID)
+	}
+}
+
+// ensureKeyVersions enforces the version policy on a key the first time it is seen, and
+// again once its observed versions are older than keyVersionsTTL
+func (s *Server) ensureKeyVersions(ctx context.Context, client *vault.Client, nodeUUID string) {
+	if s.keyVersions == nil {
+		return
+	}
+
+	if observed, seen := s.keyVersions.observed.get(nodeUUID); seen && time.Since(observed.observedAt) < keyVersionsTTL {
+		return
+	}
+
+	if _, err := s.EnforceKeyVersions(ctx, client, nodeUUID); err != nil {
+		s.logger.WarnContext(ctx, "Failed to enforce transit key versions",
+			"node", validation.SanitizeForLogging(nodeUUID),
+			"error", err)
+	}
+}
+
+// EnforceKeyVersions raises the key's minimum decryption/encryption versions to the policy.
+// Versions are never lowered and are capped at the key's latest version, as Vault requires.
+func (s *Server) EnforceKeyVersions(ctx context.Context, client *vault.Client, nodeUUID string) (KeyVersions, error) {
+	if s.keyVersions == nil {
+		return KeyVersions{}, nil
+	}
+
+	res, err := client.Secrets.TransitReadKey(ctx, nodeUUID, s.vaultRequestOption)
+	if err != nil {
+		return KeyVersions{}, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	current := KeyVersions{
+		Latest:        intValue(res.Data["latest_version"]),
+		MinDecryption: intValue(res.Data["min_decryption_version"]),
+		MinEncryption: intValue(res.Data["min_encryption_version"]),
+	}
+
+	policy := s.keyVersions.policy
+	target := current
+	target.MinDecryption = max(current.MinDecryption, min(policy.MinDecryption, current.Latest))
+	target.MinEncryption = max(current.MinEncryption, min(policy.MinEncryption, current.Latest))
+
+	if target.MinDecryption < policy.MinDecryption || target.MinEncryption < policy.MinEncryption {
+		s.logger.WarnContext(ctx, "Key version policy exceeds the key's latest version, capping",
+			"node", validation.SanitizeForLogging(nodeUUID),
+			"latestVersion", current.Latest,
+			"minDecryptionVersion", policy.MinDecryption,
+			"minEncryptionVersion", policy.MinEncryption)
+	}
+
+	if target != current {
+		req := schema.TransitConfigureKeyRequest{
+			MinDecryptionVersion: int32(target.MinDecryption),
+			MinEncryptionVersion: int32(target.MinEncryption),
+		}
+
+		if _, err := client.Secrets.TransitConfigureKey(ctx, nodeUUID, req, s.vaultRequestOption); err != nil {
+			return current, fmt.Errorf("failed to configure key versions: %w", err)
+		}
+
+		s.logger.InfoContext(ctx, "Updated transit key minimum versions",
+			"node", validation.SanitizeForLogging(nodeUUID),
+			"minDecryptionVersion", target.MinDecryption,
+			"minEncryptionVersion", target.MinEncryption)
+	}
+
+	// Responses cached before a rotation may no longer be decryptable, so drop them
+	if previous, seen := s.keyVersions.observed.get(nodeUUID); seen && previous.versions != target {
+		s.unsealCache.forgetNode(nodeUUID)
+	}
+
+	s.keyVersions.observed.put(nodeUUID, observedKeyVersions{versions: target, observedAt: time.Now()})
+
+	return target, nil
+}
+
+// intValue converts a numeric Vault response field to an int
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
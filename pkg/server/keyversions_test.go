@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+func TestServer_EnforceKeyVersions(t *testing.T) {
+	tests := []struct {
+		name          string
+		minDecryption int
+		minEncryption int
+		key           string
+		want          KeyVersions
+		wantConfigure bool
+	}{
+		{
+			name:          "raises versions",
+			minDecryption: 2,
+			minEncryption: 3,
+			key:           `{"latest_version":4,"min_decryption_version":1,"min_encryption_version":0}`,
+			want:          KeyVersions{Latest: 4, MinDecryption: 2, MinEncryption: 3},
+			wantConfigure: true,
+		},
+		{
+			name:          "caps at latest version",
+			minDecryption: 5,
+			key:           `{"latest_version":2,"min_decryption_version":1,"min_encryption_version":0}`,
+			want:          KeyVersions{Latest: 2, MinDecryption: 2},
+			wantConfigure: true,
+		},
+		{
+			name:          "never lowers versions",
+			minDecryption: 2,
+			key:           `{"latest_version":6,"min_decryption_version":4,"min_encryption_version":0}`,
+			want:          KeyVersions{Latest: 6, MinDecryption: 4},
+			wantConfigure: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var configured map[string]interface{}
+
+			vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/config") {
+					json.NewDecoder(r.Body).Decode(&configured)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data":` + tt.key + `}`))
+			}))
+			defer vaultServer.Close()
+
+			client, err := vault.New(
+				vault.WithAddress(vaultServer.URL),
+				vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+			srv.SetMinKeyVersions(tt.minDecryption, tt.minEncryption)
+
+			got, err := srv.EnforceKeyVersions(context.Background(), client, retiredNode)
+			if err != nil {
+				t.Fatalf("EnforceKeyVersions() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("EnforceKeyVersions() = %+v, want %+v", got, tt.want)
+			}
+
+			if (configured != nil) != tt.wantConfigure {
+				t.Errorf("key configured = %v, want %v", configured, tt.wantConfigure)
+			}
+
+			info := srv.KeyVersionInfo()
+			if keys := info["keys"].(map[string]KeyVersions); keys[retiredNode] != tt.want {
+				t.Errorf("KeyVersionInfo() keys = %+v, want %+v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_EnsureKeyVersionsExpires(t *testing.T) {
+	var reads int
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"latest_version":2,"min_decryption_version":2,"min_encryption_version":0}}`))
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+	srv.SetMinKeyVersions(2, 0)
+
+	srv.ensureKeyVersions(context.Background(), client, retiredNode)
+	srv.ensureKeyVersions(context.Background(), client, retiredNode)
+	if reads != 1 {
+		t.Fatalf("key reads = %d, want 1 while the observed versions are fresh", reads)
+	}
+
+	// Age the observed versions past the TTL, as after a rotation in Vault
+	observed, _ := srv.keyVersions.observed.get(retiredNode)
+	observed.observedAt = time.Now().Add(-keyVersionsTTL)
+	srv.keyVersions.observed.put(retiredNode, observed)
+
+	srv.ensureKeyVersions(context.Background(), client, retiredNode)
+	if reads != 2 {
+		t.Errorf("key reads = %d, want 2 once the observed versions expired", reads)
+	}
+
+	srv.forgetKeyVersions(retiredNode)
+	if _, ok := srv.KeyVersionInfo()["keys"].(map[string]KeyVersions)[retiredNode]; ok {
+		t.Error("Expected forgotten key versions to be dropped")
+	}
+}
+
+func TestServer_SetMinKeyVersionsDisabled(t *testing.T) {
+	srv := NewServer(nil, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+	srv.SetMinKeyVersions(0, 0)
+
+	if info := srv.KeyVersionInfo(); info != nil {
+		t.Errorf("KeyVersionInfo() = %v, want nil when disabled", info)
+	}
+}
@@ -9,10 +9,12 @@ import (
 
 	"github.com/siderolabs/kms-client/api/kms"
 	"github.com/soulkyu/talos-kms-vault/pkg/leaderelection"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
 )
 
+// warmupRetryInterval spaces Vault checks during the readiness warmup
+const warmupRetryInterval = 500 * time.Millisecond
+
 // LeaderAwareServer wraps the KMS server with leader election capabilities
 type LeaderAwareServer struct {
 	kms.UnimplementedKMSServiceServer
@@ -24,6 +26,38 @@ type LeaderAwareServer struct {
 	mu       sync.RWMutex
 	isLeader bool
 	isActive bool
+
+	// servingDelay postpones activation after acquiring leadership so the
+	// previous leader's lease is guaranteed to have expired
+	servingDelay  time.Duration
+	activationGen uint64
+
+	// afterFunc schedules the activation at the end of the serving delay
+	afterFunc func(time.Duration, func()) *time.Timer
+
+	// readinessWarmup keeps a new leader unready until a Vault check succeeds or it elapses
+	readinessWarmup time.Duration
+	warmingUp       bool
+
+	// hideLeaderIdentity keeps the leader identity out of not-leader error messages
+	hideLeaderIdentity bool
+
+	// leaderlessFailAfter switches the no-leader error to FailedPrecondition once no leader
+	// has been known for this long (0 always returns Unavailable)
+	leaderlessFailAfter time.Duration
+	leaderlessSince     time.Time
+
+	// minHealthyPeers keeps the leader unready until peerCounter finds this many other
+	// candidates (0 disables the check)
+	minHealthyPeers int
+	peerCounter     PeerCounter
+
+	// tokenStatus reports the Vault token health for the token metrics (nil disables them)
+	tokenStatus AuthStatusReporter
+
+	// preloadUUIDs are node keys to warm the first time this instance becomes leader
+	preloadUUIDs []string
+	preloadOnce  sync.Once
 }
 
 // NewLeaderAwareServer creates a new leader-aware KMS server
@@ -34,6 +68,8 @@ func NewLeaderAwareServer(server *Server, electionController *leaderelection.Ele
 		logger:             logger,
 		isLeader:           false,
 		isActive:           false,
+		leaderlessSince:    time.Now(),
+		afterFunc:          time.AfterFunc,
 	}
 }
 
@@ -61,14 +97,145 @@ func (las *LeaderAwareServer) Stop() {
 	las.electionController.Stop()
 }
 
+// SetServingDelay sets how long a new leader waits before serving requests (0 disables the delay)
+func (las *LeaderAwareServer) SetServingDelay(delay time.Duration) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.servingDelay = delay
+}
+
+// SetReadinessWarmup sets how long a new leader may stay unready while waiting for a
+// successful Vault check (0 disables the warmup)
+func (las *LeaderAwareServer) SetReadinessWarmup(warmup time.Duration) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.readinessWarmup = warmup
+}
+
+// SetHideLeaderIdentity omits the current leader from not-leader errors and from /ready. The
+// identity is then only returned as a structured error detail to clients authenticated with mTLS.
+func (las *LeaderAwareServer) SetHideLeaderIdentity(hide bool) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.hideLeaderIdentity = hide
+}
+
+// SetLeaderlessFailAfter makes requests fail fast with a non-retryable FailedPrecondition
+// once no leader has been elected for this long, instead of Unavailable (0 disables)
+func (las *LeaderAwareServer) SetLeaderlessFailAfter(after time.Duration) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.leaderlessFailAfter = after
+}
+
+// SetPreloadKeys sets the node UUIDs whose transit keys are preloaded on first becoming leader
+func (las *LeaderAwareServer) SetPreloadKeys(uuids []string) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.preloadUUIDs = uuids
+}
+
 // OnBecomeLeader is called when this instance becomes the leader
 func (las *LeaderAwareServer) OnBecomeLeader(ctx context.Context) {
+	las.startPreload()
+	las.recordPromotionToken()
+
 	las.mu.Lock()
 	las.isLeader = true
+	las.activationGen++
+	gen := las.activationGen
+	delay := las.servingDelay
+	warmup := las.readinessWarmup
+	las.warmingUp = warmup > 0
+
+	if warmup > 0 {
+		go las.warmUp(gen, warmup)
+	}
+
+	if delay <= 0 {
+		las.isActive = true
+		las.mu.Unlock()
+
+		las.logger.Info("Became leader - KMS server is now active")
+		return
+	}
+	las.mu.Unlock()
+
+	las.logger.Info("Became leader - waiting before serving requests", "delay", delay)
+
+	las.afterFunc(delay, func() {
+		las.activate(gen)
+	})
+}
+
+// startPreload warms the configured transit keys in the background, once per process
+func (las *LeaderAwareServer) startPreload() {
+	las.mu.RLock()
+	uuids := las.preloadUUIDs
+	las.mu.RUnlock()
+
+	if len(uuids) == 0 {
+		return
+	}
+
+	las.preloadOnce.Do(func() {
+		go las.server.PreloadKeys(context.Background(), uuids)
+	})
+}
+
+// activate marks the server active if leadership has not changed since the given generation
+func (las *LeaderAwareServer) activate(gen uint64) {
+	las.mu.Lock()
+	if !las.isLeader || las.activationGen != gen {
+		las.mu.Unlock()
+		las.logger.Info("Leadership changed during serving delay - not activating")
+		return
+	}
 	las.isActive = true
 	las.mu.Unlock()
 
-	las.logger.Info("Became leader - KMS server is now active")
+	las.logger.Info("Serving delay elapsed - KMS server is now active")
+}
+
+// warmUp retries the Vault check until it succeeds or the warmup elapses, then lets
+// readiness follow the regular checks if leadership has not changed since gen
+func (las *LeaderAwareServer) warmUp(gen uint64, warmup time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), warmup)
+	defer cancel()
+
+	ticker := time.NewTicker(warmupRetryInterval)
+	defer ticker.Stop()
+
+	err := las.server.CheckVault(ctx)
+	for err != nil && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			err = las.server.CheckVault(ctx)
+		}
+	}
+
+	las.mu.Lock()
+	if las.activationGen != gen {
+		las.mu.Unlock()
+		return
+	}
+	las.warmingUp = false
+	las.mu.Unlock()
+
+	if err != nil {
+		las.logger.Warn("Vault check did not succeed during readiness warmup - readiness now follows the regular checks",
+			"warmup", warmup,
+			"error", err)
+		return
+	}
+
+	las.logger.Info("Vault check succeeded - readiness warmup complete")
 }
 
 // OnLoseLeadership is called when this instance loses leadership
@@ -76,6 +243,8 @@ func (las *LeaderAwareServer) OnLoseLeadership() {
 	las.mu.Lock()
 	las.isLeader = false
 	las.isActive = false
+	las.warmingUp = false
+	las.activationGen++
 	las.mu.Unlock()
 
 	las.logger.Info("Lost leadership - KMS server is now passive")
@@ -83,13 +252,32 @@ func (las *LeaderAwareServer) OnLoseLeadership() {
 
 // OnLeaderChange is called when the leader changes
 func (las *LeaderAwareServer) OnLeaderChange(leader string) {
+	las.leaderlessFor(leader)
 	las.logger.Info("Leader changed", "currentLeader", leader)
 }
 
+// leaderlessFor tracks when the cluster last had no known leader and returns how long it
+// has been leaderless (0 while a leader is known)
+func (las *LeaderAwareServer) leaderlessFor(currentLeader string) time.Duration {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	if currentLeader != "" {
+		las.leaderlessSince = time.Time{}
+		return 0
+	}
+
+	if las.leaderlessSince.IsZero() {
+		las.leaderlessSince = time.Now()
+	}
+
+	return time.Since(las.leaderlessSince)
+}
+
 // Seal implements the KMS Seal operation (leader-only)
 func (las *LeaderAwareServer) Seal(ctx context.Context, request *kms.Request) (*kms.Response, error) {
-	if !las.checkLeadership() {
-		return nil, las.createNotLeaderError()
+	if err := las.checkLeadership(ctx); err != nil {
+		return nil, err
 	}
 
 	las.logger.Debug("Processing seal request as leader")
@@ -98,8 +286,8 @@ func (las *LeaderAwareServer) Seal(ctx context.Context, request *kms.Request) (*
 
 // Unseal implements the KMS Unseal operation (leader-only)
 func (las *LeaderAwareServer) Unseal(ctx context.Context, request *kms.Request) (*kms.Response, error) {
-	if !las.checkLeadership() {
-		return nil, las.createNotLeaderError()
+	if err := las.checkLeadership(ctx); err != nil {
+		return nil, err
 	}
 
 	las.logger.Debug("Processing unseal request as leader")
@@ -111,27 +299,59 @@ func (las *LeaderAwareServer) IsReady() bool {
 	las.mu.RLock()
 	defer las.mu.RUnlock()
 
-	return las.isActive
+	return las.isActive && !las.warmingUp
 }
 
-// checkLeadership verifies if this instance can process requests
-func (las *LeaderAwareServer) checkLeadership() bool {
+// WarmingUp reports whether a new leader is still waiting for its first successful Vault check
+func (las *LeaderAwareServer) WarmingUp() bool {
 	las.mu.RLock()
 	defer las.mu.RUnlock()
 
-	return las.isLeader && las.isActive
+	return las.warmingUp
+}
+
+// checkLeadership verifies if this instance can process requests. While the election
+// controller and this server disagree about leadership (the callbacks for a change have
+// not run yet), requests are rejected as a transition rather than racing the change.
+func (las *LeaderAwareServer) checkLeadership(ctx context.Context) error {
+	inTransition := las.electionController.InTransition()
+	controllerLeader := las.electionController.IsLeader()
+
+	las.mu.RLock()
+	isLeader, isActive, hideLeader := las.isLeader, las.isActive, las.hideLeaderIdentity
+	las.mu.RUnlock()
+
+	if inTransition || isLeader != controllerLeader {
+		return leaderelection.TransitionError()
+	}
+
+	if !isLeader || !isActive {
+		return las.createNotLeaderError(ctx, hideLeader)
+	}
+
+	return nil
 }
 
 // createNotLeaderError creates an appropriate error when not the leader
-func (las *LeaderAwareServer) createNotLeaderError() error {
+func (las *LeaderAwareServer) createNotLeaderError(ctx context.Context, hideLeader bool) error {
 	currentLeader := las.electionController.GetCurrentLeader()
+	retryDelay := las.electionController.RetryPeriod()
+
+	leaderless := las.leaderlessFor(currentLeader)
 
-	if currentLeader == "" {
-		return status.Error(codes.Unavailable, "No leader elected - service unavailable")
+	las.mu.RLock()
+	failAfter := las.leaderlessFailAfter
+	las.mu.RUnlock()
+
+	if currentLeader == "" && failAfter > 0 && leaderless >= failAfter {
+		return leaderelection.LeaderlessError(leaderless)
 	}
 
-	return status.Errorf(codes.Unavailable,
-		"Not the leader - current leader is %s", currentLeader)
+	if hideLeader {
+		return leaderelection.NotLeaderErrorWithoutIdentity(currentLeader, retryDelay, validation.IsMutualTLS(ctx))
+	}
+
+	return leaderelection.NotLeaderError(currentLeader, retryDelay)
 }
 
 // GetLeadershipInfo returns information about the current leadership state
@@ -149,7 +369,21 @@ func (las *LeaderAwareServer) GetLeadershipInfo() LeadershipInfo {
 		AcquisitionErrors: metrics.AcquisitionErrors,
 		RenewalErrors:     metrics.RenewalErrors,
 		LastLeaderChange:  metrics.LastLeaderChange,
+		History:           metrics.History,
+	}
+}
+
+// updateLeaseMetrics refreshes the lease renew age gauge from the last observed lease
+func (las *LeaderAwareServer) updateLeaseMetrics() {
+	leaseRenewAge.Reset()
+
+	leaseInfo := las.electionController.GetLastLeaseInfo()
+	// A released lease has a renew time (the release) but no holder to report
+	if leaseInfo == nil || leaseInfo.RenewTime.IsZero() || leaseInfo.HolderIdentity == "" {
+		return
 	}
+
+	leaseRenewAge.WithLabelValues(leaseInfo.HolderIdentity).Set(time.Since(leaseInfo.RenewTime).Seconds())
 }
 
 // LeadershipInfo contains information about the leadership state
@@ -161,4 +395,7 @@ type LeadershipInfo struct {
 	AcquisitionErrors int64     `json:"acquisitionErrors"`
 	RenewalErrors     int64     `json:"renewalErrors"`
 	LastLeaderChange  time.Time `json:"lastLeaderChange"`
+
+	// History lists recent leadership transitions, oldest first
+	History []leaderelection.LeadershipTransition `json:"history"`
 }
@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/siderolabs/kms-client/api/kms"
+	"github.com/soulkyu/talos-kms-vault/pkg/leaderelection"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newTestLeaderAwareServer() *LeaderAwareServer {
+	return NewLeaderAwareServer(nil, nil, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+// fakeTimers records the functions scheduled by afterFunc so tests fire them without sleeping
+type fakeTimers struct {
+	fns []func()
+}
+
+func (f *fakeTimers) afterFunc(_ time.Duration, fn func()) *time.Timer {
+	f.fns = append(f.fns, fn)
+	return nil
+}
+
+// fire runs the i-th scheduled function
+func (f *fakeTimers) fire(t *testing.T, i int) {
+	t.Helper()
+
+	if i >= len(f.fns) {
+		t.Fatalf("timer %d was never scheduled (%d scheduled)", i, len(f.fns))
+	}
+	f.fns[i]()
+}
+
+func TestLeaderAwareServer_ServingDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		loseBefore bool
+		wantActive bool
+	}{
+		{
+			name:       "no delay activates immediately",
+			delay:      0,
+			wantActive: true,
+		},
+		{
+			name:       "delay activates after elapsing",
+			delay:      20 * time.Millisecond,
+			wantActive: true,
+		},
+		{
+			name:       "losing leadership during delay cancels activation",
+			delay:      20 * time.Millisecond,
+			loseBefore: true,
+			wantActive: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timers := &fakeTimers{}
+			las := newTestLeaderAwareServer()
+			las.afterFunc = timers.afterFunc
+			las.SetServingDelay(tt.delay)
+
+			las.OnBecomeLeader(context.Background())
+
+			if tt.delay > 0 && las.IsReady() {
+				t.Fatal("Expected server to be inactive during the serving delay")
+			}
+
+			if tt.loseBefore {
+				las.OnLoseLeadership()
+			}
+
+			if tt.delay > 0 {
+				timers.fire(t, 0)
+			}
+
+			if got := las.IsReady(); got != tt.wantActive {
+				t.Errorf("IsReady() = %v, want %v", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestLeaderAwareServer_ServingDelayReacquire(t *testing.T) {
+	timers := &fakeTimers{}
+	las := newTestLeaderAwareServer()
+	las.afterFunc = timers.afterFunc
+	las.SetServingDelay(50 * time.Millisecond)
+
+	// A stale activation from a previous term must not activate the new term early
+	las.OnBecomeLeader(context.Background())
+	las.OnLoseLeadership()
+	las.OnBecomeLeader(context.Background())
+	timers.fire(t, 0)
+
+	if las.IsReady() {
+		t.Fatal("Expected stale activation to be ignored")
+	}
+
+	timers.fire(t, 1)
+
+	if !las.IsReady() {
+		t.Error("Expected server to be active after the new serving delay")
+	}
+}
+
+func TestLeaderAwareServer_ReadinessWarmup(t *testing.T) {
+	var healthy atomic.Bool
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"initialized":true,"sealed":true}`))
+			return
+		}
+		w.Write([]byte(`{"initialized":true,"sealed":false}`))
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	t.Run("ready once vault check succeeds", func(t *testing.T) {
+		healthy.Store(false)
+		las := NewLeaderAwareServer(NewServer(client, logger, "transit"), nil, logger)
+		las.SetReadinessWarmup(time.Minute)
+
+		las.OnBecomeLeader(context.Background())
+		time.Sleep(50 * time.Millisecond)
+
+		if las.IsReady() || !las.WarmingUp() {
+			t.Fatal("Expected leader to stay unready while Vault checks fail")
+		}
+
+		healthy.Store(true)
+		deadline := time.Now().Add(2 * time.Second)
+		for !las.IsReady() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if !las.IsReady() {
+			t.Error("Expected leader to become ready after a successful Vault check")
+		}
+	})
+
+	t.Run("ready once warmup elapses", func(t *testing.T) {
+		healthy.Store(false)
+		las := NewLeaderAwareServer(NewServer(client, logger, "transit"), nil, logger)
+		las.SetReadinessWarmup(50 * time.Millisecond)
+
+		las.OnBecomeLeader(context.Background())
+		time.Sleep(150 * time.Millisecond)
+
+		if !las.IsReady() {
+			t.Error("Expected warmup to end after the window elapsed")
+		}
+	})
+}
+
+// scriptedLock reports lease ownership as set by the test
+type scriptedLock struct {
+	held atomic.Bool
+}
+
+func (l *scriptedLock) AcquireLease(ctx context.Context) (bool, *leaderelection.LeaseInfo, error) {
+	holder := "other"
+	if l.held.Load() {
+		holder = "self"
+	}
+
+	return l.held.Load(), &leaderelection.LeaseInfo{HolderIdentity: holder}, nil
+}
+
+func (l *scriptedLock) ReleaseLease(ctx context.Context) error {
+	return nil
+}
+
+func TestLeaderAwareServer_RejectsDuringTransition(t *testing.T) {
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"ciphertext":"vault:v1:abcd"}}`))
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// Callbacks block until the test releases them, holding the transition window open
+	gate := make(chan struct{})
+	var las *LeaderAwareServer
+	callbacks := leaderelection.LeaderElectionCallbacks{
+		OnStartedLeading: func(ctx context.Context) {
+			<-gate
+			las.OnBecomeLeader(ctx)
+		},
+		OnStoppedLeading: func() {
+			<-gate
+			las.OnLoseLeadership()
+		},
+	}
+
+	config := leaderelection.DefaultLeaseConfig()
+	config.Identity = "self"
+	config.RetryPeriod = time.Millisecond
+
+	lock := &scriptedLock{}
+	ec := leaderelection.NewElectionControllerWithLock(config, lock, callbacks, logger)
+	las = NewLeaderAwareServer(NewServer(client, logger, "transit"), ec, logger)
+
+	if err := ec.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Stop()
+
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	seal := func() error {
+		_, err := las.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+		return err
+	}
+
+	expectTransition := func(phase string) {
+		t.Helper()
+		err := seal()
+		st := status.Convert(err)
+		if st.Code() != codes.Unavailable || !strings.Contains(st.Message(), "transition") {
+			t.Fatalf("%s: Seal() error = %v, want Unavailable transition error", phase, err)
+		}
+		if len(st.Details()) == 0 {
+			t.Errorf("%s: transition error has no retry hint", phase)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		lock.held.Store(true)
+		waitFor("leadership", ec.IsLeader)
+		expectTransition("becoming leader")
+
+		gate <- struct{}{}
+		waitFor("activation", func() bool { return !ec.InTransition() })
+		if err := seal(); err != nil {
+			t.Fatalf("Seal() as active leader error = %v", err)
+		}
+
+		lock.held.Store(false)
+		waitFor("loss of leadership", func() bool { return !ec.IsLeader() })
+		expectTransition("losing leadership")
+
+		gate <- struct{}{}
+		waitFor("deactivation", func() bool { return !ec.InTransition() })
+		if code := status.Code(seal()); code != codes.Unavailable {
+			t.Fatalf("Seal() as follower code = %v, want Unavailable", code)
+		}
+	}
+}
+
+func TestLeaderAwareServer_HideLeaderIdentity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	config := leaderelection.DefaultLeaseConfig()
+	config.Identity = "self"
+	config.RetryPeriod = time.Millisecond
+
+	ec := leaderelection.NewElectionControllerWithLock(config, &scriptedLock{}, leaderelection.LeaderElectionCallbacks{}, logger)
+	if err := ec.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer ec.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ec.GetCurrentLeader() != "other" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the leader to be observed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mtlsCtx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{{}}},
+			},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		hide        bool
+		ctx         context.Context
+		wantMessage string
+		wantDetail  bool
+		wantReady   string
+	}{
+		{
+			name:        "identity shown by default",
+			ctx:         context.Background(),
+			wantMessage: "Not the leader - current leader is other",
+			wantReady:   "not leader (current leader: other)",
+		},
+		{
+			name:        "identity hidden without mTLS",
+			hide:        true,
+			ctx:         context.Background(),
+			wantMessage: "Not the leader - service unavailable",
+			wantReady:   "not leader",
+		},
+		{
+			name:        "identity hidden but detailed over mTLS",
+			hide:        true,
+			ctx:         mtlsCtx,
+			wantMessage: "Not the leader - service unavailable",
+			wantDetail:  true,
+			wantReady:   "not leader",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			las := NewLeaderAwareServer(nil, ec, logger)
+			las.SetHideLeaderIdentity(tt.hide)
+
+			_, err := las.Seal(tt.ctx, &kms.Request{NodeUuid: retiredNode})
+			st := status.Convert(err)
+			if st.Code() != codes.Unavailable {
+				t.Fatalf("Seal() code = %v, want Unavailable", st.Code())
+			}
+			if st.Message() != tt.wantMessage {
+				t.Errorf("message = %q, want %q", st.Message(), tt.wantMessage)
+			}
+
+			var leader string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					leader = info.Metadata["leader"]
+				}
+			}
+			if gotDetail := leader == "other"; gotDetail != tt.wantDetail {
+				t.Errorf("leader detail = %q, want detail %v", leader, tt.wantDetail)
+			}
+
+			rec := httptest.NewRecorder()
+			las.CreateHealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if rec.Body.String() != tt.wantReady {
+				t.Errorf("/ready body = %q, want %q", rec.Body.String(), tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestLeaderAwareServer_LeaderlessFailAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// The controller is never started, so no leader is ever known
+	ec := leaderelection.NewElectionControllerWithLock(leaderelection.DefaultLeaseConfig(), &scriptedLock{}, leaderelection.LeaderElectionCallbacks{}, logger)
+
+	tests := []struct {
+		name       string
+		failAfter  time.Duration
+		leaderless time.Duration
+		leader     string
+		wantCode   codes.Code
+	}{
+		{name: "disabled by default", leaderless: time.Hour, wantCode: codes.Unavailable},
+		{name: "not leaderless long enough", failAfter: time.Minute, leaderless: time.Second, wantCode: codes.Unavailable},
+		{name: "leaderless too long", failAfter: time.Minute, leaderless: 2 * time.Minute, wantCode: codes.FailedPrecondition},
+		{name: "new leader resets the clock", failAfter: time.Minute, leaderless: 2 * time.Minute, leader: "other", wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			las := NewLeaderAwareServer(nil, ec, logger)
+			las.SetLeaderlessFailAfter(tt.failAfter)
+			las.leaderlessSince = time.Now().Add(-tt.leaderless)
+
+			if tt.leader != "" {
+				// A leader came and went, the clock restarts when it is released
+				las.OnLeaderChange(tt.leader)
+				las.OnLeaderChange("")
+			}
+
+			_, err := las.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode})
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Fatalf("Seal() code = %v (%q), want %v", st.Code(), st.Message(), tt.wantCode)
+			}
+
+			for _, detail := range st.Details() {
+				if _, ok := detail.(*errdetails.RetryInfo); ok && tt.wantCode == codes.FailedPrecondition {
+					t.Error("FailedPrecondition carries a retry delay, want none")
+				}
+			}
+		})
+	}
+}
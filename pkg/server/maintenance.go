@@ -0,0 +1,149 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultMaintenanceMessage is returned to Seal and Unseal callers during maintenance
+const DefaultMaintenanceMessage = "KMS is under maintenance, try again later"
+
+// MaintenanceRetryDelay is the RetryInfo delay suggested to callers during maintenance
+const MaintenanceRetryDelay = 30 * time.Second
+
+// maintenanceMode rejects every Seal and Unseal up front while enabled, so requests don't
+// reach a Vault that is down for scheduled maintenance. Unlike a drain, requests already
+// running are not waited for and nothing is finished first.
+type maintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	since   time.Time
+	message string
+}
+
+// MaintenanceStatus is returned by the maintenance endpoint
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Since   *time.Time `json:"since,omitempty"`
+	Message string     `json:"message"`
+}
+
+// SetMaintenanceMessage sets the message returned to callers during maintenance
+// (DefaultMaintenanceMessage when empty)
+func (s *Server) SetMaintenanceMessage(message string) {
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+
+	s.maintenance.message = message
+}
+
+// SetMaintenance enables or disables maintenance mode
+func (s *Server) SetMaintenance(enabled bool) {
+	m := s.maintenance
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.enabled == enabled {
+		return
+	}
+
+	m.enabled = enabled
+	m.since = time.Now()
+
+	if enabled {
+		maintenanceEnabled.Set(1)
+		s.logger.Warn("Maintenance mode enabled, rejecting Seal and Unseal", "message", m.messageLocked())
+	} else {
+		maintenanceEnabled.Set(0)
+		s.logger.Info("Maintenance mode disabled, serving Seal and Unseal again")
+	}
+}
+
+// MaintenanceStatus reports whether maintenance mode is enabled
+func (s *Server) MaintenanceStatus() MaintenanceStatus {
+	m := s.maintenance
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := MaintenanceStatus{Enabled: m.enabled, Message: m.messageLocked()}
+	if m.enabled {
+		since := m.since
+		st.Since = &since
+	}
+
+	return st
+}
+
+// maintenanceError returns the Unavailable error for Seal and Unseal during maintenance,
+// or nil when maintenance mode is disabled
+func (s *Server) maintenanceError() error {
+	m := s.maintenance
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.enabled {
+		return nil
+	}
+
+	st := status.New(codes.Unavailable, m.messageLocked())
+
+	withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(MaintenanceRetryDelay)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return withRetry.Err()
+}
+
+// messageLocked returns the configured message; m.mu must be held
+func (m *maintenanceMode) messageLocked() string {
+	if m.message == "" {
+		return DefaultMaintenanceMessage
+	}
+
+	return m.message
+}
+
+// MaintenanceController is implemented by the server to toggle maintenance mode
+type MaintenanceController interface {
+	SetMaintenance(enabled bool)
+	MaintenanceStatus() MaintenanceStatus
+}
+
+// NewMaintenanceHandler creates a handler reporting maintenance mode on GET and switching it
+// on POST /maintenance?enabled=true or off with enabled=false
+func NewMaintenanceHandler(controller MaintenanceController, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, controller.MaintenanceStatus())
+
+		case http.MethodPost:
+			enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+			if err != nil {
+				http.Error(w, "enabled must be true or false", http.StatusBadRequest)
+				return
+			}
+
+			logger.Warn("Maintenance mode change requested", "enabled", enabled, "remote", r.RemoteAddr)
+
+			controller.SetMaintenance(enabled)
+			writeJSON(w, http.StatusOK, controller.MaintenanceStatus())
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
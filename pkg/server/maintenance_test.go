@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Maintenance(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetMaintenanceMessage("vault upgrade in progress")
+
+	sealed, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	srv.SetMaintenance(true)
+	requestsBefore := transit.requestCount()
+
+	calls := map[string]func() error{
+		"Seal": func() error {
+			_, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+			return err
+		},
+		"Unseal": func() error {
+			_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: sealed.Data})
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		st := status.Convert(call())
+		if st.Code() != codes.Unavailable || st.Message() != "vault upgrade in progress" {
+			t.Errorf("%s() during maintenance = %v %q, want Unavailable with the maintenance message", name, st.Code(), st.Message())
+		}
+
+		var retryInfo *errdetails.RetryInfo
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok {
+				retryInfo = info
+			}
+		}
+		if retryInfo == nil || retryInfo.RetryDelay.AsDuration() != MaintenanceRetryDelay {
+			t.Errorf("%s() RetryInfo = %v, want %v", name, retryInfo, MaintenanceRetryDelay)
+		}
+	}
+
+	if got := transit.requestCount() - requestsBefore; got != 0 {
+		t.Errorf("Vault received %d requests during maintenance, want 0", got)
+	}
+
+	srv.SetMaintenance(false)
+	for name, call := range calls {
+		if err := call(); err != nil {
+			t.Errorf("%s() after maintenance error = %v", name, err)
+		}
+	}
+}
+
+func TestServer_MaintenanceBeforeUnsealCache(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetUnsealCache(NewUnsealCache(time.Minute, 0))
+
+	sealed, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	request := &kms.Request{NodeUuid: retiredNode, Data: sealed.Data}
+	if _, err := srv.Unseal(context.Background(), request); err != nil {
+		t.Fatalf("Unseal() error = %v", err)
+	}
+
+	srv.SetMaintenance(true)
+	if _, err := srv.Unseal(context.Background(), request); status.Code(err) != codes.Unavailable {
+		t.Errorf("cached Unseal() during maintenance error = %v, want Unavailable", err)
+	}
+
+	srv.SetMaintenance(false)
+	if _, err := srv.Unseal(context.Background(), request); err != nil {
+		t.Errorf("cached Unseal() after maintenance error = %v", err)
+	}
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	srv := NewServer(nil, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+	handler := NewMaintenanceHandler(srv, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		wantCode    int
+		wantEnabled bool
+	}{
+		{name: "status", method: http.MethodGet, target: "/maintenance", wantCode: http.StatusOK},
+		{name: "enable", method: http.MethodPost, target: "/maintenance?enabled=true", wantCode: http.StatusOK, wantEnabled: true},
+		{name: "invalid value", method: http.MethodPost, target: "/maintenance?enabled=soon", wantCode: http.StatusBadRequest, wantEnabled: true},
+		{name: "missing value", method: http.MethodPost, target: "/maintenance", wantCode: http.StatusBadRequest, wantEnabled: true},
+		{name: "wrong method", method: http.MethodDelete, target: "/maintenance", wantCode: http.StatusMethodNotAllowed, wantEnabled: true},
+		{name: "disable", method: http.MethodPost, target: "/maintenance?enabled=false", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			if rec.Code == http.StatusOK {
+				var got MaintenanceStatus
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if got.Enabled != tt.wantEnabled || got.Message != DefaultMaintenanceMessage {
+					t.Errorf("response = %+v, want enabled %v with the default message", got, tt.wantEnabled)
+				}
+				if (got.Since != nil) != tt.wantEnabled {
+					t.Errorf("since = %v, want it set only while enabled", got.Since)
+				}
+			}
+
+			if srv.MaintenanceStatus().Enabled != tt.wantEnabled {
+				t.Errorf("maintenance enabled = %v, want %v", !tt.wantEnabled, tt.wantEnabled)
+			}
+		})
+	}
+}
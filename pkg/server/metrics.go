@@ -0,0 +1,155 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var vaultSealed = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_vault_sealed",
+	Help: "Whether the Vault server reports itself as sealed (1) or unsealed (0)",
+})
+
+var sealDegraded = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_seal_degraded",
+	Help: "Whether Seal is degraded after repeated Vault permission denials (1) while Unseal is still served",
+})
+
+var healthyPeers = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_leader_healthy_peers",
+	Help: "Number of other lease candidates found by the leader's last readiness check",
+})
+
+var transitKeyMissing = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_transit_key_missing_total",
+	Help: "Total number of Seal and Unseal requests that failed because the node's transit key does not exist",
+}, []string{"operation"})
+
+var maintenanceEnabled = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "kms_maintenance_mode",
+	Help: "Whether maintenance mode is enabled (1), rejecting every Seal and Unseal",
+})
+
+var (
+	vaultStandby = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_vault_standby",
+		Help: "Whether the Vault node reports itself as a standby (1) or not (0)",
+	})
+
+	vaultPerformanceStandby = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_vault_performance_standby",
+		Help: "Whether the Vault node reports itself as a performance standby (1) or not (0)",
+	})
+)
+
+var unsealFormats = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_unseal_format_total",
+	Help: "Total number of Unseal requests by sealed data format (raw or vN)",
+}, []string{"format"})
+
+var nodeContextMismatches = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_node_context_mismatch_total",
+	Help: "Total number of Unseal requests rejected because the sealed data is bound to a different node",
+})
+
+var unsealExpiredCiphertext = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "kms_unseal_expired_ciphertext_total",
+	Help: "Total number of Unseal requests rejected because the data was sealed longer ago than the maximum ciphertext age",
+})
+
+var (
+	nodeCacheSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kms_node_cache_size",
+		Help: "Number of entries in each bounded per-node cache",
+	}, []string{"cache"})
+
+	nodeCacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_node_cache_evictions_total",
+		Help: "Total number of least recently used entries evicted from each bounded per-node cache",
+	}, []string{"cache"})
+)
+
+var leaseRenewAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "kms_lease_renew_age_seconds",
+	Help: "Seconds since the leader election lease was last renewed by its holder",
+}, []string{"holder"})
+
+var (
+	tokenHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kms_auth_token_healthy",
+		Help: "Whether this instance holds a valid, renewing Vault token (1) or not (0), by its current role",
+	}, []string{"role"})
+
+	leaderPromotions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_leader_promotions_total",
+		Help: "Total number of times this instance became leader, by whether its Vault token was healthy at that instant",
+	}, []string{"token"})
+)
+
+var (
+	globalRateLimitTokens = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_global_rate_limit_tokens",
+		Help: "Tokens available in the global rate limit bucket as of the last request",
+	})
+
+	globalRateLimitRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "kms_global_rate_limit_rejections_total",
+		Help: "Total number of requests rejected by the global rate limit",
+	})
+)
+
+var (
+	inflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_inflight_requests",
+		Help: "Number of gRPC requests currently being handled",
+	})
+
+	goroutines = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_goroutines",
+		Help: "Number of goroutines, sampled periodically",
+	})
+)
+
+var (
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_grpc_requests_total",
+		Help: "Total number of gRPC requests handled, by method and result code",
+	}, []string{"method", "code"})
+
+	// grpcRequestDuration tracks request latency (1ms up to ~65s)
+	grpcRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kms_grpc_request_duration_seconds",
+		Help:    "Duration of gRPC requests in seconds",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 17),
+	})
+
+	grpcPanics = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "kms_grpc_panics_total",
+		Help: "Total number of panics recovered while handling gRPC requests",
+	})
+)
+
+var talosVersionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kms_requests_total",
+	Help: "Total number of gRPC requests by client Talos version (vMAJOR.MINOR or unknown) and result code",
+}, []string{"talos_version", "code"})
+
+var (
+	unsealCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_unseal_cache_requests_total",
+		Help: "Total number of Unseal requests seen by the response cache, by result (hit, miss, bypass)",
+	}, []string{"result"})
+
+	unsealCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_unseal_cache_entries",
+		Help: "Number of Unseal responses held in the cache, including expired ones not yet dropped",
+	})
+)
+
+// gaugeBool converts b to a gauge value, 1 for true and 0 for false
+func gaugeBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// MountVerifyMode selects what happens when the transit mount fails startup verification
+type MountVerifyMode string
+
+const (
+	// MountVerifyOff skips the check
+	MountVerifyOff MountVerifyMode = "off"
+	// MountVerifyWarn logs a warning and keeps starting
+	MountVerifyWarn MountVerifyMode = "warn"
+	// MountVerifyFail aborts startup
+	MountVerifyFail MountVerifyMode = "fail"
+)
+
+var (
+	// ErrMountNotFound is returned when no secrets engine is mounted at the mount path
+	ErrMountNotFound = errors.New("no secrets engine mounted at mount path")
+	// ErrMountNotTransit is returned when the mount path holds another secrets engine
+	ErrMountNotTransit = errors.New("mount path is not a transit secrets engine")
+)
+
+// ParseMountVerifyMode parses a mount verification mode
+func ParseMountVerifyMode(value string) (MountVerifyMode, error) {
+	switch mode := MountVerifyMode(value); mode {
+	case MountVerifyOff, MountVerifyWarn, MountVerifyFail:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mount verification mode %q (expected off, warn or fail)", value)
+	}
+}
+
+// VerifyMount checks via sys/mounts that the mount path is a transit secrets engine. A token
+// without access to sys/mounts skips the check; other failures are logged in warn mode and
+// returned in fail mode.
+func (s *Server) VerifyMount(ctx context.Context, mode MountVerifyMode) error {
+	if mode == MountVerifyOff {
+		return nil
+	}
+
+	client, err := s.vaultClient()
+	if err != nil {
+		return err
+	}
+
+	err = verifyTransitMount(ctx, client, s.mountPath)
+
+	var respErr *vault.ResponseError
+	switch {
+	case err == nil:
+		s.logger.Info("Verified transit mount", "mountPath", s.mountPath)
+		return nil
+
+	case errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden:
+		s.logger.Info("Skipping transit mount verification - token cannot read sys/mounts",
+			"mountPath", s.mountPath)
+		return nil
+
+	case mode == MountVerifyFail:
+		return fmt.Errorf("mount verification failed for %q: %w", s.mountPath, err)
+
+	default:
+		s.logger.Warn("Transit mount verification failed - Seal and Unseal will likely fail",
+			"mountPath", s.mountPath,
+			"error", err)
+		return nil
+	}
+}
+
+// verifyTransitMount reads the mount configuration and checks its engine type
+func verifyTransitMount(ctx context.Context, client *vault.Client, mountPath string) error {
+	resp, err := client.System.MountsReadConfiguration(ctx, strings.Trim(mountPath, "/"))
+	if err != nil {
+		var respErr *vault.ResponseError
+		if errors.As(err, &respErr) &&
+			(respErr.StatusCode == http.StatusNotFound || respErr.StatusCode == http.StatusBadRequest) {
+			return ErrMountNotFound
+		}
+		return err
+	}
+
+	if resp.Data.Type != "transit" {
+		return fmt.Errorf("%w (found %q)", ErrMountNotTransit, resp.Data.Type)
+	}
+
+	return nil
+}
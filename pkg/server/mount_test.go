@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+func TestServer_VerifyMount(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		engine  string
+		mode    MountVerifyMode
+		wantErr error
+	}{
+		{name: "transit mount", engine: "transit", mode: MountVerifyFail},
+		{name: "kv mount fails", engine: "kv", mode: MountVerifyFail, wantErr: ErrMountNotTransit},
+		{name: "kv mount warns", engine: "kv", mode: MountVerifyWarn},
+		{name: "missing mount fails", status: http.StatusBadRequest, mode: MountVerifyFail, wantErr: ErrMountNotFound},
+		{name: "forbidden skips", status: http.StatusForbidden, mode: MountVerifyFail},
+		{name: "off skips", engine: "kv", mode: MountVerifyOff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+
+			vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				if tt.status != 0 {
+					writeVaultError(w, tt.status, http.StatusText(tt.status))
+					return
+				}
+				writeVaultData(w, map[string]interface{}{"type": tt.engine})
+			}))
+			defer vaultServer.Close()
+
+			client, err := vault.New(
+				vault.WithAddress(vaultServer.URL),
+				vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+
+			err = srv.VerifyMount(context.Background(), tt.mode)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("VerifyMount() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyMount() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.mode == MountVerifyOff {
+				if gotPath != "" {
+					t.Errorf("off mode queried Vault at %s", gotPath)
+				}
+			} else if gotPath != "/v1/sys/mounts/transit" {
+				t.Errorf("queried %s, want /v1/sys/mounts/transit", gotPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultNodeCacheCapacity bounds per-node state such as the observed key versions
+const DefaultNodeCacheCapacity = 10000
+
+// nodeCache is a bounded map keyed by node UUID (or a key derived from it). Once full, the
+// least recently used entry is evicted, so per-node state stays bounded across a large,
+// churning fleet. kms_node_cache_size and kms_node_cache_evictions_total report each cache
+// under its name.
+type nodeCache[V any] struct {
+	name string
+
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently used at the front
+	items    map[string]*list.Element
+}
+
+type nodeCacheEntry[V any] struct {
+	key   string
+	value V
+}
+
+// newNodeCache creates a node cache holding at most capacity entries
+// (DefaultNodeCacheCapacity when capacity is not positive)
+func newNodeCache[V any](name string, capacity int) *nodeCache[V] {
+	if capacity <= 0 {
+		capacity = DefaultNodeCacheCapacity
+	}
+
+	nodeCacheSize.WithLabelValues(name).Set(0)
+
+	return &nodeCache[V]{
+		name:     name,
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the value for key and marks it as recently used
+func (c *nodeCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*nodeCacheEntry[V]).value, true
+}
+
+// put stores the value for key, evicting the least recently used entries when full
+func (c *nodeCache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*nodeCacheEntry[V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&nodeCacheEntry[V]{key: key, value: value})
+	c.evict()
+}
+
+// delete removes key
+func (c *nodeCache[V]) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+		nodeCacheSize.WithLabelValues(c.name).Set(float64(len(c.items)))
+	}
+}
+
+// deleteFunc removes every key match reports true for
+func (c *nodeCache[V]) deleteFunc(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.items {
+		if match(key) {
+			c.order.Remove(element)
+			delete(c.items, key)
+		}
+	}
+
+	nodeCacheSize.WithLabelValues(c.name).Set(float64(len(c.items)))
+}
+
+// setCapacity changes the capacity, evicting entries if the cache is now over it
+func (c *nodeCache[V]) setCapacity(capacity int) {
+	if capacity <= 0 {
+		capacity = DefaultNodeCacheCapacity
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	c.evict()
+}
+
+// len returns the number of entries
+func (c *nodeCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// snapshot returns a copy of all entries
+func (c *nodeCache[V]) snapshot() map[string]V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make(map[string]V, len(c.items))
+	for key, element := range c.items {
+		entries[key] = element.Value.(*nodeCacheEntry[V]).value
+	}
+
+	return entries
+}
+
+// evict drops least recently used entries until the cache fits its capacity. c.mu must be held.
+func (c *nodeCache[V]) evict() {
+	for len(c.items) > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*nodeCacheEntry[V]).key)
+		nodeCacheEvictions.WithLabelValues(c.name).Inc()
+	}
+
+	nodeCacheSize.WithLabelValues(c.name).Set(float64(len(c.items)))
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNodeCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newNodeCache[int]("test_lru", 2)
+	evictionsBefore := testutil.ToFloat64(nodeCacheEvictions.WithLabelValues("test_lru"))
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+
+	// Reading a makes b the least recently used
+	if v, ok := cache.get("a"); !ok || v != 1 {
+		t.Fatalf("get(a) = %d, %v", v, ok)
+	}
+
+	cache.put("c", 3)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("b still cached, want it evicted as least recently used")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := cache.get(key); !ok || v != want {
+			t.Errorf("get(%s) = %d, %v, want %d", key, v, ok, want)
+		}
+	}
+
+	// Updating an entry does not evict
+	cache.put("a", 10)
+	if v, _ := cache.get("a"); v != 10 || cache.len() != 2 {
+		t.Errorf("get(a) = %d with %d entries after update", v, cache.len())
+	}
+
+	if got := testutil.ToFloat64(nodeCacheEvictions.WithLabelValues("test_lru")) - evictionsBefore; got != 1 {
+		t.Errorf("kms_node_cache_evictions_total increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(nodeCacheSize.WithLabelValues("test_lru")); got != 2 {
+		t.Errorf("kms_node_cache_size = %v, want 2", got)
+	}
+
+	// Shrinking evicts down to the new capacity
+	cache.setCapacity(1)
+	if cache.len() != 1 {
+		t.Errorf("len() = %d after shrinking to 1", cache.len())
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("most recently used entry evicted when shrinking")
+	}
+
+	cache.delete("a")
+	if cache.len() != 0 || testutil.ToFloat64(nodeCacheSize.WithLabelValues("test_lru")) != 0 {
+		t.Error("delete() left the entry or the size gauge behind")
+	}
+}
+
+func TestNodeCache_DefaultCapacity(t *testing.T) {
+	cache := newNodeCache[struct{}]("test_default", 0)
+	if cache.capacity != DefaultNodeCacheCapacity {
+		t.Errorf("capacity = %d, want %d", cache.capacity, DefaultNodeCacheCapacity)
+	}
+}
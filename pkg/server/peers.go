@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"fmt"
+)
+
+// PeerCounter counts the reachable lease candidates other than this instance
+type PeerCounter interface {
+	CountPeers(ctx context.Context) (int, error)
+}
+
+// SetMinHealthyPeers keeps the leader unready unless counter finds at least minPeers other
+// candidates, so a lone survivor of a major outage fails loudly instead of serving alone
+// (0 disables the check)
+func (las *LeaderAwareServer) SetMinHealthyPeers(minPeers int, counter PeerCounter) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.minHealthyPeers = minPeers
+	las.peerCounter = counter
+}
+
+// peersReady reports whether enough other candidates are reachable, with the reason when not
+func (las *LeaderAwareServer) peersReady(ctx context.Context) (bool, string) {
+	las.mu.RLock()
+	minPeers, counter := las.minHealthyPeers, las.peerCounter
+	las.mu.RUnlock()
+
+	if minPeers <= 0 || counter == nil {
+		return true, ""
+	}
+
+	peers, err := counter.CountPeers(ctx)
+	if err != nil {
+		las.logger.Warn("Unable to count healthy peers", "error", err)
+		return false, fmt.Sprintf("unable to count healthy peers: %v", err)
+	}
+
+	healthyPeers.Set(float64(peers))
+
+	if peers < minPeers {
+		return false, fmt.Sprintf("too few healthy peers (%d, need %d)", peers, minPeers)
+	}
+
+	return true, ""
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakePeerCounter reports a fixed number of peers or an error
+type fakePeerCounter struct {
+	peers int
+	err   error
+}
+
+func (f fakePeerCounter) CountPeers(ctx context.Context) (int, error) {
+	return f.peers, f.err
+}
+
+func TestLeaderAwareServer_MinHealthyPeers(t *testing.T) {
+	tests := []struct {
+		name       string
+		minPeers   int
+		counter    PeerCounter
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "disabled", minPeers: 0, counter: fakePeerCounter{}, wantStatus: http.StatusOK},
+		{name: "enough peers", minPeers: 2, counter: fakePeerCounter{peers: 2}, wantStatus: http.StatusOK},
+		{
+			name:       "lone survivor",
+			minPeers:   1,
+			counter:    fakePeerCounter{peers: 0},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "too few healthy peers (0, need 1)",
+		},
+		{
+			name:       "peers can't be counted",
+			minPeers:   1,
+			counter:    fakePeerCounter{err: errors.New("forbidden")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "unable to count healthy peers: forbidden",
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			las := NewLeaderAwareServer(NewServer(nil, logger, "transit"), nil, logger)
+			las.SetMinHealthyPeers(tt.minPeers, tt.counter)
+			las.OnBecomeLeader(context.Background())
+
+			rec := httptest.NewRecorder()
+			las.CreateHealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("/ready status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("/ready body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
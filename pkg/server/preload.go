@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/hashicorp/vault-client-go/schema"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+// PreloadSummary reports the outcome of a transit key preload
+type PreloadSummary struct {
+	Existing int `json:"existing"`
+	Created  int `json:"created"`
+	Failed   int `json:"failed"`
+}
+
+// PreloadKeys verifies that a transit key exists for each node UUID, creating missing ones.
+// Failures are logged and counted but never abort the preload.
+func (s *Server) PreloadKeys(ctx context.Context, uuids []string) PreloadSummary {
+	var summary PreloadSummary
+
+	client, err := s.vaultClient()
+	if err != nil {
+		s.logger.WarnContext(ctx, "Skipping transit key preload, no Vault client", "error", err)
+		summary.Failed = len(uuids)
+		return summary
+	}
+
+	for _, nodeUUID := range uuids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		_, err := client.Secrets.TransitReadKey(ctx, nodeUUID, s.vaultRequestOption)
+		if err == nil {
+			summary.Existing++
+			s.ensureKeyVersions(ctx, client, nodeUUID)
+			continue
+		}
+
+		if !vault.IsErrorStatus(err, http.StatusNotFound) {
+			summary.Failed++
+			s.logger.WarnContext(ctx, "Failed to look up transit key during preload",
+				"node", validation.SanitizeForLogging(nodeUUID),
+				"error", err)
+			continue
+		}
+
+		if _, err := client.Secrets.TransitCreateKey(ctx, nodeUUID, schema.TransitCreateKeyRequest{}, s.vaultRequestOption); err != nil {
+			summary.Failed++
+			s.logger.WarnContext(ctx, "Failed to create transit key during preload",
+				"node", validation.SanitizeForLogging(nodeUUID),
+				"error", err)
+			continue
+		}
+
+		summary.Created++
+		s.ensureKeyVersions(ctx, client, nodeUUID)
+	}
+
+	s.logger.InfoContext(ctx, "Transit key preload completed",
+		"requested", len(uuids),
+		"existing", summary.Existing,
+		"created", summary.Created,
+		"failed", summary.Failed)
+
+	return summary
+}
+
+// LoadPreloadUUIDs reads node UUIDs from a file with one UUID per line.
+// Blank lines, lines starting with '#' and duplicates are skipped.
+func LoadPreloadUUIDs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open preload file: %w", err)
+	}
+	defer file.Close()
+
+	var uuids []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+
+		seen[line] = true
+		uuids = append(uuids, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read preload file: %w", err)
+	}
+
+	return uuids, nil
+}
+
+// FilterPreloadUUIDs drops the node UUIDs validator rejects, logging each one, so a typo in the
+// preload file doesn't create a transit key no node will ever use
+func FilterPreloadUUIDs(uuids []string, validator *validation.UUIDValidator, logger *slog.Logger) []string {
+	valid := make([]string, 0, len(uuids))
+	for _, nodeUUID := range uuids {
+		if err := validator.ValidateNodeUUID(nodeUUID); err != nil {
+			logger.Warn("Skipping invalid node UUID in preload file",
+				"node", validation.SanitizeForLogging(nodeUUID),
+				"error", err)
+			continue
+		}
+
+		valid = append(valid, nodeUUID)
+	}
+
+	return valid
+}
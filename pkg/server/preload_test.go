@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+)
+
+func TestLoadPreloadUUIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.txt")
+	content := "# known nodes\n550e8400-e29b-41d4-a716-446655440000\n\n  6ba7b810-9dad-41d1-80b4-00c04fd430c8  \n550e8400-e29b-41d4-a716-446655440000\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids, err := LoadPreloadUUIDs(path)
+	if err != nil {
+		t.Fatalf("LoadPreloadUUIDs() error = %v", err)
+	}
+
+	want := []string{"550e8400-e29b-41d4-a716-446655440000", "6ba7b810-9dad-41d1-80b4-00c04fd430c8"}
+	if !reflect.DeepEqual(uuids, want) {
+		t.Errorf("LoadPreloadUUIDs() = %v, want %v", uuids, want)
+	}
+
+	if _, err := LoadPreloadUUIDs(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestFilterPreloadUUIDs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	uuids := []string{
+		"550e8400-e29b-41d4-a716-446655440000",
+		"not-a-uuid",
+		"../../sys/policy",
+		"00000000-0000-4000-8000-000000000000",
+		"6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+	}
+
+	tests := []struct {
+		name   string
+		config *validation.ValidationConfig
+		want   []string
+	}{
+		{
+			name:   "request validator",
+			config: validation.DefaultValidationConfig(),
+			want:   []string{"550e8400-e29b-41d4-a716-446655440000", "6ba7b810-9dad-41d1-80b4-00c04fd430c8"},
+		},
+		{
+			name:   "format only when validation is disabled",
+			config: &validation.ValidationConfig{Enabled: false},
+			want: []string{
+				"550e8400-e29b-41d4-a716-446655440000",
+				"00000000-0000-4000-8000-000000000000",
+				"6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterPreloadUUIDs(uuids, tt.config.NodeUUIDValidator(), logger)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterPreloadUUIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_PreloadKeys(t *testing.T) {
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v1/transit/keys/")
+
+		switch {
+		case r.Method == http.MethodGet && name == "existing":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data":{"name":"existing"}}`))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case name == "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+
+	summary := srv.PreloadKeys(context.Background(), []string{"existing", "missing", "broken"})
+
+	want := PreloadSummary{Existing: 1, Created: 1, Failed: 1}
+	if summary != want {
+		t.Errorf("PreloadKeys() = %+v, want %+v", summary, want)
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GlobalRateLimiter caps the total request rate across all clients to protect Vault
+type GlobalRateLimiter struct {
+	limiter *rate.Limiter
+	logger  *slog.Logger
+}
+
+// NewGlobalRateLimiter creates a token-bucket limiter allowing requestsPerSecond with
+// the given burst. A non-positive burst defaults to one second's worth of requests.
+func NewGlobalRateLimiter(requestsPerSecond float64, burst int, logger *slog.Logger) *GlobalRateLimiter {
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(requestsPerSecond)))
+	}
+
+	return &GlobalRateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+		logger:  logger.With("component", "global-rate-limiter"),
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor enforcing the global rate limit
+func (l *GlobalRateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		allowed := l.limiter.Allow()
+		globalRateLimitTokens.Set(l.limiter.Tokens())
+
+		if !allowed {
+			globalRateLimitRejections.Inc()
+			l.logger.WarnContext(ctx, "Global rate limit exceeded", "method", info.FullMethod)
+
+			return nil, status.Error(codes.ResourceExhausted, "global rate limit exceeded")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGlobalRateLimiter_UnaryServerInterceptor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// Effectively no refill during the test so only the burst is available
+	limiter := NewGlobalRateLimiter(0.001, 3, logger)
+	interceptor := limiter.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+
+	rejectedBefore := testutil.ToFloat64(globalRateLimitRejections)
+
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+			t.Fatalf("request %d: unexpected error %v", i, err)
+		}
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected %v, got %v", codes.ResourceExhausted, err)
+	}
+
+	if got := testutil.ToFloat64(globalRateLimitRejections) - rejectedBefore; got != 1 {
+		t.Errorf("Expected 1 rejection, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(globalRateLimitTokens); got >= 1 {
+		t.Errorf("Expected an empty bucket, got %v tokens", got)
+	}
+}
+
+func TestNewGlobalRateLimiter_DefaultBurst(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tests := []struct {
+		rps  float64
+		want int
+	}{
+		{rps: 0.5, want: 1},
+		{rps: 10, want: 10},
+		{rps: 2.5, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := NewGlobalRateLimiter(tt.rps, 0, logger).limiter.Burst(); got != tt.want {
+			t.Errorf("burst for %v rps = %d, want %d", tt.rps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRequestLogMaxSize is the default size at which the request log is rotated
+const DefaultRequestLogMaxSize = 100 * 1024 * 1024 // 100MB
+
+// RequestRecord is one line of the request log. It never contains plaintext or ciphertext.
+type RequestRecord struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Operation  string    `json:"operation"`
+	Node       string    `json:"node"`
+	Size       int       `json:"size"`
+	Outcome    string    `json:"outcome"`
+	DurationMS float64   `json:"durationMs"`
+
+	// Truncated SHA-256 digests of the payloads, only with digests enabled and only for
+	// requests that passed validation
+	RequestDigest  string `json:"requestDigest,omitempty"`
+	ResponseDigest string `json:"responseDigest,omitempty"`
+
+	// VaultRequestID is the request ID of the Transit call that served the request, only
+	// with Vault request IDs enabled and only when the call succeeded
+	VaultRequestID string `json:"vaultRequestId,omitempty"`
+}
+
+// RequestRecorder writes sanitized request metadata as JSON lines for DR analysis.
+// When the file grows past maxSize it is rotated to <path>.1, replacing any previous one.
+type RequestRecorder struct {
+	path    string
+	maxSize int64
+	logger  *slog.Logger
+
+	// includeDigest adds payload digests to records
+	includeDigest bool
+
+	// includeVaultRequestID adds the Vault request ID to records
+	includeVaultRequestID bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRequestRecorder opens (or creates) the request log at path for appending
+func NewRequestRecorder(path string, maxSize int64, logger *slog.Logger) (*RequestRecorder, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultRequestLogMaxSize
+	}
+
+	r := &RequestRecorder{
+		path:    path,
+		maxSize: maxSize,
+		logger:  logger.With("component", "request-recorder"),
+	}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetIncludeDigest makes records carry short SHA-256 digests of the request and response data,
+// so repeated operations on the same payload can be correlated without storing it
+func (r *RequestRecorder) SetIncludeDigest(include bool) {
+	r.includeDigest = include
+}
+
+// SetIncludeVaultRequestID makes records carry the request ID Vault returned for the Transit
+// call, so they can be joined with the Vault audit log
+func (r *RequestRecorder) SetIncludeVaultRequestID(include bool) {
+	r.includeVaultRequestID = include
+}
+
+// open opens the log file for appending; the lock must be held or the recorder unshared
+func (r *RequestRecorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open request log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat request log: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+
+	return nil
+}
+
+// Record appends a record to the log, rotating first if it would exceed the size limit
+func (r *RequestRecorder) Record(record RequestRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("request recorder is closed")
+	}
+
+	if r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+
+	return err
+}
+
+// rotate moves the current log to <path>.1 and starts a new one; the lock must be held
+func (r *RequestRecorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		r.logger.Warn("Failed to close request log before rotation", "error", err)
+	}
+	r.file = nil
+
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		// Fall back to truncating so the size limit still holds
+		r.logger.Warn("Failed to rotate request log, truncating", "error", err)
+		if err := os.Truncate(r.path, 0); err != nil {
+			return fmt.Errorf("failed to truncate request log: %w", err)
+		}
+	}
+
+	return r.open()
+}
+
+// Close closes the request log
+func (r *RequestRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor recording every KMS request
+func (r *RequestRecorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var digests *auditDigests
+		if r.includeDigest {
+			ctx, digests = withAuditDigests(ctx)
+		}
+
+		var vaultRequest *auditVaultRequest
+		if r.includeVaultRequestID {
+			ctx, vaultRequest = withAuditVaultRequest(ctx)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		record := RequestRecord{
+			Timestamp:  start.UTC(),
+			Operation:  path.Base(info.FullMethod),
+			Node:       "<unknown>",
+			Outcome:    status.Code(err).String(),
+			DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		}
+
+		if kmsReq, ok := req.(*kms.Request); ok {
+			record.Node = validation.SanitizeForLogging(kmsReq.NodeUuid)
+			record.Size = len(kmsReq.Data)
+		}
+
+		if digests != nil {
+			record.RequestDigest = digests.request
+			record.ResponseDigest = digests.response
+		}
+
+		if vaultRequest != nil {
+			record.VaultRequestID = vaultRequest.id
+		}
+
+		if recordErr := r.Record(record); recordErr != nil {
+			r.logger.WarnContext(ctx, "Failed to record request", "error", recordErr)
+		}
+
+		return resp, err
+	}
+}
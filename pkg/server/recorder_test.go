@@ -0,0 +1,241 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func readRecords(t *testing.T, path string) []RequestRecord {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []RequestRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record RequestRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestRequestRecorder_Interceptor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	recorder, err := NewRequestRecorder(path, 0, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	interceptor := recorder.UnaryServerInterceptor()
+	secret := "super-secret-plaintext"
+
+	tests := []struct {
+		method  string
+		err     error
+		outcome string
+	}{
+		{method: "/kms.KMSService/Seal", outcome: "OK"},
+		{method: "/kms.KMSService/Unseal", err: status.Error(codes.InvalidArgument, "bad"), outcome: "InvalidArgument"},
+	}
+
+	for _, tt := range tests {
+		req := &kms.Request{NodeUuid: retiredNode, Data: []byte(secret)}
+		_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: tt.method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &kms.Response{Data: []byte("vault:v1:ciphertext")}, tt.err
+			})
+		if err != tt.err {
+			t.Fatalf("interceptor returned %v, want %v", err, tt.err)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), secret) || strings.Contains(string(raw), "ciphertext") || strings.Contains(string(raw), retiredNode) {
+		t.Fatalf("request log leaks payload or full UUID: %s", raw)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != len(tests) {
+		t.Fatalf("got %d records, want %d", len(records), len(tests))
+	}
+
+	for i, tt := range tests {
+		if records[i].Outcome != tt.outcome || records[i].Size != len(secret) {
+			t.Errorf("record %d = %+v, want outcome %s and size %d", i, records[i], tt.outcome, len(secret))
+		}
+	}
+
+	if records[0].Operation != "Seal" || records[0].Node == "" {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+}
+
+func TestRequestRecorder_RotatesConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	const maxSize = 2048
+
+	recorder, err := NewRequestRecorder(path, maxSize, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := recorder.Record(RequestRecord{Operation: "Seal", Node: "<test>", Outcome: "OK"}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, p := range []string{path, path + ".1"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", p, err)
+		}
+		if info.Size() > maxSize {
+			t.Errorf("%s is %d bytes, exceeds limit of %d", p, info.Size(), maxSize)
+		}
+
+		// Every line must be intact JSON despite concurrent writers
+		readRecords(t, p)
+	}
+}
+
+func TestRequestRecorder_Digest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	recorder, err := NewRequestRecorder(path, 0, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	recorder.SetIncludeDigest(true)
+	interceptor := recorder.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+	secret := []byte("super-secret-plaintext")
+
+	// Stands in for the server, which records digests once validation has passed
+	serve := func(ctx context.Context, req interface{}) (interface{}, error) {
+		recordRequestDigest(ctx, req.(*kms.Request).Data)
+		resp := &kms.Response{Data: []byte("vault:v1:ciphertext")}
+		recordResponseDigest(ctx, resp.Data)
+		return resp, nil
+	}
+	reject := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad")
+	}
+
+	for _, handler := range []grpc.UnaryHandler{serve, serve, reject} {
+		req := &kms.Request{NodeUuid: retiredNode, Data: secret}
+		interceptor(context.Background(), req, info, handler)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), string(secret)) || strings.Contains(string(raw), "ciphertext") {
+		t.Fatalf("request log leaks payload: %s", raw)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	if records[0].RequestDigest != payloadDigest(secret) || len(records[0].RequestDigest) != 2*payloadDigestBytes {
+		t.Errorf("request digest = %q, want %q", records[0].RequestDigest, payloadDigest(secret))
+	}
+	if records[0].ResponseDigest == "" || records[0].ResponseDigest == records[0].RequestDigest {
+		t.Errorf("unexpected response digest %q", records[0].ResponseDigest)
+	}
+	if records[1].RequestDigest != records[0].RequestDigest || records[1].ResponseDigest != records[0].ResponseDigest {
+		t.Errorf("digests are not stable: %+v vs %+v", records[1], records[0])
+	}
+	if records[2].RequestDigest != "" || records[2].ResponseDigest != "" {
+		t.Errorf("rejected request has digests: %+v", records[2])
+	}
+}
+
+func TestRequestRecorder_VaultRequestID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	recorder, err := NewRequestRecorder(path, 0, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	recorder.SetIncludeVaultRequestID(true)
+	interceptor := recorder.UnaryServerInterceptor()
+
+	var logs bytes.Buffer
+	transit := newFakeTransit(t, "transit")
+	srv := NewServer(transit.client(t), slog.New(slog.NewTextHandler(&logs, nil)), transit.mount)
+	srv.SetLogVaultRequestID(true)
+
+	call := func(method string, handler func(context.Context, *kms.Request) (*kms.Response, error), data []byte) *kms.Response {
+		resp, _ := interceptor(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: data},
+			&grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/" + method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return handler(ctx, req.(*kms.Request))
+			})
+		sealed, _ := resp.(*kms.Response)
+		return sealed
+	}
+
+	sealed := call("Seal", srv.Seal, []byte("secret"))
+	call("Unseal", srv.Unseal, sealed.Data)
+	call("Unseal", srv.Unseal, []byte("vault:v1:tampered"))
+
+	records := readRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	for i, record := range records[:2] {
+		if !strings.HasPrefix(record.VaultRequestID, "fake-request-") {
+			t.Errorf("record %d vault request ID = %q, want the ID returned by Vault", i, record.VaultRequestID)
+		}
+		if !strings.Contains(logs.String(), "vaultRequestId="+record.VaultRequestID) {
+			t.Errorf("vault request ID %q not logged:\n%s", record.VaultRequestID, logs.String())
+		}
+	}
+	if records[0].VaultRequestID == records[1].VaultRequestID {
+		t.Errorf("Seal and Unseal share vault request ID %q", records[0].VaultRequestID)
+	}
+	if records[2].VaultRequestID != "" {
+		t.Errorf("failed Unseal has vault request ID %q, want none", records[2].VaultRequestID)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultGoroutineSampleInterval is how often the goroutine count is sampled
+const DefaultGoroutineSampleInterval = 15 * time.Second
+
+// InflightInterceptor returns a unary interceptor that tracks requests currently being handled
+func InflightInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		inflightRequests.Inc()
+		defer inflightRequests.Dec()
+
+		return handler(ctx, req)
+	}
+}
+
+// SampleGoroutines records the goroutine count immediately and then every interval until ctx is done
+func SampleGoroutines(ctx context.Context, interval time.Duration) {
+	goroutines.Set(float64(runtime.NumGoroutine()))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			goroutines.Set(float64(runtime.NumGoroutine()))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestInflightInterceptor(t *testing.T) {
+	interceptor := InflightInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+
+	before := testutil.ToFloat64(inflightRequests)
+
+	var during float64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		during = testutil.ToFloat64(inflightRequests)
+		return nil, context.Canceled
+	}
+
+	interceptor(context.Background(), nil, info, handler)
+
+	if during != before+1 {
+		t.Errorf("kms_inflight_requests during request = %v, want %v", during, before+1)
+	}
+	if got := testutil.ToFloat64(inflightRequests); got != before {
+		t.Errorf("kms_inflight_requests after request = %v, want %v", got, before)
+	}
+}
+
+func TestSampleGoroutines(t *testing.T) {
+	goroutines.Set(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SampleGoroutines(ctx, time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(goroutines) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("kms_goroutines was never sampled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Seal output formats
+const (
+	// SealFormatRaw returns the transit ciphertext as is
+	SealFormatRaw = 0
+
+	// SealFormatV1 prepends a header carrying the format version, flags and transit key name
+	SealFormatV1 = 1
+)
+
+// sealFormatMagic starts every framed sealed blob. Transit ciphertext starts with "vault:v"
+// and wrapping tokens with "hvs." or "s.", so raw output can never be mistaken for it.
+var sealFormatMagic = []byte("TKMS")
+
+// sealFormatV1HeaderLen is the fixed part of a v1 header: magic, version, flags and key name length
+const sealFormatV1HeaderLen = 4 + 3
+
+// sealFlagTimestamp marks a v1 header carrying the seal time as 8 bytes of big-endian Unix
+// seconds after the key name
+const sealFlagTimestamp byte = 0x01
+
+// sealFlagsKnown are the v1 header flags this server understands
+const sealFlagsKnown = sealFlagTimestamp
+
+// errNodeContextMismatch is returned by unframeSealed when a v1 header names another node's key
+var errNodeContextMismatch = errors.New("sealed data was sealed for a different node")
+
+// SetSealFormatVersion selects the Seal output format. SealFormatRaw (the default) returns the
+// transit ciphertext as is; SealFormatV1 prepends a self-describing header so future servers
+// know how to Unseal the data. Unseal accepts both formats regardless of this setting.
+func (s *Server) SetSealFormatVersion(version int) error {
+	switch version {
+	case SealFormatRaw, SealFormatV1:
+		s.sealFormatVersion = version
+		return nil
+	default:
+		return fmt.Errorf("unsupported seal format version %d (supported: %d, %d)", version, SealFormatRaw, SealFormatV1)
+	}
+}
+
+// SetMaxCiphertextAge makes Unseal refuse data sealed longer ago than maxAge, so secrets that
+// must be re-sealed periodically can't be unsealed forever (0 disables). Only v1 data carries
+// a seal time; raw transit ciphertext is never rejected for its age. The seal time is in the
+// unauthenticated header, so the check is advisory and can be bypassed by editing it.
+func (s *Server) SetMaxCiphertextAge(maxAge time.Duration) {
+	s.maxCiphertextAge = maxAge
+}
+
+// frameSealed returns the ciphertext in the configured Seal format
+//
+// A v1 blob is laid out as:
+//
+//	"TKMS" | version (1 byte) | flags (1 byte) | key name length (1 byte) | key name |
+//	seal time (8 bytes, with sealFlagTimestamp) | ciphertext
+//
+// The other flag bits are reserved for format extensions such as associated data.
+func (s Server) frameSealed(keyName, ciphertext string, sealedAt time.Time) ([]byte, error) {
+	if s.sealFormatVersion == SealFormatRaw {
+		return []byte(ciphertext), nil
+	}
+
+	if len(keyName) > 255 {
+		return nil, fmt.Errorf("key name too long for the seal format header: %d bytes", len(keyName))
+	}
+
+	framed := make([]byte, 0, sealFormatV1HeaderLen+len(keyName)+8+len(ciphertext))
+	framed = append(framed, sealFormatMagic...)
+	framed = append(framed, byte(s.sealFormatVersion), sealFlagTimestamp, byte(len(keyName)))
+	framed = append(framed, keyName...)
+	framed = binary.BigEndian.AppendUint64(framed, uint64(sealedAt.Unix()))
+	framed = append(framed, ciphertext...)
+
+	return framed, nil
+}
+
+// unframeSealed returns the transit ciphertext of sealed data in any supported format, and
+// the seal time when the header carries one. Data without a header is raw ciphertext. A
+// header must name the key being unsealed with, so a blob sealed for one node can't be
+// passed off under another node's request.
+func unframeSealed(data []byte, keyName string) (ciphertext string, version int, sealedAt time.Time, err error) {
+	if !bytes.HasPrefix(data, sealFormatMagic) {
+		return string(data), SealFormatRaw, time.Time{}, nil
+	}
+
+	errTruncated := status.Error(codes.InvalidArgument, "sealed data has a truncated format header")
+
+	if len(data) < sealFormatV1HeaderLen {
+		return "", 0, time.Time{}, errTruncated
+	}
+
+	version = int(data[4])
+	flags := data[5]
+	keyLen := int(data[6])
+
+	if version != SealFormatV1 {
+		return "", 0, time.Time{}, status.Errorf(codes.InvalidArgument, "unsupported seal format version %d", version)
+	}
+
+	if flags&^sealFlagsKnown != 0 {
+		return "", 0, time.Time{}, status.Errorf(codes.InvalidArgument, "unsupported seal format flags %#02x", flags)
+	}
+
+	rest := data[sealFormatV1HeaderLen:]
+	if len(rest) < keyLen {
+		return "", 0, time.Time{}, errTruncated
+	}
+
+	if name := string(rest[:keyLen]); name != keyName {
+		return "", 0, time.Time{}, errNodeContextMismatch
+	}
+	rest = rest[keyLen:]
+
+	if flags&sealFlagTimestamp != 0 {
+		if len(rest) < 8 {
+			return "", 0, time.Time{}, errTruncated
+		}
+
+		sealedAt = time.Unix(int64(binary.BigEndian.Uint64(rest[:8])), 0)
+		rest = rest[8:]
+	}
+
+	return string(rest), version, sealedAt, nil
+}
+
+// unframeForNode unframes sealed data unsealed by nodeUUID. Data whose header binds it to
+// another node is rejected with PermissionDenied and counted, as it points at a node trying
+// to unseal another node's secrets rather than at corrupt data.
+func (s Server) unframeForNode(ctx context.Context, data []byte, nodeUUID string) (string, int, time.Time, error) {
+	ciphertext, version, sealedAt, err := unframeSealed(data, nodeUUID)
+	if errors.Is(err, errNodeContextMismatch) {
+		nodeContextMismatches.Inc()
+		s.logger.WarnContext(ctx, "Rejecting unseal of data sealed for a different node",
+			"node", validation.SanitizeForLogging(nodeUUID))
+		return "", 0, time.Time{}, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return ciphertext, version, sealedAt, err
+}
+
+// checkCiphertextAge rejects data sealed longer ago than the maximum ciphertext age. Data
+// without a seal time always passes.
+func (s Server) checkCiphertextAge(sealedAt time.Time) error {
+	if s.maxCiphertextAge <= 0 || sealedAt.IsZero() {
+		return nil
+	}
+
+	if age := time.Since(sealedAt); age > s.maxCiphertextAge {
+		unsealExpiredCiphertext.Inc()
+		return status.Errorf(codes.FailedPrecondition,
+			"sealed data is %s old, older than the maximum ciphertext age of %s; it must be re-sealed",
+			age.Truncate(time.Second), s.maxCiphertextAge)
+	}
+
+	return nil
+}
+
+// sealFormatLabel is the kms_unseal_format_total label for a format version
+func sealFormatLabel(version int) string {
+	if version == SealFormatRaw {
+		return "raw"
+	}
+
+	return "v" + strconv.Itoa(version)
+}
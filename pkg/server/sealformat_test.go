@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_SealFormatV1(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+
+	raw := newTestServer(t, transit)
+	framed := newTestServer(t, transit)
+	if err := framed.SetSealFormatVersion(SealFormatV1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	plaintext := []byte("disk encryption key")
+
+	sealedV1, err := framed.Seal(ctx, &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !bytes.HasPrefix(sealedV1.Data, []byte("TKMS\x01\x01")) || !bytes.Contains(sealedV1.Data, []byte(retiredNode)) {
+		t.Fatalf("Seal() data = %q, want a v1 header naming the key", sealedV1.Data)
+	}
+
+	_, _, sealedAt, err := unframeSealed(sealedV1.Data, retiredNode)
+	if err != nil || time.Since(sealedAt) > time.Minute {
+		t.Fatalf("v1 header seal time = %v (err = %v), want now", sealedAt, err)
+	}
+
+	sealedRaw, err := raw.Seal(ctx, &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	// Both servers unseal both formats, so the format can be switched either way
+	for name, tt := range map[string]struct {
+		srv    *Server
+		sealed []byte
+	}{
+		"v1 server, v1 data":   {framed, sealedV1.Data},
+		"v1 server, raw data":  {framed, sealedRaw.Data},
+		"raw server, v1 data":  {raw, sealedV1.Data},
+		"raw server, raw data": {raw, sealedRaw.Data},
+	} {
+		unsealed, err := tt.srv.Unseal(ctx, &kms.Request{NodeUuid: retiredNode, Data: tt.sealed})
+		if err != nil {
+			t.Errorf("%s: Unseal() error = %v", name, err)
+			continue
+		}
+		if !bytes.Equal(unsealed.Data, plaintext) {
+			t.Errorf("%s: Unseal() data = %q, want %q", name, unsealed.Data, plaintext)
+		}
+	}
+}
+
+func TestServer_UnsealRejectsBadSealFormat(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	if err := srv.SetSealFormatVersion(SealFormatV1); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	withByte := func(i int, b byte) []byte {
+		data := bytes.Clone(sealed.Data)
+		data[i] = b
+		return data
+	}
+
+	tests := []struct {
+		name string
+		node string
+		data []byte
+	}{
+		{name: "unknown version", node: retiredNode, data: withByte(4, 2)},
+		{name: "unknown flags", node: retiredNode, data: withByte(5, 0x03)},
+		{name: "truncated seal time", node: retiredNode, data: sealed.Data[:sealFormatV1HeaderLen+len(retiredNode)+4]},
+		{name: "truncated header", node: retiredNode, data: []byte("TKMS\x01")},
+		{name: "truncated key name", node: retiredNode, data: sealed.Data[:10]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := transit.requestCount()
+
+			_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: tt.node, Data: tt.data})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Unseal() error = %v, want InvalidArgument", err)
+			}
+			if transit.requestCount() != before {
+				t.Error("a rejected sealed blob reached Vault")
+			}
+		})
+	}
+
+	t.Run("other node's key", func(t *testing.T) {
+		before := transit.requestCount()
+		mismatches := testutil.ToFloat64(nodeContextMismatches)
+
+		_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: otherNode, Data: sealed.Data})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Unseal() error = %v, want PermissionDenied", err)
+		}
+		if got := testutil.ToFloat64(nodeContextMismatches) - mismatches; got != 1 {
+			t.Errorf("node context mismatches = %v, want 1", got)
+		}
+		if transit.requestCount() != before {
+			t.Error("a rejected sealed blob reached Vault")
+		}
+	})
+
+	// Without a header naming the key, Transit failing to decrypt another node's data can't be
+	// told apart from corrupt data, so it is a generic failure and not a node context mismatch
+	if _, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: otherNode, Data: []byte("other")}); err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	raw, err := newTestServer(t, transit).Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	stripped := sealed.Data[bytes.Index(sealed.Data, []byte("vault:v")):]
+	for name, data := range map[string][]byte{"raw ciphertext": raw.Data, "header-stripped": stripped} {
+		t.Run(name+" of another node", func(t *testing.T) {
+			mismatches := testutil.ToFloat64(nodeContextMismatches)
+
+			_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: otherNode, Data: data})
+			if status.Code(err) != codes.Internal {
+				t.Errorf("Unseal() error = %v, want Internal", err)
+			}
+			if got := testutil.ToFloat64(nodeContextMismatches) - mismatches; got != 0 {
+				t.Errorf("node context mismatches = %v, want 0", got)
+			}
+		})
+	}
+}
+
+func TestServer_SetSealFormatVersion(t *testing.T) {
+	srv := newTestServer(t, newFakeTransit(t, "transit"))
+
+	for _, version := range []int{SealFormatRaw, SealFormatV1} {
+		if err := srv.SetSealFormatVersion(version); err != nil {
+			t.Errorf("SetSealFormatVersion(%d) error = %v", version, err)
+		}
+	}
+
+	for _, version := range []int{-1, 2} {
+		if err := srv.SetSealFormatVersion(version); err == nil {
+			t.Errorf("SetSealFormatVersion(%d) succeeded", version)
+		}
+	}
+}
+
+func TestServer_MaxCiphertextAge(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetMaxCiphertextAge(24 * time.Hour)
+
+	raw, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	// Reframe the transit ciphertext as v1 data sealed at different times
+	framer := Server{sealFormatVersion: SealFormatV1}
+	frameAt := func(sealedAt time.Time) []byte {
+		data, err := framer.frameSealed(retiredNode, string(raw.Data), sealedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// v1 data without the timestamp flag carries no seal time
+	untimed := append([]byte("TKMS\x01\x00"+string(rune(len(retiredNode)))+retiredNode), raw.Data...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantCode codes.Code
+	}{
+		{name: "recent v1", data: frameAt(time.Now().Add(-time.Hour)), wantCode: codes.OK},
+		{name: "expired v1", data: frameAt(time.Now().Add(-48 * time.Hour)), wantCode: codes.FailedPrecondition},
+		{name: "v1 without seal time", data: untimed, wantCode: codes.OK},
+		{name: "raw ciphertext", data: raw.Data, wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := transit.requestCount()
+			expiredBefore := testutil.ToFloat64(unsealExpiredCiphertext)
+
+			_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: tt.data})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Unseal() code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+
+			if tt.wantCode == codes.FailedPrecondition {
+				if transit.requestCount() != before {
+					t.Error("expired sealed data reached Vault")
+				}
+				if testutil.ToFloat64(unsealExpiredCiphertext)-expiredBefore != 1 {
+					t.Error("kms_unseal_expired_ciphertext_total not incremented")
+				}
+			}
+		})
+	}
+
+	// Without a maximum age nothing expires
+	srv.SetMaxCiphertextAge(0)
+	if _, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: frameAt(time.Now().Add(-48 * time.Hour))}); err != nil {
+		t.Errorf("Unseal() with the check disabled error = %v", err)
+	}
+}
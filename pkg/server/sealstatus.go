@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// VaultSealState is the seal and HA state reported by Vault
+type VaultSealState struct {
+	Sealed             bool `json:"sealed"`
+	Standby            bool `json:"standby"`
+	PerformanceStandby bool `json:"performanceStandby"`
+}
+
+// isStandby reports whether the node is a standby of either kind
+func (s VaultSealState) isStandby() bool {
+	return s.Standby || s.PerformanceStandby
+}
+
+// standbyKind names the kind of standby for log and error messages
+func (s VaultSealState) standbyKind() string {
+	if s.PerformanceStandby {
+		return "performance standby"
+	}
+
+	return "standby"
+}
+
+// SealCheckFunc fetches the current Vault seal state
+type SealCheckFunc func(ctx context.Context) (VaultSealState, error)
+
+// VaultSealStatusCheck returns a seal check calling sys/health on the current Vault client.
+// Status codes are overridden so sealed and standby nodes still return their state.
+func VaultSealStatusCheck(getClient func() (*vault.Client, error)) SealCheckFunc {
+	params := url.Values{}
+	params.Set("standbyok", "true")
+	params.Set("perfstandbyok", "true")
+	params.Set("sealedcode", "200")
+	params.Set("uninitcode", "200")
+
+	return func(ctx context.Context) (VaultSealState, error) {
+		client, err := getClient()
+		if err != nil {
+			return VaultSealState{}, err
+		}
+
+		resp, err := client.System.ReadHealthStatus(ctx, vault.WithQueryParameters(params))
+		if err != nil {
+			return VaultSealState{}, err
+		}
+
+		sealed, _ := resp.Data["sealed"].(bool)
+		standby, _ := resp.Data["standby"].(bool)
+		perfStandby, _ := resp.Data["performance_standby"].(bool)
+
+		return VaultSealState{Sealed: sealed, Standby: standby, PerformanceStandby: perfStandby}, nil
+	}
+}
+
+// SealStatusMonitor periodically checks whether Vault is sealed so readiness can
+// distinguish a sealed Vault from authentication problems
+type SealStatusMonitor struct {
+	check             SealCheckFunc
+	interval          time.Duration
+	standbyForwarding bool
+	logger            *slog.Logger
+
+	mu        sync.RWMutex
+	state     VaultSealState
+	checked   bool
+	lastError error
+}
+
+// NewSealStatusMonitor creates a new seal status monitor. When standbyForwarding is
+// false a standby Vault node is treated as unable to serve requests.
+func NewSealStatusMonitor(check SealCheckFunc, interval time.Duration, standbyForwarding bool, logger *slog.Logger) *SealStatusMonitor {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &SealStatusMonitor{
+		check:             check,
+		interval:          interval,
+		standbyForwarding: standbyForwarding,
+		logger:            logger.With("component", "vault-seal-monitor"),
+	}
+}
+
+// Start runs the periodic seal status check until the context is cancelled
+func (m *SealStatusMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.refresh(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// refresh performs a single seal status check
+func (m *SealStatusMonitor) refresh(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	state, err := m.check(checkCtx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		// Keep the last known state; connectivity is reported by the Vault health check
+		m.lastError = err
+		m.logger.Warn("Failed to check Vault seal status", "error", err)
+		return
+	}
+
+	if m.checked && state.Sealed != m.state.Sealed {
+		m.logger.Warn("Vault seal state changed", "sealed", state.Sealed)
+	}
+
+	// Log when the node first turns out to be a standby, e.g. a load balancer sending us to one
+	if state.isStandby() && (!m.checked || !m.state.isStandby() || state.PerformanceStandby != m.state.PerformanceStandby) {
+		m.logger.Warn("Vault node is a "+state.standbyKind(),
+			"performanceStandby", state.PerformanceStandby,
+			"forwarding", m.standbyForwarding)
+	} else if !state.isStandby() && m.checked && m.state.isStandby() {
+		m.logger.Info("Vault node is no longer a standby")
+	}
+
+	m.state = state
+	m.checked = true
+	m.lastError = nil
+
+	vaultSealed.Set(gaugeBool(state.Sealed))
+	vaultStandby.Set(gaugeBool(state.Standby))
+	vaultPerformanceStandby.Set(gaugeBool(state.PerformanceStandby))
+}
+
+// NotReadyReason returns why Vault cannot serve requests, or an empty string if it can
+func (m *SealStatusMonitor) NotReadyReason() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	switch {
+	case !m.checked:
+		return ""
+	case m.state.Sealed:
+		return "vault is sealed"
+	case m.state.isStandby() && !m.standbyForwarding:
+		return "vault node is a " + m.state.standbyKind() + " and request forwarding is disabled"
+	}
+
+	return ""
+}
+
+// SealBlockedReason returns why Seal cannot succeed against this Vault node, or an
+// empty string if it can. Encrypt needs the active node when forwarding is disabled.
+func (m *SealStatusMonitor) SealBlockedReason() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.checked || !m.state.isStandby() || m.standbyForwarding {
+		return ""
+	}
+
+	return "vault node is a " + m.state.standbyKind() + " and request forwarding is disabled, Seal needs the active node"
+}
+
+// State returns the last known Vault seal state
+func (m *SealStatusMonitor) State() VaultSealState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.state
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSealStatusMonitor_NotReadyReason(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tests := []struct {
+		name              string
+		state             VaultSealState
+		err               error
+		standbyForwarding bool
+		wantReason        string
+		wantSealedGauge   float64
+	}{
+		{
+			name:            "unsealed active node",
+			state:           VaultSealState{},
+			wantReason:      "",
+			wantSealedGauge: 0,
+		},
+		{
+			name:            "sealed",
+			state:           VaultSealState{Sealed: true},
+			wantReason:      "vault is sealed",
+			wantSealedGauge: 1,
+		},
+		{
+			name:              "standby with forwarding",
+			state:             VaultSealState{Standby: true},
+			standbyForwarding: true,
+			wantReason:        "",
+			wantSealedGauge:   0,
+		},
+		{
+			name:              "standby without forwarding",
+			state:             VaultSealState{Standby: true},
+			standbyForwarding: false,
+			wantReason:        "vault node is a standby and request forwarding is disabled",
+			wantSealedGauge:   0,
+		},
+		{
+			name:              "performance standby without forwarding",
+			state:             VaultSealState{Standby: true, PerformanceStandby: true},
+			standbyForwarding: false,
+			wantReason:        "vault node is a performance standby and request forwarding is disabled",
+			wantSealedGauge:   0,
+		},
+		{
+			name:       "check error before first result",
+			err:        errors.New("connection refused"),
+			wantReason: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vaultSealed.Set(0)
+
+			monitor := NewSealStatusMonitor(func(ctx context.Context) (VaultSealState, error) {
+				return tt.state, tt.err
+			}, time.Minute, tt.standbyForwarding, logger)
+
+			monitor.refresh(context.Background())
+
+			if got := monitor.NotReadyReason(); got != tt.wantReason {
+				t.Errorf("NotReadyReason() = %q, want %q", got, tt.wantReason)
+			}
+
+			if got := testutil.ToFloat64(vaultSealed); got != tt.wantSealedGauge {
+				t.Errorf("kms_vault_sealed = %v, want %v", got, tt.wantSealedGauge)
+			}
+		})
+	}
+}
+
+func TestServer_SealFailsFastOnStandby(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tests := []struct {
+		name              string
+		state             VaultSealState
+		standbyForwarding bool
+		failOnStandby     bool
+		wantCode          codes.Code
+	}{
+		{
+			name:          "active node",
+			state:         VaultSealState{},
+			failOnStandby: true,
+			wantCode:      codes.OK,
+		},
+		{
+			name:          "performance standby without forwarding",
+			state:         VaultSealState{Standby: true, PerformanceStandby: true},
+			failOnStandby: true,
+			wantCode:      codes.Unavailable,
+		},
+		{
+			name:              "standby with forwarding",
+			state:             VaultSealState{Standby: true},
+			standbyForwarding: true,
+			failOnStandby:     true,
+			wantCode:          codes.OK,
+		},
+		{
+			name:     "fail-fast disabled",
+			state:    VaultSealState{Standby: true, PerformanceStandby: true},
+			wantCode: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transit := newFakeTransit(t, "transit")
+			srv := newTestServer(t, transit)
+
+			monitor := NewSealStatusMonitor(func(ctx context.Context) (VaultSealState, error) {
+				return tt.state, nil
+			}, time.Minute, tt.standbyForwarding, logger)
+			monitor.refresh(context.Background())
+
+			srv.SetSealStatusMonitor(monitor)
+			srv.SetFailSealOnStandby(tt.failOnStandby)
+
+			_, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("key")})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("Seal() code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+
+			if tt.wantCode != codes.OK && transit.requestCount() != 0 {
+				t.Errorf("rejected Seal reached Vault %d times", transit.requestCount())
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(vaultPerformanceStandby); got != 1 {
+		t.Errorf("kms_vault_performance_standby = %v, want 1 after the last refresh", got)
+	}
+}
+
+func TestSealStatusMonitor_KeepsStateOnError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	var err error
+	monitor := NewSealStatusMonitor(func(ctx context.Context) (VaultSealState, error) {
+		return VaultSealState{Sealed: true}, err
+	}, time.Minute, true, logger)
+
+	monitor.refresh(context.Background())
+
+	err = errors.New("timeout")
+	monitor.refresh(context.Background())
+
+	if !monitor.State().Sealed {
+		t.Error("Expected last known sealed state to be kept after a failed check")
+	}
+}
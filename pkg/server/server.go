@@ -3,13 +3,17 @@ package server
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault-client-go"
 	"github.com/hashicorp/vault-client-go/schema"
 	"github.com/siderolabs/kms-client/api/kms"
+	"github.com/soulkyu/talos-kms-vault/pkg/tracing"
 	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -20,7 +24,47 @@ type Server struct {
 	logger *slog.Logger
 	client *vault.Client
 
+	// getClient optionally supplies the current client so re-authentication is picked up
+	getClient func() (*vault.Client, error)
+
+	mountPath          string
 	vaultRequestOption vault.RequestOption
+
+	// vaultHealth optionally gates readiness on Vault connectivity
+	vaultHealth *VaultHealthChecker
+
+	// sealMonitor optionally gates readiness on Vault being unsealed
+	sealMonitor *SealStatusMonitor
+
+	// failSealOnStandby rejects Seal up front while the seal monitor reports an unusable standby
+	failSealOnStandby bool
+
+	// keyVersions optionally enforces minimum key versions on node keys
+	keyVersions *keyVersionEnforcer
+
+	// responseWrapTTL optionally response-wraps Seal output (0 disables)
+	responseWrapTTL time.Duration
+
+	// sealFormatVersion selects the Seal output format (SealFormatRaw passes ciphertext through)
+	sealFormatVersion int
+
+	// maxCiphertextAge optionally rejects Unseal of v1 data sealed longer ago (0 disables)
+	maxCiphertextAge time.Duration
+
+	// sealDegradation optionally marks Seal degraded after repeated permission denials
+	sealDegradation *sealDegradation
+
+	// nodeCacheCapacity bounds per-node caches (0 uses DefaultNodeCacheCapacity)
+	nodeCacheCapacity int
+
+	// maintenance rejects Seal and Unseal while enabled
+	maintenance *maintenanceMode
+
+	// unsealCache optionally answers repeated Unseal requests from memory
+	unsealCache *UnsealCache
+
+	// logVaultRequestID logs the Vault request ID of each Transit call
+	logVaultRequestID bool
 }
 
 func wrapError(err error) error {
@@ -28,34 +72,168 @@ func wrapError(err error) error {
 		return status.Error(codes.PermissionDenied, "Forbidden")
 	}
 
+	// A request abandoned by the client is not a server fault
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	}
+
 	return status.Error(codes.Internal, "Internal Error")
 }
 
-func (s Server) Seal(ctx context.Context, request *kms.Request) (*kms.Response, error) {
+func (s Server) Seal(ctx context.Context, request *kms.Request) (_ *kms.Response, err error) {
+	ctx, span := s.startSpan(ctx, "kms.Seal", "seal", request)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := s.maintenanceError(); err != nil {
+		return nil, err
+	}
+
 	// Log with sanitized UUID
 	s.logger.InfoContext(ctx, "Sealing data", "node", validation.SanitizeForLogging(request.NodeUuid))
 
+	// Requests reach the server only after validation
+	recordRequestDigest(ctx, request.Data)
+
+	if reason := s.sealBlockedReason(); reason != "" {
+		s.logger.WarnContext(ctx, "Rejecting seal request",
+			"node", validation.SanitizeForLogging(request.NodeUuid),
+			"reason", reason)
+		return nil, status.Error(codes.Unavailable, reason)
+	}
+
+	convergent, err := convergentRequested(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.vaultClient()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
 	req := schema.TransitEncryptRequest{Plaintext: base64.StdEncoding.EncodeToString(request.Data)}
-	res, err := s.client.Secrets.TransitEncrypt(ctx, request.NodeUuid, req, s.vaultRequestOption)
+	if convergent {
+		if err := s.checkConvergentKey(ctx, client, request.NodeUuid); err != nil {
+			return nil, err
+		}
+		req.Context = derivationContext(request.NodeUuid)
+	}
+
+	res, err := client.Secrets.TransitEncrypt(ctx, request.NodeUuid, req, s.vaultRequestOption)
+
+	if !convergent && isMissingContextError(err) {
+		return nil, errConvergentKey
+	}
+
+	if isTransitKeyNotFound(err) {
+		return nil, s.transitKeyNotFoundError(ctx, "seal", request.NodeUuid)
+	}
 
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Error while sealing data",
 			"node", validation.SanitizeForLogging(request.NodeUuid),
 			"error", err)
+		return nil, s.recordSealResult(ctx, request.NodeUuid, wrapError(err))
+	}
+
+	s.recordSealResult(ctx, request.NodeUuid, nil)
+	s.recordVaultRequestID(ctx, "Sealed data", request.NodeUuid, res.RequestID)
+	s.ensureKeyVersions(ctx, client, request.NodeUuid)
+
+	sealed, err := s.frameSealed(request.NodeUuid, res.Data["ciphertext"].(string), time.Now())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error while framing sealed data",
+			"node", validation.SanitizeForLogging(request.NodeUuid),
+			"error", err)
 		return nil, wrapError(err)
 	}
 
-	data := []byte(res.Data["ciphertext"].(string))
+	if s.responseWrapTTL > 0 {
+		token, err := s.wrapCiphertext(ctx, client, string(sealed))
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Error while wrapping sealed data",
+				"node", validation.SanitizeForLogging(request.NodeUuid),
+				"error", err)
+			return nil, wrapError(err)
+		}
 
-	return &kms.Response{Data: data}, nil
+		recordResponseDigest(ctx, []byte(token))
+		return &kms.Response{Data: []byte(token)}, nil
+	}
+
+	recordResponseDigest(ctx, sealed)
+	return &kms.Response{Data: sealed}, nil
 }
 
-func (s Server) Unseal(ctx context.Context, request *kms.Request) (*kms.Response, error) {
+func (s Server) Unseal(ctx context.Context, request *kms.Request) (_ *kms.Response, err error) {
+	ctx, span := s.startSpan(ctx, "kms.Unseal", "unseal", request)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	if err := s.maintenanceError(); err != nil {
+		return nil, err
+	}
+
 	// Log with sanitized UUID
 	s.logger.InfoContext(ctx, "Unsealing data", "node", validation.SanitizeForLogging(request.NodeUuid))
 
-	req := schema.TransitDecryptRequest{Ciphertext: string(request.Data)}
-	res, err := s.client.Secrets.TransitDecrypt(ctx, request.NodeUuid, req, s.vaultRequestOption)
+	// Requests reach the server only after validation
+	recordRequestDigest(ctx, request.Data)
+
+	ciphertext, format, sealedAt, err := s.unframeForNode(ctx, request.Data, request.NodeUuid)
+	if err != nil {
+		return nil, err
+	}
+	unsealFormats.WithLabelValues(sealFormatLabel(format)).Inc()
+
+	if err := s.checkCiphertextAge(sealedAt); err != nil {
+		s.logger.WarnContext(ctx, "Rejecting unseal of expired ciphertext",
+			"node", validation.SanitizeForLogging(request.NodeUuid),
+			"sealedAt", sealedAt)
+		return nil, err
+	}
+
+	// A key version hint must match the version embedded in the ciphertext
+	version, err := keyVersionHint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > 0 {
+		if err := checkKeyVersion(ciphertext, version); err != nil {
+			s.logger.WarnContext(ctx, "Rejecting unseal not matching the key version hint",
+				"node", validation.SanitizeForLogging(request.NodeUuid),
+				"keyVersion", version,
+				"error", err)
+			return nil, err
+		}
+	}
+
+	cacheKey, cached, hit := s.unsealCache.lookup(ctx, request)
+	if hit {
+		recordResponseDigest(ctx, cached)
+		return &kms.Response{Data: cached}, nil
+	}
+
+	client, err := s.vaultClient()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	req := schema.TransitDecryptRequest{Ciphertext: ciphertext}
+	res, err := client.Secrets.TransitDecrypt(ctx, request.NodeUuid, req, s.vaultRequestOption)
+
+	// Ciphertext of a convergent key needs the derivation context it was sealed with
+	if isMissingContextError(err) {
+		req.Context = derivationContext(request.NodeUuid)
+		res, err = client.Secrets.TransitDecrypt(ctx, request.NodeUuid, req, s.vaultRequestOption)
+	}
+
+	if isTransitKeyNotFound(err) {
+		return nil, s.transitKeyNotFoundError(ctx, "unseal", request.NodeUuid)
+	}
 
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Error while unsealing data",
@@ -64,14 +242,84 @@ func (s Server) Unseal(ctx context.Context, request *kms.Request) (*kms.Response
 		return nil, wrapError(err)
 	}
 
+	s.recordVaultRequestID(ctx, "Unsealed data", request.NodeUuid, res.RequestID)
+
 	data, err := base64.StdEncoding.DecodeString(res.Data["plaintext"].(string))
 	if err != nil {
 		return nil, wrapError(err)
 	}
 
+	s.unsealCache.store(cacheKey, data)
+
+	recordResponseDigest(ctx, data)
 	return &kms.Response{Data: data}, nil
 }
 
+// startSpan starts a tracing span for a Vault Transit operation
+func (s Server) startSpan(ctx context.Context, name, operation string, request *kms.Request) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name, trace.WithAttributes(
+		tracing.AttrOperation.String(operation),
+		tracing.AttrNode.String(validation.SanitizeForLogging(request.NodeUuid)),
+		tracing.AttrMount.String(s.mountPath),
+	))
+}
+
 func NewServer(client *vault.Client, logger *slog.Logger, mountPath string) *Server {
-	return &Server{client: client, logger: logger, vaultRequestOption: vault.WithMountPath(mountPath)}
+	return &Server{
+		client:             client,
+		logger:             logger,
+		mountPath:          mountPath,
+		vaultRequestOption: vault.WithMountPath(mountPath),
+		maintenance:        &maintenanceMode{},
+	}
+}
+
+// SetClientSource makes the server fetch the Vault client on each request, so a client
+// replaced by re-authentication (e.g. after a Vault address change) is used immediately
+func (s *Server) SetClientSource(getClient func() (*vault.Client, error)) {
+	s.getClient = getClient
+}
+
+// vaultClient returns the current Vault client
+func (s *Server) vaultClient() (*vault.Client, error) {
+	if s.getClient != nil {
+		return s.getClient()
+	}
+
+	return s.client, nil
+}
+
+// SetNodeCacheCapacity bounds the per-node state the server keeps, such as the observed key
+// versions. The least recently used nodes are evicted once the capacity is reached.
+func (s *Server) SetNodeCacheCapacity(capacity int) {
+	s.nodeCacheCapacity = capacity
+
+	if s.keyVersions != nil {
+		s.keyVersions.observed.setCapacity(capacity)
+	}
+}
+
+// SetVaultHealthChecker makes readiness depend on the cached Vault health check
+func (s *Server) SetVaultHealthChecker(checker *VaultHealthChecker) {
+	s.vaultHealth = checker
+}
+
+// SetSealStatusMonitor makes readiness depend on Vault being unsealed
+func (s *Server) SetSealStatusMonitor(monitor *SealStatusMonitor) {
+	s.sealMonitor = monitor
+}
+
+// SetFailSealOnStandby makes Seal fail fast with Unavailable while the seal status monitor
+// reports a standby Vault node with request forwarding disabled
+func (s *Server) SetFailSealOnStandby(fail bool) {
+	s.failSealOnStandby = fail
+}
+
+// sealBlockedReason returns why Seal should fail fast, or an empty string
+func (s *Server) sealBlockedReason() string {
+	if !s.failSealOnStandby || s.sealMonitor == nil {
+		return ""
+	}
+
+	return s.sealMonitor.SealBlockedReason()
 }
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const otherNode = "6ba7b810-9dad-41d1-80b4-00c04fd430c8"
+
+func newTestServer(t *testing.T, transit *fakeTransit) *Server {
+	t.Helper()
+	return NewServer(transit.client(t), slog.New(slog.NewTextHandler(os.Stderr, nil)), transit.mount)
+}
+
+func TestServer_SealUnsealRoundTrip(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+
+	payloads := map[string][]byte{
+		"text":   []byte("disk encryption key"),
+		"binary": {0x00, 0xff, 0x10, 0x80},
+		"empty":  {},
+	}
+
+	for name, plaintext := range payloads {
+		t.Run(name, func(t *testing.T) {
+			sealed, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: plaintext})
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+
+			if !strings.HasPrefix(string(sealed.Data), "vault:v1:") {
+				t.Errorf("Seal() data = %q, want transit ciphertext", sealed.Data)
+			}
+
+			unsealed, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: sealed.Data})
+			if err != nil {
+				t.Fatalf("Unseal() error = %v", err)
+			}
+
+			if !bytes.Equal(unsealed.Data, plaintext) {
+				t.Errorf("Unseal() data = %x, want %x", unsealed.Data, plaintext)
+			}
+		})
+	}
+}
+
+func TestServer_ErrorMapping(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+
+	sealed, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		failStatus  int
+		node        string
+		ciphertext  []byte
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			name:        "permission denied",
+			failStatus:  http.StatusForbidden,
+			node:        retiredNode,
+			ciphertext:  sealed.Data,
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "Forbidden",
+		},
+		{
+			name:        "vault unavailable",
+			failStatus:  http.StatusServiceUnavailable,
+			node:        retiredNode,
+			ciphertext:  sealed.Data,
+			wantCode:    codes.Internal,
+			wantMessage: "Internal Error",
+		},
+		{
+			name:        "ciphertext of another node",
+			node:        otherNode,
+			ciphertext:  sealed.Data,
+			wantCode:    codes.Internal,
+			wantMessage: "Internal Error",
+		},
+		{
+			name:        "malformed ciphertext",
+			node:        retiredNode,
+			ciphertext:  []byte("not-a-ciphertext"),
+			wantCode:    codes.Internal,
+			wantMessage: "Internal Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transit.setFailure(tt.failStatus)
+			defer transit.setFailure(0)
+
+			// Make sure the other node's key exists, so only the binding is wrong
+			if tt.node == otherNode {
+				if _, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: otherNode, Data: []byte("x")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: tt.node, Data: tt.ciphertext})
+
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("Unseal() error = %v, want %v %q", err, tt.wantCode, tt.wantMessage)
+			}
+
+			// Vault error details must not leak to clients
+			if strings.Contains(st.Message(), "authentication failed") || strings.Contains(st.Message(), "prefix") {
+				t.Errorf("Unseal() leaked Vault error detail: %q", st.Message())
+			}
+		})
+	}
+
+	// Seal maps errors the same way
+	transit.setFailure(http.StatusForbidden)
+	defer transit.setFailure(0)
+
+	if _, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Seal() error = %v, want PermissionDenied", err)
+	}
+}
+
+func TestServer_ContextCancellation(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+
+	block := make(chan struct{})
+	defer close(block)
+	transit.setBlock(block)
+
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		wantCode codes.Code
+	}{
+		{
+			name: "cancelled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantCode: codes.Canceled,
+		},
+		{
+			name: "deadline exceeded",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantCode: codes.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, err := srv.Seal(ctx, &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Seal() error = %v, want %v", err, tt.wantCode)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Seal() returned after %s, want prompt return on cancellation", elapsed)
+			}
+		})
+	}
+
+	if transit.requestCount() == 0 {
+		t.Error("Expected the requests to reach Vault before being cancelled")
+	}
+}
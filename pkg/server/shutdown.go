@@ -0,0 +1,40 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long in-flight requests may take to finish on shutdown
+const DefaultShutdownTimeout = 30 * time.Second
+
+// GRPCStopper is the part of *grpc.Server used to shut it down
+type GRPCStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+// DrainGRPC stops the server accepting requests and waits up to timeout for in-flight ones
+// to finish, then cancels whatever is left. It reports whether every request finished in
+// time. The server must be created with grpc.WaitForHandlers(true): once DrainGRPC returns
+// no handler is running, so the Vault token they use can be revoked safely.
+func DrainGRPC(srv GRPCStopper, timeout time.Duration, logger *slog.Logger) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.GracefulStop()
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		logger.Warn("In-flight requests did not finish in time, cancelling them", "timeout", timeout)
+
+		// Stop cancels the remaining requests and waits for their handlers to return
+		srv.Stop()
+		<-done
+
+		return false
+	}
+}
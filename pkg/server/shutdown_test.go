@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// blockingKMS holds each Seal until released or cancelled, then records that it returned
+type blockingKMS struct {
+	kms.UnimplementedKMSServiceServer
+	started chan struct{}
+	release chan struct{}
+	events  chan string
+}
+
+func (b *blockingKMS) Seal(ctx context.Context, request *kms.Request) (*kms.Response, error) {
+	close(b.started)
+	defer func() { b.events <- "handler returned" }()
+
+	select {
+	case <-b.release:
+		return &kms.Response{Data: request.Data}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestDrainGRPC(t *testing.T) {
+	tests := []struct {
+		name        string
+		release     bool
+		wantDrained bool
+	}{
+		{name: "in-flight request finishes", release: true, wantDrained: true},
+		{name: "stuck request is cancelled", release: false, wantDrained: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := &blockingKMS{started: make(chan struct{}), release: make(chan struct{}), events: make(chan string, 2)}
+			grpcSrv := grpc.NewServer(grpc.WaitForHandlers(true))
+			kms.RegisterKMSServiceServer(grpcSrv, service)
+			go grpcSrv.Serve(lis)
+
+			conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			callErr := make(chan error, 1)
+			go func() {
+				_, err := kms.NewKMSServiceClient(conn).Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+				callErr <- err
+			}()
+			<-service.started
+
+			if tt.release {
+				time.AfterFunc(50*time.Millisecond, func() { close(service.release) })
+			}
+
+			// Shut down the way main does: drain, then revoke the token
+			drained := DrainGRPC(grpcSrv, 500*time.Millisecond, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			service.events <- "token revoked"
+
+			if drained != tt.wantDrained {
+				t.Errorf("DrainGRPC() = %v, want %v", drained, tt.wantDrained)
+			}
+
+			if first := <-service.events; first != "handler returned" {
+				t.Errorf("first shutdown event = %q, want the in-flight handler to return before the token is revoked", first)
+			}
+
+			if err := <-callErr; (err == nil) != tt.wantDrained {
+				t.Errorf("in-flight Seal() error = %v, want success only when drained", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// RequestStats are the gRPC request counters of this instance
+type RequestStats struct {
+	InFlight int64 `json:"inFlight"`
+	Total    int64 `json:"total"`
+
+	// ByMethod counts handled requests by method name and result code
+	ByMethod map[string]map[string]int64 `json:"byMethod"`
+}
+
+// StatsAggregator builds one JSON document from the stats of every subsystem, for quick
+// inspection without scraping Prometheus
+type StatsAggregator struct {
+	sources AdminStatsSources
+}
+
+// NewStatsAggregator creates an aggregator over the given sources; nil sources are left out
+func NewStatsAggregator(sources AdminStatsSources) *StatsAggregator {
+	return &StatsAggregator{sources: sources}
+}
+
+// Snapshot reads every subsystem through its own concurrency-safe accessor and stamps the
+// result with the time collection started. Subsystems are read one after the other, so
+// counters of requests completing meanwhile can be a few requests apart.
+func (a *StatsAggregator) Snapshot(ctx context.Context) AdminStats {
+	stats := AdminStats{Timestamp: time.Now().UTC()}
+
+	if a.sources.Validation != nil {
+		success, failures := a.sources.Validation.GetValidationStats()
+		stats.Validation = &ValidationStats{Successes: success, Failures: failures}
+	}
+
+	if a.sources.Leadership != nil {
+		info := a.sources.Leadership.GetLeadershipInfo()
+		stats.Leadership = &info
+	}
+
+	if a.sources.VaultHealth != nil {
+		status := a.sources.VaultHealth.Status(ctx)
+		stats.Vault = &status
+	}
+
+	if a.sources.Auth != nil {
+		status := a.sources.Auth.Status()
+		stats.Auth = &status
+	}
+
+	stats.Requests = requestStats()
+
+	return stats
+}
+
+// ServeHTTP returns the snapshot on GET
+func (a *StatsAggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.Snapshot(r.Context()))
+}
+
+// requestStats reads the gRPC request counters kept by MetricsInterceptor
+func requestStats() *RequestStats {
+	stats := &RequestStats{
+		ByMethod: make(map[string]map[string]int64),
+	}
+
+	var inflight dto.Metric
+	if err := inflightRequests.Write(&inflight); err == nil {
+		stats.InFlight = int64(inflight.GetGauge().GetValue())
+	}
+
+	samples := make(chan prometheus.Metric)
+	go func() {
+		grpcRequests.Collect(samples)
+		close(samples)
+	}()
+
+	for metric := range samples {
+		var sample dto.Metric
+		if err := metric.Write(&sample); err != nil {
+			continue
+		}
+
+		var method, code string
+		for _, label := range sample.GetLabel() {
+			switch label.GetName() {
+			case "method":
+				method = path.Base(label.GetValue())
+			case "code":
+				code = label.GetValue()
+			}
+		}
+
+		if stats.ByMethod[method] == nil {
+			stats.ByMethod[method] = make(map[string]int64)
+		}
+
+		count := int64(sample.GetCounter().GetValue())
+		stats.ByMethod[method][code] += count
+		stats.Total += count
+	}
+
+	return stats
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatsAggregator_Requests(t *testing.T) {
+	interceptor := MetricsInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+	aggregator := NewStatsAggregator(AdminStatsSources{})
+
+	before := aggregator.Snapshot(context.Background()).Requests
+
+	interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "not the leader")
+	})
+
+	start := time.Now().UTC()
+	after := aggregator.Snapshot(context.Background())
+
+	if after.Timestamp.Before(start.Add(-time.Second)) || after.Timestamp.After(time.Now().UTC()) {
+		t.Errorf("timestamp = %v, want the time of the snapshot", after.Timestamp)
+	}
+
+	if got := after.Requests.ByMethod["Seal"]["OK"] - before.ByMethod["Seal"]["OK"]; got != 1 {
+		t.Errorf("Seal OK increased by %d, want 1", got)
+	}
+	if got := after.Requests.ByMethod["Seal"]["Unavailable"] - before.ByMethod["Seal"]["Unavailable"]; got != 1 {
+		t.Errorf("Seal Unavailable increased by %d, want 1", got)
+	}
+	if got := after.Requests.Total - before.Total; got != 2 {
+		t.Errorf("total increased by %d, want 2", got)
+	}
+}
+
+func TestStatsAggregator_ConcurrentSnapshots(t *testing.T) {
+	interceptor := MetricsInterceptor()
+	server := httptest.NewServer(NewStatsAggregator(AdminStatsSources{
+		Validation: &fakeValidationStats{success: 1},
+	}))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		// Requests keep creating counters while snapshots read them
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Unseal"}
+				interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, status.Error(codes.Code(j%17), "")
+				})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				resp, err := http.Get(server.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				var stats AdminStats
+				err = json.NewDecoder(resp.Body).Decode(&stats)
+				resp.Body.Close()
+				if err != nil || stats.Requests == nil || stats.Validation == nil {
+					t.Errorf("stats = %+v, %v, want requests and validation", stats, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
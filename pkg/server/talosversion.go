@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TalosVersionMetadataKey is the gRPC metadata key a client may set to its Talos version
+const TalosVersionMetadataKey = "x-talos-version"
+
+// unknownTalosVersion buckets requests that carry no Talos version
+const unknownTalosVersion = "unknown"
+
+// otherTalosVersion buckets requests whose Talos version is malformed or over the limit
+const otherTalosVersion = "other"
+
+// DefaultMaxTalosVersions bounds how many distinct Talos versions are reported
+const DefaultMaxTalosVersions = 32
+
+var (
+	// talosVersionPattern matches a well-formed version header value such as "v1.7.4" or "v1.8.0-beta.1"
+	talosVersionPattern = regexp.MustCompile(`^v(\d{1,3})\.(\d{1,3})\.\d{1,4}(?:-[0-9A-Za-z.]+)?$`)
+
+	// talosUserAgentPattern finds the Talos version in a user agent such as "talos/v1.7.4 grpc-go/1.62.1"
+	talosUserAgentPattern = regexp.MustCompile(`\btalos/v(\d{1,3})\.(\d{1,3})\.\d{1,4}\b`)
+)
+
+// TalosVersionMeter counts requests by the Talos version of the client. Versions are
+// reduced to major.minor, and only the first maxVersions distinct well-formed versions get
+// their own label, so a client can't grow the metric without bound. Malformed versions are
+// counted as other and never take a label slot.
+type TalosVersionMeter struct {
+	maxVersions int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewTalosVersionMeter creates a meter reporting at most maxVersions distinct versions
+// (DefaultMaxTalosVersions when maxVersions is not positive)
+func NewTalosVersionMeter(maxVersions int) *TalosVersionMeter {
+	if maxVersions <= 0 {
+		maxVersions = DefaultMaxTalosVersions
+	}
+
+	return &TalosVersionMeter{maxVersions: maxVersions, seen: make(map[string]struct{})}
+}
+
+// UnaryServerInterceptor returns a unary interceptor that tags the request span with the
+// client's Talos version and counts the request by version and result code
+func (m *TalosVersionMeter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		version := m.label(talosVersion(ctx))
+		trace.SpanFromContext(ctx).SetAttributes(tracing.AttrTalosVersion.String(version))
+
+		resp, err := handler(ctx, req)
+
+		talosVersionRequests.WithLabelValues(version, status.Code(err).String()).Inc()
+
+		return resp, err
+	}
+}
+
+// label returns the metric label for version, falling back to other once the limit of
+// distinct versions is reached
+func (m *TalosVersionMeter) label(version string) string {
+	switch version {
+	case "":
+		return unknownTalosVersion
+	case otherTalosVersion:
+		return otherTalosVersion
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.seen[version]; ok {
+		return version
+	}
+
+	if len(m.seen) >= m.maxVersions {
+		return otherTalosVersion
+	}
+
+	m.seen[version] = struct{}{}
+
+	return version
+}
+
+// talosVersion returns the client's Talos version as "vMAJOR.MINOR" from the x-talos-version
+// header, or else the user agent. It is other when only a malformed header is present, and
+// empty when the client sends no version at all.
+func talosVersion(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(TalosVersionMetadataKey)
+	if len(values) == 1 {
+		if match := talosVersionPattern.FindStringSubmatch(values[0]); match != nil {
+			return majorMinor(match[1], match[2])
+		}
+	}
+
+	for _, userAgent := range md.Get("user-agent") {
+		if match := talosUserAgentPattern.FindStringSubmatch(userAgent); match != nil {
+			return majorMinor(match[1], match[2])
+		}
+	}
+
+	if len(values) > 0 {
+		return otherTalosVersion
+	}
+
+	return ""
+}
+
+// majorMinor formats matched version digits as "vMAJOR.MINOR", dropping leading zeros
+func majorMinor(major, minor string) string {
+	ma, _ := strconv.Atoi(major)
+	mi, _ := strconv.Atoi(minor)
+
+	return "v" + strconv.Itoa(ma) + "." + strconv.Itoa(mi)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTalosVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "header", md: metadata.Pairs(TalosVersionMetadataKey, "v1.7.4"), want: "v1.7"},
+		{name: "prerelease header", md: metadata.Pairs(TalosVersionMetadataKey, "v1.8.0-beta.1"), want: "v1.8"},
+		{name: "header leading zeros", md: metadata.Pairs(TalosVersionMetadataKey, "v01.07.0"), want: "v1.7"},
+		{name: "header without prefix", md: metadata.Pairs(TalosVersionMetadataKey, "1.8.0"), want: otherTalosVersion},
+		{name: "header without patch", md: metadata.Pairs(TalosVersionMetadataKey, "v1.8"), want: otherTalosVersion},
+		{name: "user agent", md: metadata.Pairs("user-agent", "talos/v1.6.7 grpc-go/1.62.1"), want: "v1.6"},
+		{
+			name: "header wins over user agent",
+			md:   metadata.Pairs(TalosVersionMetadataKey, "v1.8.1", "user-agent", "talos/v1.6.7"),
+			want: "v1.8",
+		},
+		{
+			name: "unparseable header falls back to user agent",
+			md:   metadata.Pairs(TalosVersionMetadataKey, "latest", "user-agent", "talos/v1.6.7"),
+			want: "v1.6",
+		},
+		{name: "plain grpc user agent", md: metadata.Pairs("user-agent", "grpc-go/1.62.1"), want: ""},
+		{name: "malformed user agent", md: metadata.Pairs("user-agent", "talos/v1.6 grpc-go/1.62.1"), want: ""},
+		{name: "garbage header", md: metadata.Pairs(TalosVersionMetadataKey, "v1.7.4; drop table"), want: otherTalosVersion},
+		{name: "none", md: metadata.MD{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := talosVersion(metadata.NewIncomingContext(context.Background(), tt.md)); got != tt.want {
+				t.Errorf("talosVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTalosVersionMeter(t *testing.T) {
+	interceptor := NewTalosVersionMeter(2).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Seal"}
+
+	call := func(version string, err error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TalosVersionMetadataKey, version))
+		interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+
+	counter := func(version string, code codes.Code) float64 {
+		return testutil.ToFloat64(talosVersionRequests.WithLabelValues(version, code.String()))
+	}
+
+	v17, v18Denied := counter("v1.7", codes.OK), counter("v1.8", codes.PermissionDenied)
+	otherBefore := counter(otherTalosVersion, codes.OK)
+
+	call("v1.7.4", nil)
+	call("not a version", nil)
+	call("v1.7.5", nil)
+	call("v1.8.0", status.Error(codes.PermissionDenied, "Forbidden"))
+
+	// The limit of two distinct versions is reached, later versions are other
+	call("v1.9.0", nil)
+
+	if got := counter("v1.7", codes.OK) - v17; got != 2 {
+		t.Errorf("v1.7 OK requests increased by %v, want 2", got)
+	}
+	if got := counter("v1.8", codes.PermissionDenied) - v18Denied; got != 1 {
+		t.Errorf("v1.8 PermissionDenied requests increased by %v, want 1", got)
+	}
+	if got := counter(otherTalosVersion, codes.OK) - otherBefore; got != 2 {
+		t.Errorf("other OK requests increased by %v, want 2", got)
+	}
+	if got := counter("v1.9", codes.OK); got != 0 {
+		t.Errorf("v1.9 requests = %v, want them counted as other", got)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+// Roles labelling the token health metrics
+const (
+	roleLeader   = "leader"
+	roleFollower = "follower"
+)
+
+// SetTokenStatus reports the Vault token health of this instance under its current role.
+// Followers authenticate and renew their token like the leader, so a promoted follower can
+// serve at once; the metrics show whether it actually had a valid token when promoted.
+func (las *LeaderAwareServer) SetTokenStatus(reporter AuthStatusReporter) {
+	las.mu.Lock()
+	defer las.mu.Unlock()
+
+	las.tokenStatus = reporter
+}
+
+// tokenHealthy reports whether this instance holds a valid, renewing Vault token that has
+// not outlived its TTL; known is false when no reporter is set
+func (las *LeaderAwareServer) tokenHealthy() (healthy, known bool) {
+	las.mu.RLock()
+	reporter := las.tokenStatus
+	las.mu.RUnlock()
+
+	if reporter == nil {
+		return false, false
+	}
+
+	// A token past its TTL has expired even if no renewal has failed yet
+	status := reporter.Status()
+	expired := status.TTLSeconds > 0 && status.RemainingSeconds <= 0
+
+	return status.Healthy && !expired, true
+}
+
+// recordPromotionToken counts the promotion by the token health at that instant
+func (las *LeaderAwareServer) recordPromotionToken() {
+	healthy, known := las.tokenHealthy()
+	if !known {
+		return
+	}
+
+	if !healthy {
+		leaderPromotions.WithLabelValues("unhealthy").Inc()
+		las.logger.Warn("Became leader without a healthy Vault token - requests will fail until authentication recovers")
+		return
+	}
+
+	leaderPromotions.WithLabelValues("healthy").Inc()
+}
+
+// updateTokenMetrics sets the token health gauge under the current role, clearing the other
+func (las *LeaderAwareServer) updateTokenMetrics() {
+	tokenHealthy.Reset()
+
+	healthy, known := las.tokenHealthy()
+	if !known {
+		return
+	}
+
+	las.mu.RLock()
+	isLeader := las.isLeader
+	las.mu.RUnlock()
+
+	role := roleFollower
+	if isLeader {
+		role = roleLeader
+	}
+
+	tokenHealthy.WithLabelValues(role).Set(gaugeBool(healthy))
+}
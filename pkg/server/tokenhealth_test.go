@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/soulkyu/talos-kms-vault/pkg/auth"
+)
+
+func TestLeaderAwareServer_TokenHealth(t *testing.T) {
+	las := newTestLeaderAwareServer()
+	reporter := &fakeAuthStatus{status: auth.Status{Method: auth.AuthMethodAppRole, Healthy: true}}
+	las.SetTokenStatus(reporter)
+
+	// A follower renews its token too
+	las.updateTokenMetrics()
+	if got := testutil.ToFloat64(tokenHealthy.WithLabelValues(roleFollower)); got != 1 {
+		t.Errorf("follower kms_auth_token_healthy = %v, want 1", got)
+	}
+
+	healthyBefore := testutil.ToFloat64(leaderPromotions.WithLabelValues("healthy"))
+	las.OnBecomeLeader(context.Background())
+	if got := testutil.ToFloat64(leaderPromotions.WithLabelValues("healthy")) - healthyBefore; got != 1 {
+		t.Errorf("healthy promotions increased by %v, want 1", got)
+	}
+
+	// A token past its TTL is unhealthy even before a renewal fails
+	reporter.status.TTLSeconds, reporter.status.RemainingSeconds = 3600, 0
+	las.updateTokenMetrics()
+	if got := testutil.ToFloat64(tokenHealthy.WithLabelValues(roleLeader)); got != 0 {
+		t.Errorf("leader kms_auth_token_healthy with an expired token = %v, want 0", got)
+	}
+
+	reporter.status.RemainingSeconds = 60
+	las.updateTokenMetrics()
+	if got := testutil.ToFloat64(tokenHealthy.WithLabelValues(roleLeader)); got != 1 {
+		t.Errorf("leader kms_auth_token_healthy with time left = %v, want 1", got)
+	}
+
+	reporter.status.Healthy = false
+	las.updateTokenMetrics()
+	if got := testutil.ToFloat64(tokenHealthy.WithLabelValues(roleLeader)); got != 0 {
+		t.Errorf("leader kms_auth_token_healthy = %v, want 0", got)
+	}
+
+	las.OnLoseLeadership()
+	unhealthyBefore := testutil.ToFloat64(leaderPromotions.WithLabelValues("unhealthy"))
+	las.OnBecomeLeader(context.Background())
+	if got := testutil.ToFloat64(leaderPromotions.WithLabelValues("unhealthy")) - unhealthyBefore; got != 1 {
+		t.Errorf("unhealthy promotions increased by %v, want 1", got)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrTransitKeyNotFound is wrapped by the error returned when the transit key a Seal or
+// Unseal refers to does not exist
+var ErrTransitKeyNotFound = errors.New("transit key not found")
+
+// missingKeyMessages are the Vault error messages for an operation on a key that does not exist
+var missingKeyMessages = []string{
+	"encryption key not found",
+	"no existing key named",
+}
+
+// isTransitKeyNotFound reports whether Vault failed a Transit operation because the key does
+// not exist: a 400 with one of missingKeyMessages, or a 404 other than an unmounted path
+func isTransitKeyNotFound(err error) bool {
+	var respErr *vault.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	message := err.Error()
+
+	switch respErr.StatusCode {
+	case http.StatusNotFound:
+		return !strings.Contains(message, "no handler for route")
+	case http.StatusBadRequest:
+		for _, missing := range missingKeyMessages {
+			if strings.Contains(message, missing) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// transitKeyNotFoundError counts a missing transit key and returns the FailedPrecondition
+// telling operators to create it
+func (s Server) transitKeyNotFoundError(ctx context.Context, operation, nodeUUID string) error {
+	transitKeyMissing.WithLabelValues(operation).Inc()
+
+	s.logger.ErrorContext(ctx, "Transit key does not exist - create it for the node",
+		"operation", operation,
+		"node", validation.SanitizeForLogging(nodeUUID),
+		"mountPath", s.mountPath)
+
+	return &transitKeyError{status: status.Newf(codes.FailedPrecondition,
+		"%v: create the node's key with 'vault write -f %s/keys/<node UUID>'",
+		ErrTransitKeyNotFound, strings.Trim(s.mountPath, "/"))}
+}
+
+// transitKeyError is the FailedPrecondition returned for a missing transit key. It wraps
+// ErrTransitKeyNotFound, so callers can match it with errors.Is.
+type transitKeyError struct {
+	status *status.Status
+}
+
+func (e *transitKeyError) Error() string {
+	return e.status.Err().Error()
+}
+
+// GRPCStatus returns the FailedPrecondition status sent to the client
+func (e *transitKeyError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *transitKeyError) Unwrap() error {
+	return ErrTransitKeyNotFound
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransitKeyNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "decrypt with missing key", err: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"encryption key not found"}}, want: true},
+		{name: "no existing key", err: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"no existing key named 550e8400 could be found"}}, want: true},
+		{name: "key not found", err: &vault.ResponseError{StatusCode: http.StatusNotFound}, want: true},
+		{name: "mount missing", err: &vault.ResponseError{StatusCode: http.StatusNotFound, Errors: []string{"no handler for route \"transit/decrypt/x\". route entry not found."}}},
+		{name: "other bad request", err: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid ciphertext: no prefix"}}},
+		{name: "forbidden", err: &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}}},
+		{name: "not a response error", err: errors.New("encryption key not found")},
+		{name: "nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransitKeyNotFound(tt.err); got != tt.want {
+				t.Errorf("isTransitKeyNotFound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_TransitKeyNotFound(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	ctx := context.Background()
+
+	sealed, err := srv.Seal(ctx, &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is deleted out from under the node
+	transit.mu.Lock()
+	delete(transit.keys, retiredNode)
+	transit.mu.Unlock()
+
+	wantMissing := func(t *testing.T, err error) {
+		t.Helper()
+
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("error code = %v, want FailedPrecondition (error %v)", status.Code(err), err)
+		}
+		if !errors.Is(err, ErrTransitKeyNotFound) {
+			t.Errorf("error = %v, want it to wrap ErrTransitKeyNotFound", err)
+		}
+		if msg := status.Convert(err).Message(); !strings.Contains(msg, ErrTransitKeyNotFound.Error()) || !strings.Contains(msg, "vault write -f transit/keys/") {
+			t.Errorf("error message = %q, want it to name the missing key and how to create it", msg)
+		}
+	}
+
+	missingBefore := testutil.ToFloat64(transitKeyMissing.WithLabelValues("unseal"))
+
+	_, err = srv.Unseal(ctx, &kms.Request{NodeUuid: retiredNode, Data: sealed.Data})
+	wantMissing(t, err)
+
+	if got := testutil.ToFloat64(transitKeyMissing.WithLabelValues("unseal")) - missingBefore; got != 1 {
+		t.Errorf("kms_transit_key_missing_total{operation=\"unseal\"} increased by %v, want 1", got)
+	}
+}
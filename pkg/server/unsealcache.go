@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/metadata"
+)
+
+// NoCacheMetadataKey is the gRPC metadata key that makes an Unseal bypass the cache
+const NoCacheMetadataKey = "x-no-cache"
+
+// DefaultUnsealCacheMaxEntries bounds the number of cached Unseal responses
+const DefaultUnsealCacheMaxEntries = 10000
+
+// UnsealCache serves repeated Unseal requests from memory for a TTL, so boot storms don't
+// turn into one Vault decrypt per attempt. Entries hold plaintext, so the cache is opt-in.
+// Once full, the least recently used entry makes room for a new one.
+type UnsealCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	entries *nodeCache[unsealCacheEntry]
+}
+
+type unsealCacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewUnsealCache creates an Unseal response cache
+func NewUnsealCache(ttl time.Duration, maxEntries int) *UnsealCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultUnsealCacheMaxEntries
+	}
+
+	return &UnsealCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: newNodeCache[unsealCacheEntry]("unseal", maxEntries),
+	}
+}
+
+// unsealCacheKey keys an entry on the normalized node UUID and a hash of the ciphertext
+func unsealCacheKey(nodeUUID string, ciphertext []byte) string {
+	sum := sha256.Sum256(ciphertext)
+	return unsealCacheNodePrefix(nodeUUID) + hex.EncodeToString(sum[:])
+}
+
+// unsealCacheNodePrefix is the prefix shared by the cache keys of a node
+func unsealCacheNodePrefix(nodeUUID string) string {
+	return strings.ToLower(strings.ReplaceAll(nodeUUID, "-", "")) + ":"
+}
+
+// get returns a copy of a live cached response
+func (c *UnsealCache) get(key string) ([]byte, bool) {
+	entry, ok := c.entries.get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if !c.now().Before(entry.expires) {
+		c.entries.delete(key)
+		unsealCacheEntries.Set(float64(c.entries.len()))
+		return nil, false
+	}
+
+	return append([]byte(nil), entry.data...), true
+}
+
+// put stores a response, evicting the least recently used entry when the cache is full
+func (c *UnsealCache) put(key string, data []byte) {
+	c.entries.put(key, unsealCacheEntry{data: append([]byte(nil), data...), expires: c.now().Add(c.ttl)})
+	unsealCacheEntries.Set(float64(c.entries.len()))
+}
+
+// bypassCache reports whether the request asks for a fresh decrypt, or pins a key version
+func bypassCache(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	return len(md.Get(NoCacheMetadataKey)) > 0 || len(md.Get(KeyVersionMetadataKey)) > 0
+}
+
+// lookup returns the cached response for an Unseal request and the key to store a fresh
+// response under. The key is empty when the cache is disabled or bypassed.
+func (c *UnsealCache) lookup(ctx context.Context, request *kms.Request) (string, []byte, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+
+	if bypassCache(ctx) {
+		unsealCacheRequests.WithLabelValues("bypass").Inc()
+		return "", nil, false
+	}
+
+	key := unsealCacheKey(request.NodeUuid, request.Data)
+	if data, ok := c.get(key); ok {
+		unsealCacheRequests.WithLabelValues("hit").Inc()
+		return key, data, true
+	}
+
+	unsealCacheRequests.WithLabelValues("miss").Inc()
+	return key, nil, false
+}
+
+// store caches a successful response under a key returned by lookup
+func (c *UnsealCache) store(key string, data []byte) {
+	if c == nil || key == "" {
+		return
+	}
+
+	c.put(key, data)
+}
+
+// forgetNode drops every cached response of a node, e.g. once its key is deleted or rotated
+func (c *UnsealCache) forgetNode(nodeUUID string) {
+	if c == nil {
+		return
+	}
+
+	prefix := unsealCacheNodePrefix(nodeUUID)
+	c.entries.deleteFunc(func(key string) bool { return strings.HasPrefix(key, prefix) })
+	unsealCacheEntries.Set(float64(c.entries.len()))
+}
+
+// SetUnsealCache makes Unseal answer repeated requests from the cache (nil disables it).
+// The cache is consulted after the maintenance, node and ciphertext checks, and behind the
+// leadership check of a LeaderAwareServer, so a cached response is never served to a
+// request they would reject.
+func (s *Server) SetUnsealCache(cache *UnsealCache) {
+	s.unsealCache = cache
+}
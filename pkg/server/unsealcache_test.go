@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestServer_UnsealCache(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+
+	now := time.Now()
+	cache := NewUnsealCache(time.Minute, 3)
+	cache.now = func() time.Time { return now }
+	srv.SetUnsealCache(cache)
+
+	seal := func(node, plaintext string) []byte {
+		t.Helper()
+		resp, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: node, Data: []byte(plaintext)})
+		if err != nil {
+			t.Fatalf("Seal() error = %v", err)
+		}
+		return resp.Data
+	}
+
+	abcd, efgh, fail, full := seal(retiredNode, "abcd"), seal(retiredNode, "efgh"), seal(retiredNode, "fail"), seal(retiredNode, "full")
+
+	unseal := func(ctx context.Context, node string, ciphertext []byte) error {
+		_, err := srv.Unseal(ctx, &kms.Request{NodeUuid: node, Data: ciphertext})
+		return err
+	}
+
+	requests := transit.requestCount()
+	expectDecrypts := func(step string, want int) {
+		t.Helper()
+		if got := transit.requestCount() - requests; got != want {
+			t.Fatalf("%s: Vault requests = %d, want %d", step, got, want)
+		}
+	}
+
+	ctx := context.Background()
+
+	unseal(ctx, retiredNode, abcd)
+	unseal(ctx, retiredNode, abcd)
+	expectDecrypts("repeated unseal", 1)
+
+	// Other ciphertext is not served from the entry
+	unseal(ctx, retiredNode, efgh)
+	expectDecrypts("other ciphertext", 2)
+
+	// x-no-cache and key version hints force a fresh decrypt
+	noCache := metadata.NewIncomingContext(ctx, metadata.Pairs(NoCacheMetadataKey, "1"))
+	unseal(noCache, retiredNode, abcd)
+	expectDecrypts("x-no-cache", 3)
+	keyVersion := metadata.NewIncomingContext(ctx, metadata.Pairs(KeyVersionMetadataKey, "1"))
+	unseal(keyVersion, retiredNode, abcd)
+	expectDecrypts("key version hint", 4)
+
+	// Failures are not cached
+	transit.setFailure(http.StatusInternalServerError)
+	if err := unseal(ctx, retiredNode, fail); err == nil {
+		t.Fatal("expected Unseal error")
+	}
+	transit.setFailure(0)
+	unseal(ctx, retiredNode, fail)
+	expectDecrypts("failure not cached", 6)
+	unseal(ctx, retiredNode, fail)
+	expectDecrypts("success after failure cached", 6)
+
+	// The cache is full (3 entries), so the least recently used entry (abcd) makes room
+	unseal(ctx, retiredNode, full)
+	unseal(ctx, retiredNode, full)
+	expectDecrypts("full cache", 7)
+	unseal(ctx, retiredNode, abcd)
+	expectDecrypts("evicted entry", 8)
+
+	// Hits are audited like any other Unseal
+	digestCtx, digests := withAuditDigests(ctx)
+	unseal(digestCtx, retiredNode, full)
+	expectDecrypts("audited hit", 8)
+	if digests.request == "" || digests.response != payloadDigest([]byte("full")) {
+		t.Errorf("digests on a cache hit = %+v, want request and response digests", digests)
+	}
+
+	// Entries expire after the TTL
+	now = now.Add(2 * time.Minute)
+	unseal(ctx, retiredNode, full)
+	expectDecrypts("expired entry", 9)
+}
+
+func TestUnsealCache_ForgetNode(t *testing.T) {
+	cache := NewUnsealCache(time.Minute, 0)
+
+	cache.put(unsealCacheKey(retiredNode, []byte("a")), []byte("a"))
+	cache.put(unsealCacheKey(retiredNode, []byte("b")), []byte("b"))
+	cache.put(unsealCacheKey(otherNode, []byte("a")), []byte("a"))
+
+	cache.forgetNode("550E8400E29B41D4A716446655440000")
+
+	if _, ok := cache.get(unsealCacheKey(retiredNode, []byte("a"))); ok {
+		t.Error("expected the node's entries to be dropped")
+	}
+	if _, ok := cache.get(unsealCacheKey(otherNode, []byte("a"))); !ok {
+		t.Error("expected other nodes' entries to be kept")
+	}
+}
+
+func TestServer_UnsealCacheDroppedOnRotation(t *testing.T) {
+	transit := newFakeTransit(t, "transit")
+	srv := newTestServer(t, transit)
+	srv.SetMinKeyVersions(1, 0)
+	srv.keyVersions.policy = KeyVersionPolicy{} // observe versions only, the fake cannot configure keys
+	srv.SetUnsealCache(NewUnsealCache(time.Minute, 0))
+
+	resp, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("data")})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	sealed := resp.Data
+
+	if _, err := srv.Unseal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: sealed}); err != nil {
+		t.Fatalf("Unseal() error = %v", err)
+	}
+
+	key := unsealCacheKey(retiredNode, sealed)
+	if _, ok := srv.unsealCache.get(key); !ok {
+		t.Fatal("expected the response to be cached")
+	}
+
+	// Unchanged versions keep the entry
+	if _, err := srv.EnforceKeyVersions(context.Background(), transit.client(t), retiredNode); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.unsealCache.get(key); !ok {
+		t.Fatal("expected the entry to survive unchanged key versions")
+	}
+
+	transit.mu.Lock()
+	transit.keys[retiredNode] = 2
+	transit.mu.Unlock()
+
+	if _, err := srv.EnforceKeyVersions(context.Background(), transit.client(t), retiredNode); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.unsealCache.get(key); ok {
+		t.Error("expected the entry to be dropped after the key was rotated")
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"golang.org/x/sync/singleflight"
+)
+
+// VaultCheckFunc performs a single Vault connectivity check
+type VaultCheckFunc func(ctx context.Context) error
+
+// VaultHealthStatus is a cached Vault health check result
+type VaultHealthStatus struct {
+	Healthy    bool          `json:"healthy"`
+	Error      string        `json:"error,omitempty"`
+	CheckedAt  time.Time     `json:"checkedAt"`
+	Age        time.Duration `json:"age"`
+	AgeSeconds float64       `json:"ageSeconds"`
+}
+
+// VaultHealthChecker caches Vault health checks so frequent probes share one result.
+// Real checks are single-flight and spaced by at least the minimum interval, backing
+// off exponentially up to the maximum interval while Vault is failing.
+type VaultHealthChecker struct {
+	check       VaultCheckFunc
+	minInterval time.Duration
+	maxInterval time.Duration
+	timeout     time.Duration
+
+	group singleflight.Group
+
+	mu        sync.RWMutex
+	lastErr   error
+	checkedAt time.Time
+	nextCheck time.Time
+	failures  int
+}
+
+// NewVaultHealthChecker creates a new cached Vault health checker
+func NewVaultHealthChecker(check VaultCheckFunc, minInterval, maxInterval time.Duration) *VaultHealthChecker {
+	if minInterval <= 0 {
+		minInterval = 5 * time.Second
+	}
+
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+
+	return &VaultHealthChecker{
+		check:       check,
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+		timeout:     5 * time.Second,
+	}
+}
+
+// VaultSysHealthCheck returns a check calling sys/health on the current Vault client.
+// Standby nodes are treated as healthy; sealed or uninitialized Vault is not.
+func VaultSysHealthCheck(getClient func() (*vault.Client, error)) VaultCheckFunc {
+	params := url.Values{}
+	params.Set("standbyok", "true")
+	params.Set("perfstandbyok", "true")
+
+	return func(ctx context.Context) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		// The client does not treat sys/health status codes as errors, so inspect the body
+		resp, err := client.System.ReadHealthStatus(ctx, vault.WithQueryParameters(params))
+		if err != nil {
+			return err
+		}
+
+		if initialized, ok := resp.Data["initialized"].(bool); ok && !initialized {
+			return errors.New("vault is not initialized")
+		}
+		if sealed, ok := resp.Data["sealed"].(bool); ok && sealed {
+			return errors.New("vault is sealed")
+		}
+
+		return nil
+	}
+}
+
+// CheckVault performs an uncached sys/health check against the current Vault client
+func (s *Server) CheckVault(ctx context.Context) error {
+	return VaultSysHealthCheck(s.vaultClient)(ctx)
+}
+
+// Status returns the cached health status, refreshing it if the check interval has elapsed
+func (c *VaultHealthChecker) Status(ctx context.Context) VaultHealthStatus {
+	c.mu.RLock()
+	due := time.Now().After(c.nextCheck)
+	c.mu.RUnlock()
+
+	if due {
+		result := c.group.DoChan("vault-health", func() (interface{}, error) {
+			c.refresh()
+			return nil, nil
+		})
+
+		select {
+		case <-result:
+		case <-ctx.Done():
+		}
+	}
+
+	return c.Cached()
+}
+
+// Cached returns the last health check result without triggering a new check
+func (c *VaultHealthChecker) Cached() VaultHealthStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := VaultHealthStatus{
+		Healthy:   !c.checkedAt.IsZero() && c.lastErr == nil,
+		CheckedAt: c.checkedAt,
+	}
+
+	if c.checkedAt.IsZero() {
+		status.Error = "vault health not checked yet"
+		return status
+	}
+
+	if c.lastErr != nil {
+		status.Error = c.lastErr.Error()
+	}
+
+	status.Age = time.Since(c.checkedAt)
+	status.AgeSeconds = status.Age.Seconds()
+
+	return status
+}
+
+// refresh performs a real check and schedules the next one
+func (c *VaultHealthChecker) refresh() {
+	// Re-check in case a previous flight refreshed while this one was queued
+	c.mu.RLock()
+	due := time.Now().After(c.nextCheck)
+	c.mu.RUnlock()
+
+	if !due {
+		return
+	}
+
+	// Detached from the caller so an abandoned probe doesn't fail the shared check
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	err := c.check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastErr = err
+	c.checkedAt = time.Now()
+
+	if err != nil {
+		c.failures++
+	} else {
+		c.failures = 0
+	}
+
+	c.nextCheck = c.checkedAt.Add(c.backoffInterval())
+}
+
+// backoffInterval returns the delay before the next check, doubling per consecutive failure
+func (c *VaultHealthChecker) backoffInterval() time.Duration {
+	interval := c.minInterval
+	for i := 0; i < c.failures && interval < c.maxInterval; i++ {
+		interval *= 2
+	}
+
+	return min(interval, c.maxInterval)
+}
+
+// NewVaultHealthHandler creates an HTTP handler reporting the cached Vault health status
+func NewVaultHealthHandler(checker *VaultHealthChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := checker.Status(r.Context())
+
+		code := http.StatusOK
+		if !status.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, code, status)
+	})
+}
+
+// vaultReady reports whether the optional Vault health and seal checks pass, with a reason when not
+func (s *Server) vaultReady(ctx context.Context) (bool, string) {
+	if s.sealMonitor != nil {
+		if reason := s.sealMonitor.NotReadyReason(); reason != "" {
+			return false, reason
+		}
+	}
+
+	if s.vaultHealth == nil {
+		return true, ""
+	}
+
+	status := s.vaultHealth.Status(ctx)
+	if !status.Healthy {
+		return false, "vault unavailable: " + status.Error
+	}
+
+	return true, ""
+}
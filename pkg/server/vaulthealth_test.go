@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVaultHealthChecker_CachesResult(t *testing.T) {
+	var calls atomic.Int32
+	checker := NewVaultHealthChecker(func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, time.Minute, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status := checker.Status(context.Background()); !status.Healthy {
+				t.Errorf("Expected healthy status, got %+v", status)
+			}
+		}()
+	}
+	wg.Wait()
+
+	checker.Status(context.Background())
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 real check, got %d", got)
+	}
+}
+
+func TestVaultHealthChecker_BackoffInterval(t *testing.T) {
+	checker := NewVaultHealthChecker(nil, time.Second, 10*time.Second)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: time.Second},
+		{failures: 1, want: 2 * time.Second},
+		{failures: 3, want: 8 * time.Second},
+		{failures: 4, want: 10 * time.Second},
+		{failures: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		checker.failures = tt.failures
+		if got := checker.backoffInterval(); got != tt.want {
+			t.Errorf("backoffInterval() with %d failures = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestVaultHealthHandler(t *testing.T) {
+	checker := NewVaultHealthChecker(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}, time.Minute, time.Minute)
+
+	rec := httptest.NewRecorder()
+	NewVaultHealthHandler(checker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vault/health", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	status := checker.Cached()
+	if status.Healthy || status.Error != "connection refused" || status.CheckedAt.IsZero() {
+		t.Errorf("Unexpected cached status %+v", status)
+	}
+}
+
+func TestServerReadyWithVaultHealth(t *testing.T) {
+	srv := &Server{}
+	srv.SetVaultHealthChecker(NewVaultHealthChecker(func(ctx context.Context) error {
+		return errors.New("sealed")
+	}, time.Minute, time.Minute))
+
+	rec := httptest.NewRecorder()
+	srv.CreateHealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
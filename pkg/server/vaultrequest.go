@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+
+	"github.com/soulkyu/talos-kms-vault/pkg/tracing"
+	"github.com/soulkyu/talos-kms-vault/pkg/validation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// auditVaultRequest collects the Vault request ID of one KMS request for the request recorder
+type auditVaultRequest struct {
+	id string
+}
+
+type auditVaultRequestKey struct{}
+
+// withAuditVaultRequest returns a context in which the server records the Vault request ID
+func withAuditVaultRequest(ctx context.Context) (context.Context, *auditVaultRequest) {
+	request := &auditVaultRequest{}
+	return context.WithValue(ctx, auditVaultRequestKey{}, request), request
+}
+
+// SetLogVaultRequestID makes Seal and Unseal log the request ID Vault returned for the Transit
+// call, so KMS logs can be joined with the Vault audit log
+func (s *Server) SetLogVaultRequestID(log bool) {
+	s.logVaultRequestID = log
+}
+
+// recordVaultRequestID notes the request ID of the Transit call that served a request: on the
+// span, for the request recorder, and in the log when enabled
+func (s Server) recordVaultRequestID(ctx context.Context, message, nodeUUID, requestID string) {
+	if requestID == "" {
+		return
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrVaultRequestID.String(requestID))
+
+	if request, ok := ctx.Value(auditVaultRequestKey{}).(*auditVaultRequest); ok {
+		request.id = requestID
+	}
+
+	if s.logVaultRequestID {
+		s.logger.InfoContext(ctx, message,
+			"node", validation.SanitizeForLogging(nodeUUID),
+			"vaultRequestId", requestID)
+	}
+}
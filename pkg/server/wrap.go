@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+)
+
+// SetResponseWrapTTL makes Seal return a Vault response-wrapping token (valid for ttl) in place
+// of the ciphertext; clients unwrap it via sys/wrapping/unwrap to obtain the ciphertext.
+// This changes the client contract and is incompatible with stock Talos clients. 0 disables it.
+func (s *Server) SetResponseWrapTTL(ttl time.Duration) {
+	s.responseWrapTTL = ttl
+}
+
+// wrapCiphertext response-wraps a ciphertext and returns the wrapping token
+func (s Server) wrapCiphertext(ctx context.Context, client *vault.Client, ciphertext string) (string, error) {
+	resp, err := client.System.Wrap(ctx, map[string]interface{}{"ciphertext": ciphertext}, vault.WithResponseWrapping(s.responseWrapTTL))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap ciphertext: %w", err)
+	}
+
+	if resp.WrapInfo == nil || resp.WrapInfo.Token == "" {
+		return "", fmt.Errorf("no wrapping token in response")
+	}
+
+	return resp.WrapInfo.Token, nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault-client-go"
+	"github.com/siderolabs/kms-client/api/kms"
+)
+
+func TestServer_SealResponseWrapping(t *testing.T) {
+	var gotWrapTTL string
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/" + retiredNode:
+			w.Write([]byte(`{"data":{"ciphertext":"vault:v1:abcd"}}`))
+		case "/v1/sys/wrapping/wrap":
+			gotWrapTTL = r.Header.Get("X-Vault-Wrap-TTL")
+			w.Write([]byte(`{"data":null,"wrap_info":{"token":"hvs.wrapping-token","ttl":300}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	client, err := vault.New(
+		vault.WithAddress(vaultServer.URL),
+		vault.WithRetryConfiguration(vault.RetryConfiguration{RetryMax: -1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		wrapTTL  time.Duration
+		wantData string
+		wantTTL  string
+	}{
+		{
+			name:     "disabled returns ciphertext",
+			wantData: "vault:v1:abcd",
+		},
+		{
+			name:     "enabled returns wrapping token",
+			wrapTTL:  5 * time.Minute,
+			wantData: "hvs.wrapping-token",
+			wantTTL:  "5m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotWrapTTL = ""
+
+			srv := NewServer(client, slog.New(slog.NewTextHandler(os.Stderr, nil)), "transit")
+			srv.SetResponseWrapTTL(tt.wrapTTL)
+
+			resp, err := srv.Seal(context.Background(), &kms.Request{NodeUuid: retiredNode, Data: []byte("secret")})
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+
+			if string(resp.Data) != tt.wantData {
+				t.Errorf("Seal() data = %q, want %q", resp.Data, tt.wantData)
+			}
+			if gotWrapTTL != tt.wantTTL {
+				t.Errorf("wrap TTL header = %q, want %q", gotWrapTTL, tt.wantTTL)
+			}
+		})
+	}
+}
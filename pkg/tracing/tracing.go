@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies spans created by this module
+const InstrumentationName = "github.com/soulkyu/talos-kms-vault"
+
+// Span attribute keys shared across the request lifecycle
+const (
+	AttrOperation = attribute.Key("kms.operation")
+	AttrNode      = attribute.Key("kms.node_uuid_sanitized")
+	AttrMount     = attribute.Key("kms.vault_mount")
+	AttrOutcome   = attribute.Key("kms.outcome")
+
+	AttrTalosVersion = attribute.Key("kms.talos_version")
+
+	// AttrVaultRequestID is the request ID Vault returned, as found in the Vault audit log
+	AttrVaultRequestID = attribute.Key("vault.request_id")
+)
+
+// Setup installs an OTLP gRPC trace exporter as the global tracer provider.
+// The exporter is configured through the standard OTEL_EXPORTER_OTLP_* environment variables.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the module tracer from the global provider (a no-op until Setup is called)
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// WrapTransport instruments an HTTP transport so trace context propagates to Vault
+func WrapTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// EndSpan records the outcome of an operation and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(AttrOutcome.String("error"))
+	} else {
+		span.SetAttributes(AttrOutcome.String("success"))
+	}
+
+	span.End()
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEndSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer(InstrumentationName)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantOutcome string
+	}{
+		{name: "success", err: nil, wantOutcome: "success"},
+		{name: "failure", err: errors.New("vault unavailable"), wantOutcome: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := tracer.Start(context.Background(), tt.name)
+			EndSpan(span, tt.err)
+
+			spans := recorder.Ended()
+			ended := spans[len(spans)-1]
+
+			var outcome string
+			for _, attr := range ended.Attributes() {
+				if attr.Key == AttrOutcome {
+					outcome = attr.Value.AsString()
+				}
+			}
+
+			if outcome != tt.wantOutcome {
+				t.Errorf("outcome = %q, want %q", outcome, tt.wantOutcome)
+			}
+		})
+	}
+}
+
+func TestWrapTransportPropagatesContext(t *testing.T) {
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	ctx, span := provider.Tracer(InstrumentationName).Start(context.Background(), "parent")
+	defer span.End()
+
+	client := &http.Client{Transport: WrapTransport(http.DefaultTransport)}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if traceparent == "" {
+		t.Error("Expected traceparent header to be propagated")
+	}
+}
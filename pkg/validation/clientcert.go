@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrClientCertRejected is returned when a verified client certificate fails the policy
+var ErrClientCertRejected = errors.New("client certificate rejected")
+
+// ClientCertPolicy holds additional requirements on verified mTLS client certificates
+type ClientCertPolicy struct {
+	// RequireClientAuthEKU requires the leaf to explicitly list the clientAuth extended key
+	// usage. Certificates without any EKU (or with anyExtendedKeyUsage) are rejected.
+	RequireClientAuthEKU bool
+
+	// Organization, when set, must appear in the leaf subject's organizations
+	Organization string
+
+	// OrganizationalUnit, when set, must appear in the leaf subject's organizational units
+	OrganizationalUnit string
+}
+
+// VerifyPeerCertificate implements the tls.Config callback of the same name. It runs after
+// chain verification, so only the verified leaf is checked.
+func (p ClientCertPolicy) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return fmt.Errorf("%w: no verified certificate chain", ErrClientCertRejected)
+	}
+
+	return p.check(verifiedChains[0][0])
+}
+
+// check applies the policy to a leaf certificate
+func (p ClientCertPolicy) check(cert *x509.Certificate) error {
+	if p.RequireClientAuthEKU && !slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth) {
+		return fmt.Errorf("%w: %q does not carry the clientAuth extended key usage", ErrClientCertRejected, cert.Subject.String())
+	}
+
+	if p.Organization != "" && !slices.Contains(cert.Subject.Organization, p.Organization) {
+		return fmt.Errorf("%w: %q is not in organization %q", ErrClientCertRejected, cert.Subject.String(), p.Organization)
+	}
+
+	if p.OrganizationalUnit != "" && !slices.Contains(cert.Subject.OrganizationalUnit, p.OrganizationalUnit) {
+		return fmt.Errorf("%w: %q is not in organizational unit %q", ErrClientCertRejected, cert.Subject.String(), p.OrganizationalUnit)
+	}
+
+	return nil
+}
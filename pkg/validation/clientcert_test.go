@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+)
+
+func TestClientCertPolicy_VerifyPeerCertificate(t *testing.T) {
+	clientCert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "node", Organization: []string{"os:node"}, OrganizationalUnit: []string{"talos"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	serverCert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "apiserver", Organization: []string{"os:node"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	noEKUCert := &x509.Certificate{Subject: pkix.Name{CommonName: "legacy"}}
+
+	tests := []struct {
+		name    string
+		policy  ClientCertPolicy
+		chains  [][]*x509.Certificate
+		wantErr bool
+	}{
+		{
+			name:   "empty policy accepts any verified cert",
+			chains: [][]*x509.Certificate{{noEKUCert}},
+		},
+		{
+			name:   "client auth EKU accepted",
+			policy: ClientCertPolicy{RequireClientAuthEKU: true},
+			chains: [][]*x509.Certificate{{clientCert}},
+		},
+		{
+			name:    "server cert rejected",
+			policy:  ClientCertPolicy{RequireClientAuthEKU: true},
+			chains:  [][]*x509.Certificate{{serverCert}},
+			wantErr: true,
+		},
+		{
+			name:    "cert without EKU rejected",
+			policy:  ClientCertPolicy{RequireClientAuthEKU: true},
+			chains:  [][]*x509.Certificate{{noEKUCert}},
+			wantErr: true,
+		},
+		{
+			name:   "matching organization and unit accepted",
+			policy: ClientCertPolicy{RequireClientAuthEKU: true, Organization: "os:node", OrganizationalUnit: "talos"},
+			chains: [][]*x509.Certificate{{clientCert}},
+		},
+		{
+			name:    "wrong organization rejected",
+			policy:  ClientCertPolicy{Organization: "os:admin"},
+			chains:  [][]*x509.Certificate{{clientCert}},
+			wantErr: true,
+		},
+		{
+			name:    "wrong organizational unit rejected",
+			policy:  ClientCertPolicy{OrganizationalUnit: "ops"},
+			chains:  [][]*x509.Certificate{{clientCert}},
+			wantErr: true,
+		},
+		{
+			name:    "no verified chain rejected",
+			policy:  ClientCertPolicy{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.VerifyPeerCertificate(nil, tt.chains)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyPeerCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrClientCertRejected) {
+				t.Errorf("error %v does not wrap ErrClientCertRejected", err)
+			}
+		})
+	}
+}
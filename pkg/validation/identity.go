@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// IdentityField selects the client certificate field that carries the node identity
+type IdentityField string
+
+const (
+	// IdentityFieldCommonName uses the certificate subject common name
+	IdentityFieldCommonName IdentityField = "cn"
+	// IdentityFieldDNSSAN uses the certificate DNS subject alternative names
+	IdentityFieldDNSSAN IdentityField = "dns-san"
+	// IdentityFieldURISAN uses the certificate URI subject alternative names (e.g. urn:uuid:<uuid>)
+	IdentityFieldURISAN IdentityField = "uri-san"
+)
+
+// NodeIdentityMatcher ensures the node UUID in a request matches the identity
+// asserted by the verified client certificate
+type NodeIdentityMatcher struct {
+	field  IdentityField
+	logger *slog.Logger
+}
+
+// NewNodeIdentityMatcher creates a new node identity matcher for the given certificate field
+func NewNodeIdentityMatcher(field IdentityField, logger *slog.Logger) (*NodeIdentityMatcher, error) {
+	switch field {
+	case IdentityFieldCommonName, IdentityFieldDNSSAN, IdentityFieldURISAN:
+	default:
+		return nil, fmt.Errorf("unsupported identity field: %q", field)
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &NodeIdentityMatcher{
+		field:  field,
+		logger: logger.With("component", "node-identity"),
+	}, nil
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor enforcing node identity matching.
+// Requests without a verified client certificate are passed through unchanged.
+func (m *NodeIdentityMatcher) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		kmsReq, ok := req.(*kms.Request)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		cert := verifiedClientCert(ctx)
+		if cert == nil {
+			return handler(ctx, req)
+		}
+
+		if !m.matches(cert, kmsReq.NodeUuid) {
+			m.logger.WarnContext(ctx, "Node UUID does not match client certificate identity",
+				"method", info.FullMethod,
+				"node_uuid_sanitized", SanitizeForLogging(kmsReq.NodeUuid),
+				"peer", peerAddress(ctx),
+				"field", m.field,
+				"subject", cert.Subject.String(),
+			)
+
+			return nil, status.Error(codes.PermissionDenied, "node UUID does not match client certificate identity")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// matches reports whether any identity in the certificate matches the node UUID
+func (m *NodeIdentityMatcher) matches(cert *x509.Certificate, nodeUUID string) bool {
+	if nodeUUID == "" {
+		return false
+	}
+
+	for _, identity := range m.identities(cert) {
+		if strings.EqualFold(identity, nodeUUID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// identities extracts the candidate identities from the configured certificate field
+func (m *NodeIdentityMatcher) identities(cert *x509.Certificate) []string {
+	return certIdentities(cert, m.field)
+}
+
+// certIdentities extracts the candidate identities from a certificate field
+func certIdentities(cert *x509.Certificate, field IdentityField) []string {
+	switch field {
+	case IdentityFieldCommonName:
+		return []string{cert.Subject.CommonName}
+
+	case IdentityFieldDNSSAN:
+		return cert.DNSNames
+
+	case IdentityFieldURISAN:
+		identities := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			if uri.Scheme == "urn" && strings.HasPrefix(strings.ToLower(uri.Opaque), "uuid:") {
+				identities = append(identities, uri.Opaque[len("uuid:"):])
+				continue
+			}
+			identities = append(identities, uri.String())
+		}
+		return identities
+	}
+
+	return nil
+}
+
+// IsMutualTLS reports whether the request arrived over a connection with a verified client certificate
+func IsMutualTLS(ctx context.Context) bool {
+	return verifiedClientCert(ctx) != nil
+}
+
+// verifiedClientCert returns the verified leaf client certificate, if any
+func verifiedClientCert(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0]
+}
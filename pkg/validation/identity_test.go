@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testNodeUUID = "550e8400-e29b-41d4-a716-446655440000"
+
+// contextWithClientCert returns a context carrying a verified client certificate
+func contextWithClientCert(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{cert}},
+			},
+		},
+	})
+}
+
+func TestNewNodeIdentityMatcher(t *testing.T) {
+	if _, err := NewNodeIdentityMatcher("bogus", nil); err == nil {
+		t.Error("Expected error for unsupported identity field")
+	}
+
+	for _, field := range []IdentityField{IdentityFieldCommonName, IdentityFieldDNSSAN, IdentityFieldURISAN} {
+		if _, err := NewNodeIdentityMatcher(field, nil); err != nil {
+			t.Errorf("NewNodeIdentityMatcher(%q) error = %v", field, err)
+		}
+	}
+}
+
+func TestNodeIdentityMatcher_UnaryServerInterceptor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+
+	uuidURI, _ := url.Parse("urn:uuid:" + testNodeUUID)
+
+	tests := []struct {
+		name     string
+		field    IdentityField
+		ctx      context.Context
+		nodeUUID string
+		wantErr  bool
+	}{
+		{
+			name:     "no peer - passthrough",
+			field:    IdentityFieldCommonName,
+			ctx:      context.Background(),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "peer without TLS - passthrough",
+			field:    IdentityFieldCommonName,
+			ctx:      peer.NewContext(context.Background(), &peer.Peer{}),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "matching common name",
+			field:    IdentityFieldCommonName,
+			ctx:      contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: testNodeUUID}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "matching common name with different case",
+			field:    IdentityFieldCommonName,
+			ctx:      contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: "550E8400-E29B-41D4-A716-446655440000"}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "mismatched common name",
+			field:    IdentityFieldCommonName,
+			ctx:      contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: "other-node"}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  true,
+		},
+		{
+			name:     "matching DNS SAN",
+			field:    IdentityFieldDNSSAN,
+			ctx:      contextWithClientCert(&x509.Certificate{DNSNames: []string{"node.example.com", testNodeUUID}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "matching URI SAN",
+			field:    IdentityFieldURISAN,
+			ctx:      contextWithClientCert(&x509.Certificate{URIs: []*url.URL{uuidURI}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  false,
+		},
+		{
+			name:     "URI SAN field with identity only in CN",
+			field:    IdentityFieldURISAN,
+			ctx:      contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: testNodeUUID}}),
+			nodeUUID: testNodeUUID,
+			wantErr:  true,
+		},
+		{
+			name:     "empty node UUID with cert",
+			field:    IdentityFieldCommonName,
+			ctx:      contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: ""}}),
+			nodeUUID: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := NewNodeIdentityMatcher(tt.field, logger)
+			if err != nil {
+				t.Fatalf("NewNodeIdentityMatcher() error = %v", err)
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/kms.KMSService/Unseal"}
+			req := &kms.Request{NodeUuid: tt.nodeUUID, Data: []byte("vault:v1:data")}
+
+			_, err = matcher.UnaryServerInterceptor()(tt.ctx, req, info, handler)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("interceptor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr && status.Code(err) != codes.PermissionDenied {
+				t.Errorf("expected status code %v, got %v", codes.PermissionDenied, status.Code(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMetadataAllowlist returns the metadata keys sent by standard gRPC and tracing clients
+func DefaultMetadataAllowlist() []string {
+	return []string{
+		"content-type",
+		"user-agent",
+		"grpc-accept-encoding",
+		"traceparent",
+		"tracestate",
+		"baggage",
+		"x-kms-key-version",
+		"x-kms-convergent",
+		"x-no-cache",
+		"x-node-uuid",
+		"x-talos-version",
+	}
+}
+
+// MetadataPolicyConfig holds configuration for the metadata policy interceptor
+type MetadataPolicyConfig struct {
+	// Enable or disable the policy (disabled by default)
+	Enabled bool
+
+	// MaxEntries limits the number of metadata keys per request (0 disables the check)
+	MaxEntries int
+
+	// MaxSize limits the combined size of metadata keys and values in bytes (0 disables the check)
+	MaxSize int
+
+	// AllowedKeys lists the permitted metadata keys (empty allows all).
+	// HTTP/2 pseudo-headers such as :authority are always allowed.
+	AllowedKeys []string
+}
+
+// DefaultMetadataPolicyConfig returns the default metadata policy configuration
+func DefaultMetadataPolicyConfig() *MetadataPolicyConfig {
+	return &MetadataPolicyConfig{
+		Enabled:     false,
+		MaxEntries:  16,
+		MaxSize:     8 * 1024, // 8KB
+		AllowedKeys: DefaultMetadataAllowlist(),
+	}
+}
+
+// MetadataPolicy rejects requests carrying unexpected or oversized gRPC metadata
+type MetadataPolicy struct {
+	maxEntries  int
+	maxSize     int
+	allowedKeys map[string]struct{}
+	logger      *slog.Logger
+}
+
+// NewMetadataPolicy creates a metadata policy from config, returning nil when disabled
+func NewMetadataPolicy(config *MetadataPolicyConfig, logger *slog.Logger) *MetadataPolicy {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	allowed := make(map[string]struct{}, len(config.AllowedKeys))
+	for _, key := range config.AllowedKeys {
+		allowed[strings.ToLower(strings.TrimSpace(key))] = struct{}{}
+	}
+
+	return &MetadataPolicy{
+		maxEntries:  config.MaxEntries,
+		maxSize:     config.MaxSize,
+		allowedKeys: allowed,
+		logger:      logger.With("component", "metadata-policy"),
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor enforcing the metadata policy
+func (p *MetadataPolicy) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if err := p.check(md); err != nil {
+			metadataRejections.WithLabelValues(info.FullMethod).Inc()
+			p.logger.WarnContext(ctx, "Rejected request with disallowed metadata",
+				"method", info.FullMethod,
+				"peer", peerAddress(ctx),
+				"error", err.Error(),
+			)
+
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// check validates the metadata against the policy
+func (p *MetadataPolicy) check(md metadata.MD) error {
+	if p.maxEntries > 0 && md.Len() > p.maxEntries {
+		return status.Errorf(codes.InvalidArgument, "too many metadata entries: %d exceeds limit of %d",
+			md.Len(), p.maxEntries)
+	}
+
+	size := 0
+	for key, values := range md {
+		if !p.isKeyAllowed(key) {
+			return status.Errorf(codes.InvalidArgument, "metadata key %q is not allowed",
+				SanitizeForLogging(key))
+		}
+
+		size += len(key)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+
+	if p.maxSize > 0 && size > p.maxSize {
+		return status.Errorf(codes.InvalidArgument, "metadata too large: %d bytes exceeds limit of %d bytes",
+			size, p.maxSize)
+	}
+
+	return nil
+}
+
+// isKeyAllowed reports whether a metadata key is in the allowlist
+func (p *MetadataPolicy) isKeyAllowed(key string) bool {
+	if len(p.allowedKeys) == 0 || strings.HasPrefix(key, ":") {
+		return true
+	}
+
+	_, ok := p.allowedKeys[key]
+	return ok
+}
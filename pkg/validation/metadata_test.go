@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewMetadataPolicy_DisabledByDefault(t *testing.T) {
+	if policy := NewMetadataPolicy(DefaultMetadataPolicyConfig(), nil); policy != nil {
+		t.Error("Expected nil policy with default config")
+	}
+}
+
+func TestMetadataPolicy_UnaryServerInterceptor(t *testing.T) {
+	config := DefaultMetadataPolicyConfig()
+	config.Enabled = true
+	config.MaxEntries = 4
+	config.MaxSize = 64
+
+	policy := NewMetadataPolicy(config, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	interceptor := policy.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: MethodSeal}
+
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		wantErr bool
+	}{
+		{
+			name: "standard metadata",
+			md:   metadata.Pairs(":authority", "kms:8080", "content-type", "application/grpc", "user-agent", "grpc-go"),
+		},
+		{
+			name: "no metadata",
+		},
+		{
+			name:    "unknown key",
+			md:      metadata.Pairs("x-smuggled", "value"),
+			wantErr: true,
+		},
+		{
+			name:    "too many entries",
+			md:      metadata.Pairs("content-type", "a", "user-agent", "b", "traceparent", "c", "tracestate", "d", "baggage", "e"),
+			wantErr: true,
+		},
+		{
+			name:    "oversized value",
+			md:      metadata.Pairs("user-agent", strings.Repeat("a", 100)),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			called := false
+			_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			})
+
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Errorf("Expected InvalidArgument, got %v", err)
+				}
+				if called {
+					t.Error("Handler should not be called on policy violation")
+				}
+				return
+			}
+
+			if err != nil || !called {
+				t.Errorf("Expected request to pass, err = %v, called = %v", err, called)
+			}
+		})
+	}
+}
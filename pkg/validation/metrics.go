@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// requestBytes tracks KMS request payload sizes (64B up to 16MiB)
+	requestBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kms_request_bytes",
+		Help:    "Size of Seal/Unseal request payloads in bytes",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 10),
+	})
+
+	oversizeRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_oversize_rejections_total",
+		Help: "Total number of requests rejected for exceeding the size limit",
+	}, []string{"method"})
+
+	metadataRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_metadata_rejections_total",
+		Help: "Total number of requests rejected by the metadata policy",
+	}, []string{"method"})
+
+	nodeUUIDMismatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_node_uuid_metadata_mismatches_total",
+		Help: "Total number of requests rejected because the body and x-node-uuid metadata disagree",
+	}, []string{"method"})
+
+	selfTestSpoofs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_self_test_uuid_rejections_total",
+		Help: "Total number of client requests rejected for using the reserved self-test node UUID",
+	}, []string{"method"})
+
+	operationDenials = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_operation_denials_total",
+		Help: "Total number of requests rejected by the per-identity operation policy",
+	}, []string{"method"})
+
+	validatorInternalErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kms_validation_internal_errors_total",
+		Help: "Total number of requests whose validation failed internally, by whether they were blocked or allowed",
+	}, []string{"result"})
+
+	entropyCheckEnabled = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kms_entropy_check_enabled",
+		Help: "Whether UUID entropy checking is in effect (1) or not (0)",
+	})
+)
+
+// gaugeBool converts b to a gauge value, 1 for true and 0 for false
+func gaugeBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
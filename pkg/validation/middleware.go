@@ -2,18 +2,59 @@ package validation
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 
 	"github.com/siderolabs/kms-client/api/kms"
+	"github.com/soulkyu/talos-kms-vault/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
+// KMS service methods
+const (
+	MethodSeal   = "/kms.KMSService/Seal"
+	MethodUnseal = "/kms.KMSService/Unseal"
+)
+
+// DefaultMaxRequestSize is the default limit on request data size
+const DefaultMaxRequestSize = 4 * 1024 * 1024 // 4MB
+
+// DefaultMethodAllowlist returns the gRPC methods permitted by default
+func DefaultMethodAllowlist() []string {
+	return []string{MethodSeal, MethodUnseal}
+}
+
 // ValidationMiddleware provides gRPC middleware for request validation
 type ValidationMiddleware struct {
-	validator *UUIDValidator
-	logger    *slog.Logger
+	// validatorMu guards validator and config, which are replaced when the config is reloaded
+	validatorMu sync.RWMutex
+	validator   *UUIDValidator
+	logger      *slog.Logger
+
+	// config is the effective config, nil when the middleware wasn't built from one
+	config *ValidationConfig
+
+	// allowedMethods lists the permitted gRPC methods (empty allows all)
+	allowedMethods map[string]struct{}
+
+	// Data size limits; the per-method limits fall back to maxRequestSize when zero
+	maxRequestSize int
+	maxSealSize    int
+	maxUnsealSize  int
+
+	// selfTestUUID is reserved for an internal self-test (empty disables the reservation)
+	selfTestUUID string
+
+	// failOpen lets requests through when the validator fails internally instead of rejecting them
+	failOpen bool
 
 	// Metrics for validation failures (can be extended with Prometheus later)
 	validationFailures int64
@@ -31,11 +72,34 @@ func NewValidationMiddleware(validator *UUIDValidator, logger *slog.Logger) *Val
 	}
 
 	return &ValidationMiddleware{
-		validator: validator,
-		logger:    logger.With("component", "validation-middleware"),
+		validator:      validator,
+		logger:         logger.With("component", "validation-middleware"),
+		allowedMethods: methodSet(DefaultMethodAllowlist()),
+		maxRequestSize: DefaultMaxRequestSize,
+		selfTestUUID:   DefaultSelfTestUUID,
 	}
 }
 
+// methodSet converts a method list into a lookup set
+func methodSet(methods []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+
+	return set
+}
+
+// isMethodAllowed reports whether the gRPC method is in the allowlist
+func (vm *ValidationMiddleware) isMethodAllowed(method string) bool {
+	if len(vm.allowedMethods) == 0 {
+		return true
+	}
+
+	_, ok := vm.allowedMethods[method]
+	return ok
+}
+
 // UnaryServerInterceptor returns a gRPC unary server interceptor for validation
 func (vm *ValidationMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(
@@ -44,13 +108,39 @@ func (vm *ValidationMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterce
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
+		// Reject methods that have not been explicitly allowed
+		if !vm.isMethodAllowed(info.FullMethod) {
+			atomic.AddInt64(&vm.validationFailures, 1)
+			vm.logger.WarnContext(ctx, "Rejected request for method not in allowlist",
+				"method", info.FullMethod,
+				"peer", peerAddress(ctx),
+			)
+
+			return nil, status.Errorf(codes.PermissionDenied, "method %s is not allowed", info.FullMethod)
+		}
+
 		// Only validate KMS requests
 		if kmsReq, ok := req.(*kms.Request); ok {
-			if err := vm.validateKMSRequest(ctx, kmsReq, info.FullMethod); err != nil {
-				vm.validationFailures++
+			if err := vm.rejectSelfTestUUID(ctx, kmsReq, info.FullMethod); err != nil {
+				return nil, err
+			}
+
+			_, span := tracing.Tracer().Start(ctx, "kms.validate", trace.WithAttributes(
+				attribute.String("rpc.method", info.FullMethod),
+				tracing.AttrNode.String(SanitizeForLogging(kmsReq.NodeUuid)),
+			))
+			err := vm.safeValidateKMSRequest(ctx, kmsReq, info.FullMethod)
+			tracing.EndSpan(span, err)
+
+			if errors.Is(err, ErrValidatorInternal) {
+				return vm.handleInternalError(ctx, kmsReq, info.FullMethod, err, req, handler)
+			}
+
+			if err != nil {
+				atomic.AddInt64(&vm.validationFailures, 1)
 				return nil, err
 			}
-			vm.validationSuccess++
+			atomic.AddInt64(&vm.validationSuccess, 1)
 		}
 
 		// Continue with the request
@@ -58,13 +148,56 @@ func (vm *ValidationMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterce
 	}
 }
 
+// safeValidateKMSRequest validates a KMS request, turning a validator panic into ErrValidatorInternal
+func (vm *ValidationMiddleware) safeValidateKMSRequest(ctx context.Context, req *kms.Request, method string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: panic: %v", ErrValidatorInternal, r)
+		}
+	}()
+
+	return vm.validateKMSRequest(ctx, req, method)
+}
+
+// handleInternalError rejects a request whose validation failed internally, or passes it to
+// handler with a warning when the middleware fails open
+func (vm *ValidationMiddleware) handleInternalError(ctx context.Context, req *kms.Request, method string, err error, rawReq interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	if vm.failOpen {
+		validatorInternalErrors.WithLabelValues("allowed").Inc()
+		vm.logger.WarnContext(ctx, "Validator failed internally, letting the request through (fail-open)",
+			"method", method,
+			"node_uuid_sanitized", SanitizeForLogging(req.NodeUuid),
+			"peer", peerAddress(ctx),
+			"error", err.Error(),
+		)
+
+		return handler(ctx, rawReq)
+	}
+
+	atomic.AddInt64(&vm.validationFailures, 1)
+	validatorInternalErrors.WithLabelValues("blocked").Inc()
+	vm.logger.ErrorContext(ctx, "Validator failed internally, rejecting the request (fail-closed)",
+		"method", method,
+		"node_uuid_sanitized", SanitizeForLogging(req.NodeUuid),
+		"peer", peerAddress(ctx),
+		"error", err.Error(),
+	)
+
+	return nil, status.Error(codes.Internal, "request validation failed")
+}
+
 // validateKMSRequest validates a KMS request
 func (vm *ValidationMiddleware) validateKMSRequest(ctx context.Context, req *kms.Request, method string) error {
 	// Validate NodeUuid
-	if err := vm.validator.ValidateNodeUUID(req.NodeUuid); err != nil {
+	if err := vm.currentValidator().ValidateNodeUUID(req.NodeUuid); err != nil {
+		if !isUUIDValidationError(err) {
+			return fmt.Errorf("%w: %v", ErrValidatorInternal, err)
+		}
+
 		vm.logger.WarnContext(ctx, "Invalid node UUID in request",
 			"method", method,
 			"node_uuid_sanitized", SanitizeForLogging(req.NodeUuid),
+			"peer", peerAddress(ctx),
 			"error", err.Error(),
 		)
 
@@ -76,6 +209,7 @@ func (vm *ValidationMiddleware) validateKMSRequest(ctx context.Context, req *kms
 		vm.logger.WarnContext(ctx, "Invalid request data",
 			"method", method,
 			"node_uuid_sanitized", SanitizeForLogging(req.NodeUuid),
+			"peer", peerAddress(ctx),
 			"error", err.Error(),
 		)
 
@@ -91,24 +225,112 @@ func (vm *ValidationMiddleware) validateKMSRequest(ctx context.Context, req *kms
 	return nil
 }
 
+// currentValidator returns the UUID validator in effect
+func (vm *ValidationMiddleware) currentValidator() *UUIDValidator {
+	vm.validatorMu.RLock()
+	defer vm.validatorMu.RUnlock()
+	return vm.validator
+}
+
+// ReloadConfig rebuilds the UUID validator from config, so UUID mode, version and entropy
+// settings take effect for the next request without a restart. Enabling or disabling
+// validation and the size and method limits still require a restart.
+func (vm *ValidationMiddleware) ReloadConfig(config *ValidationConfig) {
+	validator := newUUIDValidatorFromConfig(config)
+
+	vm.validatorMu.Lock()
+	vm.validator = validator
+	if vm.config != nil {
+		vm.config = reloadedConfig(vm.config, config)
+	}
+	vm.validatorMu.Unlock()
+
+	entropyCheckEnabled.Set(gaugeBool(config.EntropyCheckActive()))
+
+	vm.logger.Info("Validation config reloaded",
+		"uuidMode", config.UUIDValidationMode,
+		"requireUUIDv4", config.RequireUUIDv4,
+		"checkEntropy", config.CheckEntropy,
+		"entropyLevel", config.EntropyLevel,
+		"minUniqueChars", config.MinUniqueChars,
+		"entropyExemptUUIDs", len(config.EntropyExemptUUIDs))
+}
+
+// reloadedConfig returns current with the UUID settings of reloaded, the ones ReloadConfig applies
+func reloadedConfig(current, reloaded *ValidationConfig) *ValidationConfig {
+	config := *current
+	config.UUIDValidationMode = reloaded.UUIDValidationMode
+	config.RequireUUIDv4 = reloaded.RequireUUIDv4
+	config.CheckEntropy = reloaded.CheckEntropy
+	config.EntropyLevel = reloaded.EntropyLevel
+	config.MaxUUIDLength = reloaded.MaxUUIDLength
+	config.MinUniqueChars = reloaded.MinUniqueChars
+	config.EntropyExemptUUIDs = append([]string(nil), reloaded.EntropyExemptUUIDs...)
+
+	return &config
+}
+
+// Config returns a copy of the config in effect, including UUID settings applied by
+// ReloadConfig, or nil when the middleware wasn't built from a config
+func (vm *ValidationMiddleware) Config() *ValidationConfig {
+	vm.validatorMu.RLock()
+	defer vm.validatorMu.RUnlock()
+
+	if vm.config == nil {
+		return nil
+	}
+
+	config := *vm.config
+	config.EntropyExemptUUIDs = append([]string(nil), vm.config.EntropyExemptUUIDs...)
+	config.MethodAllowlist = append([]string(nil), vm.config.MethodAllowlist...)
+
+	return &config
+}
+
+// peerAddress returns the remote address of the gRPC client, if known
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "<unknown>"
+	}
+
+	return p.Addr.String()
+}
+
+// sizeLimit returns the data size limit for a method and the name of the setting it came from
+func (vm *ValidationMiddleware) sizeLimit(method string) (int, string) {
+	switch {
+	case method == MethodSeal && vm.maxSealSize > 0:
+		return vm.maxSealSize, "MaxSealSize"
+	case method == MethodUnseal && vm.maxUnsealSize > 0:
+		return vm.maxUnsealSize, "MaxUnsealSize"
+	case vm.maxRequestSize > 0:
+		return vm.maxRequestSize, "MaxRequestSize"
+	default:
+		return DefaultMaxRequestSize, "MaxRequestSize"
+	}
+}
+
 // validateRequestData validates additional request data constraints
 func (vm *ValidationMiddleware) validateRequestData(req *kms.Request, method string) error {
-	// Check data size limits
-	const maxDataSize = 4 * 1024 * 1024 // 4MB limit
+	requestBytes.Observe(float64(len(req.Data)))
 
-	if len(req.Data) > maxDataSize {
-		return status.Error(codes.InvalidArgument, "request data too large")
+	// Check data size limits
+	if limit, setting := vm.sizeLimit(method); len(req.Data) > limit {
+		oversizeRejections.WithLabelValues(method).Inc()
+		return status.Errorf(codes.InvalidArgument, "request data too large: %d bytes exceeds %s of %d bytes",
+			len(req.Data), setting, limit)
 	}
 
 	// Method-specific validation
 	switch method {
-	case "/kms.KMSService/Seal":
+	case MethodSeal:
 		// For seal operations, ensure we have data to encrypt
 		if len(req.Data) == 0 {
 			return status.Error(codes.InvalidArgument, "seal operation requires data")
 		}
 
-	case "/kms.KMSService/Unseal":
+	case MethodUnseal:
 		// For unseal operations, ensure we have ciphertext to decrypt
 		if len(req.Data) == 0 {
 			return status.Error(codes.InvalidArgument, "unseal operation requires ciphertext")
@@ -126,13 +348,13 @@ func (vm *ValidationMiddleware) validateRequestData(req *kms.Request, method str
 
 // GetValidationStats returns validation statistics
 func (vm *ValidationMiddleware) GetValidationStats() (success, failures int64) {
-	return vm.validationSuccess, vm.validationFailures
+	return atomic.LoadInt64(&vm.validationSuccess), atomic.LoadInt64(&vm.validationFailures)
 }
 
 // ResetValidationStats resets validation statistics
 func (vm *ValidationMiddleware) ResetValidationStats() {
-	vm.validationFailures = 0
-	vm.validationSuccess = 0
+	atomic.StoreInt64(&vm.validationFailures, 0)
+	atomic.StoreInt64(&vm.validationSuccess, 0)
 }
 
 // ValidationConfig holds configuration for the validation middleware
@@ -146,10 +368,31 @@ type ValidationConfig struct {
 	// UUID validation settings
 	RequireUUIDv4 bool
 	CheckEntropy  bool
+	EntropyLevel  EntropyLevel
 	MaxUUIDLength int
 
-	// Request size limits
+	// MinUniqueChars is the fewest distinct hex digits an entropy-checked UUID may contain
+	MinUniqueChars int
+
+	// EntropyExemptUUIDs lists known node UUIDs that skip the entropy checks, for legacy
+	// nodes whose legitimate UUIDs look predictable. All other UUIDs are still checked.
+	EntropyExemptUUIDs []string
+
+	// Request size limits; MaxSealSize and MaxUnsealSize fall back to MaxRequestSize when zero
 	MaxRequestSize int
+	MaxSealSize    int
+	MaxUnsealSize  int
+
+	// MethodAllowlist lists the gRPC methods clients may call (empty allows all)
+	MethodAllowlist []string
+
+	// FailOpen lets a request through with a warning when the validator fails internally, as
+	// opposed to rejecting a request that fails validation. The default (false) fails closed.
+	FailOpen bool
+
+	// SelfTestUUID is the node UUID reserved for an internal self-test. Client requests using
+	// it are rejected.
+	SelfTestUUID string
 
 	// Logging settings
 	LogSuccessfulValidation bool
@@ -163,27 +406,100 @@ func DefaultValidationConfig() *ValidationConfig {
 		UUIDValidationMode:      ValidationModeStrict, // Default to strict RFC 4122
 		RequireUUIDv4:           true,
 		CheckEntropy:            true,
+		EntropyLevel:            EntropyLevelBasic,
 		MaxUUIDLength:           36,
-		MaxRequestSize:          4 * 1024 * 1024, // 4MB
-		LogSuccessfulValidation: false,           // Too verbose for production
+		MinUniqueChars:          DefaultMinUniqueChars,
+		MaxRequestSize:          DefaultMaxRequestSize,
+		LogSuccessfulValidation: false, // Too verbose for production
 		LogFailedValidation:     true,
+		MethodAllowlist:         DefaultMethodAllowlist(),
+		SelfTestUUID:            DefaultSelfTestUUID,
+	}
+}
+
+// Validate checks settings that would otherwise make every request fail in confusing ways:
+// the UUID length limit must be positive, size limits must not be negative, the entropy
+// diversity threshold must be between 0 and 16 and entropy-exempt UUIDs must be well-formed.
+// A disabled config is always valid.
+func (c *ValidationConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.MaxUUIDLength <= 0 {
+		return fmt.Errorf("max UUID length must be positive, got %d", c.MaxUUIDLength)
+	}
+
+	if c.MaxRequestSize < 0 || c.MaxSealSize < 0 || c.MaxUnsealSize < 0 {
+		return fmt.Errorf("request size limits must not be negative (request %d, seal %d, unseal %d)",
+			c.MaxRequestSize, c.MaxSealSize, c.MaxUnsealSize)
+	}
+
+	// A UUID has 16 possible hex digits, so a higher threshold would reject every UUID
+	if c.MinUniqueChars < 0 || c.MinUniqueChars > 16 {
+		return fmt.Errorf("entropy min unique chars must be between 0 and 16, got %d", c.MinUniqueChars)
+	}
+
+	format := &UUIDValidator{ValidationMode: ValidationModeRelaxed, AllowHyphens: true, MaxLength: 36}
+	for _, uuid := range c.EntropyExemptUUIDs {
+		// Exemptions match with or without hyphens, so check them in hyphenated form
+		if err := format.ValidateNodeUUID(hyphenateUUID(entropyExemptionKey(uuid))); err != nil {
+			return fmt.Errorf("invalid entropy-exempt UUID %q: %w", uuid, err)
+		}
 	}
+
+	return nil
+}
+
+// EntropyCheckActive reports whether UUID entropy is actually checked, which requires
+// validation to be enabled in strict mode with the entropy check on
+func (c *ValidationConfig) EntropyCheckActive() bool {
+	return c.Enabled && c.CheckEntropy && c.UUIDValidationMode != ValidationModeRelaxed
 }
 
 // NewValidationMiddlewareFromConfig creates validation middleware from config
 func NewValidationMiddlewareFromConfig(config *ValidationConfig, logger *slog.Logger) *ValidationMiddleware {
+	entropyCheckEnabled.Set(gaugeBool(config.EntropyCheckActive()))
+
 	if !config.Enabled {
 		return nil
 	}
 
-	validator := &UUIDValidator{
-		ValidationMode:  config.UUIDValidationMode,
-		RequireVersion4: config.RequireUUIDv4,
-		CheckEntropy:    config.CheckEntropy,
-		AllowHyphens:    true,
-		MaxLength:       config.MaxUUIDLength,
-		MinEntropyBits:  122, // Standard for UUID v4
+	middleware := NewValidationMiddleware(newUUIDValidatorFromConfig(config), logger)
+	middleware.allowedMethods = methodSet(config.MethodAllowlist)
+	middleware.maxRequestSize = config.MaxRequestSize
+	middleware.maxSealSize = config.MaxSealSize
+	middleware.maxUnsealSize = config.MaxUnsealSize
+	middleware.selfTestUUID = config.SelfTestUUID
+	middleware.failOpen = config.FailOpen
+	middleware.config = reloadedConfig(config, config)
+	middleware.config.MethodAllowlist = append([]string(nil), config.MethodAllowlist...)
+
+	return middleware
+}
+
+// NodeUUIDValidator returns the validator node UUIDs from other sources (such as the preload
+// file) are checked with: the request validator, or a format-only check when validation is
+// disabled
+func (c *ValidationConfig) NodeUUIDValidator() *UUIDValidator {
+	if !c.Enabled {
+		return &UUIDValidator{ValidationMode: ValidationModeRelaxed, AllowHyphens: true, MaxLength: 36}
 	}
 
-	return NewValidationMiddleware(validator, logger)
+	return newUUIDValidatorFromConfig(c)
+}
+
+// newUUIDValidatorFromConfig creates the UUID validator described by config
+func newUUIDValidatorFromConfig(config *ValidationConfig) *UUIDValidator {
+	return &UUIDValidator{
+		ValidationMode:    config.UUIDValidationMode,
+		RequireVersion4:   config.RequireUUIDv4,
+		CheckEntropy:      config.CheckEntropy,
+		EntropyLevel:      config.EntropyLevel,
+		MinUniqueChars:    config.MinUniqueChars,
+		EntropyExemptions: NewEntropyExemptions(config.EntropyExemptUUIDs),
+		AllowHyphens:      true,
+		MaxLength:         config.MaxUUIDLength,
+		MinEntropyBits:    122, // Standard for UUID v4
+	}
 }
@@ -3,12 +3,18 @@ package validation
 import (
 	"context"
 	"log/slog"
+	"net"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/siderolabs/kms-client/api/kms"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -105,6 +111,78 @@ func TestValidationMiddleware_UnaryServerInterceptor(t *testing.T) {
 	}
 }
 
+func TestValidationMiddleware_MethodAllowlist(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+
+	request := &kms.Request{
+		NodeUuid: "550e8400-e29b-41d4-a716-446655440000",
+		Data:     []byte("vault:v1:data"),
+	}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		method    string
+		wantErr   bool
+	}{
+		{
+			name:      "seal allowed by default",
+			allowlist: DefaultMethodAllowlist(),
+			method:    MethodSeal,
+			wantErr:   false,
+		},
+		{
+			name:      "unseal allowed by default",
+			allowlist: DefaultMethodAllowlist(),
+			method:    MethodUnseal,
+			wantErr:   false,
+		},
+		{
+			name:      "unknown method rejected by default",
+			allowlist: DefaultMethodAllowlist(),
+			method:    "/kms.KMSService/Rotate",
+			wantErr:   true,
+		},
+		{
+			name:      "method missing from custom allowlist",
+			allowlist: []string{MethodSeal},
+			method:    MethodUnseal,
+			wantErr:   true,
+		},
+		{
+			name:      "empty allowlist allows all",
+			allowlist: nil,
+			method:    "/kms.KMSService/Rotate",
+			wantErr:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultValidationConfig()
+			config.CheckEntropy = false
+			config.MethodAllowlist = tt.allowlist
+
+			interceptor := NewValidationMiddlewareFromConfig(config, logger).UnaryServerInterceptor()
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+
+			_, err := interceptor(context.Background(), request, info, handler)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("interceptor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr && status.Code(err) != codes.PermissionDenied {
+				t.Errorf("expected status code %v, got %v", codes.PermissionDenied, status.Code(err))
+			}
+		})
+	}
+}
+
 func TestValidationMiddleware_RequestDataValidation(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	validator := NewUUIDValidator()
@@ -176,6 +254,67 @@ func TestValidationMiddleware_RequestDataValidation(t *testing.T) {
 	}
 }
 
+func TestValidationMiddleware_SizeMetrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	middleware := NewValidationMiddleware(nil, logger)
+
+	const method = "/kms.KMSService/Seal"
+	observedBefore := histogramCount(t, requestBytes)
+	rejectedBefore := testutil.ToFloat64(oversizeRejections.WithLabelValues(method))
+
+	ok := &kms.Request{Data: []byte("small payload")}
+	if err := middleware.validateRequestData(ok, method); err != nil {
+		t.Fatalf("validateRequestData() unexpected error = %v", err)
+	}
+
+	oversized := &kms.Request{Data: make([]byte, 4*1024*1024+1)}
+	if err := middleware.validateRequestData(oversized, method); err == nil {
+		t.Fatal("validateRequestData() expected error for oversized request")
+	}
+
+	if got := histogramCount(t, requestBytes) - observedBefore; got != 2 {
+		t.Errorf("Expected 2 size observations, got %d", got)
+	}
+
+	if got := testutil.ToFloat64(oversizeRejections.WithLabelValues(method)) - rejectedBefore; got != 1 {
+		t.Errorf("Expected 1 oversize rejection, got %v", got)
+	}
+}
+
+func TestPeerAddress(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.42"), Port: 50000}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "peer present",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{Addr: addr}),
+			want: "10.0.0.42:50000",
+		},
+		{
+			name: "no peer in context",
+			ctx:  context.Background(),
+			want: "<unknown>",
+		},
+		{
+			name: "peer without address",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{}),
+			want: "<unknown>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peerAddress(tt.ctx); got != tt.want {
+				t.Errorf("peerAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidationMiddleware_Stats(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	middleware := NewValidationMiddleware(nil, logger)
@@ -212,6 +351,46 @@ func TestDefaultValidationConfig(t *testing.T) {
 	if config.MaxUUIDLength != 36 {
 		t.Errorf("Default max UUID length should be 36, got %d", config.MaxUUIDLength)
 	}
+
+	if config.FailOpen {
+		t.Error("Default config should fail closed on internal validator errors")
+	}
+}
+
+func TestValidationConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*ValidationConfig)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(c *ValidationConfig) {}},
+		{name: "exempt UUIDs", modify: func(c *ValidationConfig) { c.EntropyExemptUUIDs = []string{"00000000-0000-4000-8000-000000000001"} }},
+		{name: "hyphenless exempt UUID", modify: func(c *ValidationConfig) { c.EntropyExemptUUIDs = []string{"00000000000040008000000000000001"} }},
+		{name: "malformed exempt UUID", modify: func(c *ValidationConfig) { c.EntropyExemptUUIDs = []string{"node-1"} }, wantErr: true},
+		{name: "negative seal size", modify: func(c *ValidationConfig) { c.MaxSealSize = -1 }, wantErr: true},
+		{name: "zero UUID length", modify: func(c *ValidationConfig) { c.MaxUUIDLength = 0 }, wantErr: true},
+		{name: "zero min unique chars", modify: func(c *ValidationConfig) { c.MinUniqueChars = 0 }},
+		{name: "negative min unique chars", modify: func(c *ValidationConfig) { c.MinUniqueChars = -1 }, wantErr: true},
+		{name: "min unique chars above 16", modify: func(c *ValidationConfig) { c.MinUniqueChars = 17 }, wantErr: true},
+		{
+			name: "disabled config is not checked",
+			modify: func(c *ValidationConfig) {
+				c.Enabled = false
+				c.MaxSealSize = -1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultValidationConfig()
+			tt.modify(config)
+
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
 }
 
 func TestNewValidationMiddlewareFromConfig(t *testing.T) {
@@ -231,3 +410,175 @@ func TestNewValidationMiddlewareFromConfig(t *testing.T) {
 		t.Error("Middleware should not be nil when validation is enabled")
 	}
 }
+
+func TestValidationConfig_EntropyCheckGauge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tests := []struct {
+		name   string
+		modify func(*ValidationConfig)
+		want   float64
+	}{
+		{name: "default", modify: func(c *ValidationConfig) {}, want: 1},
+		{name: "entropy check disabled", modify: func(c *ValidationConfig) { c.CheckEntropy = false }, want: 0},
+		{name: "relaxed mode skips entropy", modify: func(c *ValidationConfig) { c.UUIDValidationMode = ValidationModeRelaxed }, want: 0},
+		{name: "validation disabled", modify: func(c *ValidationConfig) { c.Enabled = false }, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultValidationConfig()
+			tt.modify(config)
+
+			NewValidationMiddlewareFromConfig(config, logger)
+
+			if got := testutil.ToFloat64(entropyCheckEnabled); got != tt.want {
+				t.Errorf("kms_entropy_check_enabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationMiddleware_PerMethodSizeLimits(t *testing.T) {
+	config := DefaultValidationConfig()
+	config.MaxRequestSize = 1024
+	config.MaxUnsealSize = 64
+
+	middleware := NewValidationMiddlewareFromConfig(config, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	tests := []struct {
+		name        string
+		method      string
+		size        int
+		wantSetting string
+	}{
+		{name: "seal within request limit", method: MethodSeal, size: 1024},
+		{name: "seal falls back to request limit", method: MethodSeal, size: 1025, wantSetting: "MaxRequestSize"},
+		{name: "unseal within unseal limit", method: MethodUnseal, size: 64},
+		{name: "unseal over unseal limit", method: MethodUnseal, size: 65, wantSetting: "MaxUnsealSize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := middleware.validateRequestData(&kms.Request{Data: make([]byte, tt.size)}, tt.method)
+
+			if tt.wantSetting == "" {
+				if err != nil {
+					t.Errorf("validateRequestData() unexpected error = %v", err)
+				}
+				return
+			}
+
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tt.wantSetting) {
+				t.Errorf("validateRequestData() error = %v, want InvalidArgument mentioning %s", err, tt.wantSetting)
+			}
+		})
+	}
+
+	config.MaxSealSize = 2048
+	middleware = NewValidationMiddlewareFromConfig(config, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err := middleware.validateRequestData(&kms.Request{Data: make([]byte, 2049)}, MethodSeal); err == nil || !strings.Contains(err.Error(), "MaxSealSize") {
+		t.Errorf("validateRequestData() error = %v, want MaxSealSize rejection", err)
+	}
+}
+
+func TestValidationMiddleware_ReloadConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	middleware := NewValidationMiddlewareFromConfig(DefaultValidationConfig(), logger)
+	interceptor := middleware.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: MethodSeal}
+
+	// A v1 UUID is rejected while UUID v4 is required
+	v1Request := &kms.Request{NodeUuid: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", Data: []byte("data")}
+	if _, err := interceptor(context.Background(), v1Request, info, handler); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected v1 UUID to be rejected before reload, got %v", err)
+	}
+
+	loosened := DefaultValidationConfig()
+	loosened.RequireUUIDv4 = false
+	loosened.CheckEntropy = false
+	middleware.ReloadConfig(loosened)
+
+	if _, err := interceptor(context.Background(), v1Request, info, handler); err != nil {
+		t.Errorf("Expected v1 UUID to be accepted after reload, got %v", err)
+	}
+	if got := testutil.ToFloat64(entropyCheckEnabled); got != 0 {
+		t.Errorf("kms_entropy_check_enabled = %v after disabling entropy, want 0", got)
+	}
+
+	middleware.ReloadConfig(DefaultValidationConfig())
+
+	if _, err := interceptor(context.Background(), v1Request, info, handler); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected v1 UUID to be rejected after tightening, got %v", err)
+	}
+}
+
+func TestValidationMiddleware_InternalError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	info := &grpc.UnaryServerInfo{FullMethod: MethodSeal}
+	request := &kms.Request{NodeUuid: "550e8400-e29b-41d4-a716-446655440000", Data: []byte("data")}
+
+	tests := []struct {
+		name       string
+		failOpen   bool
+		wantCode   codes.Code
+		wantCalled bool
+		wantResult string
+	}{
+		// The security-critical default: an internal error never lets a request through
+		{name: "fail closed", failOpen: false, wantCode: codes.Internal, wantResult: "blocked"},
+		{name: "fail open", failOpen: true, wantCode: codes.OK, wantCalled: true, wantResult: "allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultValidationConfig()
+			config.FailOpen = tt.failOpen
+			middleware := NewValidationMiddlewareFromConfig(config, logger)
+
+			// A missing validator makes validation panic
+			middleware.validator = nil
+
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return req, nil
+			}
+
+			before := testutil.ToFloat64(validatorInternalErrors.WithLabelValues(tt.wantResult))
+
+			_, err := middleware.UnaryServerInterceptor()(context.Background(), request, info, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v (err %v)", code, tt.wantCode, err)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if got := testutil.ToFloat64(validatorInternalErrors.WithLabelValues(tt.wantResult)) - before; got != 1 {
+				t.Errorf("kms_validation_internal_errors_total{result=%q} increased by %v, want 1", tt.wantResult, got)
+			}
+
+			// Requests that fail validation are rejected in both modes
+			middleware.validator = NewUUIDValidator()
+			invalid := &kms.Request{NodeUuid: "not-a-uuid", Data: []byte("data")}
+			if _, err := middleware.UnaryServerInterceptor()(context.Background(), invalid, info, handler); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("invalid UUID code = %v, want InvalidArgument", status.Code(err))
+			}
+		})
+	}
+}
+
+// histogramCount returns the number of observations recorded by h
+func histogramCount(t *testing.T, h prometheus.Histogram) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
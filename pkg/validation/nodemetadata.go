@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NodeUUIDMetadataKey is the metadata header some clients use to send the node UUID
+const NodeUUIDMetadataKey = "x-node-uuid"
+
+// NodeUUIDMetadata reconciles the node UUID in the request body with the x-node-uuid
+// metadata header. When only one is present it is used; when both are present they must
+// match. The header is stripped before the request continues.
+type NodeUUIDMetadata struct {
+	logger *slog.Logger
+}
+
+// NewNodeUUIDMetadata creates a new node UUID metadata reconciler
+func NewNodeUUIDMetadata(logger *slog.Logger) *NodeUUIDMetadata {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &NodeUUIDMetadata{logger: logger.With("component", "node-uuid-metadata")}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor reconciling the body and
+// metadata node UUIDs. It must run before validation so the resulting UUID is validated.
+func (n *NodeUUIDMetadata) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		kmsReq, ok := req.(*kms.Request)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		values := md.Get(NodeUUIDMetadataKey)
+		if len(values) == 0 {
+			return handler(ctx, req)
+		}
+
+		if len(values) > 1 {
+			return nil, status.Errorf(codes.InvalidArgument, "%s metadata must be set at most once", NodeUUIDMetadataKey)
+		}
+
+		header := values[0]
+		switch {
+		case kmsReq.NodeUuid == "":
+			kmsReq.NodeUuid = header
+		case !sameNodeUUID(kmsReq.NodeUuid, header):
+			nodeUUIDMismatches.WithLabelValues(info.FullMethod).Inc()
+			n.logger.WarnContext(ctx, "Node UUID in request body does not match metadata",
+				"method", info.FullMethod,
+				"node_uuid_sanitized", SanitizeForLogging(kmsReq.NodeUuid),
+				"metadata_uuid_sanitized", SanitizeForLogging(header),
+				"peer", peerAddress(ctx),
+			)
+
+			return nil, status.Errorf(codes.InvalidArgument, "node UUID in request does not match %s metadata", NodeUUIDMetadataKey)
+		}
+
+		md = md.Copy()
+		md.Delete(NodeUUIDMetadataKey)
+
+		return handler(metadata.NewIncomingContext(ctx, md), req)
+	}
+}
+
+// sameNodeUUID compares two UUIDs ignoring case and hyphens
+func sameNodeUUID(a, b string) bool {
+	normalize := func(uuid string) string {
+		return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(uuid), "-", ""))
+	}
+
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeUUIDMetadata_UnaryServerInterceptor(t *testing.T) {
+	const node = "550e8400-e29b-41d4-a716-446655440000"
+
+	interceptor := NewNodeUUIDMetadata(slog.New(slog.NewTextHandler(os.Stderr, nil))).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: MethodUnseal}
+
+	tests := []struct {
+		name     string
+		body     string
+		md       metadata.MD
+		wantCode codes.Code
+		wantUUID string
+	}{
+		{
+			name:     "body only",
+			body:     node,
+			wantCode: codes.OK,
+			wantUUID: node,
+		},
+		{
+			name:     "metadata only",
+			md:       metadata.Pairs(NodeUUIDMetadataKey, node),
+			wantCode: codes.OK,
+			wantUUID: node,
+		},
+		{
+			name:     "matching body and metadata",
+			body:     node,
+			md:       metadata.Pairs(NodeUUIDMetadataKey, "550E8400E29B41D4A716446655440000"),
+			wantCode: codes.OK,
+			wantUUID: node,
+		},
+		{
+			name:     "mismatch rejected",
+			body:     node,
+			md:       metadata.Pairs(NodeUUIDMetadataKey, "6ba7b810-9dad-41d1-80b4-00c04fd430c8"),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "repeated metadata rejected",
+			md:       metadata.Pairs(NodeUUIDMetadataKey, node, NodeUUIDMetadataKey, node),
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			var gotUUID string
+			var headerSeen bool
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotUUID = req.(*kms.Request).NodeUuid
+				md, _ := metadata.FromIncomingContext(ctx)
+				headerSeen = len(md.Get(NodeUUIDMetadataKey)) > 0
+				return &kms.Response{}, nil
+			}
+
+			_, err := interceptor(ctx, &kms.Request{NodeUuid: tt.body}, info, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("interceptor code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if gotUUID != tt.wantUUID {
+				t.Errorf("handler NodeUuid = %q, want %q", gotUUID, tt.wantUUID)
+			}
+			if headerSeen {
+				t.Errorf("%s metadata reached the handler", NodeUUIDMetadataKey)
+			}
+		})
+	}
+}
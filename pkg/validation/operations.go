@@ -0,0 +1,204 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OperationPolicyWildcard is the policy entry applied to identities without their own entry
+const OperationPolicyWildcard = "*"
+
+// OperationPolicy restricts the KMS operations each mTLS client identity may call.
+//
+// The policy file is a JSON object mapping identities to operation names, e.g.
+//
+//	{"backup-node": ["Unseal"], "*": ["Seal", "Unseal"]}
+//
+// Identities without an entry fall back to the "*" entry, or are allowed everything
+// when there is none. Requests without a verified client certificate are not checked.
+type OperationPolicy struct {
+	path   string
+	field  IdentityField
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	rules   map[string]map[string]bool
+	modTime time.Time
+}
+
+// NewOperationPolicy loads an operation policy from path. Client identities are read from
+// the given certificate field.
+func NewOperationPolicy(path string, field IdentityField, logger *slog.Logger) (*OperationPolicy, error) {
+	switch field {
+	case IdentityFieldCommonName, IdentityFieldDNSSAN, IdentityFieldURISAN:
+	default:
+		return nil, fmt.Errorf("unsupported identity field: %q", field)
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	p := &OperationPolicy{
+		path:   path,
+		field:  field,
+		logger: logger.With("component", "operation-policy"),
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Reload re-reads the policy file. On error the current rules are kept.
+func (p *OperationPolicy) Reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat operation policy: %w", err)
+	}
+
+	rules, err := loadOperationRules(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.rules = rules
+	p.modTime = info.ModTime()
+	p.mu.Unlock()
+
+	p.logger.Info("Operation policy loaded", "path", p.path, "identities", len(rules))
+	return nil
+}
+
+// Watch reloads the policy whenever the file's modification time changes, until ctx is done
+func (p *OperationPolicy) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			info, err := os.Stat(p.path)
+			if err != nil {
+				p.logger.Warn("Failed to stat operation policy, keeping current rules", "path", p.path, "error", err)
+				continue
+			}
+
+			p.mu.RLock()
+			unchanged := info.ModTime().Equal(p.modTime)
+			p.mu.RUnlock()
+
+			if unchanged {
+				continue
+			}
+
+			if err := p.Reload(); err != nil {
+				p.logger.Warn("Failed to reload operation policy, keeping current rules", "path", p.path, "error", err)
+			}
+		}
+	}
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor enforcing the policy
+func (p *OperationPolicy) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		cert := verifiedClientCert(ctx)
+		if cert == nil {
+			return handler(ctx, req)
+		}
+
+		operation := path.Base(info.FullMethod)
+		identities := certIdentities(cert, p.field)
+
+		if !p.Allowed(identities, operation) {
+			operationDenials.WithLabelValues(info.FullMethod).Inc()
+			p.logger.WarnContext(ctx, "Operation not allowed for client identity",
+				"method", info.FullMethod,
+				"peer", peerAddress(ctx),
+				"field", p.field,
+				"subject", cert.Subject.String(),
+			)
+
+			return nil, status.Errorf(codes.PermissionDenied, "operation %s not allowed for this client", operation)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Allowed reports whether any of the identities may perform the operation
+func (p *OperationPolicy) Allowed(identities []string, operation string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	operation = strings.ToLower(operation)
+	matched := false
+
+	for _, identity := range identities {
+		ops, ok := p.rules[strings.ToLower(identity)]
+		if !ok {
+			continue
+		}
+
+		matched = true
+		if ops[operation] {
+			return true
+		}
+	}
+
+	if matched {
+		return false
+	}
+
+	if ops, ok := p.rules[OperationPolicyWildcard]; ok {
+		return ops[operation]
+	}
+
+	return true
+}
+
+// loadOperationRules parses an operation policy file into lowercased identity and operation sets
+func loadOperationRules(path string) (map[string]map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation policy: %w", err)
+	}
+
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse operation policy %s: %w", path, err)
+	}
+
+	rules := make(map[string]map[string]bool, len(raw))
+	for identity, operations := range raw {
+		ops := make(map[string]bool, len(operations))
+		for _, operation := range operations {
+			ops[strings.ToLower(strings.TrimSpace(operation))] = true
+		}
+		rules[strings.ToLower(strings.TrimSpace(identity))] = ops
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,132 @@
+package validation
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func writeOperationPolicy(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOperationPolicy_UnaryServerInterceptor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeOperationPolicy(t, path, `{"backup-node": ["Unseal"], "Admin": ["seal", "unseal"], "*": ["Seal"]}`)
+
+	policy, err := NewOperationPolicy(path, IdentityFieldCommonName, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withCN := func(cn string) context.Context {
+		return contextWithClientCert(&x509.Certificate{Subject: pkix.Name{CommonName: cn}})
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "listed identity allowed", ctx: withCN("backup-node"), method: "/kms.KMSService/Unseal", wantCode: codes.OK},
+		{name: "listed identity denied", ctx: withCN("backup-node"), method: "/kms.KMSService/Seal", wantCode: codes.PermissionDenied},
+		{name: "identity match is case-insensitive", ctx: withCN("admin"), method: "/kms.KMSService/Seal", wantCode: codes.OK},
+		{name: "unlisted identity uses wildcard", ctx: withCN("worker"), method: "/kms.KMSService/Seal", wantCode: codes.OK},
+		{name: "wildcard denies unlisted operation", ctx: withCN("worker"), method: "/kms.KMSService/Unseal", wantCode: codes.PermissionDenied},
+		{name: "no client certificate passes through", ctx: context.Background(), method: "/kms.KMSService/Seal", wantCode: codes.OK},
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.UnaryServerInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestOperationPolicy_Allowed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeOperationPolicy(t, path, `{"backup-node": ["Unseal"]}`)
+
+	policy, err := NewOperationPolicy(path, IdentityFieldDNSSAN, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a wildcard entry, unlisted identities keep full access
+	if !policy.Allowed([]string{"worker"}, "Seal") {
+		t.Error("unlisted identity should be allowed without a wildcard entry")
+	}
+
+	// Any matching identity granting the operation is enough
+	if !policy.Allowed([]string{"backup-node", "other"}, "Unseal") {
+		t.Error("expected Unseal to be allowed for backup-node")
+	}
+	if policy.Allowed([]string{"backup-node", "other"}, "Seal") {
+		t.Error("expected Seal to be denied for backup-node")
+	}
+}
+
+func TestOperationPolicy_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writeOperationPolicy(t, path, `{"node": ["Seal", "Unseal"]}`)
+
+	policy, err := NewOperationPolicy(path, IdentityFieldCommonName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go policy.Watch(ctx, 10*time.Millisecond)
+
+	// Bump the modification time explicitly so coarse filesystem timestamps still change
+	writeOperationPolicy(t, path, `{"node": ["Unseal"]}`)
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for policy.Allowed([]string{"node"}, "Seal") {
+		if time.Now().After(deadline) {
+			t.Fatal("policy change was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// An invalid file keeps the last good rules
+	writeOperationPolicy(t, path, `not json`)
+	if err := policy.Reload(); err == nil {
+		t.Error("Reload() of invalid policy should fail")
+	}
+	if !policy.Allowed([]string{"node"}, "Unseal") {
+		t.Error("invalid reload should keep the previous rules")
+	}
+
+	if _, err := NewOperationPolicy(path, IdentityFieldCommonName, nil); err == nil {
+		t.Error("NewOperationPolicy() with invalid file should fail")
+	}
+	if _, err := NewOperationPolicy(path, "bogus", nil); err == nil {
+		t.Error("NewOperationPolicy() with unsupported field should fail")
+	}
+}
@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultSelfTestUUID is the node UUID reserved for an internal self-test. It is deliberately
+// recognizable, and its low entropy would fail regular validation.
+const DefaultSelfTestUUID = "00000000-0000-4000-8000-000000000000"
+
+// isSelfTestUUID reports whether uuid is the reserved self-test UUID, ignoring case and
+// hyphens so clients can't slip a variant of it past the reservation
+func (vm *ValidationMiddleware) isSelfTestUUID(uuid string) bool {
+	return vm.selfTestUUID != "" && sameNodeUUID(uuid, vm.selfTestUUID)
+}
+
+// rejectSelfTestUUID rejects client requests using the reserved self-test UUID
+func (vm *ValidationMiddleware) rejectSelfTestUUID(ctx context.Context, req *kms.Request, method string) error {
+	if !vm.isSelfTestUUID(req.NodeUuid) {
+		return nil
+	}
+
+	atomic.AddInt64(&vm.validationFailures, 1)
+	selfTestSpoofs.WithLabelValues(method).Inc()
+	vm.logger.WarnContext(ctx, "Rejected client request using the reserved self-test node UUID",
+		"method", method,
+		"peer", peerAddress(ctx),
+	)
+
+	return status.Error(codes.PermissionDenied, "node UUID is reserved for the internal self-test")
+}
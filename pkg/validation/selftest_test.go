@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/siderolabs/kms-client/api/kms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidationMiddleware_SelfTestUUID(t *testing.T) {
+	config := DefaultValidationConfig()
+	vm := NewValidationMiddlewareFromConfig(config, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	interceptor := vm.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: MethodSeal}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &kms.Response{}, nil
+	}
+
+	if err := NewUUIDValidator().ValidateNodeUUID(DefaultSelfTestUUID); err == nil {
+		t.Fatal("the self-test UUID passes regular validation, the reservation is not needed")
+	}
+
+	tests := []struct {
+		name     string
+		uuid     string
+		wantCode codes.Code
+	}{
+		{
+			name:     "client request rejected",
+			uuid:     DefaultSelfTestUUID,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "client variant rejected",
+			uuid:     strings.ToUpper(strings.ReplaceAll(DefaultSelfTestUUID, "-", "")),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "other UUIDs validated as usual",
+			uuid:     "12345678-1234-1234-1234-123456789012",
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, failuresBefore := vm.GetValidationStats()
+
+			_, err := interceptor(context.Background(), &kms.Request{NodeUuid: tt.uuid, Data: []byte("data")}, info, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("interceptor code = %v, want %v (err = %v)", code, tt.wantCode, err)
+			}
+
+			if _, failures := vm.GetValidationStats(); failures != failuresBefore+1 {
+				t.Errorf("validation failures = %d, want %d", failures, failuresBefore+1)
+			}
+		})
+	}
+}
+
+func TestValidationMiddleware_SelfTestUUIDDisabled(t *testing.T) {
+	config := DefaultValidationConfig()
+	config.SelfTestUUID = ""
+	vm := NewValidationMiddlewareFromConfig(config, nil)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &kms.Response{}, nil
+	}
+
+	// Without a reservation the UUID is validated like any other
+	_, err := vm.UnaryServerInterceptor()(context.Background(),
+		&kms.Request{NodeUuid: DefaultSelfTestUUID, Data: []byte("data")},
+		&grpc.UnaryServerInfo{FullMethod: MethodSeal}, handler)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("interceptor error = %v, want InvalidArgument", err)
+	}
+}
@@ -2,9 +2,11 @@ package validation
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,30 @@ const (
 	ValidationModeRelaxed ValidationMode = "relaxed"
 )
 
+// EntropyLevel defines how aggressively UUID entropy is checked
+type EntropyLevel string
+
+const (
+	// EntropyLevelBasic rejects repeating, sequential and low-diversity patterns
+	EntropyLevelBasic EntropyLevel = "basic"
+	// EntropyLevelStrict additionally rejects UUIDs whose byte distribution is far from uniform
+	EntropyLevelStrict EntropyLevel = "strict"
+)
+
+// Byte distribution thresholds for EntropyLevelStrict. Both were chosen so that random
+// v4 UUIDs essentially never trip them (well under one in a billion).
+const (
+	// maxNibbleChiSquared bounds the chi-squared statistic of the 32 hex digits over 16 values
+	maxNibbleChiSquared = 100.0
+	// minRandomByteRange is the minimum spread between the smallest and largest random byte
+	minRandomByteRange = 32
+)
+
+// DefaultMinUniqueChars is the default character diversity threshold. Random v4 UUIDs
+// have fewer than 8 distinct hex digits far less than once in a million, so lowering it
+// barely changes the false-positive rate while admitting more predictable UUIDs.
+const DefaultMinUniqueChars = 8
+
 var (
 	// ErrInvalidUUID is returned when the UUID format is invalid
 	ErrInvalidUUID = errors.New("invalid UUID format")
@@ -33,6 +59,9 @@ var (
 
 	// ErrUUIDTooLong is returned when the UUID is too long
 	ErrUUIDTooLong = errors.New("UUID too long")
+
+	// ErrValidatorInternal indicates the validator itself failed, not that the request is invalid
+	ErrValidatorInternal = errors.New("validator internal error")
 )
 
 // UUID validation patterns
@@ -61,6 +90,16 @@ type UUIDValidator struct {
 	// MinEntropyBits minimum entropy required (default: 122 bits for UUID v4)
 	MinEntropyBits int
 
+	// EntropyLevel selects the entropy checks performed (default: basic)
+	EntropyLevel EntropyLevel
+
+	// MinUniqueChars is the fewest distinct hex digits an entropy-checked UUID may contain
+	// (0 uses DefaultMinUniqueChars, values above 16 are capped)
+	MinUniqueChars int
+
+	// EntropyExemptions lists UUIDs (keyed by entropyExemptionKey) that skip the entropy checks
+	EntropyExemptions map[string]struct{}
+
 	// AllowHyphens allows UUIDs with hyphens
 	AllowHyphens bool
 
@@ -77,9 +116,21 @@ func NewUUIDValidator() *UUIDValidator {
 		MinEntropyBits:  122,                  // UUID v4 has 122 bits of entropy
 		AllowHyphens:    true,                 // Allow standard UUID format
 		MaxLength:       36,                   // Standard UUID length with hyphens
+		MinUniqueChars:  DefaultMinUniqueChars,
 	}
 }
 
+// isUUIDValidationError reports whether err is one of the UUID validation failures
+func isUUIDValidationError(err error) bool {
+	for _, target := range []error{ErrEmptyUUID, ErrUUIDTooLong, ErrInvalidUUID, ErrUUIDVersionNotSupported, ErrInsufficientEntropy} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ValidateNodeUUID validates a Talos node UUID
 func (v *UUIDValidator) ValidateNodeUUID(uuid string) error {
 	if uuid == "" {
@@ -146,14 +197,88 @@ func (v *UUIDValidator) validateEntropy(uuid string) error {
 	// Remove hyphens for analysis
 	cleanUUID := strings.ReplaceAll(uuid, "-", "")
 
+	// Known legacy UUIDs are exempt from the heuristics
+	if _, ok := v.EntropyExemptions[entropyExemptionKey(uuid)]; ok {
+		return nil
+	}
+
 	// Check for obviously non-random patterns
 	if v.hasInsufficientEntropy(cleanUUID) {
 		return fmt.Errorf("%w: UUID appears to have predictable patterns", ErrInsufficientEntropy)
 	}
 
+	// Statistical check of the byte distribution
+	if v.EntropyLevel == EntropyLevelStrict && hasSkewedByteDistribution(cleanUUID) {
+		return fmt.Errorf("%w: UUID byte distribution deviates from uniform", ErrInsufficientEntropy)
+	}
+
 	return nil
 }
 
+// entropyExemptionKey normalizes a UUID for exemption lookups (lowercase, no hyphens)
+func entropyExemptionKey(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(uuid, "-", ""))
+}
+
+// hyphenateUUID formats 32 hex digits as a hyphenated UUID, leaving any other string as is
+func hyphenateUUID(digits string) string {
+	if len(digits) != 32 {
+		return digits
+	}
+
+	return digits[:8] + "-" + digits[8:12] + "-" + digits[12:16] + "-" + digits[16:20] + "-" + digits[20:]
+}
+
+// NewEntropyExemptions builds an entropy exemption set from a list of UUIDs
+func NewEntropyExemptions(uuids []string) map[string]struct{} {
+	exemptions := make(map[string]struct{}, len(uuids))
+	for _, uuid := range uuids {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			exemptions[entropyExemptionKey(uuid)] = struct{}{}
+		}
+	}
+
+	return exemptions
+}
+
+// hasSkewedByteDistribution reports whether the UUID bytes deviate wildly from uniform:
+// either the hex digits fail a chi-squared uniformity test, or the random bytes all
+// fall in a narrow range. The version and variant bytes are excluded from the range check.
+func hasSkewedByteDistribution(cleanUUID string) bool {
+	bytes, err := hex.DecodeString(cleanUUID)
+	if err != nil || len(bytes) != 16 {
+		return false
+	}
+
+	var counts [16]int
+	for _, b := range bytes {
+		counts[b>>4]++
+		counts[b&0x0f]++
+	}
+
+	expected := float64(len(bytes)*2) / float64(len(counts))
+	chiSquared := 0.0
+	for _, count := range counts {
+		diff := float64(count) - expected
+		chiSquared += diff * diff / expected
+	}
+
+	if chiSquared > maxNibbleChiSquared {
+		return true
+	}
+
+	low, high := 255, 0
+	for i, b := range bytes {
+		if i == 6 || i == 8 {
+			continue
+		}
+		low = min(low, int(b))
+		high = max(high, int(b))
+	}
+
+	return high-low < minRandomByteRange
+}
+
 // hasInsufficientEntropy performs basic entropy checks
 func (v *UUIDValidator) hasInsufficientEntropy(cleanUUID string) bool {
 	// Check for all zeros or all same character
@@ -167,7 +292,7 @@ func (v *UUIDValidator) hasInsufficientEntropy(cleanUUID string) bool {
 	}
 
 	// Check for insufficient character diversity
-	if hasLowCharacterDiversity(cleanUUID) {
+	if hasLowCharacterDiversity(cleanUUID, v.minUniqueChars()) {
 		return true
 	}
 
@@ -207,37 +332,107 @@ func hasSequentialPattern(uuid string) bool {
 	return false
 }
 
-// hasLowCharacterDiversity checks if there are too few unique characters
-func hasLowCharacterDiversity(uuid string) bool {
+// minUniqueChars returns the character diversity threshold, defaulting when unset and
+// capped at the 16 hex digits a UUID can contain
+func (v *UUIDValidator) minUniqueChars() int {
+	if v.MinUniqueChars <= 0 {
+		return DefaultMinUniqueChars
+	}
+
+	return min(v.MinUniqueChars, 16)
+}
+
+// hasLowCharacterDiversity checks if there are fewer than minUnique unique characters
+func hasLowCharacterDiversity(uuid string, minUnique int) bool {
 	uniqueChars := make(map[rune]bool)
 	for _, char := range uuid {
 		uniqueChars[char] = true
 	}
 
 	// UUID should have reasonable character diversity
-	// For a 32-character hex string, we expect at least 8 different characters
-	return len(uniqueChars) < 8
+	return len(uniqueChars) < minUnique
 }
 
-// SanitizeForLogging sanitizes a UUID for safe logging
+// sanitizedUUIDTemplate is the masked form of a UUID. The first 6 and last 4 hex digits
+// are filled in at sanitizedPrefixLen and sanitizedSuffixAt.
+const (
+	sanitizedUUIDTemplate = "------**-****-****-**----"
+	sanitizedPrefixLen    = 6
+	sanitizedSuffixAt     = 21
+)
+
+// uuidGroupLengths are the hex digit counts of the hyphen-separated UUID groups
+var uuidGroupLengths = [...]int{8, 4, 4, 4, 12}
+
+// SanitizeForLogging sanitizes a UUID for safe logging. It runs on every request, so the
+// valid case is handled with a fixed-size buffer and a single allocation.
 func SanitizeForLogging(uuid string) string {
 	if uuid == "" {
 		return "<empty>"
 	}
 
-	// Check both strict and relaxed patterns for sanitization
-	if !uuidPattern.MatchString(uuid) && !uuidRelaxedPattern.MatchString(uuid) {
-		return fmt.Sprintf("<invalid-uuid-len-%d>", len(uuid))
+	// Accepts what uuidPattern or uuidRelaxedPattern would, collecting the 32 hex digits
+	hexDigits, ok := parseLoggableUUID(uuid)
+	if !ok {
+		var buf [40]byte
+		out := append(buf[:0], "<invalid-uuid-len-"...)
+		out = strconv.AppendInt(out, int64(len(uuid)), 10)
+		out = append(out, '>')
+		return string(out)
 	}
 
-	// Simple approach: show first 6 chars, last 4 chars, mask the middle
-	cleanUUID := strings.ReplaceAll(uuid, "-", "")
-	if len(cleanUUID) >= 32 {
-		// Format: 550e84**-****-****-**440000 (6 + 4 chars visible)
-		return fmt.Sprintf("%s**-****-****-**%s", cleanUUID[:6], cleanUUID[28:])
+	// Show first 6 chars, last 4 chars, mask the middle
+	// Format: 550e84**-****-****-**0000 (6 + 4 chars visible)
+	var out [len(sanitizedUUIDTemplate)]byte
+	copy(out[:], sanitizedUUIDTemplate)
+	copy(out[:sanitizedPrefixLen], hexDigits[:sanitizedPrefixLen])
+	copy(out[sanitizedSuffixAt:], hexDigits[28:])
+
+	return string(out[:])
+}
+
+// parseLoggableUUID reports whether uuid matches uuidPattern or uuidRelaxedPattern without
+// running either regex, and returns its 32 hex digits. Hyphens between groups are optional
+// in the strict pattern, which also constrains the version and variant digits. The relaxed
+// pattern requires every hyphen but accepts any hex digits.
+func parseLoggableUUID(uuid string) (hexDigits [32]byte, ok bool) {
+	pos, n, hyphens := 0, 0, 0
+
+	for group, length := range uuidGroupLengths {
+		if group > 0 && pos < len(uuid) && uuid[pos] == '-' {
+			pos++
+			hyphens++
+		}
+
+		if len(uuid)-pos < length {
+			return hexDigits, false
+		}
+
+		for _, c := range []byte(uuid[pos : pos+length]) {
+			if !isHexDigit(c) {
+				return hexDigits, false
+			}
+			hexDigits[n] = c
+			n++
+		}
+		pos += length
+	}
+
+	if pos != len(uuid) {
+		return hexDigits, false
 	}
 
-	return fmt.Sprintf("<malformed-uuid-len-%d>", len(uuid))
+	if hyphens == len(uuidGroupLengths)-1 {
+		return hexDigits, true // Relaxed pattern
+	}
+
+	version, variant := hexDigits[12], hexDigits[16]
+	return hexDigits, version >= '1' && version <= '5' && strings.IndexByte("89abAB", variant) >= 0
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
 }
 
 // GenerateSecureUUIDv4 generates a cryptographically secure UUID v4 for testing
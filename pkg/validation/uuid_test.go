@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -225,13 +227,44 @@ func TestHasLowCharacterDiversity(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := hasLowCharacterDiversity(tt.uuid); got != tt.want {
+			if got := hasLowCharacterDiversity(tt.uuid, DefaultMinUniqueChars); got != tt.want {
 				t.Errorf("hasLowCharacterDiversity() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestUUIDValidator_MinUniqueChars(t *testing.T) {
+	// A valid v4 UUID with only 7 distinct hex digits
+	const uuid = "1248a9c1-48a9-4c12-8a9c-1248a9c1248a"
+
+	tests := []struct {
+		name           string
+		minUniqueChars int
+		wantErr        bool
+	}{
+		{name: "unset uses default", minUniqueChars: 0, wantErr: true},
+		{name: "default", minUniqueChars: DefaultMinUniqueChars, wantErr: true},
+		{name: "lowered", minUniqueChars: 7, wantErr: false},
+		{name: "capped at 16", minUniqueChars: 100, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewUUIDValidator()
+			v.MinUniqueChars = tt.minUniqueChars
+
+			err := v.ValidateNodeUUID(uuid)
+			if tt.wantErr && !errors.Is(err, ErrInsufficientEntropy) {
+				t.Errorf("ValidateNodeUUID() error = %v, want ErrInsufficientEntropy", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidateNodeUUID() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
 func TestSanitizeForLogging(t *testing.T) {
 	tests := []struct {
 		name string
@@ -263,6 +296,31 @@ func TestSanitizeForLogging(t *testing.T) {
 			uuid: "550e8400-e29b-41d4-a716",
 			want: "<invalid-uuid-len-23>",
 		},
+		{
+			name: "31 hex digits without hyphens",
+			uuid: "550e8400e29b41d4a71644665544000",
+			want: "<invalid-uuid-len-31>",
+		},
+		{
+			name: "33 hex digits without hyphens",
+			uuid: "550e8400e29b41d4a7164466554400000",
+			want: "<invalid-uuid-len-33>",
+		},
+		{
+			name: "31 hex digits with hyphens",
+			uuid: "550e8400-e29b-41d4-a716-44665544000",
+			want: "<invalid-uuid-len-35>",
+		},
+		{
+			name: "misplaced hyphen",
+			uuid: "550e840-0e29b-41d4-a716-446655440000",
+			want: "<invalid-uuid-len-36>",
+		},
+		{
+			name: "uppercase UUID",
+			uuid: "550E8400-E29B-41D4-A716-446655440000",
+			want: "550E84**-****-****-**0000",
+		},
 	}
 
 	for _, tt := range tests {
@@ -274,6 +332,107 @@ func TestSanitizeForLogging(t *testing.T) {
 	}
 }
 
+func TestSanitizeForLoggingNeverLeaks(t *testing.T) {
+	const uuid = "550e8400-e29b-41d4-a716-446655440000"
+
+	// Every prefix and suffix of a valid UUID is either fully masked or rejected
+	for i := 0; i <= len(uuid); i++ {
+		for _, input := range []string{uuid[:i], uuid[i:]} {
+			if input == uuid {
+				continue
+			}
+
+			if got := SanitizeForLogging(input); strings.Contains(got, "e29b") || strings.Contains(got, "a716") {
+				t.Errorf("SanitizeForLogging(%q) = %q leaks the masked middle", input, got)
+			}
+		}
+	}
+}
+
+func TestSanitizeForLoggingMatchesPatterns(t *testing.T) {
+	const uuid = "550e8400-e29b-41d4-a716-446655440000"
+
+	inputs := []string{
+		uuid,
+		strings.ToUpper(uuid),
+		strings.ReplaceAll(uuid, "-", ""),
+		"550e8400-e29b-01d4-a716-446655440000", // Version 0, relaxed only
+		"550e8400e29b01d4a716446655440000",     // Version 0 without hyphens
+		"550e8400-e29b41d4-c716-446655440000",  // Missing hyphen, bad variant
+		"550e8400-e29b41d4-a716446655440000",   // Some hyphens
+		"550e8400--e29b-41d4-a716-446655440000",
+		"550e8400-e29b-41d4-a716-44665544000g",
+		"-550e8400e29b41d4a716446655440000",
+		"550e8400e29b41d4a716446655440000-",
+	}
+
+	// Every hyphen subset, and every single character replaced by a hyphen or a non-hex
+	for mask := 0; mask < 16; mask++ {
+		var b strings.Builder
+		for i, group := range strings.Split(uuid, "-") {
+			if i > 0 && mask&(1<<(i-1)) != 0 {
+				b.WriteByte('-')
+			}
+			b.WriteString(group)
+		}
+		inputs = append(inputs, b.String())
+	}
+	for i := range uuid {
+		inputs = append(inputs, uuid[:i]+"-"+uuid[i+1:], uuid[:i]+"x"+uuid[i+1:], uuid[:i], uuid[i:])
+	}
+
+	for _, input := range inputs {
+		if input == "" {
+			continue // Covered by TestSanitizeForLogging
+		}
+
+		hexDigits, ok := parseLoggableUUID(input)
+		if want := uuidPattern.MatchString(input) || uuidRelaxedPattern.MatchString(input); ok != want {
+			t.Errorf("parseLoggableUUID(%q) ok = %v, want %v", input, ok, want)
+			continue
+		}
+
+		if !ok {
+			if got, want := SanitizeForLogging(input), fmt.Sprintf("<invalid-uuid-len-%d>", len(input)); got != want {
+				t.Errorf("SanitizeForLogging(%q) = %q, want %q", input, got, want)
+			}
+			continue
+		}
+
+		clean := strings.ReplaceAll(input, "-", "")
+		if string(hexDigits[:]) != clean {
+			t.Errorf("parseLoggableUUID(%q) digits = %q, want %q", input, hexDigits, clean)
+		}
+		if got, want := SanitizeForLogging(input), fmt.Sprintf("%s**-****-****-**%s", clean[:6], clean[28:]); got != want {
+			t.Errorf("SanitizeForLogging(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSanitizeForLoggingAllocs(t *testing.T) {
+	tests := []struct {
+		name       string
+		uuid       string
+		wantAllocs float64
+	}{
+		{name: "valid", uuid: "550e8400-e29b-41d4-a716-446655440000", wantAllocs: 1},
+		{name: "valid without hyphens", uuid: "550e8400e29b41d4a716446655440000", wantAllocs: 1},
+		{name: "invalid", uuid: "not-a-uuid", wantAllocs: 1},
+		{name: "empty", uuid: "", wantAllocs: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = SanitizeForLogging(tt.uuid)
+			})
+			if allocs > tt.wantAllocs {
+				t.Errorf("SanitizeForLogging() allocations = %v, want at most %v", allocs, tt.wantAllocs)
+			}
+		})
+	}
+}
+
 func TestValidateAndNormalize(t *testing.T) {
 	validator := NewUUIDValidator()
 	validator.CheckEntropy = false // Disable for testing
@@ -438,10 +597,118 @@ func BenchmarkValidateNodeUUID(b *testing.B) {
 }
 
 func BenchmarkSanitizeForLogging(b *testing.B) {
-	uuid := "550e8400-e29b-41d4-a716-446655440000"
+	inputs := []struct {
+		name string
+		uuid string
+	}{
+		{name: "valid", uuid: "550e8400-e29b-41d4-a716-446655440000"},
+		{name: "valid without hyphens", uuid: "550e8400e29b41d4a716446655440000"},
+		{name: "invalid", uuid: "not-a-uuid"},
+		{name: "empty", uuid: ""},
+	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = SanitizeForLogging(uuid)
+	for _, input := range inputs {
+		b.Run(input.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = SanitizeForLogging(input.uuid)
+			}
+		})
+	}
+}
+
+func TestHasSkewedByteDistribution(t *testing.T) {
+	tests := []struct {
+		name string
+		uuid string
+		want bool
+	}{
+		{
+			name: "random UUID",
+			uuid: "550e8400e29b41d4a716446655440000",
+			want: false,
+		},
+		{
+			name: "bytes in a narrow range",
+			uuid: "4a5b4c5d4e5f4a4b8c4d4e5f4a5b4c5d",
+			want: true,
+		},
+		{
+			name: "one hex digit dominates",
+			uuid: "1111111111114111a1111f2e3d5c6b7a",
+			want: true,
+		},
+		{
+			name: "not hex",
+			uuid: "not-a-uuid",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasSkewedByteDistribution(tt.uuid); got != tt.want {
+				t.Errorf("hasSkewedByteDistribution() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUUIDValidator_StrictEntropyLevel(t *testing.T) {
+	basic := NewUUIDValidator()
+	strict := NewUUIDValidator()
+	strict.EntropyLevel = EntropyLevelStrict
+
+	// Passes the basic heuristics but is clearly not uniformly distributed
+	skewed := "4a5b4c5d-4e5f-4a4b-8c4d-4e5f4a5b4c5d"
+
+	if err := basic.ValidateNodeUUID(skewed); err != nil {
+		t.Errorf("basic level should accept %s, got %v", skewed, err)
+	}
+
+	if err := strict.ValidateNodeUUID(skewed); !errors.Is(err, ErrInsufficientEntropy) {
+		t.Errorf("strict level should reject %s with ErrInsufficientEntropy, got %v", skewed, err)
+	}
+
+	// Legitimate random UUIDs must not be rejected by the strict check
+	const samples = 20000
+	for i := 0; i < samples; i++ {
+		uuid, err := GenerateSecureUUIDv4()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hasSkewedByteDistribution(strings.ReplaceAll(uuid, "-", "")) {
+			t.Fatalf("byte distribution check rejected random UUID %s (sample %d of %d)", uuid, i+1, samples)
+		}
+	}
+}
+
+func TestUUIDValidator_EntropyExemptions(t *testing.T) {
+	legacy := "11111111-1111-4111-8111-111111111111"
+	other := "22222222-2222-4222-8222-222222222222"
+
+	validator := NewUUIDValidator()
+	validator.EntropyLevel = EntropyLevelStrict
+
+	if err := validator.ValidateNodeUUID(legacy); !errors.Is(err, ErrInsufficientEntropy) {
+		t.Fatalf("expected %s to fail the entropy check, got %v", legacy, err)
+	}
+
+	// Exemptions match regardless of case and hyphenation
+	validator.EntropyExemptions = NewEntropyExemptions([]string{strings.ToUpper(strings.ReplaceAll(legacy, "-", ""))})
+
+	if err := validator.ValidateNodeUUID(legacy); err != nil {
+		t.Errorf("exempt UUID %s should pass, got %v", legacy, err)
+	}
+
+	if err := validator.ValidateNodeUUID(other); !errors.Is(err, ErrInsufficientEntropy) {
+		t.Errorf("non-exempt UUID %s should still fail the entropy check, got %v", other, err)
+	}
+
+	// The exemption only skips entropy, not the format checks
+	validator.EntropyExemptions = NewEntropyExemptions([]string{"11111111-1111-1111-8111-111111111111"})
+	if err := validator.ValidateNodeUUID("11111111-1111-1111-8111-111111111111"); !errors.Is(err, ErrUUIDVersionNotSupported) {
+		t.Errorf("exempt UUID must still be UUID v4, got %v", err)
 	}
 }